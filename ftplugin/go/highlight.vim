@@ -10,3 +10,19 @@ highlight delveCallStackSign   guifg=#394d39  guibg=None
 highlight GoCoverMiss          guifg=#ff9999 guibg=None gui=None
 highlight GoCoverPartial       guifg=#fafd9b guibg=None gui=None
 highlight GoCoverHit           guifg=#acedab guibg=None gui=None
+
+highlight default link goSemanticPackage    Include
+highlight default link goSemanticType       Type
+highlight default link goSemanticInterface  Structure
+highlight default link goSemanticFunction   Function
+highlight default link goSemanticMethod     Function
+highlight default link goSemanticField      Identifier
+highlight default link goSemanticParameter  Special
+highlight default link goSemanticConstant   Constant
+highlight default link goSemanticShadowed   WarningMsg
+highlight default link goSemanticUnused     Comment
+
+highlight default link goSignatureActiveParameter Search
+
+highlight default link goSameIdsRead        Search
+highlight default link goSameIdsWrite       IncSearch
@@ -17,6 +17,9 @@ nnoremap <silent><Plug>(nvim-go-pointsto)              :<C-u>call GoGuru('points
 nnoremap <silent><Plug>(nvim-go-referrers)             :<C-u>call GoGuru('referrers')<CR>
 nnoremap <silent><Plug>(nvim-go-whicherrs)             :<C-u>call GoGuru('whicherrs')<CR>
 
+" GoHover
+nnoremap <silent><Plug>(nvim-go-hover)                 :<C-u>GoHover<CR>
+
 " GoIferr
 nnoremap <silent><Plug>(nvim-go-iferr)                 :<C-u>GoIferr<CR>
 
@@ -37,6 +40,11 @@ nnoremap <silent><Plug>(nvim-go-rename)                :<C-u>GoRename<CR>
 nnoremap <silent><Plug>(nvim-go-run)                   :<C-u>GoRun<CR>
 nnoremap <silent><Plug>(nvim-go-runlast)               :<C-u>GoRunLast<CR>
 
+" GoSameIds
+nnoremap <silent><Plug>(nvim-go-sameids-toggle)        :<C-u>GoSameIdsToggle<CR>
+nnoremap <silent><Plug>(nvim-go-sameids-next)          :<C-u>GoSameIdsNext<CR>
+nnoremap <silent><Plug>(nvim-go-sameids-prev)          :<C-u>GoSameIdsPrev<CR>
+
 " GoVet
 nnoremap <silent><Plug>(nvim-go-vet)                   :<C-u>GoVet<CR>
 
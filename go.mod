@@ -27,6 +27,7 @@ require (
 	golang.org/x/arch v0.0.0-20170711125641-f40095975f84 // indirect
 	golang.org/x/debug v0.0.0-20160621010512-fb508927b491 // indirect
 	golang.org/x/lint v0.0.0-20200302205851-738671d3881b
+	golang.org/x/mod v0.3.0
 	golang.org/x/sync v0.0.0-20201008141435-b3e1573b7520
 	golang.org/x/sys v0.0.0-20200819141100-7c7a22168250
 	golang.org/x/tools v0.0.0-20201017001424-6003fad69a88
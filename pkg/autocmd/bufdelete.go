@@ -0,0 +1,33 @@
+// Copyright 2018 The nvim-go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package autocmd
+
+import (
+	"context"
+
+	"go.uber.org/zap"
+
+	"github.com/zchee/nvim-go/pkg/logger"
+	"github.com/zchee/nvim-go/pkg/monitoring"
+)
+
+// bufDeleteEval represents the deleted buffer number and file name.
+type bufDeleteEval struct {
+	BufNr int    `eval:"str2nr(expand('<abuf>'))"`
+	File  string `eval:"expand('<afile>:p')"`
+}
+
+// BufDelete closes the gopls document of the deleted buffer, and evicts the cached type checked file of the buffer
+// on BufDelete and BufWipeout autocmd.
+func (a *Autocmd) BufDelete(pctx context.Context, eval *bufDeleteEval) {
+	ctx, span := monitoring.StartSpan(pctx, "BufDelete")
+	defer span.End()
+
+	a.cmd.EvictBuffer(eval.BufNr)
+
+	if err := a.cmd.GoplsDidClose(ctx, eval.File); err != nil {
+		logger.FromContext(ctx).Error("BufDelete", zap.Error(err))
+	}
+}
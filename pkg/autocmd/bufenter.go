@@ -15,11 +15,12 @@ import (
 	"github.com/zchee/nvim-go/pkg/monitoring"
 )
 
-// bufEnterEval represents the current buffer number, windows ID and buffer files directory.
+// bufEnterEval represents the current buffer number, windows ID, buffer files directory and buffer file name.
 type bufEnterEval struct {
 	BufNr int    `eval:"bufnr('%')"`
 	WinID int    `eval:"win_getid()"`
 	Dir   string `eval:"expand('%:p:h')"`
+	File  string `eval:"expand('%:p')"`
 
 	Cfg *config.Config
 }
@@ -41,4 +42,16 @@ func (a *Autocmd) BufEnter(pctx context.Context, eval *bufEnterEval) {
 	if eval.Dir != "" && a.buildContext.PrevDir != eval.Dir {
 		a.buildContext.SetContext(eval.Dir)
 	}
+
+	a.cmd.WarmSymbolIndex(pctx)
+	a.cmd.WarmPackages()
+	a.cmd.WarmTypeCheck(eval.File)
+
+	if err := a.cmd.GoplsSync(pctx, eval.BufNr, eval.File); err != nil {
+		logger.FromContext(ctx).Error("BufEnter", zap.Error(err))
+	}
+
+	if err := a.cmd.AnalyzeRefresh(ctx, eval.BufNr, eval.WinID); err != nil {
+		logger.FromContext(ctx).Error("BufEnter", zap.Error(err))
+	}
 }
@@ -9,9 +9,11 @@ import (
 	"path/filepath"
 
 	"github.com/neovim/go-client/nvim"
+	"go.uber.org/zap"
 
 	"github.com/zchee/nvim-go/pkg/command"
 	"github.com/zchee/nvim-go/pkg/config"
+	"github.com/zchee/nvim-go/pkg/logger"
 	"github.com/zchee/nvim-go/pkg/monitoring"
 	"github.com/zchee/nvim-go/pkg/nvimutil"
 )
@@ -28,6 +30,17 @@ func (a *Autocmd) BufWritePost(pctx context.Context, eval *bufWritePostEval) err
 
 	dir := filepath.Dir(eval.File)
 
+	if err := a.cmd.GoplsDidSave(ctx, eval.File); err != nil {
+		logger.FromContext(ctx).Error("BufWritePost", zap.Error(err))
+	}
+
+	a.wg.Add(1)
+	go func() {
+		defer a.wg.Done()
+
+		a.cmd.UpdateSymbols(ctx, eval.File)
+	}()
+
 	if config.FmtAutosave {
 		err := <-a.bufWritePreChan
 		switch e := err.(type) {
@@ -111,6 +124,15 @@ func (a *Autocmd) BufWritePost(pctx context.Context, eval *bufWritePostEval) err
 		}()
 	}
 
+	a.wg.Add(1)
+	go func() {
+		defer a.wg.Done()
+
+		if err := a.cmd.AnalyzeRefresh(ctx, a.buildContext.BufNr, a.buildContext.WinID); err != nil {
+			nvimutil.ErrorWrap(a.Nvim, err)
+		}
+	}()
+
 	if config.TestAutosave {
 		a.wg.Add(1)
 		go func() {
@@ -0,0 +1,36 @@
+// Copyright 2018 The nvim-go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package autocmd
+
+import (
+	"context"
+
+	"go.uber.org/zap"
+
+	"github.com/zchee/nvim-go/pkg/logger"
+	"github.com/zchee/nvim-go/pkg/monitoring"
+)
+
+// completedItem represents a v:completed_item.
+type completedItem struct {
+	Word     string      `msgpack:"word"`
+	UserData interface{} `msgpack:"user_data"`
+}
+
+// completeDoneEval represents the current buffer and the completed item.
+type completeDoneEval struct {
+	BufNr int           `eval:"bufnr('%')"`
+	Item  completedItem `eval:"v:completed_item"`
+}
+
+// CompleteDone adds the import declaration of the completed unimported package symbol on CompleteDone autocmd.
+func (a *Autocmd) CompleteDone(pctx context.Context, eval *completeDoneEval) {
+	ctx, span := monitoring.StartSpan(pctx, "CompleteDone")
+	defer span.End()
+
+	if err := a.cmd.AutoImport(ctx, eval.BufNr, eval.Item.UserData); err != nil {
+		logger.FromContext(ctx).Error("CompleteDone", zap.Error(err))
+	}
+}
@@ -0,0 +1,49 @@
+// Copyright 2018 The nvim-go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package autocmd
+
+import (
+	"context"
+
+	"go.uber.org/zap"
+
+	"github.com/zchee/nvim-go/pkg/command"
+	"github.com/zchee/nvim-go/pkg/logger"
+	"github.com/zchee/nvim-go/pkg/monitoring"
+)
+
+// cursorHoldEval represents the cursor position of the current buffer.
+type cursorHoldEval struct {
+	Cwd      string `eval:"getcwd()"`
+	File     string `eval:"expand('%:p')"`
+	BufNr    int    `eval:"bufnr('%')"`
+	WinID    int    `eval:"win_getid()"`
+	Modified int    `eval:"&modified"`
+	Tick     int    `eval:"b:changedtick"`
+	Offset   int    `eval:"line2byte(line('.')) + (col('.')-2)"`
+}
+
+// CursorHold highlights the references of the identifier under the cursor, and shows the hover floating window of it on CursorHold autocmd.
+func (a *Autocmd) CursorHold(pctx context.Context, eval *cursorHoldEval) {
+	ctx, span := monitoring.StartSpan(pctx, "CursorHold")
+	defer span.End()
+
+	err := a.cmd.SameIdsAuto(ctx, eval.File, eval.BufNr, eval.Tick, eval.Offset)
+	if err != nil {
+		logger.FromContext(ctx).Error("CursorHold", zap.Error(err))
+	}
+
+	err = a.cmd.HoverAuto(ctx, &command.CmdHoverEval{
+		Cwd:      eval.Cwd,
+		File:     eval.File,
+		BufNr:    eval.BufNr,
+		WinID:    eval.WinID,
+		Modified: eval.Modified,
+		Offset:   eval.Offset,
+	})
+	if err != nil {
+		logger.FromContext(ctx).Error("CursorHold", zap.Error(err))
+	}
+}
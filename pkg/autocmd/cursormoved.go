@@ -0,0 +1,28 @@
+// Copyright 2018 The nvim-go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package autocmd
+
+import (
+	"context"
+
+	"github.com/zchee/nvim-go/pkg/monitoring"
+	"github.com/zchee/nvim-go/pkg/nvimutil"
+)
+
+// cursorMovedEval represents the current buffer number and cursor byte offset.
+type cursorMovedEval struct {
+	BufNr  int `eval:"bufnr('%')"`
+	Offset int `eval:"line2byte(line('.')) + (col('.')-2)"`
+}
+
+// CursorMoved highlights the GoAnalyze outline node enclosing the cursor on CursorMoved autocmd.
+func (a *Autocmd) CursorMoved(pctx context.Context, eval *cursorMovedEval) {
+	ctx, span := monitoring.StartSpan(pctx, "CursorMoved")
+	defer span.End()
+
+	if err := a.cmd.AnalyzeCursor(ctx, eval.BufNr, eval.Offset); err != nil {
+		nvimutil.ErrorWrap(a.Nvim, err)
+	}
+}
@@ -54,6 +54,36 @@ func Register(ctx context.Context, p *plugin.Plugin, buildContext *buildctxt.Con
 
 	// p.HandleAutocmd(&plugin.AutocmdOptions{Event: "WinEnter", Group: "nvim-go", Pattern: "*.go", Eval: "*"}, func(eval *winEnterEval) { autocmd.WinEnter(ctx, eval) })
 
+	// Handle the cursor moved for the GoAnalyze outline buffer.
+	p.HandleAutocmd(&plugin.AutocmdOptions{Event: "CursorMoved", Pattern: "*.go", Group: "nvim-go", Eval: "*"},
+		func(eval *cursorMovedEval) {
+			autocmd.CursorMoved(ctx, eval)
+		})
+
+	// Handle the cursor hold for the same ids highlighting and the hover floating window.
+	p.HandleAutocmd(&plugin.AutocmdOptions{Event: "CursorHold", Pattern: "*.go", Group: "nvim-go", Eval: "*"},
+		func(eval *cursorHoldEval) {
+			autocmd.CursorHold(ctx, eval)
+		})
+
+	// Handle the buffer changes and window scroll for the semantic highlighting.
+	p.HandleAutocmd(&plugin.AutocmdOptions{Event: "BufWinEnter,TextChanged,TextChangedI,WinScrolled", Pattern: "*.go", Group: "nvim-go", Eval: "*"},
+		func(eval *textChangedEval) {
+			autocmd.TextChanged(ctx, eval)
+		})
+
+	// Handle the completion done for the auto-import of unimported package symbols.
+	p.HandleAutocmd(&plugin.AutocmdOptions{Event: "CompleteDone", Pattern: "*.go", Group: "nvim-go", Eval: "*"},
+		func(eval *completeDoneEval) {
+			autocmd.CompleteDone(ctx, eval)
+		})
+
+	// Handle the delete the buffer for the gopls document synchronization and the type checked file cache.
+	p.HandleAutocmd(&plugin.AutocmdOptions{Event: "BufDelete,BufWipeout", Pattern: "*.go", Group: "nvim-go", Eval: "*"},
+		func(eval *bufDeleteEval) {
+			autocmd.BufDelete(ctx, eval)
+		})
+
 	// Handle the before the write to file.
 	p.HandleAutocmd(&plugin.AutocmdOptions{Event: "BufWritePre", Pattern: "*.go", Group: "nvim-go", Eval: "*"},
 		func(eval *bufWritePreEval) {
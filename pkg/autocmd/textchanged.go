@@ -0,0 +1,49 @@
+// Copyright 2018 The nvim-go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package autocmd
+
+import (
+	"context"
+
+	"go.uber.org/zap"
+
+	"github.com/zchee/nvim-go/pkg/logger"
+	"github.com/zchee/nvim-go/pkg/monitoring"
+)
+
+// textChangedEval represents the current buffer, changedtick, visible line range and cursor of the current window.
+type textChangedEval struct {
+	BufNr  int    `eval:"bufnr('%')"`
+	File   string `eval:"expand('%:p')"`
+	Tick   int    `eval:"b:changedtick"`
+	Start  int    `eval:"line('w0')"`
+	End    int    `eval:"line('w$')"`
+	Mode   string `eval:"mode()"`
+	Offset int    `eval:"line2byte(line('.')) + (col('.')-2)"`
+}
+
+// TextChanged updates the semantic highlight of the visible window range on BufWinEnter, TextChanged, TextChangedI and WinScrolled autocmd.
+// Also synchronizes the buffer to gopls, and updates the signature help if the current mode is insert mode.
+func (a *Autocmd) TextChanged(pctx context.Context, eval *textChangedEval) {
+	ctx, span := monitoring.StartSpan(pctx, "TextChanged")
+	defer span.End()
+
+	// debounce the type check while typing in insert mode
+	if eval.Mode == "i" {
+		a.cmd.SemanticHighlightDebounced(pctx, eval.BufNr, eval.File, eval.Tick, eval.Start, eval.End)
+	} else if err := a.cmd.SemanticHighlight(ctx, eval.BufNr, eval.File, eval.Tick, eval.Start, eval.End); err != nil {
+		logger.FromContext(ctx).Error("TextChanged", zap.Error(err))
+	}
+
+	if err := a.cmd.GoplsSync(pctx, eval.BufNr, eval.File); err != nil {
+		logger.FromContext(ctx).Error("TextChanged", zap.Error(err))
+	}
+
+	if eval.Mode == "i" {
+		if err := a.cmd.SignatureHelp(ctx, eval.BufNr, eval.File, eval.Offset); err != nil {
+			logger.FromContext(ctx).Error("TextChanged", zap.Error(err))
+		}
+	}
+}
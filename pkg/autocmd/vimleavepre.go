@@ -7,6 +7,8 @@ package autocmd
 import (
 	"context"
 
+	"go.uber.org/zap"
+
 	"github.com/zchee/nvim-go/pkg/logger"
 	"github.com/zchee/nvim-go/pkg/monitoring"
 )
@@ -15,6 +17,11 @@ func (a *Autocmd) VimLeavePre(pctx context.Context) {
 	ctx, span := monitoring.StartSpan(pctx, "VimLeavePre")
 	defer span.End()
 
-	logger.FromContext(ctx).Named("VimLeavePre").Debug("canceled")
+	log := logger.FromContext(ctx).Named("VimLeavePre")
+	if err := a.cmd.GoplsShutdown(ctx); err != nil {
+		log.Error("GoplsShutdown", zap.Error(err))
+	}
+
+	log.Debug("canceled")
 	<-ctx.Done()
 }
@@ -0,0 +1,425 @@
+// Copyright 2018 The nvim-go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package command
+
+import (
+	"context"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"strings"
+	"sync"
+
+	"github.com/neovim/go-client/nvim"
+	"github.com/pkg/errors"
+	"go.opencensus.io/trace"
+
+	"github.com/zchee/nvim-go/pkg/monitoring"
+	"github.com/zchee/nvim-go/pkg/nvimutil"
+)
+
+const (
+	// analyzeBufferName buffer name of the GoAnalyze outline buffer.
+	analyzeBufferName = "__GoAnalyze__"
+	// filetypeGoAnalyze filetype of the GoAnalyze buffers.
+	filetypeGoAnalyze = "goanalyze"
+	// hlGoAnalyzeCurrent highlight group of the node enclosing the cursor.
+	hlGoAnalyzeCurrent = "goAnalyzeCurrent"
+)
+
+// treeView represents a sidebar tree buffer and the source buffer of the tree.
+type treeView struct {
+	mu sync.Mutex
+
+	buffer nvim.Buffer
+	window nvim.Window
+	nsID   int
+
+	srcBuffer nvim.Buffer
+	srcWindow nvim.Window
+	srcFile   string
+
+	tree *nvimutil.Tree
+}
+
+// isOpened reports whether the tree buffer is displayed.
+func (tv *treeView) isOpened(v *nvim.Nvim) bool {
+	if tv.buffer == 0 || !nvimutil.IsBufferValid(v, tv.buffer) {
+		return false
+	}
+	valid, _ := v.IsWindowValid(tv.window)
+
+	return valid
+}
+
+// render renders the tree to the tree buffer.
+func (tv *treeView) render(v *nvim.Nvim) error {
+	defer nvimutil.Modifiable(v, tv.buffer)()
+
+	return v.SetBufferLines(tv.buffer, 0, -1, true, tv.tree.Render())
+}
+
+type cmdAnalyzeEval struct {
+	File  string `eval:"expand('%:p')"`
+	BufNr int    `eval:"bufnr('%')"`
+	WinID int    `eval:"win_getid()"`
+}
+
+func (c *Command) cmdAnalyze(ctx context.Context, eval *cmdAnalyzeEval) {
+	errch := make(chan interface{}, 1)
+	go func() {
+		errch <- c.Analyze(ctx, eval)
+	}()
+
+	select {
+	case <-ctx.Done():
+		return
+	case err := <-errch:
+		switch e := err.(type) {
+		case error:
+			nvimutil.ErrorWrap(c.Nvim, e)
+		case nil:
+			// nothing to do
+		}
+	}
+}
+
+// Analyze toggles the tagbar like outline buffer of the current buffer.
+func (c *Command) Analyze(ctx context.Context, eval *cmdAnalyzeEval) interface{} {
+	var span *trace.Span
+	ctx, span = monitoring.StartSpan(ctx, "Analyze")
+	defer span.End()
+
+	a := c.analyze
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	// close the outline buffer if already opened
+	if a.buffer != 0 && nvimutil.IsBufferValid(c.Nvim, a.buffer) {
+		if valid, _ := c.Nvim.IsWindowValid(a.window); valid {
+			a.buffer = 0
+			return c.Nvim.CloseWindow(a.window, true)
+		}
+	}
+
+	a.srcBuffer = nvim.Buffer(eval.BufNr)
+	a.srcWindow = nvim.Window(eval.WinID)
+	a.srcFile = eval.File
+
+	buf := nvimutil.NewBuffer(c.Nvim)
+	if err := buf.Create(analyzeBufferName, filetypeGoAnalyze, "belowright 40vsplit", sidebarOption(filetypeGoAnalyze)); err != nil {
+		span.SetStatus(trace.Status{Code: trace.StatusCodeInternal, Message: err.Error()})
+		return errors.WithStack(err)
+	}
+	buf.SetLocalMapping(nvimutil.NoremapNormal, map[string]string{
+		"<CR>": ":<C-u>call GoAnalyzeAction('jump')<CR>",
+		"o":    ":<C-u>call GoAnalyzeAction('toggle')<CR>",
+		"za":   ":<C-u>call GoAnalyzeAction('toggle')<CR>",
+		"q":    ":<C-u>close<CR>",
+	})
+	a.buffer = buf.Buffer()
+	a.window = buf.Window
+
+	if a.nsID == 0 {
+		nsID, err := c.Nvim.CreateNamespace("nvim-go-analyze")
+		if err != nil {
+			span.SetStatus(trace.Status{Code: trace.StatusCodeInternal, Message: err.Error()})
+			return errors.WithStack(err)
+		}
+		a.nsID = nsID
+	}
+
+	if err := c.analyzeUpdate(ctx); err != nil {
+		span.SetStatus(trace.Status{Code: trace.StatusCodeInternal, Message: err.Error()})
+		return errors.WithStack(err)
+	}
+
+	return c.Nvim.SetCurrentWindow(a.srcWindow)
+}
+
+// AnalyzeRefresh re-parses the bufnr buffer and updates the outline buffer if opened.
+func (c *Command) AnalyzeRefresh(ctx context.Context, bufnr, winID int) error {
+	a := c.analyze
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if !a.isOpened(c.Nvim) {
+		return nil
+	}
+
+	file, err := c.Nvim.BufferName(nvim.Buffer(bufnr))
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	a.srcBuffer = nvim.Buffer(bufnr)
+	a.srcWindow = nvim.Window(winID)
+	a.srcFile = file
+
+	return c.analyzeUpdate(ctx)
+}
+
+// AnalyzeCursor highlights the outline node enclosing the cursor offset.
+func (c *Command) AnalyzeCursor(ctx context.Context, bufnr, offset int) error {
+	a := c.analyze
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if !a.isOpened(c.Nvim) || nvim.Buffer(bufnr) != a.srcBuffer || a.tree == nil {
+		return nil
+	}
+
+	batch := c.Nvim.NewBatch()
+	batch.ClearBufferNamespace(a.buffer, a.nsID, 0, -1)
+	if n := a.tree.Enclosing(a.srcFile, offset); n != nil {
+		line := a.tree.LineOf(n)
+		var src int
+		batch.AddBufferHighlight(a.buffer, a.nsID, hlGoAnalyzeCurrent, line-1, 0, -1, &src)
+		batch.SetWindowCursor(a.window, [2]int{line, 0})
+	}
+
+	return batch.Execute()
+}
+
+func (c *Command) funcAnalyzeAction(ctx context.Context, args []string, line int) {
+	if err := c.AnalyzeAction(ctx, args, line); err != nil {
+		nvimutil.ErrorWrap(c.Nvim, err)
+	}
+}
+
+// AnalyzeAction runs the action of outline buffer to the node at line.
+//
+// The available actions are "jump" and "toggle".
+func (c *Command) AnalyzeAction(ctx context.Context, args []string, line int) error {
+	a := c.analyze
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.tree == nil || len(args) == 0 {
+		return nil
+	}
+
+	switch args[0] {
+	case "jump":
+		n := a.tree.NodeAt(line)
+		if n == nil || !n.Start.IsValid() {
+			return nil
+		}
+		return jumpToPos(c.Nvim, a.srcWindow, n.Start)
+
+	case "toggle":
+		if !a.tree.Toggle(line) {
+			return nil
+		}
+		return a.render(c.Nvim)
+
+	default:
+		return errors.Errorf("unknown GoAnalyze action: %s", args[0])
+	}
+}
+
+// analyzeUpdate parses the source buffer and renders the outline tree.
+func (c *Command) analyzeUpdate(ctx context.Context) error {
+	a := c.analyze
+
+	buf, err := c.Nvim.BufferLines(a.srcBuffer, 0, -1, true)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, a.srcFile, nvimutil.ToByteSlice(buf), parser.AllErrors)
+	if f == nil {
+		return errors.WithStack(err)
+	}
+	a.tree = nvimutil.NewTree(outlineTree(fset, f)...)
+
+	return a.render(c.Nvim)
+}
+
+// jumpToPos jumps the w window cursor to pos. If pos is other file, edit pos file on the w window.
+func jumpToPos(v *nvim.Nvim, w nvim.Window, pos token.Position) error {
+	if valid, _ := v.IsWindowValid(w); !valid {
+		return errors.New("source window was closed")
+	}
+
+	batch := v.NewBatch()
+	batch.SetCurrentWindow(w)
+	batch.Command("normal! m'")
+	if pos.Filename != "" {
+		var name, escaped string
+		batch.Call("expand", &name, "%:p")
+		// escape the special characters of the file name such as space, '%', '#' and '|'
+		batch.Call("fnameescape", &escaped, pos.Filename)
+		if err := batch.Execute(); err != nil {
+			return errors.WithStack(err)
+		}
+		if name != pos.Filename {
+			batch.Command("edit " + escaped)
+		}
+	}
+	batch.SetWindowCursor(w, [2]int{pos.Line, pos.Column - 1})
+	batch.Command("normal! zz")
+
+	return batch.Execute()
+}
+
+// sidebarOption returns the buffer and window options of the sidebar buffer.
+func sidebarOption(filetype string) map[nvimutil.NvimOption]map[string]interface{} {
+	return map[nvimutil.NvimOption]map[string]interface{}{
+		nvimutil.BufferOption: {
+			nvimutil.BufOptionBufhidden:  nvimutil.BufhiddenWipe,
+			nvimutil.BufOptionBuflisted:  false,
+			nvimutil.BufOptionBuftype:    nvimutil.BuftypeNofile,
+			nvimutil.BufOptionFiletype:   filetype,
+			nvimutil.BufOptionModifiable: false,
+			nvimutil.BufOptionSwapfile:   false,
+		},
+		nvimutil.WindowOption: {
+			nvimutil.WinOptionList:           false,
+			nvimutil.WinOptionNumber:         false,
+			nvimutil.WinOptionRelativenumber: false,
+			nvimutil.WinOptionWinfixwidth:    true,
+			nvimutil.WinOptionWrap:           false,
+		},
+	}
+}
+
+// outlineTree returns the outline tree nodes of the imports, consts, vars, types and funcs in f.
+func outlineTree(fset *token.FileSet, f *ast.File) []*nvimutil.TreeNode {
+	newNode := func(text string, node ast.Node) *nvimutil.TreeNode {
+		return &nvimutil.TreeNode{
+			Text:     text,
+			Start:    fset.Position(node.Pos()),
+			End:      fset.Position(node.End()),
+			Expanded: true,
+		}
+	}
+
+	imports := &nvimutil.TreeNode{Text: "imports", Expanded: true}
+	consts := &nvimutil.TreeNode{Text: "consts", Expanded: true}
+	vars := &nvimutil.TreeNode{Text: "vars", Expanded: true}
+	typs := &nvimutil.TreeNode{Text: "types", Expanded: true}
+	funcs := &nvimutil.TreeNode{Text: "funcs", Expanded: true}
+
+	typeNodes := make(map[string]*nvimutil.TreeNode)
+	var methods []*ast.FuncDecl
+
+	for _, decl := range f.Decls {
+		switch decl := decl.(type) {
+		case *ast.GenDecl:
+			for _, spec := range decl.Specs {
+				var node ast.Node = spec
+				if !decl.Lparen.IsValid() {
+					node = decl
+				}
+
+				switch spec := spec.(type) {
+				case *ast.ImportSpec:
+					text := spec.Path.Value
+					if spec.Name != nil {
+						text = spec.Name.Name + " " + text
+					}
+					imports.Children = append(imports.Children, newNode(text, node))
+
+				case *ast.ValueSpec:
+					group := vars
+					if decl.Tok == token.CONST {
+						group = consts
+					}
+					for _, name := range spec.Names {
+						text := name.Name
+						if spec.Type != nil {
+							text += " " + types.ExprString(spec.Type)
+						}
+						group.Children = append(group.Children, newNode(text, node))
+					}
+
+				case *ast.TypeSpec:
+					n := newNode(spec.Name.Name, node)
+					switch t := spec.Type.(type) {
+					case *ast.StructType:
+						for _, field := range t.Fields.List {
+							n.Children = append(n.Children, newNode(fieldText(field), field))
+						}
+					case *ast.InterfaceType:
+						for _, method := range t.Methods.List {
+							n.Children = append(n.Children, newNode(fieldText(method), method))
+						}
+					default:
+						n.Text += " " + types.ExprString(spec.Type)
+					}
+					typeNodes[spec.Name.Name] = n
+					typs.Children = append(typs.Children, n)
+				}
+			}
+
+		case *ast.FuncDecl:
+			if decl.Recv != nil && len(decl.Recv.List) > 0 {
+				methods = append(methods, decl)
+				continue
+			}
+			funcs.Children = append(funcs.Children, newNode(decl.Name.Name+funcSignature(decl.Type), decl))
+		}
+	}
+
+	for _, method := range methods {
+		recv := recvTypeName(method)
+		n := newNode(method.Name.Name+funcSignature(method.Type), method)
+		if typ, ok := typeNodes[recv]; ok {
+			typ.Children = append(typ.Children, n)
+			continue
+		}
+		// the receiver type is declared in the other file
+		n.Text = "(" + types.ExprString(method.Recv.List[0].Type) + ") " + n.Text
+		funcs.Children = append(funcs.Children, n)
+	}
+
+	var roots []*nvimutil.TreeNode
+	for _, group := range []*nvimutil.TreeNode{imports, consts, vars, typs, funcs} {
+		if !group.IsLeaf() {
+			roots = append(roots, group)
+		}
+	}
+
+	return roots
+}
+
+// fieldText returns the outline text of struct field or interface method.
+func fieldText(field *ast.Field) string {
+	var names []string
+	for _, name := range field.Names {
+		names = append(names, name.Name)
+	}
+
+	if ft, ok := field.Type.(*ast.FuncType); ok && len(names) > 0 {
+		return names[0] + funcSignature(ft)
+	}
+
+	typ := types.ExprString(field.Type)
+	if len(names) == 0 {
+		return typ // embedded
+	}
+
+	return strings.Join(names, ", ") + " " + typ
+}
+
+// funcSignature returns the function signature string without "func" keyword.
+func funcSignature(ft *ast.FuncType) string {
+	return strings.TrimPrefix(types.ExprString(ft), "func")
+}
+
+// recvTypeName returns the receiver type name of method.
+func recvTypeName(method *ast.FuncDecl) string {
+	typ := method.Recv.List[0].Type
+	if star, ok := typ.(*ast.StarExpr); ok {
+		typ = star.X
+	}
+	if id, ok := typ.(*ast.Ident); ok {
+		return id.Name
+	}
+
+	return ""
+}
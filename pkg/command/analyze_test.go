@@ -0,0 +1,89 @@
+// Copyright 2018 The nvim-go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package command
+
+import (
+	"go/parser"
+	"go/token"
+	"reflect"
+	"testing"
+
+	"github.com/zchee/nvim-go/pkg/nvimutil"
+)
+
+const outlineSrc = `package foo
+
+import (
+	"fmt"
+	str "strings"
+)
+
+const Max = 10
+
+var (
+	debug bool
+	name  string
+)
+
+type Foo struct {
+	ID   int
+	Name string
+	fmt.Stringer
+}
+
+type Bar interface {
+	Do(n int) error
+}
+
+type ID int
+
+func (f *Foo) String() string { return str.ToUpper(f.Name) }
+
+func NewFoo(id int) *Foo { return &Foo{ID: id} }
+
+func (b baz) Qux() {}
+`
+
+func TestOutlineTree(t *testing.T) {
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "foo.go", outlineSrc, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tree := nvimutil.NewTree(outlineTree(fset, f)...)
+	got := tree.Render()
+	want := [][]byte{
+		[]byte(`▼ imports`),
+		[]byte(`  - "fmt"`),
+		[]byte(`  - str "strings"`),
+		[]byte(`▼ consts`),
+		[]byte(`  - Max`),
+		[]byte(`▼ vars`),
+		[]byte(`  - debug bool`),
+		[]byte(`  - name string`),
+		[]byte(`▼ types`),
+		[]byte(`  ▼ Foo`),
+		[]byte(`    - ID int`),
+		[]byte(`    - Name string`),
+		[]byte(`    - fmt.Stringer`),
+		[]byte(`    - String() string`),
+		[]byte(`  ▼ Bar`),
+		[]byte(`    - Do(n int) error`),
+		[]byte(`  - ID int`),
+		[]byte(`▼ funcs`),
+		[]byte(`  - NewFoo(id int) *Foo`),
+		[]byte(`  - (baz) Qux()`),
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("outlineTree() = \n%s\nwant\n%s", got, want)
+	}
+
+	// the cursor in the String method body
+	offset := fset.Position(f.Decls[len(f.Decls)-3].Pos()).Offset + 10
+	if n := tree.Enclosing("foo.go", offset); n == nil || n.Text != "String() string" {
+		t.Errorf("Tree.Enclosing(%d) = %v, want String() string", offset, n)
+	}
+}
@@ -0,0 +1,333 @@
+// Copyright 2018 The nvim-go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package command
+
+import (
+	"context"
+	"fmt"
+	"go/ast"
+	"go/token"
+	"go/types"
+	"reflect"
+	"strings"
+
+	"github.com/neovim/go-client/nvim"
+	"github.com/pkg/errors"
+	"go.opencensus.io/trace"
+	"golang.org/x/tools/go/ast/astutil"
+
+	"github.com/zchee/nvim-go/pkg/monitoring"
+	"github.com/zchee/nvim-go/pkg/nvimutil"
+)
+
+const (
+	// astViewBufferName buffer name of the GoAstView buffer.
+	astViewBufferName = "__GoAstView__"
+	// hlGoAstViewRange highlight group of the selected node source range.
+	hlGoAstViewRange = "goAstViewRange"
+)
+
+type cmdAstViewEval struct {
+	File   string `eval:"expand('%:p')"`
+	BufNr  int    `eval:"bufnr('%')"`
+	WinID  int    `eval:"win_getid()"`
+	Offset int    `eval:"line2byte(line('.')) + (col('.')-2)"`
+}
+
+func (c *Command) cmdAstView(ctx context.Context, eval *cmdAstViewEval) {
+	errch := make(chan interface{}, 1)
+	go func() {
+		errch <- c.AstView(ctx, eval)
+	}()
+
+	select {
+	case <-ctx.Done():
+		return
+	case err := <-errch:
+		switch e := err.(type) {
+		case error:
+			nvimutil.ErrorWrap(c.Nvim, e)
+		case nil:
+			// nothing to do
+		}
+	}
+}
+
+// AstView shows the AST node path enclosing the cursor as the tree, with the types information of each node.
+func (c *Command) AstView(ctx context.Context, eval *cmdAstViewEval) interface{} {
+	var span *trace.Span
+	ctx, span = monitoring.StartSpan(ctx, "AstView")
+	defer span.End()
+
+	a := c.astView
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	buf, err := c.Nvim.BufferLines(nvim.Buffer(eval.BufNr), 0, -1, true)
+	if err != nil {
+		span.SetStatus(trace.Status{Code: trace.StatusCodeInternal, Message: err.Error()})
+		return errors.WithStack(err)
+	}
+
+	cf, err := typeCheck(eval.File, nvimutil.ToByteSlice(buf))
+	if err != nil {
+		span.SetStatus(trace.Status{Code: trace.StatusCodeInternal, Message: err.Error()})
+		return errors.WithStack(err)
+	}
+
+	pos := cf.Fset.File(cf.File.Pos()).Pos(eval.Offset)
+	path, _ := astutil.PathEnclosingInterval(cf.File, pos, pos)
+	if len(path) == 0 {
+		return errors.New("no AST node here")
+	}
+
+	if a.nsID == 0 {
+		nsID, err := c.Nvim.CreateNamespace("nvim-go-astview")
+		if err != nil {
+			span.SetStatus(trace.Status{Code: trace.StatusCodeInternal, Message: err.Error()})
+			return errors.WithStack(err)
+		}
+		a.nsID = nsID
+	}
+	if a.srcBuffer != 0 && nvimutil.IsBufferValid(c.Nvim, a.srcBuffer) {
+		c.Nvim.ClearBufferNamespace(a.srcBuffer, a.nsID, 0, -1)
+	}
+
+	a.srcBuffer = nvim.Buffer(eval.BufNr)
+	a.srcWindow = nvim.Window(eval.WinID)
+	a.srcFile = eval.File
+	a.tree = nvimutil.NewTree(astPathTree(cf, path))
+
+	if !a.isOpened(c.Nvim) {
+		b := nvimutil.NewBuffer(c.Nvim)
+		if err := b.Create(astViewBufferName, filetypeGoAnalyze, "belowright 60vsplit", sidebarOption(filetypeGoAnalyze)); err != nil {
+			span.SetStatus(trace.Status{Code: trace.StatusCodeInternal, Message: err.Error()})
+			return errors.WithStack(err)
+		}
+		b.SetLocalMapping(nvimutil.NoremapNormal, map[string]string{
+			"<CR>": ":<C-u>call GoAstViewAction('jump')<CR>",
+			"o":    ":<C-u>call GoAstViewAction('toggle')<CR>",
+			"za":   ":<C-u>call GoAstViewAction('toggle')<CR>",
+			"q":    ":<C-u>call GoAstViewAction('close')<CR>",
+		})
+		a.buffer = b.Buffer()
+		a.window = b.Window
+
+		// highlight the source range of the node under the cursor of the GoAstView buffer
+		batch := c.Nvim.NewBatch()
+		batch.Command("augroup nvim-go-astview")
+		batch.Command(fmt.Sprintf("autocmd! * <buffer=%d>", a.buffer))
+		batch.Command(fmt.Sprintf("autocmd CursorMoved <buffer=%d> call GoAstViewAction('select')", a.buffer))
+		batch.Command("augroup END")
+		if err := batch.Execute(); err != nil {
+			span.SetStatus(trace.Status{Code: trace.StatusCodeInternal, Message: err.Error()})
+			return errors.WithStack(err)
+		}
+	}
+
+	if err := a.render(c.Nvim); err != nil {
+		span.SetStatus(trace.Status{Code: trace.StatusCodeInternal, Message: err.Error()})
+		return errors.WithStack(err)
+	}
+
+	// move the cursor to the innermost node
+	innermost := a.tree.LineOf(findTreeNode(a.tree.Roots, path[0]))
+	if innermost == 0 {
+		innermost = 1
+	}
+	batch := c.Nvim.NewBatch()
+	batch.SetCurrentWindow(a.window)
+	batch.SetWindowCursor(a.window, [2]int{innermost, 0})
+
+	return batch.Execute()
+}
+
+func (c *Command) funcAstViewAction(ctx context.Context, args []string, line int) {
+	if err := c.AstViewAction(ctx, args, line); err != nil {
+		nvimutil.ErrorWrap(c.Nvim, err)
+	}
+}
+
+// AstViewAction runs the action of GoAstView buffer to the node at line.
+//
+// The available actions are "select", "jump", "toggle" and "close".
+func (c *Command) AstViewAction(ctx context.Context, args []string, line int) error {
+	a := c.astView
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.tree == nil || len(args) == 0 {
+		return nil
+	}
+
+	switch args[0] {
+	case "select":
+		if !nvimutil.IsBufferValid(c.Nvim, a.srcBuffer) {
+			return nil
+		}
+		batch := c.Nvim.NewBatch()
+		batch.ClearBufferNamespace(a.srcBuffer, a.nsID, 0, -1)
+		if n := a.tree.NodeAt(line); n != nil && n.Start.IsValid() && n.End.IsValid() {
+			var id int
+			batch.SetBufferExtmark(a.srcBuffer, a.nsID, 0, n.Start.Line-1, n.Start.Column-1, map[string]interface{}{
+				"end_line": n.End.Line - 1,
+				"end_col":  n.End.Column - 1,
+				"hl_group": hlGoAstViewRange,
+			}, &id)
+		}
+		return batch.Execute()
+
+	case "jump":
+		n := a.tree.NodeAt(line)
+		if n == nil || !n.Start.IsValid() {
+			return nil
+		}
+		return jumpToPos(c.Nvim, a.srcWindow, n.Start)
+
+	case "toggle":
+		if !a.tree.Toggle(line) {
+			return nil
+		}
+		return a.render(c.Nvim)
+
+	case "close":
+		if nvimutil.IsBufferValid(c.Nvim, a.srcBuffer) {
+			c.Nvim.ClearBufferNamespace(a.srcBuffer, a.nsID, 0, -1)
+		}
+		a.tree = nil
+		return c.Nvim.CloseWindow(a.window, true)
+
+	default:
+		return errors.Errorf("unknown GoAstView action: %s", args[0])
+	}
+}
+
+// astPathTree returns the tree of path. The outermost node is the root of the tree.
+//
+// Each path node has the key fields of the node, types information and the next inner path node as the children.
+func astPathTree(cf *checkedFile, path []ast.Node) *nvimutil.TreeNode {
+	var child *nvimutil.TreeNode
+	for _, node := range path {
+		n := &nvimutil.TreeNode{
+			Text:     fmt.Sprintf("%s %s", astNodeType(node), astRange(cf.Fset, node)),
+			Start:    cf.Fset.Position(node.Pos()),
+			End:      cf.Fset.Position(node.End()),
+			Expanded: true,
+			Data:     node,
+		}
+		n.Children = append(n.Children, astFields(cf.Fset, node)...)
+		n.Children = append(n.Children, astTypesInfo(cf, node)...)
+		if child != nil {
+			n.Children = append(n.Children, child)
+		}
+		child = n
+	}
+
+	return child
+}
+
+// astFields returns the tree nodes of key fields of node.
+func astFields(fset *token.FileSet, node ast.Node) []*nvimutil.TreeNode {
+	v := reflect.ValueOf(node)
+	if v.Kind() != reflect.Ptr || v.Elem().Kind() != reflect.Struct {
+		return nil
+	}
+	v = v.Elem()
+
+	var fields []*nvimutil.TreeNode
+	for i := 0; i < v.NumField(); i++ {
+		name, fv := v.Type().Field(i).Name, v.Field(i)
+		if !fv.CanInterface() {
+			continue
+		}
+
+		switch x := fv.Interface().(type) {
+		case token.Pos:
+			if x.IsValid() {
+				p := fset.Position(x)
+				fields = append(fields, &nvimutil.TreeNode{Text: fmt.Sprintf("%s: %d:%d", name, p.Line, p.Column), Start: p, End: p})
+			}
+			continue
+		case token.Token:
+			fields = append(fields, &nvimutil.TreeNode{Text: fmt.Sprintf("%s: %s", name, x)})
+			continue
+		case *ast.Object, *ast.Scope:
+			// the deprecated resolver data
+			continue
+		case ast.Node:
+			if fv.IsNil() {
+				continue
+			}
+			fields = append(fields, &nvimutil.TreeNode{
+				Text:  fmt.Sprintf("%s: %s", name, astNodeType(x)),
+				Start: fset.Position(x.Pos()),
+				End:   fset.Position(x.End()),
+			})
+			continue
+		}
+
+		switch fv.Kind() {
+		case reflect.String, reflect.Bool, reflect.Int:
+			fields = append(fields, &nvimutil.TreeNode{Text: fmt.Sprintf("%s: %v", name, fv.Interface())})
+		case reflect.Slice:
+			fields = append(fields, &nvimutil.TreeNode{Text: fmt.Sprintf("%s: %s (len=%d)", name, fv.Type(), fv.Len())})
+		}
+	}
+
+	return fields
+}
+
+// astTypesInfo returns the tree nodes of types information of node.
+func astTypesInfo(cf *checkedFile, node ast.Node) []*nvimutil.TreeNode {
+	qualifier := types.RelativeTo(cf.Pkg)
+
+	var infos []*nvimutil.TreeNode
+	if expr, ok := node.(ast.Expr); ok {
+		if tv, ok := cf.Info.Types[expr]; ok {
+			infos = append(infos, &nvimutil.TreeNode{Text: "types.Type: " + types.TypeString(tv.Type, qualifier)})
+			if tv.Value != nil {
+				infos = append(infos, &nvimutil.TreeNode{Text: "constant.Value: " + tv.Value.ExactString()})
+			}
+		}
+	}
+	if id, ok := node.(*ast.Ident); ok {
+		if obj := cf.Info.ObjectOf(id); obj != nil {
+			n := &nvimutil.TreeNode{Text: "types.Object: " + types.ObjectString(obj, qualifier)}
+			if obj.Pos().IsValid() {
+				n.Start = cf.position(obj.Pos())
+				n.End = cf.position(obj.Pos() + token.Pos(len(obj.Name())))
+			}
+			infos = append(infos, n)
+		}
+	}
+
+	return infos
+}
+
+// astNodeType returns the type name of node such as "ast.CallExpr".
+func astNodeType(node ast.Node) string {
+	return strings.TrimPrefix(reflect.TypeOf(node).String(), "*")
+}
+
+// astRange returns the "line:col-line:col" format source range of node.
+func astRange(fset *token.FileSet, node ast.Node) string {
+	start, end := fset.Position(node.Pos()), fset.Position(node.End())
+
+	return fmt.Sprintf("[%d:%d-%d:%d]", start.Line, start.Column, end.Line, end.Column)
+}
+
+// findTreeNode returns the tree node which data is node.
+func findTreeNode(nodes []*nvimutil.TreeNode, node ast.Node) *nvimutil.TreeNode {
+	for _, n := range nodes {
+		if n.Data == node {
+			return n
+		}
+		if found := findTreeNode(n.Children, node); found != nil {
+			return found
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,73 @@
+// Copyright 2018 The nvim-go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package command
+
+import (
+	"strings"
+	"testing"
+
+	"golang.org/x/tools/go/ast/astutil"
+
+	"github.com/zchee/nvim-go/pkg/nvimutil"
+)
+
+const astViewSrc = `package foo
+
+const answer = 40 + 2
+
+func double(n int) int { return n * 2 }
+`
+
+func TestAstPathTree(t *testing.T) {
+	_, cf := testCheckedFile(t, astViewSrc)
+
+	tests := []struct {
+		name   string
+		offset int
+		want   []string
+	}{
+		{
+			name:   "constant",
+			offset: strings.Index(astViewSrc, "40"),
+			want: []string{
+				"▼ ast.File [1:1-5:40]",
+				"  - Name: ast.Ident",
+				"      - Kind: INT",
+				"      - Value: 40",
+				"      - types.Type: untyped int",
+				"      - constant.Value: 40",
+				"    ▼ ast.BinaryExpr [3:16-3:22]",
+				"      - Op: +",
+			},
+		},
+		{
+			name:   "object",
+			offset: strings.Index(astViewSrc, "n * 2"),
+			want: []string{
+				"          - Name: n",
+				"          - types.Type: int",
+				"          - types.Object: var n int",
+			},
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			pos := cf.Fset.File(cf.File.Pos()).Pos(tt.offset)
+			path, _ := astutil.PathEnclosingInterval(cf.File, pos, pos)
+
+			var lines []string
+			for _, line := range nvimutil.NewTree(astPathTree(cf, path)).Render() {
+				lines = append(lines, string(line))
+			}
+			got := strings.Join(lines, "\n")
+			for _, want := range tt.want {
+				if !strings.Contains(got, want+"\n") && !strings.HasSuffix(got, want) {
+					t.Errorf("astPathTree() = \n%s\ndoes not contain %q", got, want)
+				}
+			}
+		})
+	}
+}
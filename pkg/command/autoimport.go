@@ -0,0 +1,118 @@
+// Copyright 2018 The nvim-go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package command
+
+import (
+	"bytes"
+	"context"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"strings"
+
+	"github.com/neovim/go-client/nvim"
+	"github.com/pkg/errors"
+	"go.opencensus.io/trace"
+	"golang.org/x/tools/go/ast/astutil"
+	"golang.org/x/tools/imports"
+
+	"github.com/zchee/nvim-go/pkg/config"
+	"github.com/zchee/nvim-go/pkg/monitoring"
+	"github.com/zchee/nvim-go/pkg/nvimutil"
+)
+
+// autoImportPrefix prefix of the completion item user_data which is the unimported package symbol.
+const autoImportPrefix = "nvim-go:import:"
+
+// WarmSymbolIndex starts building the symbol index of unimported packages in the background.
+func (c *Command) WarmSymbolIndex(ctx context.Context) {
+	c.symbols.Warm(ctx)
+}
+
+// AutoImport adds the import declaration of the accepted completion item to the bufnr buffer.
+// userData is the user_data of v:completed_item, does nothing if it is not the unimported package symbol.
+func (c *Command) AutoImport(ctx context.Context, bufnr int, userData interface{}) error {
+	s, ok := userData.(string)
+	if !ok || !strings.HasPrefix(s, autoImportPrefix) {
+		return nil
+	}
+	importPath := strings.TrimPrefix(s, autoImportPrefix)
+
+	var span *trace.Span
+	ctx, span = monitoring.StartSpan(ctx, "AutoImport")
+	defer span.End()
+
+	b := nvim.Buffer(bufnr)
+	in, err := c.Nvim.BufferLines(b, 0, -1, true)
+	if err != nil {
+		span.SetStatus(trace.Status{Code: trace.StatusCodeInternal, Message: err.Error()})
+		return errors.WithStack(err)
+	}
+
+	src, err := addImport(nvimutil.ToByteSlice(in), importPath)
+	if err != nil {
+		span.SetStatus(trace.Status{Code: trace.StatusCodeInternal, Message: err.Error()})
+		return errors.WithStack(err)
+	}
+	if src == nil {
+		return nil
+	}
+
+	return minUpdate(ctx, c.Nvim, b, in, nvimutil.ToBufferLines(bytes.TrimSuffix(src, []byte{'\n'})))
+}
+
+// addImport adds the importPath import declaration to src, and sorts the imports into the standard
+// library group and the third party groups as goimports.
+// Returns nil if src already imports importPath.
+//
+// Only the package clause and import declarations are rewritten, because the rest of the source
+// code is usually incomplete while typing.
+func addImport(src []byte, importPath string) ([]byte, error) {
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "", src, parser.ImportsOnly)
+	if err != nil {
+		return nil, err
+	}
+	end := f.Name.End()
+	if n := len(f.Decls); n > 0 {
+		end = f.Decls[n-1].End()
+	}
+	headerEnd := fset.Position(end).Offset
+	// the trailing comment of the last line, such as `import "fmt" // for Println`, belongs to the header
+	if rest := src[headerEnd:]; len(rest) > 0 {
+		if i := bytes.IndexByte(rest, '\n'); i >= 0 {
+			rest = rest[:i]
+		}
+		if trimmed := bytes.TrimSpace(rest); len(trimmed) == 0 || bytes.HasPrefix(trimmed, []byte("//")) {
+			headerEnd += len(rest)
+		}
+	}
+
+	fset = token.NewFileSet()
+	f, err = parser.ParseFile(fset, "", src[:headerEnd], parser.ParseComments)
+	if err != nil {
+		return nil, err
+	}
+	if !astutil.AddImport(fset, f, importPath) {
+		return nil, nil
+	}
+
+	var buf bytes.Buffer
+	if err := format.Node(&buf, fset, f); err != nil {
+		return nil, err
+	}
+
+	if locals := config.FmtGoImportsLocal; len(locals) > 0 {
+		imports.LocalPrefix = strings.Join(locals, ",")
+	}
+	opt := importsOptions
+	opt.FormatOnly = true
+	header, err := imports.Process("", buf.Bytes(), &opt)
+	if err != nil {
+		return nil, err
+	}
+
+	return append(bytes.TrimSuffix(header, []byte{'\n'}), src[headerEnd:]...), nil
+}
@@ -92,6 +92,10 @@ func (c *Command) Build(pctx context.Context, args []string, bang bool, eval *Cm
 				span.SetStatus(trace.Status{Code: trace.StatusCodeInternal, Message: err.Error()})
 				return errors.WithStack(err)
 			}
+			// show the import statements of the import cycle which is not reported with the position
+			if cycle := importCycleErrors(ctx, eval.Cwd, stderr.Bytes()); cycle != nil {
+				errlist = append(cycle, errlist...)
+			}
 			return errlist
 		}
 		span.SetStatus(trace.Status{Code: trace.StatusCodeInternal, Message: buildErr.Error()})
@@ -0,0 +1,403 @@
+// Copyright 2018 The nvim-go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package command
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"go/build"
+	"go/parser"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/neovim/go-client/nvim"
+	"github.com/pkg/errors"
+	"go.opencensus.io/trace"
+	"golang.org/x/tools/go/ast/astutil"
+	"golang.org/x/tools/go/buildutil"
+	"golang.org/x/tools/go/callgraph"
+	"golang.org/x/tools/go/callgraph/cha"
+	"golang.org/x/tools/go/callgraph/rta"
+	"golang.org/x/tools/go/callgraph/static"
+	"golang.org/x/tools/go/loader"
+	"golang.org/x/tools/go/ssa"
+	"golang.org/x/tools/go/ssa/ssautil"
+
+	"github.com/zchee/nvim-go/pkg/config"
+	"github.com/zchee/nvim-go/pkg/fs"
+	"github.com/zchee/nvim-go/pkg/monitoring"
+	"github.com/zchee/nvim-go/pkg/nvimutil"
+)
+
+// List of GoCallGraph root filters.
+const (
+	// callGraphFrom keeps the paths from the function under the cursor.
+	callGraphFrom = "from"
+	// callGraphTo keeps the paths to the function under the cursor.
+	callGraphTo = "to"
+)
+
+// callGraphEdge represents a call edge of the exported call graph.
+type callGraphEdge struct {
+	Caller string `json:"caller"`
+	Callee string `json:"callee"`
+	// Pos position of the call site, such as "file.go:10:2". Empty if the call is synthetic.
+	Pos string `json:"pos,omitempty"`
+	// Dynamic reports whether the call is the dynamic call through the interface method or function value.
+	Dynamic bool `json:"dynamic,omitempty"`
+}
+
+// cmdCallGraphEval represents the current buffer and cursor offset.
+type cmdCallGraphEval struct {
+	Cwd    string `eval:"getcwd()"`
+	File   string `eval:"expand('%:p')"`
+	Offset int    `eval:"line2byte(line('.')) + (col('.')-2)"`
+}
+
+func (c *Command) cmdCallGraph(ctx context.Context, args []string, eval *cmdCallGraphEval) {
+	errch := make(chan interface{}, 1)
+	go func() {
+		errch <- c.CallGraph(ctx, args, eval)
+	}()
+
+	// the whole program analysis takes a while, do not block the following commands
+	go func() {
+		select {
+		case <-ctx.Done():
+			return
+		case err := <-errch:
+			switch e := err.(type) {
+			case error:
+				nvimutil.ErrorWrap(c.Nvim, e)
+			case nil:
+				// nothing to do
+			}
+		}
+	}()
+}
+
+// CallGraph builds the call graph of the main packages and tests in the project which contains the current buffer,
+// and writes it to the config.CallGraphOutput file in the config.CallGraphFormat format.
+//
+// The first argument is the algorithm of the call graph, "static", "cha" or "rta". The default is "static".
+// The second argument is the root filter, "from" or "to" keeps the paths from or to the function under the cursor,
+// and the other value keeps the calls between the packages which import path has the prefix.
+func (c *Command) CallGraph(ctx context.Context, args []string, eval *cmdCallGraphEval) interface{} {
+	var span *trace.Span
+	ctx, span = monitoring.StartSpan(ctx, "CallGraph")
+	defer span.End()
+
+	algo := "static"
+	if len(args) > 0 {
+		algo = args[0]
+	}
+	var root string
+	if len(args) > 1 {
+		root = args[1]
+	}
+
+	format := config.CallGraphFormat
+	if format != "dot" && format != "json" {
+		err := errors.Errorf("unknown call graph format: %s", format)
+		span.SetStatus(trace.Status{Code: trace.StatusCodeInvalidArgument, Message: err.Error()})
+		return err
+	}
+
+	scopes, err := c.guruScopes(eval.File)
+	if err != nil {
+		span.SetStatus(trace.Status{Code: trace.StatusCodeInternal, Message: err.Error()})
+		return err
+	}
+	overlay, err := c.modifiedOverlay()
+	if err != nil {
+		span.SetStatus(trace.Status{Code: trace.StatusCodeInternal, Message: err.Error()})
+		return err
+	}
+	ctxt := buildutil.OverlayContext(&build.Default, overlay)
+
+	nvimutil.EchoProgress(c.Nvim, "GoCallGraph", "loading packages")
+	lprog, err := loadScope(ctxt, scopes)
+	if err != nil {
+		span.SetStatus(trace.Status{Code: trace.StatusCodeInternal, Message: err.Error()})
+		return err
+	}
+	prog := ssautil.CreateProgram(lprog, 0)
+	prog.Build()
+
+	nvimutil.EchoProgress(c.Nvim, "GoCallGraph", "building %s call graph", algo)
+	g, err := buildCallGraph(prog, lprog, algo)
+	if err != nil {
+		span.SetStatus(trace.Status{Code: trace.StatusCodeInvalidArgument, Message: err.Error()})
+		return err
+	}
+
+	var edges []callGraphEdge
+	switch root {
+	case callGraphFrom, callGraphTo:
+		fn := enclosingSSAFunction(prog, lprog, eval.File, eval.Offset)
+		if fn == nil {
+			err := errors.New("no function under the cursor")
+			span.SetStatus(trace.Status{Code: trace.StatusCodeNotFound, Message: err.Error()})
+			return err
+		}
+		edges = callGraphEdges(g, eval.Cwd, reachableNodes(g.Nodes[fn], root == callGraphFrom))
+	default:
+		edges = callGraphEdges(g, eval.Cwd, prefixNodes(g, root))
+	}
+
+	output := config.CallGraphOutput
+	if output == "" {
+		output = filepath.Join(os.TempDir(), "nvim-go-callgraph."+format)
+	}
+	f, err := os.Create(output)
+	if err != nil {
+		span.SetStatus(trace.Status{Code: trace.StatusCodeInternal, Message: err.Error()})
+		return errors.WithStack(err)
+	}
+	if err := writeCallGraph(f, format, edges); err != nil {
+		f.Close()
+		span.SetStatus(trace.Status{Code: trace.StatusCodeInternal, Message: err.Error()})
+		return err
+	}
+	if err := f.Close(); err != nil {
+		span.SetStatus(trace.Status{Code: trace.StatusCodeInternal, Message: err.Error()})
+		return errors.WithStack(err)
+	}
+
+	return nvimutil.EchoSuccess(c.Nvim, "GoCallGraph", fmt.Sprintf("wrote %d edges to %s", len(edges), output))
+}
+
+func (c *Command) cmdCallGraphComplete(ctx context.Context, a *nvim.CommandCompletionArgs) ([]string, error) {
+	// the index of the completing argument, the first is the command name
+	n := len(strings.Fields(a.CmdLine))
+	if a.ArgLead == "" {
+		n++
+	}
+
+	var candidates []string
+	switch n {
+	case 2:
+		candidates = []string{"static", "cha", "rta"}
+	case 3:
+		candidates = []string{callGraphFrom, callGraphTo}
+		for _, pkg := range c.packages.CachedPackages() {
+			candidates = append(candidates, pkg.ImportPath)
+		}
+	}
+
+	var matches []string
+	for _, cand := range candidates {
+		if strings.HasPrefix(cand, a.ArgLead) {
+			matches = append(matches, cand)
+		}
+	}
+
+	return matches, nil
+}
+
+// loadScope loads the packages of the scopes patterns with the tests.
+func loadScope(ctxt *build.Context, scopes []string) (*loader.Program, error) {
+	pkgs := buildutil.ExpandPatterns(ctxt, scopes)
+	if len(pkgs) == 0 {
+		return nil, errors.New("no packages in the analysis scope")
+	}
+
+	conf := loader.Config{
+		Build:       ctxt,
+		AllowErrors: true,
+		// keep the comments for the generated file detection of GoDeadCode
+		ParserMode: parser.ParseComments,
+		// the ImportPkgs value true imports the package with the tests
+		ImportPkgs: pkgs,
+	}
+	conf.TypeChecker.Error = func(err error) {}
+	lprog, err := conf.Load()
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	return lprog, nil
+}
+
+// mainPackages returns the main packages of the initial packages of lprog.
+// The test main package is used for the package which is not the main package.
+func mainPackages(prog *ssa.Program, lprog *loader.Program) []*ssa.Package {
+	var mains []*ssa.Package
+	for _, info := range lprog.InitialPackages() {
+		p := prog.Package(info.Pkg)
+		if p == nil {
+			continue
+		}
+		if p.Pkg.Name() == "main" && p.Func("main") != nil {
+			mains = append(mains, p)
+		} else if main := prog.CreateTestMainPackage(p); main != nil {
+			mains = append(mains, main)
+		}
+	}
+
+	return mains
+}
+
+// buildCallGraph builds the call graph of prog by the algo algorithm.
+// The rta algorithm analyzes the program from the main and init functions of the main packages.
+func buildCallGraph(prog *ssa.Program, lprog *loader.Program, algo string) (*callgraph.Graph, error) {
+	var g *callgraph.Graph
+	switch algo {
+	case "static":
+		g = static.CallGraph(prog)
+	case "cha":
+		g = cha.CallGraph(prog)
+	case "rta":
+		mains := mainPackages(prog, lprog)
+		if len(mains) == 0 {
+			return nil, errors.New("analysis scope has no main and no tests")
+		}
+		var roots []*ssa.Function
+		for _, main := range mains {
+			roots = append(roots, main.Func("init"), main.Func("main"))
+		}
+		g = rta.Analyze(roots, true).CallGraph
+	default:
+		return nil, errors.Errorf("unknown call graph algorithm: %s", algo)
+	}
+	g.DeleteSyntheticNodes()
+
+	return g, nil
+}
+
+// enclosingSSAFunction returns the function which encloses the offset of file.
+func enclosingSSAFunction(prog *ssa.Program, lprog *loader.Program, file string, offset int) *ssa.Function {
+	for _, info := range lprog.AllPackages {
+		for _, f := range info.Files {
+			tf := lprog.Fset.File(f.Pos())
+			if tf == nil || tf.Name() != file || offset < 0 || offset > tf.Size() {
+				continue
+			}
+			pos := tf.Pos(offset)
+			path, _ := astutil.PathEnclosingInterval(f, pos, pos)
+			if p := prog.Package(info.Pkg); p != nil {
+				return ssa.EnclosingFunction(p, path)
+			}
+		}
+	}
+
+	return nil
+}
+
+// reachableNodes returns the nodes which are reachable from n through the callee edges, or the caller edges if forward is false.
+func reachableNodes(n *callgraph.Node, forward bool) map[*callgraph.Node]bool {
+	seen := make(map[*callgraph.Node]bool)
+	if n == nil {
+		return seen
+	}
+
+	stack := []*callgraph.Node{n}
+	seen[n] = true
+	for len(stack) > 0 {
+		n := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+
+		edges := n.Out
+		if !forward {
+			edges = n.In
+		}
+		for _, e := range edges {
+			next := e.Callee
+			if !forward {
+				next = e.Caller
+			}
+			if !seen[next] {
+				seen[next] = true
+				stack = append(stack, next)
+			}
+		}
+	}
+
+	return seen
+}
+
+// prefixNodes returns the nodes of the function which package import path has the prefix.
+func prefixNodes(g *callgraph.Graph, prefix string) map[*callgraph.Node]bool {
+	nodes := make(map[*callgraph.Node]bool)
+	for fn, n := range g.Nodes {
+		if fn == nil {
+			continue
+		}
+		if pkg := fn.Package(); prefix == "" || (pkg != nil && strings.HasPrefix(pkg.Pkg.Path(), prefix)) {
+			nodes[n] = true
+		}
+	}
+
+	return nodes
+}
+
+// callGraphEdges returns the sorted edges of g which caller and callee are in nodes.
+// The call site positions are relative to cwd.
+func callGraphEdges(g *callgraph.Graph, cwd string, nodes map[*callgraph.Node]bool) []callGraphEdge {
+	var edges []callGraphEdge
+	callgraph.GraphVisitEdges(g, func(e *callgraph.Edge) error {
+		if !nodes[e.Caller] || !nodes[e.Callee] {
+			return nil
+		}
+
+		edge := callGraphEdge{
+			Caller: e.Caller.Func.String(),
+			Callee: e.Callee.Func.String(),
+		}
+		if e.Site != nil {
+			edge.Dynamic = e.Site.Common().StaticCallee() == nil
+			if pos := e.Pos(); pos.IsValid() {
+				p := e.Caller.Func.Prog.Fset.Position(pos)
+				p.Filename = fs.Rel(cwd, p.Filename)
+				edge.Pos = p.String()
+			}
+		}
+		edges = append(edges, edge)
+
+		return nil
+	})
+	sort.Slice(edges, func(i, j int) bool {
+		if edges[i].Caller != edges[j].Caller {
+			return edges[i].Caller < edges[j].Caller
+		}
+		if edges[i].Callee != edges[j].Callee {
+			return edges[i].Callee < edges[j].Callee
+		}
+		return edges[i].Pos < edges[j].Pos
+	})
+
+	return edges
+}
+
+// writeCallGraph writes edges to w in the format, "dot" or "json".
+func writeCallGraph(w io.Writer, format string, edges []callGraphEdge) error {
+	switch format {
+	case "dot":
+		fmt.Fprintln(w, "digraph callgraph {")
+		for _, e := range edges {
+			attr := ""
+			if e.Dynamic {
+				attr = " [style=dashed]"
+			}
+			fmt.Fprintf(w, "\t%q -> %q%s;\n", e.Caller, e.Callee, attr)
+		}
+		_, err := fmt.Fprintln(w, "}")
+		return errors.WithStack(err)
+
+	case "json":
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "\t")
+		if edges == nil {
+			edges = []callGraphEdge{}
+		}
+		return errors.WithStack(enc.Encode(edges))
+	}
+
+	return errors.Errorf("unknown call graph format: %s", format)
+}
@@ -0,0 +1,215 @@
+// Copyright 2018 The nvim-go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package command
+
+import (
+	"bytes"
+	"go/build"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"golang.org/x/tools/go/loader"
+	"golang.org/x/tools/go/ssa/ssautil"
+)
+
+const callGraphSrc = `package main
+
+type I interface{ M() }
+
+type T struct{}
+
+func (T) M() { leaf() }
+
+func leaf() {}
+
+func mid(i I) {
+	i.M()
+	leaf()
+}
+
+func main() {
+	mid(T{})
+}
+
+func unused() { leaf() }
+`
+
+func TestCallGraph(t *testing.T) {
+	dir, err := ioutil.TempDir("", "nvim-go-callgraph")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "main.go")
+	writeFile(t, file, callGraphSrc)
+
+	conf := loader.Config{Build: &build.Default}
+	f, err := conf.ParseFile(file, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	conf.CreateFromFiles("main", f)
+	lprog, err := conf.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	prog := ssautil.CreateProgram(lprog, 0)
+	prog.Build()
+
+	edgeStrings := func(edges []callGraphEdge) []string {
+		var s []string
+		for _, e := range edges {
+			s = append(s, e.Caller+" -> "+e.Callee+" "+e.Pos)
+		}
+		return s
+	}
+
+	tests := []struct {
+		name string
+		algo string
+		root string
+		at   string
+		want []string
+	}{
+		{
+			name: "static from",
+			algo: "static",
+			root: callGraphFrom,
+			at:   "mid(T{})",
+			want: []string{
+				"main.main -> main.mid main.go:17:5",
+				"main.mid -> main.leaf main.go:13:6",
+			},
+		},
+		{
+			name: "cha from",
+			algo: "cha",
+			root: callGraphFrom,
+			at:   "i.M()",
+			want: []string{
+				"(main.T).M -> main.leaf main.go:7:20",
+				"main.mid -> (main.T).M main.go:12:5",
+				"main.mid -> main.leaf main.go:13:6",
+			},
+		},
+		{
+			name: "static to",
+			algo: "static",
+			root: callGraphTo,
+			at:   "func leaf",
+			want: []string{
+				"(main.T).M -> main.leaf main.go:7:20",
+				"main.main -> main.mid main.go:17:5",
+				"main.mid -> main.leaf main.go:13:6",
+				"main.unused -> main.leaf main.go:20:21",
+			},
+		},
+		{
+			name: "rta",
+			algo: "rta",
+			want: []string{
+				"(main.T).M -> main.leaf main.go:7:20",
+				"main.main -> main.mid main.go:17:5",
+				"main.mid -> (main.T).M main.go:12:5",
+				"main.mid -> main.leaf main.go:13:6",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g, err := buildCallGraph(prog, lprog, tt.algo)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var edges []callGraphEdge
+			if tt.root == "" {
+				edges = callGraphEdges(g, dir, prefixNodes(g, "main"))
+			} else {
+				fn := enclosingSSAFunction(prog, lprog, file, strings.Index(callGraphSrc, tt.at))
+				if fn == nil {
+					t.Fatalf("no function at %q", tt.at)
+				}
+				edges = callGraphEdges(g, dir, reachableNodes(g.Nodes[fn], tt.root == callGraphFrom))
+			}
+			// the rta graph has the edges from the runtime package initialization
+			var got []string
+			for _, e := range edgeStrings(edges) {
+				if !strings.HasPrefix(e, "main.init") {
+					got = append(got, e)
+				}
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("callGraphEdges() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	if _, err := buildCallGraph(prog, lprog, "pointer"); err == nil {
+		t.Error("buildCallGraph(pointer): want error")
+	}
+}
+
+func TestWriteCallGraph(t *testing.T) {
+	edges := []callGraphEdge{
+		{Caller: "main.main", Callee: "main.f", Pos: "main.go:3:2"},
+		{Caller: "main.f", Callee: "(main.T).M", Pos: "main.go:7:2", Dynamic: true},
+	}
+
+	tests := []struct {
+		format string
+		edges  []callGraphEdge
+		want   string
+	}{
+		{
+			format: "dot",
+			edges:  edges,
+			want: `digraph callgraph {
+	"main.main" -> "main.f";
+	"main.f" -> "(main.T).M" [style=dashed];
+}
+`,
+		},
+		{
+			format: "json",
+			edges:  edges,
+			want: `[
+	{
+		"caller": "main.main",
+		"callee": "main.f",
+		"pos": "main.go:3:2"
+	},
+	{
+		"caller": "main.f",
+		"callee": "(main.T).M",
+		"pos": "main.go:7:2",
+		"dynamic": true
+	}
+]
+`,
+		},
+		{
+			format: "json",
+			want:   "[]\n",
+		},
+	}
+	for _, tt := range tests {
+		var buf bytes.Buffer
+		if err := writeCallGraph(&buf, tt.format, tt.edges); err != nil {
+			t.Fatal(err)
+		}
+		if got := buf.String(); got != tt.want {
+			t.Errorf("writeCallGraph(%s) = %q, want %q", tt.format, got, tt.want)
+		}
+	}
+
+	if err := writeCallGraph(new(bytes.Buffer), "svg", edges); err == nil {
+		t.Error("writeCallGraph(svg): want error")
+	}
+}
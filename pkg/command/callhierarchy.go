@@ -0,0 +1,487 @@
+// Copyright 2018 The nvim-go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package command
+
+import (
+	"context"
+	"fmt"
+	"go/ast"
+	"go/token"
+	"go/types"
+	"io/ioutil"
+	"regexp"
+	"sort"
+
+	"github.com/neovim/go-client/nvim"
+	"github.com/pkg/errors"
+	"go.opencensus.io/trace"
+	"golang.org/x/tools/cmd/guru/serial"
+	"golang.org/x/tools/go/ast/astutil"
+
+	"github.com/zchee/nvim-go/pkg/fs"
+	"github.com/zchee/nvim-go/pkg/monitoring"
+	"github.com/zchee/nvim-go/pkg/nvimutil"
+)
+
+// callHierarchyBufferName buffer name of the GoCallHierarchy buffer.
+const callHierarchyBufferName = "__GoCallHierarchy__"
+
+// callHierarchy represents the GoCallHierarchy tree buffer.
+type callHierarchy struct {
+	treeView
+
+	// incoming whether the tree shows the callers instead of the callees.
+	incoming bool
+	cwd      string
+}
+
+// callNode is the data of the GoCallHierarchy tree node.
+type callNode struct {
+	// Name is the full name of the function, such as "(*github.com/foo/bar.T).Method".
+	Name string
+	// Func is the position enclosed by the function. If the node is the dynamic call site, Func is the call site.
+	Func token.Position
+	// Dynamic whether the node is the dynamic call site, which callees are unknown until the pointer analysis.
+	Dynamic bool
+
+	parent *callNode
+}
+
+// inCycle reports whether the ancestors of n have the same function with n.
+func (n *callNode) inCycle() bool {
+	for p := n.parent; p != nil; p = p.parent {
+		if !p.Dynamic && p.Name == n.Name {
+			return true
+		}
+	}
+
+	return false
+}
+
+type cmdCallHierarchyEval struct {
+	Cwd    string `eval:"getcwd()"`
+	File   string `eval:"expand('%:p')"`
+	BufNr  int    `eval:"bufnr('%')"`
+	WinID  int    `eval:"win_getid()"`
+	Offset int    `eval:"line2byte(line('.')) + (col('.')-2)"`
+}
+
+func (c *Command) cmdCallHierarchy(ctx context.Context, args []string, eval *cmdCallHierarchyEval) {
+	errch := make(chan interface{}, 1)
+	go func() {
+		errch <- c.CallHierarchy(ctx, args, eval)
+	}()
+
+	// the incoming calls runs the guru query, do not block the following GoCancel
+	go c.callHierarchyResult(ctx, errch)
+}
+
+// callHierarchyResult waits the result of CallHierarchy from errch and shows it.
+func (c *Command) callHierarchyResult(ctx context.Context, errch chan interface{}) {
+	select {
+	case <-ctx.Done():
+		return
+	case err := <-errch:
+		switch e := err.(type) {
+		case error:
+			if errors.Cause(e) == context.Canceled {
+				return
+			}
+			nvimutil.ErrorWrap(c.Nvim, e)
+		case nil:
+			// nothing to do
+		}
+	}
+}
+
+// CallHierarchy shows the call hierarchy tree rooted at the function under the cursor.
+//
+// The direction is "incoming" (callers, default) or "outgoing" (callees). The children of each node are
+// loaded lazily when the node is expanded.
+func (c *Command) CallHierarchy(ctx context.Context, args []string, eval *cmdCallHierarchyEval) interface{} {
+	var span *trace.Span
+	ctx, span = monitoring.StartSpan(ctx, "CallHierarchy")
+	defer span.End()
+
+	incoming := true
+	if len(args) > 0 {
+		switch args[0] {
+		case "incoming":
+		case "outgoing":
+			incoming = false
+		default:
+			err := errors.Errorf("unknown call hierarchy direction: %s", args[0])
+			span.SetStatus(trace.Status{Code: trace.StatusCodeInvalidArgument, Message: err.Error()})
+			return err
+		}
+	}
+
+	h := c.callHierarchy
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	buf, err := c.Nvim.BufferLines(nvim.Buffer(eval.BufNr), 0, -1, true)
+	if err != nil {
+		span.SetStatus(trace.Status{Code: trace.StatusCodeInternal, Message: err.Error()})
+		return errors.WithStack(err)
+	}
+
+	cf, err := typeCheck(eval.File, nvimutil.ToByteSlice(buf))
+	if err != nil {
+		span.SetStatus(trace.Status{Code: trace.StatusCodeInternal, Message: err.Error()})
+		return errors.WithStack(err)
+	}
+
+	fn, err := callHierarchyRoot(cf, eval.Offset)
+	if err != nil {
+		span.SetStatus(trace.Status{Code: trace.StatusCodeInvalidArgument, Message: err.Error()})
+		return err
+	}
+
+	h.incoming = incoming
+	h.cwd = eval.Cwd
+	h.srcBuffer = nvim.Buffer(eval.BufNr)
+	h.srcWindow = nvim.Window(eval.WinID)
+	h.srcFile = eval.File
+
+	pos := cf.position(fn.Pos())
+	h.tree = nvimutil.NewTree(h.newNode(&callNode{Name: fn.FullName(), Func: pos}, pos))
+
+	if !h.isOpened(c.Nvim) {
+		b := nvimutil.NewBuffer(c.Nvim)
+		if err := b.Create(callHierarchyBufferName, filetypeGoAnalyze, "belowright 60vsplit", sidebarOption(filetypeGoAnalyze)); err != nil {
+			span.SetStatus(trace.Status{Code: trace.StatusCodeInternal, Message: err.Error()})
+			return errors.WithStack(err)
+		}
+		b.SetLocalMapping(nvimutil.NoremapNormal, map[string]string{
+			"<CR>": ":<C-u>call GoCallHierarchyAction('jump')<CR>",
+			"o":    ":<C-u>call GoCallHierarchyAction('toggle')<CR>",
+			"za":   ":<C-u>call GoCallHierarchyAction('toggle')<CR>",
+			"q":    ":<C-u>call GoCallHierarchyAction('close')<CR>",
+		})
+		h.buffer = b.Buffer()
+		h.window = b.Window
+	}
+
+	// expand the root node at first
+	if err := h.expand(ctx, c, h.tree.Roots[0]); err != nil {
+		span.SetStatus(trace.Status{Code: trace.StatusCodeInternal, Message: err.Error()})
+		return errors.WithStack(err)
+	}
+	h.tree.Roots[0].Expanded = true
+
+	if err := h.render(c.Nvim); err != nil {
+		span.SetStatus(trace.Status{Code: trace.StatusCodeInternal, Message: err.Error()})
+		return errors.WithStack(err)
+	}
+
+	batch := c.Nvim.NewBatch()
+	batch.SetCurrentWindow(h.window)
+	batch.SetWindowCursor(h.window, [2]int{1, 0})
+
+	return batch.Execute()
+}
+
+func (c *Command) funcCallHierarchyAction(ctx context.Context, args []string, line int) {
+	// expanding the node runs the guru query, do not block the following GoCancel
+	go func() {
+		if err := c.CallHierarchyAction(ctx, args, line); err != nil && errors.Cause(err) != context.Canceled {
+			nvimutil.ErrorWrap(c.Nvim, err)
+		}
+	}()
+}
+
+// CallHierarchyAction runs the action of GoCallHierarchy buffer to the node at line.
+//
+// The available actions are "jump", "toggle" and "close".
+func (c *Command) CallHierarchyAction(ctx context.Context, args []string, line int) error {
+	h := c.callHierarchy
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.tree == nil || len(args) == 0 {
+		return nil
+	}
+
+	switch args[0] {
+	case "jump":
+		n := h.tree.NodeAt(line)
+		if n == nil || !n.Start.IsValid() {
+			return nil
+		}
+		return jumpToPos(c.Nvim, h.srcWindow, n.Start)
+
+	case "toggle":
+		n := h.tree.NodeAt(line)
+		if n == nil {
+			return nil
+		}
+		if err := h.expand(ctx, c, n); err != nil {
+			return err
+		}
+		if !h.tree.Toggle(line) {
+			return nil
+		}
+		return h.render(c.Nvim)
+
+	case "close":
+		h.tree = nil
+		return c.Nvim.CloseWindow(h.window, true)
+
+	default:
+		return errors.Errorf("unknown GoCallHierarchy action: %s", args[0])
+	}
+}
+
+// expand loads the children of n if n is not loaded yet.
+func (h *callHierarchy) expand(ctx context.Context, c *Command, n *nvimutil.TreeNode) error {
+	if !n.Expandable {
+		return nil
+	}
+	cn := n.Data.(*callNode)
+
+	overlay, err := c.modifiedOverlay()
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	var children []*nvimutil.TreeNode
+	switch {
+	case cn.Dynamic:
+		children, err = h.dynamicCallees(ctx, c, cn, overlay)
+	case h.incoming:
+		children, err = h.callers(ctx, c, cn, overlay)
+	default:
+		children, err = h.callees(cn, overlay)
+	}
+	nvimutil.ClearMsg(c.Nvim)
+	if err != nil {
+		return err
+	}
+
+	sort.SliceStable(children, func(i, j int) bool {
+		pi, pj := children[i].Start, children[j].Start
+		if pi.Filename != pj.Filename {
+			return pi.Filename < pj.Filename
+		}
+		return pi.Line < pj.Line || pi.Line == pj.Line && pi.Column < pj.Column
+	})
+	n.Children = children
+	n.Expandable = false
+
+	return nil
+}
+
+// callers returns the nodes of the callers of the cn function. The position of each node is the call site.
+func (h *callHierarchy) callers(ctx context.Context, c *Command, cn *callNode, overlay map[string][]byte) ([]*nvimutil.TreeNode, error) {
+	pos, err := guruPos(cn.Func, overlay)
+	if err != nil {
+		return nil, err
+	}
+	res, err := c.guruQuery(ctx, "callers", h.srcFile, pos, overlay)
+	if err != nil {
+		return nil, err
+	}
+	v, ok := res.([]serial.Caller)
+	if !ok {
+		return nil, errTypeAssertion
+	}
+
+	children := make([]*nvimutil.TreeNode, 0, len(v))
+	for _, clr := range v {
+		site := parseLineColPos(clr.Pos)
+		children = append(children, h.newNode(&callNode{Name: clr.Caller, Func: site, parent: cn}, site))
+	}
+
+	return children, nil
+}
+
+// callees returns the nodes of the calls in the body of the cn function. The position of each node is the call site.
+//
+// The statically dispatched callee is resolved by the type information. The dynamic call is returned as
+// the dynamic call site node, which callees are analysed by guru when the node is expanded.
+func (h *callHierarchy) callees(cn *callNode, overlay map[string][]byte) ([]*nvimutil.TreeNode, error) {
+	src, err := overlaySource(overlay, cn.Func.Filename)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	cf, err := typeCheck(cn.Func.Filename, src)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	pos := cf.Fset.File(cf.File.Pos()).Pos(posOffset(src, cn.Func.Line, cn.Func.Column))
+	path, _ := astutil.PathEnclosingInterval(cf.File, pos, pos)
+	var body *ast.BlockStmt
+	for _, n := range path {
+		switch n := n.(type) {
+		case *ast.FuncDecl:
+			body = n.Body
+		case *ast.FuncLit:
+			body = n.Body
+		default:
+			continue
+		}
+		break
+	}
+	if body == nil {
+		// the function declared without body, such as the assembly function
+		return nil, nil
+	}
+
+	var children []*nvimutil.TreeNode
+	ast.Inspect(body, func(n ast.Node) bool {
+		call, ok := n.(*ast.CallExpr)
+		if !ok {
+			return true
+		}
+		if tv, ok := cf.Info.Types[call.Fun]; ok && tv.IsType() {
+			return true // conversion
+		}
+
+		var id *ast.Ident
+		switch fun := astutil.Unparen(call.Fun).(type) {
+		case *ast.Ident:
+			id = fun
+		case *ast.SelectorExpr:
+			id = fun.Sel
+			if sel := cf.Info.Selections[fun]; sel != nil && types.IsInterface(sel.Recv()) {
+				id = nil // interface method call
+			}
+		}
+
+		var obj types.Object
+		if id != nil {
+			obj = cf.Info.Uses[id]
+		}
+		switch obj := obj.(type) {
+		case *types.Builtin:
+			// nothing to do
+		case *types.Func:
+			site := cf.Fset.Position(id.Pos())
+			children = append(children, h.newNode(&callNode{Name: obj.FullName(), Func: cf.position(obj.Pos()), parent: cn}, site))
+		default:
+			site := cf.Fset.Position(call.Lparen)
+			children = append(children, h.newNode(&callNode{Name: exprText(cf.Fset, src, call.Fun), Func: site, Dynamic: true, parent: cn}, site))
+		}
+		return true
+	})
+
+	return children, nil
+}
+
+// dynamicCallees returns the nodes of the possible callees of the cn dynamic call site.
+// The position of each node is the declaration of the callee.
+func (h *callHierarchy) dynamicCallees(ctx context.Context, c *Command, cn *callNode, overlay map[string][]byte) ([]*nvimutil.TreeNode, error) {
+	pos, err := guruPos(cn.Func, overlay)
+	if err != nil {
+		return nil, err
+	}
+	res, err := c.guruQuery(ctx, "callees", h.srcFile, pos, overlay)
+	if err != nil {
+		return nil, err
+	}
+	v, ok := res.(*serial.Callees)
+	if !ok {
+		return nil, errTypeAssertion
+	}
+
+	children := make([]*nvimutil.TreeNode, 0, len(v.Callees))
+	for _, cle := range v.Callees {
+		decl := parseLineColPos(cle.Pos)
+		children = append(children, h.newNode(&callNode{Name: cle.Name, Func: decl, parent: cn}, decl))
+	}
+
+	return children, nil
+}
+
+// newNode returns the tree node of cn which jump destination is pos.
+// The node of the function which is already appeared in the ancestors is marked as the cycle, and is not expandable.
+func (h *callHierarchy) newNode(cn *callNode, pos token.Position) *nvimutil.TreeNode {
+	n := &nvimutil.TreeNode{
+		Text:       fmt.Sprintf("%s  %s:%d", trimPkgPath(cn.Name), fs.Rel(h.cwd, pos.Filename), pos.Line),
+		Start:      pos,
+		End:        pos,
+		Expandable: pos.IsValid(),
+		Data:       cn,
+	}
+	if cn.Dynamic {
+		n.Text = fmt.Sprintf("%s (dynamic)  %s:%d", cn.Name, fs.Rel(h.cwd, pos.Filename), pos.Line)
+	} else if cn.inCycle() {
+		n.Text += " (cycle)"
+		n.Expandable = false
+	}
+
+	return n
+}
+
+// callHierarchyRoot returns the function of the identifier at offset, or the function declaration
+// enclosing offset.
+func callHierarchyRoot(cf *checkedFile, offset int) (*types.Func, error) {
+	pos := cf.Fset.File(cf.File.Pos()).Pos(offset)
+	path, _ := astutil.PathEnclosingInterval(cf.File, pos, pos)
+
+	var fn *types.Func
+	if id, ok := path[0].(*ast.Ident); ok {
+		fn, _ = cf.Info.ObjectOf(id).(*types.Func)
+	}
+	if fn == nil {
+		for _, n := range path {
+			if decl, ok := n.(*ast.FuncDecl); ok {
+				fn, _ = cf.Info.Defs[decl.Name].(*types.Func)
+				break
+			}
+		}
+	}
+	if fn == nil {
+		return nil, errors.New("no function here")
+	}
+	if recv := fn.Type().(*types.Signature).Recv(); recv != nil && types.IsInterface(recv.Type()) {
+		return nil, errors.Errorf("%s is an interface method", fn.Name())
+	}
+	if !fn.Pos().IsValid() {
+		return nil, errors.Errorf("%s has no declaration", fn.Name())
+	}
+
+	return fn, nil
+}
+
+// pkgPathRe matches the package path prefix of the qualified name.
+var pkgPathRe = regexp.MustCompile(`[^()*\[\]\s]*/`)
+
+// trimPkgPath returns the function or type name without the package path prefix,
+// such as "(*bar.T).Method" for "(*github.com/foo/bar.T).Method".
+func trimPkgPath(name string) string {
+	return pkgPathRe.ReplaceAllString(name, "")
+}
+
+// exprText returns the source text of expr.
+func exprText(fset *token.FileSet, src []byte, expr ast.Expr) string {
+	start, end := fset.Position(expr.Pos()).Offset, fset.Position(expr.End()).Offset
+	if start < 0 || end > len(src) || start > end {
+		return ""
+	}
+
+	return string(src[start:end])
+}
+
+// overlaySource returns the contents of file in overlay, or reads file if it is not in overlay.
+func overlaySource(overlay map[string][]byte, file string) ([]byte, error) {
+	if src, ok := overlay[file]; ok {
+		return src, nil
+	}
+
+	return ioutil.ReadFile(file)
+}
+
+// guruPos returns the guru query position of pos, such as "foo.go:#123".
+func guruPos(pos token.Position, overlay map[string][]byte) (string, error) {
+	src, err := overlaySource(overlay, pos.Filename)
+	if err != nil {
+		return "", errors.WithStack(err)
+	}
+
+	return fmt.Sprintf("%s:#%d", pos.Filename, posOffset(src, pos.Line, pos.Column)), nil
+}
@@ -0,0 +1,103 @@
+// Copyright 2018 The nvim-go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package command
+
+import (
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const callHierarchySrc = `package p
+
+type I interface{ M() }
+
+type T struct{}
+
+func (T) M() {}
+
+func fact(n int) int {
+	if n == 0 {
+		return 1
+	}
+	return n * fact(n-1)
+}
+
+func f(i I, g func()) {
+	_ = len("x")
+	_ = int64(fact(3))
+	i.M()
+	g()
+	T{}.M()
+}
+`
+
+func TestCallHierarchyCallees(t *testing.T) {
+	file, cf := testCheckedFile(t, callHierarchySrc)
+	dir := filepath.Dir(file)
+
+	tests := []struct {
+		name string
+		at   string
+		want []string
+	}{
+		{
+			name: "static and dynamic calls",
+			at:   "func f(",
+			want: []string{
+				"p.fact  p.go:18",
+				"i.M (dynamic)  p.go:19",
+				"g (dynamic)  p.go:20",
+				"(p.T).M  p.go:21",
+			},
+		},
+		{
+			name: "recursion",
+			at:   "n * fact",
+			want: []string{"p.fact  p.go:13 (cycle)"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fn, err := callHierarchyRoot(cf, strings.Index(callHierarchySrc, tt.at))
+			if err != nil {
+				t.Fatal(err)
+			}
+			h := &callHierarchy{cwd: dir}
+			root := &callNode{Name: fn.FullName(), Func: cf.Fset.Position(fn.Pos())}
+			children, err := h.callees(root, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var got []string
+			for _, n := range children {
+				got = append(got, n.Text)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("callees() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTrimPkgPath(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+	}{
+		{name: "github.com/foo/bar.Baz", want: "bar.Baz"},
+		{name: "(*github.com/foo/bar.T).Method", want: "(*bar.T).Method"},
+		{name: "github.com/foo/bar.f$1", want: "bar.f$1"},
+		{name: "fmt.Println", want: "fmt.Println"},
+		{name: "map[string]*github.com/foo/bar.T", want: "map[string]*bar.T"},
+	}
+	for _, tt := range tests {
+		if got := trimPkgPath(tt.name); got != tt.want {
+			t.Errorf("trimPkgPath(%q) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
@@ -19,13 +19,58 @@ type Command struct {
 	buildContext *buildctxt.Context
 	errs         *sync.Map
 	namespaceID  int
+
+	analyze       *treeView
+	astView       *treeView
+	callHierarchy *callHierarchy
+	typeHierarchy *treeView
+	imports       *importGraph
+
+	semantic   *semanticCache
+	sameIds    *sameIds
+	signature  *signatureHelp
+	hover      *hoverWindow
+	docBrowser *docBrowser
+	packages   *packageCache
+	symbols    *symbolIndex
+	workspace  *workspaceIndex
+	gopls      *goplsBridge
+
+	guruQueries *guruQueries
+	defStack    *defStack
+
+	renamePreview *renamePreview
 }
 
 // NewCommand return the new Command type with initialize some variables.
 func NewCommand(ctx context.Context, v *nvim.Nvim, bctxt *buildctxt.Context) *Command {
-	return &Command{
-		Nvim:         v,
-		buildContext: bctxt,
-		errs:         new(sync.Map),
+	c := &Command{
+		Nvim:          v,
+		buildContext:  bctxt,
+		errs:          new(sync.Map),
+		analyze:       new(treeView),
+		astView:       new(treeView),
+		callHierarchy: new(callHierarchy),
+		typeHierarchy: new(treeView),
+		imports:       new(importGraph),
+		semantic: &semanticCache{
+			tick: make(map[nvim.Buffer]int),
+			file: make(map[nvim.Buffer]*checkedFile),
+		},
+		sameIds:    new(sameIds),
+		signature:  new(signatureHelp),
+		hover:      new(hoverWindow),
+		docBrowser: new(docBrowser),
+		packages:   new(packageCache),
+		workspace:  new(workspaceIndex),
+		gopls:      new(goplsBridge),
+
+		guruQueries: new(guruQueries),
+		defStack:    new(defStack),
+
+		renamePreview: new(renamePreview),
 	}
+	c.symbols = newSymbolIndex(c.packages.Packages)
+
+	return c
 }
@@ -0,0 +1,577 @@
+// Copyright 2018 The nvim-go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package command
+
+import (
+	"bytes"
+	"context"
+	"go/ast"
+	"go/token"
+	"go/types"
+	"io/ioutil"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/neovim/go-client/nvim"
+	"github.com/pkg/errors"
+	"go.opencensus.io/trace"
+	"golang.org/x/tools/go/ast/astutil"
+	"golang.org/x/tools/go/types/typeutil"
+
+	"github.com/zchee/nvim-go/pkg/monitoring"
+	"github.com/zchee/nvim-go/pkg/nvimutil"
+)
+
+// List of completion item kinds.
+const (
+	completeKindVar     = "var"
+	completeKindConst   = "const"
+	completeKindFunc    = "func"
+	completeKindMethod  = "method"
+	completeKindField   = "field"
+	completeKindType    = "type"
+	completeKindPackage = "package"
+	completeKindKeyword = "keyword"
+)
+
+// List of completion item scores which ranks the item by the type compatibility with the expected type.
+const (
+	scoreKeyword = iota - 1
+	scoreNone
+	scoreFuncResult
+	scoreAssignable
+	scoreIdentical
+)
+
+// completeKeywords Go keywords which are offered by the identifier completion.
+var completeKeywords = []string{
+	"break", "case", "chan", "const", "continue", "default", "defer", "else", "fallthrough", "for",
+	"func", "go", "goto", "if", "import", "interface", "map", "package", "range", "return",
+	"select", "struct", "switch", "type", "var",
+}
+
+// completionItem represents a complete-items of Neovim.
+type completionItem struct {
+	Word  string `msgpack:"word"`
+	Kind  string `msgpack:"kind"`
+	Menu  string `msgpack:"menu"`
+	Info  string `msgpack:"info"`
+	Icase int    `msgpack:"icase"`
+	Dup   int    `msgpack:"dup"`
+	// UserData autoImportPrefix and the import path if the item is the unimported package symbol.
+	UserData string `msgpack:"user_data,omitempty"`
+
+	score int
+	// rank rank of the package of the unimported symbol. Lower is better.
+	rank int
+}
+
+// funcCompleteArgs represents the arguments of the omnifunc.
+type funcCompleteArgs struct {
+	FindStart int `msgpack:",array"`
+	Base      string
+}
+
+type funcCompleteEval struct {
+	File   string `eval:"expand('%:p')"`
+	BufNr  int    `eval:"bufnr('%')"`
+	Offset int    `eval:"line2byte(line('.')) + (col('.')-2)"`
+}
+
+func (c *Command) funcComplete(ctx context.Context, args *funcCompleteArgs, eval *funcCompleteEval) (interface{}, error) {
+	result, err := c.Complete(ctx, args.FindStart == 1, args.Base, eval)
+	if err != nil {
+		nvimutil.ErrorWrap(c.Nvim, err)
+		if args.FindStart == 1 {
+			return -3, nil // cancel silently and not leave the completion mode
+		}
+		return []completionItem{}, nil
+	}
+
+	return result, nil
+}
+
+// Complete implements the omnifunc of Go source code.
+//
+// If findstart is true, returns the start column of the completion word. Otherwise returns the
+// completion items which have the base prefix. The items are type checked with the current
+// unsaved buffer contents, and ranked by the type compatibility with the expected type of the cursor position.
+func (c *Command) Complete(ctx context.Context, findstart bool, base string, eval *funcCompleteEval) (interface{}, error) {
+	var span *trace.Span
+	ctx, span = monitoring.StartSpan(ctx, "Complete")
+	defer span.End()
+
+	buf, err := c.Nvim.BufferLines(nvim.Buffer(eval.BufNr), 0, -1, true)
+	if err != nil {
+		span.SetStatus(trace.Status{Code: trace.StatusCodeInternal, Message: err.Error()})
+		return nil, errors.WithStack(err)
+	}
+	src := nvimutil.ToByteSlice(buf)
+	if eval.Offset < 0 || len(src) < eval.Offset {
+		return nil, errors.Errorf("invalid cursor offset: %d", eval.Offset)
+	}
+
+	if findstart {
+		return completionStart(src, eval.Offset), nil
+	}
+
+	items, err := completions(eval.File, src, eval.Offset, base, c.symbols)
+	if err != nil {
+		span.SetStatus(trace.Status{Code: trace.StatusCodeInternal, Message: err.Error()})
+		return nil, errors.WithStack(err)
+	}
+
+	return items, nil
+}
+
+// WarmTypeCheck type checks the file in the background, so that the packages imported by the file are
+// already in the type check cache at the first completion.
+func (c *Command) WarmTypeCheck(file string) {
+	if !strings.HasSuffix(file, ".go") {
+		return
+	}
+
+	go func() {
+		src, err := ioutil.ReadFile(file)
+		if err != nil {
+			return
+		}
+		typeCheck(file, src)
+	}()
+}
+
+// completionStart returns the 0-based byte column of the start of the identifier before the offset.
+func completionStart(src []byte, offset int) int {
+	lineStart := bytes.LastIndexByte(src[:offset], '\n') + 1
+	start := offset
+	for start > lineStart && isIdentByte(src[start-1]) {
+		start--
+	}
+
+	return start - lineStart
+}
+
+// isIdentByte reports whether the b is the part of the Go identifier.
+// The non-ASCII bytes are treated as the identifier for the unicode letters.
+func isIdentByte(b byte) bool {
+	return 'a' <= b && b <= 'z' || 'A' <= b && b <= 'Z' || '0' <= b && b <= '9' || b == '_' || b >= 0x80
+}
+
+// completions returns the completion items of the identifier which has the prefix at the offset of src.
+// src is the source code that the prefix is already removed.
+//
+// If the selector is the name of the unimported package, returns the symbols of packages in idx.
+// idx can be nil.
+func completions(file string, src []byte, offset int, prefix string, idx *symbolIndex) ([]completionItem, error) {
+	ident := prefix
+	if ident == "" {
+		ident = "_"
+	}
+	patched := make([]byte, 0, len(src)+len(ident))
+	patched = append(patched, src[:offset]...)
+	patched = append(patched, ident...)
+	patched = append(patched, src[offset:]...)
+	if site := findCallSite(patched, offset+len(ident)); site != nil {
+		patched = site.closedSource(patched, offset+len(ident))
+	}
+
+	cf, err := typeCheck(file, patched)
+	if err != nil {
+		return nil, err
+	}
+
+	pos := cf.Fset.File(cf.File.Pos()).Pos(offset)
+	path, _ := astutil.PathEnclosingInterval(cf.File, pos, pos)
+	if len(path) == 0 {
+		return nil, nil
+	}
+	expected := expectedType(cf, path, pos)
+	qf := types.RelativeTo(cf.Pkg)
+
+	var (
+		objs     []types.Object
+		items    []completionItem
+		keywords bool
+	)
+	if id, ok := path[0].(*ast.Ident); ok && len(path) > 1 {
+		if sel, ok := path[1].(*ast.SelectorExpr); ok && sel.Sel == id {
+			objs = selectorObjects(cf, sel)
+			if x, ok := sel.X.(*ast.Ident); ok && idx != nil && cf.Info.ObjectOf(x) == nil {
+				items = unimportedItems(idx.Lookup(x.Name), prefix)
+			}
+		} else {
+			objs = scopeObjects(cf, pos)
+			keywords = true
+		}
+	} else {
+		objs = scopeObjects(cf, pos)
+		keywords = true
+	}
+
+	for _, obj := range objs {
+		if obj.Name() == "_" || !strings.HasPrefix(obj.Name(), prefix) {
+			continue
+		}
+		if item, ok := objectItem(obj, qf); ok {
+			item.score = completionScore(obj, expected)
+			items = append(items, item)
+		}
+	}
+	if keywords {
+		for _, kw := range completeKeywords {
+			if strings.HasPrefix(kw, prefix) {
+				items = append(items, completionItem{Word: kw, Kind: completeKindKeyword, score: scoreKeyword})
+			}
+		}
+	}
+
+	sort.SliceStable(items, func(i, j int) bool {
+		if items[i].score != items[j].score {
+			return items[i].score > items[j].score
+		}
+		if items[i].Word != items[j].Word {
+			return items[i].Word < items[j].Word
+		}
+		return items[i].rank < items[j].rank
+	})
+
+	return items, nil
+}
+
+// unimportedItems returns the completion items of the pkgs symbols which have the prefix.
+// The items carry the import path to add the import declaration when the item is accepted.
+func unimportedItems(pkgs []*indexedPackage, prefix string) []completionItem {
+	var items []completionItem
+	for rank, pkg := range pkgs {
+		for _, sym := range pkg.Symbols {
+			if !strings.HasPrefix(sym.Name, prefix) {
+				continue
+			}
+			info := sym.Kind + " " + pkg.Name + "." + sym.Name
+			switch sym.Kind {
+			case completeKindFunc:
+				info += strings.TrimPrefix(sym.Detail, "func")
+			default:
+				if sym.Detail != "" {
+					info += " " + sym.Detail
+				}
+			}
+			items = append(items, completionItem{
+				Word:     sym.Name,
+				Kind:     sym.Kind,
+				Menu:     pkg.ImportPath,
+				Info:     info + "\n\nimport " + strconv.Quote(pkg.ImportPath),
+				Dup:      1,
+				UserData: autoImportPrefix + pkg.ImportPath,
+				rank:     rank + 1,
+			})
+		}
+	}
+
+	return items
+}
+
+// selectorObjects returns the objects which can be selected by the sel selector expression.
+func selectorObjects(cf *checkedFile, sel *ast.SelectorExpr) []types.Object {
+	if id, ok := sel.X.(*ast.Ident); ok {
+		if pkgName, ok := cf.Info.Uses[id].(*types.PkgName); ok {
+			scope := pkgName.Imported().Scope()
+			var objs []types.Object
+			for _, name := range scope.Names() {
+				if obj := scope.Lookup(name); obj.Exported() {
+					objs = append(objs, obj)
+				}
+			}
+			return objs
+		}
+	}
+
+	tv, ok := cf.Info.Types[sel.X]
+	if !ok || tv.Type == nil || tv.Type == types.Typ[types.Invalid] {
+		return nil
+	}
+
+	visible := func(obj types.Object) bool {
+		return obj.Exported() || obj.Pkg() == cf.Pkg
+	}
+
+	var objs []types.Object
+	seen := make(map[string]bool)
+	if !tv.IsType() {
+		for _, field := range promotedFields(tv.Type) {
+			if visible(field) && !seen[field.Name()] {
+				seen[field.Name()] = true
+				objs = append(objs, field)
+			}
+		}
+	}
+	for _, msel := range typeutil.IntuitiveMethodSet(tv.Type, nil) {
+		if m := msel.Obj(); visible(m) && !seen[m.Name()] {
+			seen[m.Name()] = true
+			objs = append(objs, m)
+		}
+	}
+
+	return objs
+}
+
+// promotedFields returns the fields of the typ struct including the promoted fields of the embedded fields.
+// The shallower fields come first.
+func promotedFields(typ types.Type) []*types.Var {
+	var fields []*types.Var
+	seen := make(map[types.Type]bool)
+
+	queue := []types.Type{typ}
+	for len(queue) > 0 {
+		t := queue[0]
+		queue = queue[1:]
+		if p, ok := t.Underlying().(*types.Pointer); ok {
+			t = p.Elem()
+		}
+		if seen[t] {
+			continue
+		}
+		seen[t] = true
+
+		st, ok := t.Underlying().(*types.Struct)
+		if !ok {
+			continue
+		}
+		for i := 0; i < st.NumFields(); i++ {
+			field := st.Field(i)
+			fields = append(fields, field)
+			if field.Anonymous() {
+				queue = append(queue, field.Type())
+			}
+		}
+	}
+
+	return fields
+}
+
+// scopeObjects returns the objects which are visible at the pos.
+func scopeObjects(cf *checkedFile, pos token.Pos) []types.Object {
+	scope := cf.Pkg.Scope().Innermost(pos)
+	if scope == nil {
+		scope = cf.Info.Scopes[cf.File]
+	}
+
+	var objs []types.Object
+	seen := make(map[string]bool)
+	for s := scope; s != nil; s = s.Parent() {
+		local := s != cf.Pkg.Scope() && s != types.Universe && s != cf.Info.Scopes[cf.File]
+		for _, name := range s.Names() {
+			obj := s.Lookup(name)
+			if seen[name] || (local && obj.Pos() > pos) {
+				continue
+			}
+			seen[name] = true
+			objs = append(objs, obj)
+		}
+	}
+
+	return objs
+}
+
+// objectItem returns the completion item of obj. Returns false if obj is not completable such as label.
+func objectItem(obj types.Object, qf types.Qualifier) (completionItem, bool) {
+	item := completionItem{
+		Word: obj.Name(),
+		Info: types.ObjectString(obj, qf),
+	}
+
+	switch obj := obj.(type) {
+	case *types.Var:
+		item.Kind = completeKindVar
+		if obj.IsField() {
+			item.Kind = completeKindField
+		}
+		item.Menu = types.TypeString(obj.Type(), qf)
+	case *types.Const:
+		item.Kind = completeKindConst
+		item.Menu = types.TypeString(obj.Type(), qf)
+	case *types.Func:
+		item.Kind = completeKindFunc
+		if sig, ok := obj.Type().(*types.Signature); ok && sig.Recv() != nil {
+			item.Kind = completeKindMethod
+		}
+		item.Menu = types.TypeString(obj.Type(), qf)
+	case *types.TypeName:
+		item.Kind = completeKindType
+		switch obj.Type().Underlying().(type) {
+		case *types.Struct:
+			item.Menu = "struct"
+		case *types.Interface:
+			item.Menu = "interface"
+		default:
+			item.Menu = types.TypeString(obj.Type().Underlying(), qf)
+		}
+	case *types.PkgName:
+		item.Kind = completeKindPackage
+		item.Menu = obj.Imported().Path()
+	case *types.Builtin:
+		item.Kind = completeKindFunc
+		item.Info = "builtin " + obj.Name()
+	case *types.Nil:
+		item.Kind = completeKindVar
+		item.Menu = "untyped nil"
+	default:
+		return item, false
+	}
+
+	return item, true
+}
+
+// completionScore returns the score of obj which is ranked by the type compatibility with expected.
+func completionScore(obj types.Object, expected types.Type) int {
+	if expected == nil {
+		return scoreNone
+	}
+
+	switch obj := obj.(type) {
+	case *types.Var, *types.Const:
+		switch t := obj.Type(); {
+		case types.Identical(t, expected):
+			return scoreIdentical
+		case types.AssignableTo(t, expected):
+			return scoreAssignable
+		}
+	case *types.Func:
+		sig, ok := obj.Type().(*types.Signature)
+		if ok && sig.Results().Len() == 1 && types.AssignableTo(sig.Results().At(0).Type(), expected) {
+			return scoreFuncResult
+		}
+	}
+
+	return scoreNone
+}
+
+// expectedType returns the type which is expected at the pos such as the call argument,
+// the right hand side of the assignment and the return value. Returns nil if unknown.
+func expectedType(cf *checkedFile, path []ast.Node, pos token.Pos) types.Type {
+	valid := func(t types.Type) types.Type {
+		if t == nil || t == types.Typ[types.Invalid] {
+			return nil
+		}
+		return t
+	}
+	index := func(exprs []ast.Expr) int {
+		for i, expr := range exprs {
+			if pos <= expr.End() {
+				return i
+			}
+		}
+		return len(exprs)
+	}
+
+	for i, node := range path[1:] {
+		switch n := node.(type) {
+		case *ast.CallExpr:
+			if pos <= n.Lparen || (n.Rparen.IsValid() && n.Rparen < pos) {
+				return nil
+			}
+			typ := cf.Info.TypeOf(n.Fun)
+			if typ == nil {
+				return nil
+			}
+			sig, ok := typ.Underlying().(*types.Signature)
+			if !ok {
+				return valid(typ) // type conversion
+			}
+			arg, params := index(n.Args), sig.Params()
+			switch {
+			case sig.Variadic() && arg >= params.Len()-1 && !n.Ellipsis.IsValid():
+				return params.At(params.Len() - 1).Type().(*types.Slice).Elem()
+			case arg < params.Len():
+				return params.At(arg).Type()
+			}
+			return nil
+
+		case *ast.AssignStmt:
+			if pos <= n.TokPos || n.Tok == token.DEFINE || len(n.Lhs) != len(n.Rhs) {
+				return nil
+			}
+			if arg := index(n.Rhs); arg < len(n.Lhs) {
+				return valid(cf.Info.TypeOf(n.Lhs[arg]))
+			}
+			return nil
+
+		case *ast.ValueSpec:
+			if n.Type == nil {
+				return nil
+			}
+			return valid(cf.Info.TypeOf(n.Type))
+
+		case *ast.ReturnStmt:
+			sig := enclosingSignature(cf, path[i+1:])
+			if sig == nil {
+				return nil
+			}
+			if arg := index(n.Results); arg < sig.Results().Len() {
+				return sig.Results().At(arg).Type()
+			}
+			return nil
+
+		case *ast.BinaryExpr:
+			if pos >= n.Y.Pos() {
+				return valid(cf.Info.TypeOf(n.X))
+			}
+			return valid(cf.Info.TypeOf(n.Y))
+
+		case *ast.CompositeLit:
+			typ := valid(cf.Info.TypeOf(n))
+			if typ == nil {
+				return nil
+			}
+			switch t := typ.Underlying().(type) {
+			case *types.Slice:
+				return t.Elem()
+			case *types.Array:
+				return t.Elem()
+			case *types.Map:
+				return t.Elem()
+			}
+			return nil
+
+		case *ast.KeyValueExpr:
+			if pos <= n.Colon {
+				return nil
+			}
+			if key, ok := n.Key.(*ast.Ident); ok {
+				if field, ok := cf.Info.ObjectOf(key).(*types.Var); ok && field.IsField() {
+					return field.Type()
+				}
+			}
+			return nil
+
+		case ast.Stmt, *ast.FuncDecl, *ast.FuncLit:
+			return nil
+		}
+	}
+
+	return nil
+}
+
+// enclosingSignature returns the signature of the innermost function in path.
+func enclosingSignature(cf *checkedFile, path []ast.Node) *types.Signature {
+	for _, node := range path {
+		var typ types.Type
+		switch n := node.(type) {
+		case *ast.FuncLit:
+			typ = cf.Info.TypeOf(n)
+		case *ast.FuncDecl:
+			if obj := cf.Info.Defs[n.Name]; obj != nil {
+				typ = obj.Type()
+			}
+		default:
+			continue
+		}
+		sig, _ := typ.(*types.Signature)
+		return sig
+	}
+
+	return nil
+}
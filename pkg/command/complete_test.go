@@ -0,0 +1,171 @@
+// Copyright 2018 The nvim-go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package command
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestCompletionStart(t *testing.T) {
+	tests := []struct {
+		src  string
+		want int
+	}{
+		{src: "\tfoo.Ba|", want: 5},
+		{src: "package a\n\tfoo|", want: 1},
+		{src: "x := |", want: 5},
+		{src: "|", want: 0},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.src, func(t *testing.T) {
+			t.Parallel()
+
+			offset := strings.Index(tt.src, "|")
+			if got := completionStart([]byte(strings.Replace(tt.src, "|", "", 1)), offset); got != tt.want {
+				t.Errorf("completionStart(%q) = %d, want %d", tt.src, got, tt.want)
+			}
+		})
+	}
+}
+
+const completeSrc = `package foo
+
+import "strings"
+
+type Base struct{ ID int }
+
+func (b *Base) Name() string { return "" }
+
+type T struct {
+	Base
+	Count int
+	label string
+}
+
+func (t T) Len() int { return 0 }
+
+func use(n int, s string) {}
+
+func f() string {
+	var t T
+	var total int
+	var name string
+	%s
+}
+
+func g() {}
+`
+
+func TestCompletions(t *testing.T) {
+	file, _ := testCheckedFile(t, strings.Replace(completeSrc, "%s", "", 1))
+
+	words := func(items []completionItem) []string {
+		var ws []string
+		for _, item := range items {
+			ws = append(ws, item.Word)
+		}
+		return ws
+	}
+
+	tests := []struct {
+		name   string
+		stmt   string
+		prefix string
+		// want is the completion words which must be included.
+		want []string
+		// exact reports whether the completion words must be equal to want.
+		exact bool
+		// wantFirst is the first completion words.
+		wantFirst []string
+		wantKind  map[string]string
+	}{
+		{
+			name:     "fields and methods",
+			stmt:     "t.|",
+			want:     []string{"Base", "Count", "ID", "Len", "Name", "label"},
+			wantKind: map[string]string{"Count": completeKindField, "Len": completeKindMethod, "Name": completeKindMethod},
+			exact:    true,
+		},
+		{
+			name:   "fields prefix",
+			stmt:   "t.|",
+			prefix: "C",
+			want:   []string{"Count"},
+			exact:  true,
+		},
+		{
+			name:     "package members",
+			stmt:     "strings.|",
+			prefix:   "ToU",
+			want:     []string{"ToUpper", "ToUpperSpecial"},
+			wantKind: map[string]string{"ToUpper": completeKindFunc},
+			exact:    true,
+		},
+		{
+			name:      "local variables ranked by argument type",
+			stmt:      "use(|",
+			wantFirst: []string{"total"},
+			want:      []string{"name", "t", "f", "g", "strings", "use", "for", "return"},
+			wantKind:  map[string]string{"total": completeKindVar, "strings": completeKindPackage, "T": completeKindType, "for": completeKindKeyword},
+		},
+		{
+			name:      "second argument",
+			stmt:      "use(total, |)",
+			wantFirst: []string{"name"},
+		},
+		{
+			name:      "return value",
+			stmt:      "return |",
+			wantFirst: []string{"name"},
+		},
+		{
+			name:   "keywords",
+			stmt:   "|",
+			prefix: "ret",
+			want:   []string{"return"},
+			exact:  true,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			src := strings.Replace(completeSrc, "%s", tt.stmt, 1)
+			offset := strings.Index(src, "|")
+			src = strings.Replace(src, "|", "", 1)
+
+			items, err := completions(file, []byte(src), offset, tt.prefix, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := words(items)
+
+			if tt.wantFirst != nil {
+				if len(got) < len(tt.wantFirst) || !reflect.DeepEqual(got[:len(tt.wantFirst)], tt.wantFirst) {
+					t.Errorf("completions() = %v, want the first items %v", got, tt.wantFirst)
+				}
+			}
+			if tt.exact {
+				if !reflect.DeepEqual(got, tt.want) {
+					t.Errorf("completions() = %v, want %v", got, tt.want)
+				}
+			} else {
+				for _, w := range tt.want {
+					if !contains(got, w) {
+						t.Errorf("completions() = %v, want to include %q", got, w)
+					}
+				}
+			}
+
+			for _, item := range items {
+				if kind, ok := tt.wantKind[item.Word]; ok && item.Kind != kind {
+					t.Errorf("kind of %q = %q, want %q", item.Word, item.Kind, kind)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,291 @@
+// Copyright 2018 The nvim-go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package command
+
+import (
+	"context"
+	"fmt"
+	"go/ast"
+	"go/build"
+	"go/token"
+	"go/types"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/neovim/go-client/nvim"
+	"github.com/pkg/errors"
+	"go.opencensus.io/trace"
+	"golang.org/x/tools/go/buildutil"
+	"golang.org/x/tools/go/callgraph/rta"
+	"golang.org/x/tools/go/loader"
+	"golang.org/x/tools/go/ssa"
+	"golang.org/x/tools/go/ssa/ssautil"
+
+	"github.com/zchee/nvim-go/pkg/config"
+	"github.com/zchee/nvim-go/pkg/fs"
+	"github.com/zchee/nvim-go/pkg/monitoring"
+	"github.com/zchee/nvim-go/pkg/nvimutil"
+)
+
+// generatedRx matches the generated file comment, see https://golang.org/s/generatedcode.
+var generatedRx = regexp.MustCompile(`^// Code generated .* DO NOT EDIT\.$`)
+
+// deadCodeOption represents the files which are excluded from the GoDeadCode results.
+type deadCodeOption struct {
+	ExcludeGenerated bool
+	ExcludeTests     bool
+}
+
+// deadIdent represents an unreachable function or an exported identifier which is not used outside the package.
+type deadIdent struct {
+	Pos  token.Position
+	Text string
+}
+
+// cmdDeadCodeEval represents the current working directory and the current buffer.
+type cmdDeadCodeEval struct {
+	Cwd  string `eval:"getcwd()"`
+	File string `eval:"expand('%:p')"`
+}
+
+func (c *Command) cmdDeadCode(ctx context.Context, eval *cmdDeadCodeEval) {
+	errch := make(chan interface{}, 1)
+	go func() {
+		errch <- c.DeadCode(ctx, eval)
+	}()
+
+	// the whole program analysis takes a while, do not block the following commands
+	go func() {
+		select {
+		case <-ctx.Done():
+			return
+		case err := <-errch:
+			switch e := err.(type) {
+			case error:
+				nvimutil.ErrorWrap(c.Nvim, e)
+			case nil:
+				// nothing to do
+			}
+		}
+	}()
+}
+
+// DeadCode reports the functions and methods which are unreachable from the main packages and tests in the project
+// which contains the current buffer by the RTA analysis, and the exported identifiers which are never used outside
+// the package, to the quickfix list.
+func (c *Command) DeadCode(ctx context.Context, eval *cmdDeadCodeEval) interface{} {
+	var span *trace.Span
+	ctx, span = monitoring.StartSpan(ctx, "DeadCode")
+	defer span.End()
+
+	scopes, err := c.guruScopes(eval.File)
+	if err != nil {
+		span.SetStatus(trace.Status{Code: trace.StatusCodeInternal, Message: err.Error()})
+		return err
+	}
+	overlay, err := c.modifiedOverlay()
+	if err != nil {
+		span.SetStatus(trace.Status{Code: trace.StatusCodeInternal, Message: err.Error()})
+		return err
+	}
+	ctxt := buildutil.OverlayContext(&build.Default, overlay)
+
+	nvimutil.EchoProgress(c.Nvim, "GoDeadCode", "loading packages")
+	lprog, err := loadScope(ctxt, scopes)
+	if err != nil {
+		span.SetStatus(trace.Status{Code: trace.StatusCodeInternal, Message: err.Error()})
+		return err
+	}
+	prog := ssautil.CreateProgram(lprog, 0)
+	prog.Build()
+
+	nvimutil.EchoProgress(c.Nvim, "GoDeadCode", "analyzing reachability")
+	idents, err := deadCode(prog, lprog, deadCodeOption{
+		ExcludeGenerated: config.DeadCodeExcludeGenerated,
+		ExcludeTests:     config.DeadCodeExcludeTests,
+	})
+	if err != nil {
+		span.SetStatus(trace.Status{Code: trace.StatusCodeInvalidArgument, Message: err.Error()})
+		return err
+	}
+	if len(idents) == 0 {
+		return nvimutil.EchoSuccess(c.Nvim, "GoDeadCode", "no dead code")
+	}
+
+	qflist := make([]*nvim.QuickfixError, 0, len(idents))
+	for _, id := range idents {
+		qflist = append(qflist, &nvim.QuickfixError{
+			FileName: fs.Rel(eval.Cwd, id.Pos.Filename),
+			LNum:     id.Pos.Line,
+			Col:      id.Pos.Column,
+			Text:     id.Text,
+		})
+	}
+
+	w, err := c.Nvim.CurrentWindow()
+	if err != nil {
+		span.SetStatus(trace.Status{Code: trace.StatusCodeInternal, Message: err.Error()})
+		return errors.WithStack(err)
+	}
+	batch := c.Nvim.NewBatch()
+	nvimutil.SetQuickfix(batch, qflist)
+	if err := nvimutil.OpenOuickfix(batch, w, false); err != nil {
+		span.SetStatus(trace.Status{Code: trace.StatusCodeInternal, Message: err.Error()})
+		return errors.WithStack(err)
+	}
+	nvimutil.ClearMsg(c.Nvim)
+
+	return nil
+}
+
+// deadCode returns the dead code of the initial packages of lprog sorted by the position.
+//
+// The function or method is dead if it is unreachable from the init and main functions of the main packages,
+// or the test main packages, by the RTA analysis. The exported package-level identifier of the non-main package is
+// dead if it is not used by any other packages. The unreachable function is reported only once.
+func deadCode(prog *ssa.Program, lprog *loader.Program, opt deadCodeOption) ([]deadIdent, error) {
+	mains := mainPackages(prog, lprog)
+	if len(mains) == 0 {
+		return nil, errors.New("analysis scope has no main and no tests")
+	}
+	var roots []*ssa.Function
+	for _, main := range mains {
+		roots = append(roots, main.Func("init"), main.Func("main"))
+	}
+	reachable := rta.Analyze(roots, false).Reachable
+	// the roots are not in the reachable set unless they are called
+	for _, root := range roots {
+		reachable[root] = struct{ AddrTaken bool }{}
+	}
+
+	// objects which are used by the other package
+	usedOutside := make(map[types.Object]bool)
+	for _, info := range lprog.AllPackages {
+		for _, obj := range info.Uses {
+			if obj.Pkg() != nil && obj.Pkg() != info.Pkg {
+				usedOutside[obj] = true
+			}
+		}
+	}
+
+	var idents []deadIdent
+	for _, info := range lprog.InitialPackages() {
+		isMain := info.Pkg.Name() == "main"
+		for _, f := range info.Files {
+			pos := lprog.Fset.Position(f.Pos())
+			isTest := strings.HasSuffix(pos.Filename, "_test.go")
+			if (opt.ExcludeTests && isTest) || (opt.ExcludeGenerated && isGeneratedFile(f)) {
+				continue
+			}
+
+			for _, decl := range f.Decls {
+				if fn, ok := decl.(*ast.FuncDecl); ok {
+					if id, ok := unreachableFunc(prog, info, fn, reachable); ok {
+						idents = append(idents, deadIdent{Pos: lprog.Fset.Position(fn.Name.Pos()), Text: id})
+						continue
+					}
+				}
+				// the exported identifiers of the main package and the test file are not the package API
+				if isMain || isTest {
+					continue
+				}
+				for _, id := range declIdents(decl) {
+					obj := info.Defs[id]
+					if obj == nil || !id.IsExported() || usedOutside[obj] {
+						continue
+					}
+					idents = append(idents, deadIdent{
+						Pos:  lprog.Fset.Position(id.Pos()),
+						Text: fmt.Sprintf("exported %s is not used outside package %s", deadObjectName(obj), info.Pkg.Name()),
+					})
+				}
+			}
+		}
+	}
+
+	sort.Slice(idents, func(i, j int) bool {
+		pi, pj := idents[i].Pos, idents[j].Pos
+		if pi.Filename != pj.Filename {
+			return pi.Filename < pj.Filename
+		}
+		return pi.Offset < pj.Offset
+	})
+
+	return idents, nil
+}
+
+// unreachableFunc reports whether the function of decl is unreachable, and returns the quickfix text of it.
+// The init functions and the functions without the body are not reported.
+func unreachableFunc(prog *ssa.Program, info *loader.PackageInfo, decl *ast.FuncDecl, reachable map[*ssa.Function]struct{ AddrTaken bool }) (string, bool) {
+	if decl.Body == nil || (decl.Recv == nil && decl.Name.Name == "init") {
+		return "", false
+	}
+	obj, ok := info.Defs[decl.Name].(*types.Func)
+	if !ok {
+		return "", false
+	}
+	fn := prog.FuncValue(obj)
+	if fn == nil {
+		return "", false
+	}
+	if _, ok := reachable[fn]; ok {
+		return "", false
+	}
+
+	return fmt.Sprintf("unreachable %s", deadObjectName(obj)), true
+}
+
+// deadObjectName returns the kind and name of obj, such as "func Name", "method (T).Name" or "type Name".
+func deadObjectName(obj types.Object) string {
+	switch obj := obj.(type) {
+	case *types.Func:
+		if recv := obj.Type().(*types.Signature).Recv(); recv != nil {
+			return fmt.Sprintf("%s (%s).%s", completeKindMethod, types.TypeString(recv.Type(), types.RelativeTo(obj.Pkg())), obj.Name())
+		}
+		return completeKindFunc + " " + obj.Name()
+	case *types.TypeName:
+		return completeKindType + " " + obj.Name()
+	case *types.Const:
+		return completeKindConst + " " + obj.Name()
+	default:
+		return completeKindVar + " " + obj.Name()
+	}
+}
+
+// declIdents returns the package-level identifiers which are declared by decl. The methods are not included.
+func declIdents(decl ast.Decl) []*ast.Ident {
+	var idents []*ast.Ident
+	switch decl := decl.(type) {
+	case *ast.FuncDecl:
+		if decl.Recv == nil {
+			idents = append(idents, decl.Name)
+		}
+	case *ast.GenDecl:
+		for _, spec := range decl.Specs {
+			switch spec := spec.(type) {
+			case *ast.TypeSpec:
+				idents = append(idents, spec.Name)
+			case *ast.ValueSpec:
+				idents = append(idents, spec.Names...)
+			}
+		}
+	}
+
+	return idents
+}
+
+// isGeneratedFile reports whether f has the generated file comment.
+func isGeneratedFile(f *ast.File) bool {
+	for _, cg := range f.Comments {
+		for _, c := range cg.List {
+			if generatedRx.MatchString(c.Text) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
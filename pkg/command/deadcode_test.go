@@ -0,0 +1,115 @@
+// Copyright 2018 The nvim-go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package command
+
+import (
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"golang.org/x/tools/go/buildutil"
+	"golang.org/x/tools/go/ssa/ssautil"
+)
+
+func TestDeadCode(t *testing.T) {
+	ctxt := buildutil.FakeContext(map[string]map[string]string{
+		"lib": {
+			"lib.go": `package lib
+
+type Greeter interface{ Greet() string }
+
+type T struct{}
+
+func (T) Greet() string { return "hello" }
+
+func (T) unused() {}
+
+func Hello(g Greeter) string { return g.Greet() }
+
+func Unused() {}
+
+func helper() {}
+
+const Version = "1"
+`,
+			"gen.go": `// Code generated by stringer. DO NOT EDIT.
+
+package lib
+
+func Generated() {}
+`,
+			// the fake context has no testing package
+			"lib_test.go": `package lib
+
+func testOnly() { helper() }
+`,
+		},
+		"cmd": {
+			"main.go": `package main
+
+import "lib"
+
+func main() { println(lib.Hello(lib.T{})) }
+
+func dead() {}
+`,
+		},
+	})
+
+	lprog, err := loadScope(ctxt, []string{"lib", "cmd"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	prog := ssautil.CreateProgram(lprog, 0)
+	prog.Build()
+
+	tests := []struct {
+		name string
+		opt  deadCodeOption
+		want []string
+	}{
+		{
+			name: "all",
+			want: []string{
+				"main.go:7: unreachable func dead",
+				"gen.go:5: unreachable func Generated",
+				"lib.go:3: exported type Greeter is not used outside package lib",
+				"lib.go:9: unreachable method (T).unused",
+				"lib.go:13: unreachable func Unused",
+				"lib.go:15: unreachable func helper",
+				"lib.go:17: exported const Version is not used outside package lib",
+				"lib_test.go:3: unreachable func testOnly",
+			},
+		},
+		{
+			name: "exclude generated and tests",
+			opt:  deadCodeOption{ExcludeGenerated: true, ExcludeTests: true},
+			want: []string{
+				"main.go:7: unreachable func dead",
+				"lib.go:3: exported type Greeter is not used outside package lib",
+				"lib.go:9: unreachable method (T).unused",
+				"lib.go:13: unreachable func Unused",
+				"lib.go:15: unreachable func helper",
+				"lib.go:17: exported const Version is not used outside package lib",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			idents, err := deadCode(prog, lprog, tt.opt)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, id := range idents {
+				got = append(got, fmt.Sprintf("%s:%d: %s", filepath.Base(id.Pos.Filename), id.Pos.Line, id.Text))
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("deadCode() = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
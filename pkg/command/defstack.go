@@ -0,0 +1,193 @@
+// Copyright 2018 The nvim-go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package command
+
+import (
+	"context"
+	"fmt"
+	"go/build"
+	"go/token"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/neovim/go-client/nvim"
+	"github.com/pkg/errors"
+
+	"github.com/zchee/nvim-go/pkg/fs"
+	"github.com/zchee/nvim-go/pkg/nvimutil"
+)
+
+// defStackEntry represents the position which the definition jump is started from.
+type defStackEntry struct {
+	// Name is the identifier which is jumped to the definition.
+	Name string
+	// From is the position of the cursor before the jump.
+	From token.Position
+}
+
+// defStack represents the definition jump stack of each window, like the tag stack of ctags.
+type defStack struct {
+	mu     sync.Mutex
+	stacks map[nvim.Window][]*defStackEntry
+}
+
+// push pushes e to the stack of w. If w is the new window which is opened by the jump from the from window,
+// the stack of w starts with the copy of the stack of from.
+func (ds *defStack) push(from, w nvim.Window, e *defStackEntry) {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+
+	if ds.stacks == nil {
+		ds.stacks = make(map[nvim.Window][]*defStackEntry)
+	}
+	stack := ds.stacks[w]
+	if from != w {
+		stack = append([]*defStackEntry(nil), ds.stacks[from]...)
+	}
+	ds.stacks[w] = append(stack, e)
+}
+
+// entries returns the copy of the stack of w. The first element is the bottom of the stack.
+func (ds *defStack) entries(w nvim.Window) []*defStackEntry {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+
+	return append([]*defStackEntry(nil), ds.stacks[w]...)
+}
+
+// popTo pops the entries of the stack of w above the n index entry, and returns the n index entry.
+// Returns nil if the stack has no n index entry.
+func (ds *defStack) popTo(w nvim.Window, n int) *defStackEntry {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+
+	stack := ds.stacks[w]
+	if n < 0 || n >= len(stack) {
+		return nil
+	}
+	e := stack[n]
+	ds.stacks[w] = stack[:n]
+
+	return e
+}
+
+// prune removes the stacks of the closed windows.
+func (ds *defStack) prune(v *nvim.Nvim) {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+
+	for w := range ds.stacks {
+		if valid, _ := v.IsWindowValid(w); !valid {
+			delete(ds.stacks, w)
+		}
+	}
+}
+
+// pushDefStack pushes the cursor position of the from window to the definition stack of w.
+// name is the identifier which is jumped to the definition.
+func (c *Command) pushDefStack(from, w nvim.Window, name string, pos token.Position) {
+	c.defStack.prune(c.Nvim)
+	c.defStack.push(from, w, &defStackEntry{Name: name, From: pos})
+}
+
+func (c *Command) cmdDefPop(ctx context.Context, args []string) {
+	if err := c.DefPop(ctx, args); err != nil {
+		nvimutil.ErrorWrap(c.Nvim, err)
+	}
+}
+
+// DefPop returns to the position before the definition jump, like the ":pop" of tag stack.
+// The optional argument is the count of the entries to pop.
+func (c *Command) DefPop(ctx context.Context, args []string) error {
+	count := 1
+	if len(args) > 0 {
+		n, err := strconv.Atoi(args[0])
+		if err != nil || n < 1 {
+			return errors.Errorf("invalid count: %s", args[0])
+		}
+		count = n
+	}
+
+	w, err := c.Nvim.CurrentWindow()
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	stack := c.defStack.entries(w)
+	if len(stack) == 0 {
+		return errors.New("definition stack is empty")
+	}
+	if count > len(stack) {
+		count = len(stack)
+	}
+
+	return c.popDefStack(w, len(stack)-count)
+}
+
+func (c *Command) cmdDefStack(ctx context.Context, cwd string) {
+	if err := c.DefStack(ctx, cwd); err != nil {
+		nvimutil.ErrorWrap(c.Nvim, err)
+	}
+}
+
+// DefStack lists the definition stack of the current window, and jumps to the selected entry.
+// The entries above the selected entry are popped.
+func (c *Command) DefStack(ctx context.Context, cwd string) error {
+	w, err := c.Nvim.CurrentWindow()
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	stack := c.defStack.entries(w)
+	if len(stack) == 0 {
+		return errors.New("definition stack is empty")
+	}
+
+	var choice int
+	if err := c.Nvim.Call("inputlist", &choice, defStackList(cwd, stack)); err != nil {
+		return errors.WithStack(err)
+	}
+	if choice < 1 || choice > len(stack) {
+		return nil
+	}
+
+	return c.popDefStack(w, choice-1)
+}
+
+// popDefStack pops the stack of w to the n index entry, and jumps to the position of the entry.
+func (c *Command) popDefStack(w nvim.Window, n int) error {
+	e := c.defStack.popTo(w, n)
+	if e == nil {
+		return nil
+	}
+
+	return jumpToPos(c.Nvim, w, e.From)
+}
+
+// defStackList returns the inputlist items of stack. The top of the stack comes last, like ":tags".
+func defStackList(cwd string, stack []*defStackEntry) []string {
+	items := []string{"  # TO definition      FROM line  in file"}
+	for i, e := range stack {
+		items = append(items, fmt.Sprintf("%3d %-18s %9d  %s", i+1, e.Name, e.From.Line, fs.Rel(cwd, e.From.Filename)))
+	}
+
+	return items
+}
+
+// isReadOnlySource reports whether the file is in the GOROOT or the module cache, which should not be edited.
+func isReadOnlySource(ctxt *build.Context, file string) bool {
+	dirs := []string{filepath.Join(ctxt.GOROOT, "src")}
+	if modCache := moduleCacheDir(ctxt); modCache != "" {
+		dirs = append(dirs, modCache)
+	}
+
+	for _, dir := range dirs {
+		if dir != "" && strings.HasPrefix(file, dir+string(filepath.Separator)) {
+			return true
+		}
+	}
+
+	return false
+}
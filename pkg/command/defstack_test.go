@@ -0,0 +1,62 @@
+// Copyright 2018 The nvim-go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package command
+
+import (
+	"go/build"
+	"go/token"
+	"path/filepath"
+	"testing"
+)
+
+func TestDefStack(t *testing.T) {
+	ds := new(defStack)
+	entry := func(name string, line int) *defStackEntry {
+		return &defStackEntry{Name: name, From: token.Position{Filename: "foo.go", Line: line, Column: 1}}
+	}
+
+	ds.push(1, 1, entry("a", 1))
+	ds.push(1, 1, entry("b", 2))
+	// the split window inherits the stack of the original window
+	ds.push(1, 2, entry("c", 3))
+
+	if got := len(ds.entries(1)); got != 2 {
+		t.Errorf("len(entries(1)) = %d, want 2", got)
+	}
+	if got := len(ds.entries(2)); got != 3 {
+		t.Errorf("len(entries(2)) = %d, want 3", got)
+	}
+
+	if e := ds.popTo(2, 1); e == nil || e.Name != "b" {
+		t.Fatalf("popTo(2, 1) = %+v, want b", e)
+	}
+	if got := len(ds.entries(2)); got != 1 {
+		t.Errorf("len(entries(2)) = %d, want 1 after popTo", got)
+	}
+	if e := ds.popTo(2, 1); e != nil {
+		t.Errorf("popTo(2, 1) = %+v, want nil for out of range", e)
+	}
+}
+
+func TestIsReadOnlySource(t *testing.T) {
+	ctxt := build.Default
+	ctxt.GOROOT = filepath.FromSlash("/usr/local/go")
+	ctxt.GOPATH = filepath.FromSlash("/home/gopher/go")
+
+	tests := []struct {
+		file string
+		want bool
+	}{
+		{file: "/usr/local/go/src/fmt/print.go", want: true},
+		{file: filepath.ToSlash(moduleCacheDir(&ctxt)) + "/github.com/pkg/errors@v0.8.0/errors.go", want: true},
+		{file: "/home/gopher/go/src/github.com/foo/bar/bar.go", want: false},
+		{file: "/usr/local/gopher/main.go", want: false},
+	}
+	for _, tt := range tests {
+		if got := isReadOnlySource(&ctxt, filepath.FromSlash(tt.file)); got != tt.want {
+			t.Errorf("isReadOnlySource(%s) = %v, want %v", tt.file, got, tt.want)
+		}
+	}
+}
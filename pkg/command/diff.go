@@ -0,0 +1,207 @@
+// Copyright 2018 The nvim-go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package command
+
+import (
+	"bytes"
+	"fmt"
+)
+
+// diffContext number of the context lines of the unified diff hunk.
+const diffContext = 3
+
+// diffOp represents an operation of the line based edit script.
+type diffOp struct {
+	// Kind is ' ' for the equal line, '-' for the deleted line and '+' for the inserted line.
+	Kind byte
+	Line []byte
+}
+
+// splitLines splits src into lines without the line terminators.
+func splitLines(src []byte) [][]byte {
+	if len(src) == 0 {
+		return nil
+	}
+	return bytes.Split(bytes.TrimSuffix(src, []byte{'\n'}), []byte{'\n'})
+}
+
+// diffLines returns the shortest edit script which converts a to b by the Myers' algorithm.
+func diffLines(a, b [][]byte) []diffOp {
+	// trim the common prefix and suffix, the renaming usually changes few lines
+	var prefix, suffix int
+	for prefix < len(a) && prefix < len(b) && bytes.Equal(a[prefix], b[prefix]) {
+		prefix++
+	}
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && bytes.Equal(a[len(a)-1-suffix], b[len(b)-1-suffix]) {
+		suffix++
+	}
+
+	ops := make([]diffOp, 0, len(a)+len(b))
+	for _, line := range a[:prefix] {
+		ops = append(ops, diffOp{Kind: ' ', Line: line})
+	}
+	ops = append(ops, myers(a[prefix:len(a)-suffix], b[prefix:len(b)-suffix])...)
+	for _, line := range a[len(a)-suffix:] {
+		ops = append(ops, diffOp{Kind: ' ', Line: line})
+	}
+
+	return ops
+}
+
+// myers returns the shortest edit script which converts a to b.
+func myers(a, b [][]byte) []diffOp {
+	n, m := len(a), len(b)
+	max := n + m
+	off := max + 1
+	v := make([]int, 2*max+3)
+
+	// trace[d] is the furthest reaching x of each diagonal k before the d-th step
+	var trace [][]int
+search:
+	for d := 0; d <= max; d++ {
+		trace = append(trace, append([]int(nil), v...))
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[off+k-1] < v[off+k+1]) {
+				x = v[off+k+1] // down: insert
+			} else {
+				x = v[off+k-1] + 1 // right: delete
+			}
+			y := x - k
+			for x < n && y < m && bytes.Equal(a[x], b[y]) {
+				x++
+				y++
+			}
+			v[off+k] = x
+			if x >= n && y >= m {
+				break search
+			}
+		}
+	}
+
+	// backtrack from the end
+	var ops []diffOp
+	x, y := n, m
+	for d := len(trace) - 1; d > 0; d-- {
+		v := trace[d]
+		k := x - y
+		var prevK int
+		if k == -d || (k != d && v[off+k-1] < v[off+k+1]) {
+			prevK = k + 1
+		} else {
+			prevK = k - 1
+		}
+		prevX := v[off+prevK]
+		prevY := prevX - prevK
+
+		for x > prevX && y > prevY {
+			ops = append(ops, diffOp{Kind: ' ', Line: a[x-1]})
+			x--
+			y--
+		}
+		if x == prevX {
+			ops = append(ops, diffOp{Kind: '+', Line: b[y-1]})
+			y--
+		} else {
+			ops = append(ops, diffOp{Kind: '-', Line: a[x-1]})
+			x--
+		}
+	}
+	for x > 0 && y > 0 {
+		ops = append(ops, diffOp{Kind: ' ', Line: a[x-1]})
+		x--
+		y--
+	}
+
+	for i, j := 0, len(ops)-1; i < j; i, j = i+1, j-1 {
+		ops[i], ops[j] = ops[j], ops[i]
+	}
+
+	return ops
+}
+
+// unifiedDiff returns the unified diff of a and b. Returns nil if a and b are the same.
+func unifiedDiff(aName, bName string, a, b []byte) []byte {
+	ops := diffLines(splitLines(a), splitLines(b))
+
+	var buf bytes.Buffer
+	for i := 0; i < len(ops); {
+		// find the next change
+		for i < len(ops) && ops[i].Kind == ' ' {
+			i++
+		}
+		if i == len(ops) {
+			break
+		}
+
+		// extend the hunk while the changes are separated by less than the twice of context lines
+		start := i - diffContext
+		if start < 0 {
+			start = 0
+		}
+		end := i
+		for end < len(ops) {
+			if ops[end].Kind != ' ' {
+				end++
+				continue
+			}
+			next := end
+			for next < len(ops) && ops[next].Kind == ' ' {
+				next++
+			}
+			if next == len(ops) || next-end > 2*diffContext {
+				break
+			}
+			end = next
+		}
+		stop := end + diffContext
+		if stop > len(ops) {
+			stop = len(ops)
+		}
+
+		if buf.Len() == 0 {
+			fmt.Fprintf(&buf, "--- %s\n+++ %s\n", aName, bName)
+		}
+
+		// line numbers of the hunk start
+		aLine, bLine := 1, 1
+		for _, op := range ops[:start] {
+			if op.Kind != '+' {
+				aLine++
+			}
+			if op.Kind != '-' {
+				bLine++
+			}
+		}
+		var aCount, bCount int
+		for _, op := range ops[start:stop] {
+			if op.Kind != '+' {
+				aCount++
+			}
+			if op.Kind != '-' {
+				bCount++
+			}
+		}
+		if aCount == 0 {
+			aLine--
+		}
+		if bCount == 0 {
+			bLine--
+		}
+		fmt.Fprintf(&buf, "@@ -%d,%d +%d,%d @@\n", aLine, aCount, bLine, bCount)
+		for _, op := range ops[start:stop] {
+			buf.WriteByte(op.Kind)
+			buf.Write(op.Line)
+			buf.WriteByte('\n')
+		}
+
+		i = stop
+	}
+
+	if buf.Len() == 0 {
+		return nil
+	}
+	return buf.Bytes()
+}
@@ -0,0 +1,103 @@
+// Copyright 2018 The nvim-go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package command
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestUnifiedDiff(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b string
+		want string
+	}{
+		{
+			name: "same",
+			a:    "a\nb\n",
+			b:    "a\nb\n",
+			want: "",
+		},
+		{
+			name: "replace",
+			a:    "1\n2\n3\n4\n5\n6\n7\n8\n9\n",
+			b:    "1\n2\n3\n4\nfive\n6\n7\n8\n9\n",
+			want: "--- a\n+++ b\n@@ -2,7 +2,7 @@\n 2\n 3\n 4\n-5\n+five\n 6\n 7\n 8\n",
+		},
+		{
+			name: "two hunks",
+			a:    "1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11\n12\n",
+			b:    "one\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11\ntwelve\n",
+			want: "--- a\n+++ b\n@@ -1,4 +1,4 @@\n-1\n+one\n 2\n 3\n 4\n@@ -9,4 +9,4 @@\n 9\n 10\n 11\n-12\n+twelve\n",
+		},
+		{
+			name: "merged hunk",
+			a:    "1\n2\n3\n4\n5\n",
+			b:    "one\n2\n3\n4\nfive\n",
+			want: "--- a\n+++ b\n@@ -1,5 +1,5 @@\n-1\n+one\n 2\n 3\n 4\n-5\n+five\n",
+		},
+		{
+			name: "insert to empty",
+			a:    "",
+			b:    "a\n",
+			want: "--- a\n+++ b\n@@ -0,0 +1,1 @@\n+a\n",
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := string(unifiedDiff("a", "b", []byte(tt.a), []byte(tt.b))); got != tt.want {
+				t.Errorf("unifiedDiff() =\n%s\nwant:\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDiffLines(t *testing.T) {
+	tests := []struct {
+		a, b string
+	}{
+		{a: "abcabba", b: "cbabac"},
+		{a: "", b: "abc"},
+		{a: "abc", b: ""},
+		{a: "xaxbxc", b: "abc"},
+		{a: "abcdef", b: "abcdef"},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.a+"_"+tt.b, func(t *testing.T) {
+			t.Parallel()
+
+			a := bytes.Split([]byte(tt.a), nil)
+			b := bytes.Split([]byte(tt.b), nil)
+			ops := diffLines(a, b)
+
+			// the edit script must reconstruct both a and b
+			var gotA, gotB []string
+			var edits int
+			for _, op := range ops {
+				if op.Kind != '+' {
+					gotA = append(gotA, string(op.Line))
+				}
+				if op.Kind != '-' {
+					gotB = append(gotB, string(op.Line))
+				}
+				if op.Kind != ' ' {
+					edits++
+				}
+			}
+			if strings.Join(gotA, "") != tt.a || strings.Join(gotB, "") != tt.b {
+				t.Errorf("diffLines(%q, %q) reconstructs %q, %q", tt.a, tt.b, strings.Join(gotA, ""), strings.Join(gotB, ""))
+			}
+			if tt.a == "abcabba" && edits != 5 {
+				t.Errorf("diffLines(%q, %q) edits = %d, want the shortest 5", tt.a, tt.b, edits)
+			}
+		})
+	}
+}
@@ -0,0 +1,536 @@
+// Copyright 2018 The nvim-go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package command
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"go/ast"
+	"go/build"
+	"go/doc"
+	"go/parser"
+	"go/token"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/neovim/go-client/nvim"
+	"github.com/pkg/errors"
+	"go.opencensus.io/trace"
+
+	"github.com/zchee/nvim-go/pkg/monitoring"
+	"github.com/zchee/nvim-go/pkg/nvimutil"
+)
+
+// docBrowseBufferName buffer name of the GoDocBrowse buffer.
+const docBrowseBufferName = "__GoDocBrowse__"
+
+// docBrowser represents a GoDocBrowse buffer and the page history.
+type docBrowser struct {
+	mu sync.Mutex
+
+	buffer    nvim.Buffer
+	window    nvim.Window
+	srcWindow nvim.Window
+
+	page    *docPage
+	history []*docPage
+}
+
+type cmdDocBrowseEval struct {
+	File  string `eval:"expand('%:p')"`
+	WinID int    `eval:"win_getid()"`
+}
+
+func (c *Command) cmdDocBrowse(ctx context.Context, args []string, eval *cmdDocBrowseEval) {
+	errch := make(chan interface{}, 1)
+	go func() {
+		errch <- c.DocBrowse(ctx, args, eval)
+	}()
+
+	select {
+	case <-ctx.Done():
+		return
+	case err := <-errch:
+		switch e := err.(type) {
+		case error:
+			nvimutil.ErrorWrap(c.Nvim, e)
+		case nil:
+			// nothing to do
+		}
+	}
+}
+
+// DocBrowse renders the whole documentation of the args[0] import path package to the GoDocBrowse buffer.
+// If args is empty, renders the package of the current buffer.
+func (c *Command) DocBrowse(ctx context.Context, args []string, eval *cmdDocBrowseEval) interface{} {
+	var span *trace.Span
+	ctx, span = monitoring.StartSpan(ctx, "DocBrowse")
+	defer span.End()
+
+	srcDir := filepath.Dir(eval.File)
+	importPath := "."
+	if len(args) > 0 {
+		importPath = args[0]
+	}
+
+	page, err := c.loadDocPage(importPath, srcDir)
+	if err != nil {
+		span.SetStatus(trace.Status{Code: trace.StatusCodeNotFound, Message: err.Error()})
+		return errors.WithStack(err)
+	}
+
+	d := c.docBrowser
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if !d.isOpened(c.Nvim) {
+		d.srcWindow = nvim.Window(eval.WinID)
+		d.history = nil
+
+		b := nvimutil.NewBuffer(c.Nvim)
+		option := map[nvimutil.NvimOption]map[string]interface{}{
+			nvimutil.BufferOption: {
+				nvimutil.BufOptionBufhidden:  nvimutil.BufhiddenWipe,
+				nvimutil.BufOptionBuflisted:  false,
+				nvimutil.BufOptionBuftype:    nvimutil.BuftypeNofile,
+				nvimutil.BufOptionFiletype:   filetypeGoDoc,
+				nvimutil.BufOptionModifiable: false,
+				nvimutil.BufOptionSwapfile:   false,
+			},
+			nvimutil.WindowOption: {
+				nvimutil.WinOptionList:           false,
+				nvimutil.WinOptionNumber:         false,
+				nvimutil.WinOptionRelativenumber: false,
+			},
+		}
+		if err := b.Create(docBrowseBufferName, filetypeGoDoc, "belowright split", option); err != nil {
+			span.SetStatus(trace.Status{Code: trace.StatusCodeInternal, Message: err.Error()})
+			return errors.WithStack(err)
+		}
+		b.SetLocalMapping(nvimutil.NoremapNormal, map[string]string{
+			"<CR>": ":<C-u>call GoDocBrowseAction('jump')<CR>",
+			"<BS>": ":<C-u>call GoDocBrowseAction('back')<CR>",
+			"q":    ":<C-u>close<CR>",
+		})
+		d.buffer = b.Buffer()
+		d.window = b.Window
+	}
+
+	return d.show(c.Nvim, page, "")
+}
+
+func (c *Command) cmdDocBrowseComplete(ctx context.Context, a *nvim.CommandCompletionArgs) ([]string, error) {
+	// do not walk the package directories in the completion callback, which blocks the command line
+	var candidates []string
+	for _, pkg := range c.packages.CachedPackages() {
+		if strings.HasPrefix(pkg.ImportPath, a.ArgLead) {
+			candidates = append(candidates, pkg.ImportPath)
+		}
+	}
+
+	return candidates, nil
+}
+
+// funcDocBrowseActionEval represents the cursor position of the GoDocBrowse buffer.
+type funcDocBrowseActionEval struct {
+	Line int `msgpack:",array"`
+	Col  int
+}
+
+func (c *Command) funcDocBrowseAction(ctx context.Context, args []string, eval *funcDocBrowseActionEval) {
+	if err := c.DocBrowseAction(ctx, args, eval); err != nil {
+		nvimutil.ErrorWrap(c.Nvim, err)
+	}
+}
+
+// DocBrowseAction runs the action of GoDocBrowse buffer to the cursor position.
+//
+// The available actions are "jump" and "back".
+func (c *Command) DocBrowseAction(ctx context.Context, args []string, eval *funcDocBrowseActionEval) error {
+	d := c.docBrowser
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.page == nil || len(args) == 0 {
+		return nil
+	}
+
+	switch args[0] {
+	case "jump":
+		link := d.page.LinkAt(eval.Line, eval.Col-1)
+		if link == nil {
+			return nil
+		}
+		switch {
+		case link.Pos.IsValid():
+			return jumpToPos(c.Nvim, d.srcWindow, link.Pos)
+		case link.ImportPath == "":
+			return d.jumpSection(c.Nvim, link.Symbol)
+		default:
+			page, err := c.loadDocPage(link.ImportPath, d.page.Dir)
+			if err != nil {
+				return err
+			}
+			d.history = append(d.history, d.page)
+			return d.show(c.Nvim, page, link.Symbol)
+		}
+
+	case "back":
+		if len(d.history) == 0 {
+			return nil
+		}
+		page := d.history[len(d.history)-1]
+		d.history = d.history[:len(d.history)-1]
+		return d.show(c.Nvim, page, "")
+
+	default:
+		return errors.Errorf("unknown GoDocBrowse action: %s", args[0])
+	}
+}
+
+// isOpened reports whether the GoDocBrowse buffer is displayed.
+func (d *docBrowser) isOpened(v *nvim.Nvim) bool {
+	if d.buffer == 0 || !nvimutil.IsBufferValid(v, d.buffer) {
+		return false
+	}
+	valid, _ := v.IsWindowValid(d.window)
+
+	return valid
+}
+
+// show renders page to the GoDocBrowse buffer, and moves the cursor to the section of symbol.
+func (d *docBrowser) show(v *nvim.Nvim, page *docPage, symbol string) error {
+	d.page = page
+
+	restore := nvimutil.Modifiable(v, d.buffer)
+	err := v.SetBufferLines(d.buffer, 0, -1, true, page.Lines)
+	restore()
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	return d.jumpSection(v, symbol)
+}
+
+// jumpSection moves the cursor to the section line of symbol. If symbol is empty, moves to the first line.
+func (d *docBrowser) jumpSection(v *nvim.Nvim, symbol string) error {
+	line := 1
+	if l, ok := d.page.Sections[symbol]; ok {
+		line = l
+	}
+
+	batch := v.NewBatch()
+	batch.SetCurrentWindow(d.window)
+	batch.Command("normal! m'")
+	batch.SetWindowCursor(d.window, [2]int{line, 0})
+	batch.Command("normal! zt")
+
+	return batch.Execute()
+}
+
+// docLink represents a link of the span in the GoDocBrowse buffer line.
+type docLink struct {
+	// StartCol and EndCol 0-based byte column range of the link text.
+	StartCol, EndCol int
+	// ImportPath import path of the link target package. Empty if the target is the current page.
+	ImportPath string
+	// Symbol link target symbol name.
+	Symbol string
+	// Pos link target source position. Valid only if the link target is the source code.
+	Pos token.Position
+}
+
+// docPage represents a rendered package documentation page.
+type docPage struct {
+	ImportPath string
+	Dir        string
+	Lines      [][]byte
+	// Links links of each 1-based line number.
+	Links map[int][]docLink
+	// Sections 1-based line number of each symbol section.
+	Sections map[string]int
+}
+
+// LinkAt returns the link at the 1-based line and 0-based byte col.
+func (p *docPage) LinkAt(line, col int) *docLink {
+	for _, link := range p.Links[line] {
+		if link.StartCol <= col && col < link.EndCol {
+			link := link
+			return &link
+		}
+	}
+
+	return nil
+}
+
+// loadDocPage finds the importPath package from srcDir and renders the documentation page.
+// If the package is not importable from srcDir, finds the package from the GOROOT, GOPATH and module cache.
+func (c *Command) loadDocPage(importPath, srcDir string) (*docPage, error) {
+	bp, err := build.Default.Import(importPath, srcDir, build.FindOnly)
+	if err == nil {
+		if bp.ImportPath == "." || bp.ImportPath == "" {
+			bp.ImportPath = dirImportPath(bp.Dir, filepath.Base(bp.Dir))
+		}
+		return renderDocPage(bp.Dir, bp.ImportPath)
+	}
+
+	for _, pkg := range c.packages.Packages() {
+		if pkg.ImportPath == importPath {
+			return renderDocPage(pkg.Dir, pkg.ImportPath)
+		}
+	}
+
+	return nil, errors.Errorf("could not find package %q", importPath)
+}
+
+// identRe matches the qualified identifier or the exported identifier.
+var identRe = regexp.MustCompile(`\b([A-Za-z_]\w*)\.([A-Z]\w*)|\b([A-Z]\w*)\b`)
+
+// pageWriter writes the lines and links of docPage.
+type pageWriter struct {
+	page    *docPage
+	fset    *token.FileSet
+	imports map[string]string
+	symbols map[string]bool
+}
+
+// line appends the s line without links.
+func (w *pageWriter) line(s string) {
+	w.page.Lines = append(w.page.Lines, []byte(s))
+}
+
+// text appends the multi-line text with the identifier links. Each line is prefixed by indent.
+// If pos is valid, the first line links to the source position pos.
+func (w *pageWriter) text(text, indent string, pos token.Position) {
+	for i, s := range strings.Split(strings.TrimRight(text, "\n"), "\n") {
+		if s != "" {
+			s = indent + s
+		}
+		w.line(s)
+		lnum := len(w.page.Lines)
+
+		for _, m := range identRe.FindAllStringSubmatchIndex(s, -1) {
+			switch {
+			case m[2] >= 0:
+				if importPath, ok := w.imports[s[m[2]:m[3]]]; ok {
+					w.page.Links[lnum] = append(w.page.Links[lnum], docLink{StartCol: m[0], EndCol: m[1], ImportPath: importPath, Symbol: s[m[4]:m[5]]})
+				}
+			case m[6] >= 0:
+				if sym := s[m[6]:m[7]]; w.symbols[sym] {
+					w.page.Links[lnum] = append(w.page.Links[lnum], docLink{StartCol: m[0], EndCol: m[1], Symbol: sym})
+				}
+			}
+		}
+		if i == 0 && pos.IsValid() {
+			w.page.Links[lnum] = append(w.page.Links[lnum], docLink{StartCol: 0, EndCol: len(s), Pos: pos})
+		}
+	}
+}
+
+// doc appends the doc comment text.
+func (w *pageWriter) doc(text, indent string) {
+	if text == "" {
+		return
+	}
+	var buf bytes.Buffer
+	doc.ToText(&buf, text, indent, indent+"    ", docWidth)
+	w.text(buf.String(), "", token.Position{})
+}
+
+// section records the current line as the section of symbols.
+func (w *pageWriter) section(symbols ...string) {
+	for _, sym := range symbols {
+		if _, ok := w.page.Sections[sym]; !ok {
+			w.page.Sections[sym] = len(w.page.Lines) + 1
+		}
+	}
+}
+
+// decl appends the declaration node and the doc comment.
+func (w *pageWriter) decl(node ast.Node, docText string, symbols ...string) {
+	w.section(symbols...)
+	w.text(nodeString(w.fset, node), "", w.fset.Position(node.Pos()))
+	w.doc(docText, "    ")
+	w.line("")
+}
+
+// indexEntry appends the index entry line which links to the section of symbol.
+func (w *pageWriter) indexEntry(indent, text, symbol string) {
+	if i := strings.Index(text, "\n"); i >= 0 {
+		text = text[:i]
+	}
+	w.line(indent + text)
+	lnum := len(w.page.Lines)
+	w.page.Links[lnum] = append(w.page.Links[lnum], docLink{StartCol: 0, EndCol: len(indent + text), Symbol: symbol})
+}
+
+// renderDocPage renders the package documentation in dir.
+func renderDocPage(dir, importPath string) (*docPage, error) {
+	dpkg, fset, err := loadDocPackage(dir, importPath)
+	if err != nil {
+		return nil, err
+	}
+
+	page := &docPage{
+		ImportPath: importPath,
+		Dir:        dir,
+		Links:      make(map[int][]docLink),
+		Sections:   make(map[string]int),
+	}
+	w := &pageWriter{
+		page:    page,
+		fset:    fset,
+		imports: make(map[string]string),
+		symbols: make(map[string]bool),
+	}
+	for _, importPath := range dpkg.Imports {
+		w.imports[importName(importPath)] = importPath
+	}
+	for _, sym := range docSymbols(dpkg, false) {
+		w.symbols[sym] = true
+	}
+
+	w.line(fmt.Sprintf("package %s // import %q", dpkg.Name, importPath))
+	w.line("")
+	if dpkg.Doc != "" {
+		w.doc(dpkg.Doc, "")
+		w.line("")
+	}
+
+	// index
+	w.line("INDEX")
+	w.line("")
+	if len(dpkg.Consts) > 0 {
+		w.indexEntry("    ", "Constants", "#constants")
+	}
+	if len(dpkg.Vars) > 0 {
+		w.indexEntry("    ", "Variables", "#variables")
+	}
+	for _, f := range dpkg.Funcs {
+		w.indexEntry("    ", nodeString(fset, f.Decl), f.Name)
+	}
+	for _, t := range dpkg.Types {
+		w.indexEntry("    ", "type "+t.Name, t.Name)
+		for _, f := range t.Funcs {
+			w.indexEntry("        ", nodeString(fset, f.Decl), f.Name)
+		}
+		for _, m := range t.Methods {
+			w.indexEntry("        ", nodeString(fset, m.Decl), t.Name+"."+m.Name)
+		}
+	}
+	w.line("")
+
+	examples, exampleFset := loadExamples(dir)
+	if len(examples) > 0 {
+		w.line("EXAMPLES")
+		w.line("")
+		for _, ex := range examples {
+			w.indexEntry("    ", exampleTitle(ex), "#example-"+ex.Name)
+		}
+		w.line("")
+	}
+
+	if len(dpkg.Consts) > 0 {
+		w.section("#constants")
+		w.line("CONSTANTS")
+		w.line("")
+		for _, v := range dpkg.Consts {
+			w.decl(v.Decl, v.Doc, v.Names...)
+		}
+	}
+	if len(dpkg.Vars) > 0 {
+		w.section("#variables")
+		w.line("VARIABLES")
+		w.line("")
+		for _, v := range dpkg.Vars {
+			w.decl(v.Decl, v.Doc, v.Names...)
+		}
+	}
+	if len(dpkg.Funcs) > 0 {
+		w.line("FUNCTIONS")
+		w.line("")
+		for _, f := range dpkg.Funcs {
+			w.decl(f.Decl, f.Doc, f.Name)
+		}
+	}
+	if len(dpkg.Types) > 0 {
+		w.line("TYPES")
+		w.line("")
+		for _, t := range dpkg.Types {
+			w.decl(t.Decl, t.Doc, t.Name)
+			for _, v := range append(append([]*doc.Value{}, t.Consts...), t.Vars...) {
+				w.decl(v.Decl, v.Doc, v.Names...)
+			}
+			for _, f := range t.Funcs {
+				w.decl(f.Decl, f.Doc, f.Name)
+			}
+			for _, m := range t.Methods {
+				w.decl(m.Decl, m.Doc, t.Name+"."+m.Name)
+			}
+		}
+	}
+
+	for _, ex := range examples {
+		w.section("#example-" + ex.Name)
+		w.line(exampleTitle(ex))
+		w.line("")
+		w.doc(ex.Doc, "    ")
+		if code := nodeString(exampleFset, ex.Code); code != "" {
+			w.text(code, "    ", token.Position{})
+		}
+		if ex.Output != "" || ex.EmptyOutput {
+			w.line("")
+			w.line("    // Output:")
+			w.text(ex.Output, "    // ", token.Position{})
+		}
+		w.line("")
+	}
+
+	return page, nil
+}
+
+// loadExamples parses the test files in dir and returns the examples and the file set of test files.
+func loadExamples(dir string) ([]*doc.Example, *token.FileSet) {
+	fset := token.NewFileSet()
+	filter := func(fi os.FileInfo) bool { return strings.HasSuffix(fi.Name(), "_test.go") }
+	pkgs, err := parser.ParseDir(fset, dir, filter, parser.ParseComments)
+	if err != nil && len(pkgs) == 0 {
+		return nil, fset
+	}
+
+	var files []*ast.File
+	for _, pkg := range pkgs {
+		for _, f := range pkg.Files {
+			files = append(files, f)
+		}
+	}
+
+	return doc.Examples(files...), fset
+}
+
+// exampleTitle returns the title line of example.
+func exampleTitle(ex *doc.Example) string {
+	if ex.Name == "" {
+		return "Example (package)"
+	}
+
+	return "Example " + strings.Replace(ex.Name, "_", " ", 1)
+}
+
+// importName returns the guessed package name of importPath such as "yaml" of "gopkg.in/yaml.v2".
+func importName(importPath string) string {
+	name := path.Base(importPath)
+	if i := strings.Index(name, ".v"); i > 0 {
+		name = name[:i]
+	}
+	name = strings.TrimPrefix(name, "go-")
+
+	return strings.Replace(name, "-", "_", -1)
+}
@@ -0,0 +1,88 @@
+// Copyright 2018 The nvim-go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package command
+
+import (
+	"bytes"
+	"go/build"
+	"path/filepath"
+	"testing"
+)
+
+func TestRenderDocPage(t *testing.T) {
+	dir := filepath.Join(build.Default.GOROOT, "src", "strings")
+	page, err := renderDocPage(dir, "strings")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, sym := range []string{"ToUpper", "Builder", "Builder.Len", "#example-ToUpper"} {
+		lnum, ok := page.Sections[sym]
+		if !ok {
+			t.Errorf("section of %q not found", sym)
+			continue
+		}
+		if lnum < 1 || len(page.Lines) < lnum {
+			t.Errorf("section of %q is out of range: %d", sym, lnum)
+		}
+	}
+
+	index := -1
+	for i, line := range page.Lines {
+		if bytes.Equal(line, []byte("INDEX")) {
+			index = i + 1
+			break
+		}
+	}
+	if index < 0 {
+		t.Fatal("INDEX line not found")
+	}
+
+	var found bool
+	for lnum := index + 2; lnum <= len(page.Lines) && len(page.Lines[lnum-1]) > 0; lnum++ {
+		link := page.LinkAt(lnum, 4)
+		if link != nil && link.Symbol == "ToUpper" {
+			found = true
+			if _, ok := page.Sections[link.Symbol]; !ok {
+				t.Errorf("index link %q has no section", link.Symbol)
+			}
+		}
+	}
+	if !found {
+		t.Error("index entry of ToUpper not found")
+	}
+
+	lnum := page.Sections["ToUpper"]
+	link := page.LinkAt(lnum, 0)
+	if link == nil || !link.Pos.IsValid() {
+		t.Fatalf("declaration line of ToUpper does not link to the source: %+v", link)
+	}
+	if filepath.Dir(link.Pos.Filename) != dir {
+		t.Errorf("link.Pos.Filename = %q, want the file in %q", link.Pos.Filename, dir)
+	}
+}
+
+func TestImportName(t *testing.T) {
+	tests := []struct {
+		importPath string
+		want       string
+	}{
+		{importPath: "fmt", want: "fmt"},
+		{importPath: "net/http", want: "http"},
+		{importPath: "gopkg.in/yaml.v2", want: "yaml"},
+		{importPath: "github.com/mattn/go-isatty", want: "isatty"},
+		{importPath: "github.com/foo/bar-baz", want: "bar_baz"},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.importPath, func(t *testing.T) {
+			t.Parallel()
+
+			if got := importName(tt.importPath); got != tt.want {
+				t.Errorf("importName(%q) = %q, want %q", tt.importPath, got, tt.want)
+			}
+		})
+	}
+}
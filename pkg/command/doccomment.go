@@ -0,0 +1,293 @@
+// Copyright 2018 The nvim-go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package command
+
+import (
+	"context"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"sort"
+	"strings"
+
+	"github.com/neovim/go-client/nvim"
+	"github.com/pkg/errors"
+	"go.opencensus.io/trace"
+
+	"github.com/zchee/nvim-go/pkg/monitoring"
+	"github.com/zchee/nvim-go/pkg/nvimutil"
+)
+
+type cmdDocCommentEval struct {
+	File   string `eval:"expand('%:p')"`
+	Offset int    `eval:"line2byte(line('.')) + (col('.')-2)"`
+}
+
+func (c *Command) cmdDocComment(ctx context.Context, bang bool, eval *cmdDocCommentEval) {
+	errch := make(chan interface{}, 1)
+	go func() {
+		errch <- c.DocComment(ctx, bang, eval)
+	}()
+
+	select {
+	case <-ctx.Done():
+		return
+	case err := <-errch:
+		switch e := err.(type) {
+		case error:
+			nvimutil.ErrorWrap(c.Nvim, e)
+		case nil:
+			// nothing to do
+		}
+	}
+}
+
+// DocComment inserts the typical Go doc comment to the declaration under the cursor.
+// If bang is true, inserts the doc comment to all undocumented exported identifiers in the current buffer.
+func (c *Command) DocComment(ctx context.Context, bang bool, eval *cmdDocCommentEval) interface{} {
+	var span *trace.Span
+	ctx, span = monitoring.StartSpan(ctx, "DocComment")
+	defer span.End()
+
+	b := nvim.Buffer(c.buildContext.BufNr)
+	buf, err := c.Nvim.BufferLines(b, 0, -1, true)
+	if err != nil {
+		span.SetStatus(trace.Status{Code: trace.StatusCodeInternal, Message: err.Error()})
+		return errors.WithStack(err)
+	}
+
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, eval.File, nvimutil.ToByteSlice(buf), parser.ParseComments)
+	if err != nil {
+		span.SetStatus(trace.Status{Code: trace.StatusCodeInternal, Message: err.Error()})
+		return errors.WithStack(err)
+	}
+
+	var comments []docComment
+	if bang {
+		comments = undocumentedComments(fset, f)
+	} else {
+		comment, err := cursorComment(fset, f, eval.Offset)
+		if err != nil {
+			span.SetStatus(trace.Status{Code: trace.StatusCodeNotFound, Message: err.Error()})
+			return err
+		}
+		comments = append(comments, comment)
+	}
+	if len(comments) == 0 {
+		return nvimutil.EchoSuccess(c.Nvim, "GoDocComment", "all exported identifiers are documented")
+	}
+
+	// insert from the bottom so as not to shift the line number of the other comments
+	sort.Slice(comments, func(i, j int) bool { return comments[i].Line > comments[j].Line })
+
+	batch := c.Nvim.NewBatch()
+	for _, comment := range comments {
+		indent := leadingSpace(buf[comment.Line-1])
+		batch.SetBufferLines(b, comment.Line-1, comment.Line-1, true, [][]byte{[]byte(indent + comment.Text)})
+	}
+	if err := batch.Execute(); err != nil {
+		span.SetStatus(trace.Status{Code: trace.StatusCodeInternal, Message: err.Error()})
+		return errors.WithStack(err)
+	}
+
+	return nil
+}
+
+// docComment represents a doc comment text and the line number of the documented declaration.
+type docComment struct {
+	Line int
+	Text string
+}
+
+// docTemplates map of the ast.ObjKind and doc comment template.
+var docTemplates = map[ast.ObjKind]string{
+	ast.Pkg: "// Package %s implements ...",
+	ast.Con: "// %s is the ...",
+	ast.Typ: "// %s represents a ...",
+	ast.Var: "// %s is the ...",
+	ast.Fun: "// %s returns ...",
+}
+
+// commentText returns the doc comment text of name based on the kind.
+func commentText(kind ast.ObjKind, name string, fn *ast.FuncDecl) string {
+	if fn != nil {
+		switch {
+		case fn.Recv != nil && name == "String" && isStringMethod(fn):
+			return "// String implements a fmt.Stringer interface."
+		case fn.Recv != nil && name == "Error" && isStringMethod(fn):
+			return "// Error implements the error interface."
+		case fn.Type.Results == nil || len(fn.Type.Results.List) == 0:
+			return fmt.Sprintf("// %s ...", name)
+		case fn.Recv == nil && strings.HasPrefix(name, "New") && len(name) > len("New"):
+			return fmt.Sprintf("// %s returns the new %s.", name, strings.TrimPrefix(name, "New"))
+		}
+	}
+
+	return fmt.Sprintf(docTemplates[kind], name)
+}
+
+// isStringMethod reports whether the fn signature is "func() string".
+func isStringMethod(fn *ast.FuncDecl) bool {
+	if len(fn.Type.Params.List) != 0 || fn.Type.Results == nil || len(fn.Type.Results.List) != 1 {
+		return false
+	}
+	result := fn.Type.Results.List[0]
+	id, ok := result.Type.(*ast.Ident)
+
+	return ok && id.Name == "string" && len(result.Names) <= 1
+}
+
+// cursorComment returns the doc comment of the declaration at the offset.
+// Returns an error if the declaration is already documented.
+func cursorComment(fset *token.FileSet, f *ast.File, offset int) (docComment, error) {
+	pos := fset.File(f.Pos()).Pos(offset)
+	line := func(p token.Pos) int { return fset.Position(p).Line }
+
+	if line(f.Package) == line(pos) {
+		if f.Doc != nil {
+			return docComment{}, errors.Errorf("package %s is already documented", f.Name.Name)
+		}
+		return docComment{Line: line(f.Package), Text: commentText(ast.Pkg, f.Name.Name, nil)}, nil
+	}
+
+	for _, decl := range f.Decls {
+		if pos < decl.Pos() || decl.End() < pos {
+			continue
+		}
+
+		switch decl := decl.(type) {
+		case *ast.FuncDecl:
+			if decl.Doc != nil {
+				return docComment{}, errors.Errorf("%s is already documented", decl.Name.Name)
+			}
+			return docComment{Line: line(decl.Pos()), Text: commentText(ast.Fun, decl.Name.Name, decl)}, nil
+
+		case *ast.GenDecl:
+			if !decl.Lparen.IsValid() || len(decl.Specs) == 0 {
+				if len(decl.Specs) == 0 {
+					break
+				}
+				name, kind := specName(decl.Specs[0])
+				if decl.Doc != nil {
+					return docComment{}, errors.Errorf("%s is already documented", name)
+				}
+				return docComment{Line: line(decl.Pos()), Text: commentText(kind, name, nil)}, nil
+			}
+			for _, spec := range decl.Specs {
+				if line(spec.Pos()) <= line(pos) && line(pos) <= line(spec.End()) {
+					name, kind := specName(spec)
+					if specDoc(spec) != nil {
+						return docComment{}, errors.Errorf("%s is already documented", name)
+					}
+					return docComment{Line: line(spec.Pos()), Text: commentText(kind, name, nil)}, nil
+				}
+			}
+		}
+	}
+
+	return docComment{}, errors.New("no declaration here")
+}
+
+// undocumentedComments returns the doc comments of all undocumented exported identifiers in f.
+func undocumentedComments(fset *token.FileSet, f *ast.File) []docComment {
+	line := func(p token.Pos) int { return fset.Position(p).Line }
+
+	var comments []docComment
+	if f.Doc == nil && f.Name.Name != "main" && !strings.HasSuffix(f.Name.Name, "_test") {
+		comments = append(comments, docComment{Line: line(f.Package), Text: commentText(ast.Pkg, f.Name.Name, nil)})
+	}
+
+	for _, decl := range f.Decls {
+		switch decl := decl.(type) {
+		case *ast.FuncDecl:
+			if decl.Doc != nil || !decl.Name.IsExported() || !isExportedRecv(decl) {
+				continue
+			}
+			comments = append(comments, docComment{Line: line(decl.Pos()), Text: commentText(ast.Fun, decl.Name.Name, decl)})
+
+		case *ast.GenDecl:
+			if decl.Doc != nil || decl.Tok == token.IMPORT {
+				continue
+			}
+			if !decl.Lparen.IsValid() {
+				if len(decl.Specs) == 0 {
+					continue
+				}
+				if name, kind := specName(decl.Specs[0]); ast.IsExported(name) {
+					comments = append(comments, docComment{Line: line(decl.Pos()), Text: commentText(kind, name, nil)})
+				}
+				continue
+			}
+			for _, spec := range decl.Specs {
+				if specDoc(spec) != nil {
+					continue
+				}
+				if name, kind := specName(spec); ast.IsExported(name) {
+					comments = append(comments, docComment{Line: line(spec.Pos()), Text: commentText(kind, name, nil)})
+				}
+			}
+		}
+	}
+
+	return comments
+}
+
+// specName returns the first name and object kind of spec.
+func specName(spec ast.Spec) (string, ast.ObjKind) {
+	switch spec := spec.(type) {
+	case *ast.TypeSpec:
+		return spec.Name.Name, ast.Typ
+	case *ast.ValueSpec:
+		kind := ast.Var
+		if obj := spec.Names[0].Obj; obj != nil {
+			kind = obj.Kind
+		}
+		return spec.Names[0].Name, kind
+	}
+
+	return "", ast.Bad
+}
+
+// specDoc returns the doc comment group of spec.
+func specDoc(spec ast.Spec) *ast.CommentGroup {
+	switch spec := spec.(type) {
+	case *ast.TypeSpec:
+		return spec.Doc
+	case *ast.ValueSpec:
+		return spec.Doc
+	case *ast.ImportSpec:
+		return spec.Doc
+	}
+
+	return nil
+}
+
+// isExportedRecv reports whether the fn is function or the method of exported type.
+func isExportedRecv(fn *ast.FuncDecl) bool {
+	if fn.Recv == nil || len(fn.Recv.List) == 0 {
+		return true
+	}
+
+	typ := fn.Recv.List[0].Type
+	if star, ok := typ.(*ast.StarExpr); ok {
+		typ = star.X
+	}
+	id, ok := typ.(*ast.Ident)
+
+	return ok && id.IsExported()
+}
+
+// leadingSpace returns the leading white space of line.
+func leadingSpace(line []byte) string {
+	for i, b := range line {
+		if b != ' ' && b != '\t' {
+			return string(line[:i])
+		}
+	}
+
+	return string(line)
+}
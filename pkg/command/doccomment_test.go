@@ -0,0 +1,108 @@
+// Copyright 2018 The nvim-go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package command
+
+import (
+	"go/parser"
+	"go/token"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const docCommentSrc = `package foo
+
+type Foo struct{}
+
+func NewFoo() *Foo { return &Foo{} }
+
+func (f *Foo) String() string { return "" }
+
+func (f *Foo) Error() string { return "" }
+
+func (f *Foo) Run() {}
+
+// Documented is documented.
+func Documented() {}
+
+func unexported() int { return 0 }
+
+const (
+	A = 1
+	b = 2
+)
+
+var Bar, Baz int
+`
+
+func TestUndocumentedComments(t *testing.T) {
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "foo.go", docCommentSrc, parser.ParseComments)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []docComment{
+		{Line: 1, Text: "// Package foo implements ..."},
+		{Line: 3, Text: "// Foo represents a ..."},
+		{Line: 5, Text: "// NewFoo returns the new Foo."},
+		{Line: 7, Text: "// String implements a fmt.Stringer interface."},
+		{Line: 9, Text: "// Error implements the error interface."},
+		{Line: 11, Text: "// Run ..."},
+		{Line: 19, Text: "// A is the ..."},
+		{Line: 23, Text: "// Bar is the ..."},
+	}
+	if got := undocumentedComments(fset, f); !reflect.DeepEqual(got, want) {
+		t.Errorf("undocumentedComments() = %v, want %v", got, want)
+	}
+}
+
+func TestCursorComment(t *testing.T) {
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "foo.go", docCommentSrc, parser.ParseComments)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		offset  int
+		want    docComment
+		wantErr bool
+	}{
+		{
+			name:   "unexported func",
+			offset: strings.Index(docCommentSrc, "unexported"),
+			want:   docComment{Line: 16, Text: "// unexported returns ..."},
+		},
+		{
+			name:   "grouped const",
+			offset: strings.Index(docCommentSrc, "b = 2"),
+			want:   docComment{Line: 20, Text: "// b is the ..."},
+		},
+		{
+			name:    "documented func",
+			offset:  strings.Index(docCommentSrc, "func Documented"),
+			wantErr: true,
+		},
+		{
+			name:    "no declaration",
+			offset:  strings.Index(docCommentSrc, "{}\n\nfunc NewFoo") + 3,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := cursorComment(fset, f, tt.offset)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("cursorComment() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("cursorComment() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
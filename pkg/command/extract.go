@@ -0,0 +1,874 @@
+// Copyright 2018 The nvim-go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package command
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/scanner"
+	"go/token"
+	"go/types"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/neovim/go-client/nvim"
+	"github.com/pkg/errors"
+	"go.opencensus.io/trace"
+	"golang.org/x/tools/go/ast/astutil"
+
+	"github.com/zchee/nvim-go/pkg/monitoring"
+	"github.com/zchee/nvim-go/pkg/nvimutil"
+)
+
+type cmdExtractEval struct {
+	File  string `eval:"expand('%:p')"`
+	BufNr int    `eval:"bufnr('%')"`
+	Start []int  `eval:"getpos(\"'<\")[1:2]"`
+	End   []int  `eval:"getpos(\"'>\")[1:2]"`
+}
+
+func (c *Command) cmdExtractFunc(ctx context.Context, args []string, ranges [2]int, eval *cmdExtractEval) {
+	errch := make(chan interface{}, 1)
+	go func() {
+		errch <- c.ExtractFunc(ctx, args, ranges, eval)
+	}()
+
+	select {
+	case <-ctx.Done():
+		return
+	case err := <-errch:
+		switch e := err.(type) {
+		case error:
+			nvimutil.ErrorWrap(c.Nvim, e)
+		case nil:
+			// nothing to do
+		}
+	}
+}
+
+// ExtractFunc extracts the statements of the range lines to the new function, and replaces them with
+// the call of the new function.
+func (c *Command) ExtractFunc(ctx context.Context, args []string, ranges [2]int, eval *cmdExtractEval) interface{} {
+	var span *trace.Span
+	ctx, span = monitoring.StartSpan(ctx, "ExtractFunc")
+	defer span.End()
+
+	b := nvim.Buffer(eval.BufNr)
+	in, err := c.Nvim.BufferLines(b, 0, -1, true)
+	if err != nil {
+		span.SetStatus(trace.Status{Code: trace.StatusCodeInternal, Message: err.Error()})
+		return errors.WithStack(err)
+	}
+	src := append(nvimutil.ToByteSlice(in), '\n')
+
+	out, err := extractFunc(eval.File, src, lineOffset(src, ranges[0]), lineOffset(src, ranges[1]+1), args[0])
+	if err != nil {
+		span.SetStatus(trace.Status{Code: trace.StatusCodeInvalidArgument, Message: err.Error()})
+		return err
+	}
+
+	return minUpdate(ctx, c.Nvim, b, in, nvimutil.ToBufferLines(bytes.TrimSuffix(out, []byte{'\n'})))
+}
+
+func (c *Command) cmdExtractVar(ctx context.Context, args []string, ranges [2]int, eval *cmdExtractEval) {
+	errch := make(chan interface{}, 1)
+	go func() {
+		errch <- c.ExtractVar(ctx, args, ranges, eval)
+	}()
+
+	select {
+	case <-ctx.Done():
+		return
+	case err := <-errch:
+		switch e := err.(type) {
+		case error:
+			nvimutil.ErrorWrap(c.Nvim, e)
+		case nil:
+			// nothing to do
+		}
+	}
+}
+
+// ExtractVar extracts the visual selected expression to the new variable declared before the statement,
+// and replaces the expression with the variable.
+func (c *Command) ExtractVar(ctx context.Context, args []string, ranges [2]int, eval *cmdExtractEval) interface{} {
+	var span *trace.Span
+	ctx, span = monitoring.StartSpan(ctx, "ExtractVar")
+	defer span.End()
+
+	if len(eval.Start) != 2 || len(eval.End) != 2 || eval.Start[0] != ranges[0] || eval.End[0] != ranges[1] {
+		return errors.New("select the expression in visual mode")
+	}
+
+	b := nvim.Buffer(eval.BufNr)
+	in, err := c.Nvim.BufferLines(b, 0, -1, true)
+	if err != nil {
+		span.SetStatus(trace.Status{Code: trace.StatusCodeInternal, Message: err.Error()})
+		return errors.WithStack(err)
+	}
+	src := append(nvimutil.ToByteSlice(in), '\n')
+
+	start := posOffset(src, eval.Start[0], eval.Start[1])
+	end := posOffset(src, eval.End[0], eval.End[1])
+	if end < len(src) && src[end] != '\n' {
+		// the end of visual selection is inclusive
+		_, size := utf8.DecodeRune(src[end:])
+		end += size
+	}
+
+	out, err := extractVar(eval.File, src, start, end, args[0])
+	if err != nil {
+		span.SetStatus(trace.Status{Code: trace.StatusCodeInvalidArgument, Message: err.Error()})
+		return err
+	}
+
+	return minUpdate(ctx, c.Nvim, b, in, nvimutil.ToBufferLines(bytes.TrimSuffix(out, []byte{'\n'})))
+}
+
+// lineOffset returns the byte offset of the 1-based line in src.
+func lineOffset(src []byte, line int) int {
+	var offset int
+	for ; line > 1; line-- {
+		i := bytes.IndexByte(src[offset:], '\n')
+		if i < 0 {
+			return len(src)
+		}
+		offset += i + 1
+	}
+
+	return offset
+}
+
+// posOffset returns the byte offset of the 1-based line and column in src.
+// The column is clamped to the end of line, such as the column of the linewise visual selection.
+func posOffset(src []byte, line, col int) int {
+	offset := lineOffset(src, line)
+	eol := bytes.IndexByte(src[offset:], '\n')
+	if eol < 0 {
+		eol = len(src) - offset
+	}
+	if col < 1 {
+		col = 1
+	}
+	if col-1 > eol {
+		return offset + eol
+	}
+
+	return offset + col - 1
+}
+
+// stmtList returns the statement list of the block like node n, or nil if n is not a block.
+func stmtList(n ast.Node) []ast.Stmt {
+	switch n := n.(type) {
+	case *ast.BlockStmt:
+		return n.List
+	case *ast.CaseClause:
+		return n.Body
+	case *ast.CommClause:
+		return n.Body
+	}
+
+	return nil
+}
+
+// extractFunc extracts the statements between the start and end offset of src to the new function
+// named name, and returns the new contents of file.
+func extractFunc(file string, src []byte, start, end int, name string) ([]byte, error) {
+	if !token.IsIdentifier(name) {
+		return nil, errors.Errorf("invalid function name %q", name)
+	}
+
+	cf, err := typeCheck(file, src)
+	if err != nil {
+		return nil, err
+	}
+	tf := cf.Fset.File(cf.File.Pos())
+	if end > tf.Size() {
+		end = tf.Size()
+	}
+	if start >= end {
+		return nil, errors.New("no statements are selected")
+	}
+
+	path, _ := astutil.PathEnclosingInterval(cf.File, tf.Pos(start), tf.Pos(end))
+
+	// find the statements in the innermost block which encloses the selection
+	var (
+		block ast.Node
+		scope *types.Scope
+		stmts []ast.Stmt
+		decl  *ast.FuncDecl
+	)
+	for i, n := range path {
+		if block == nil && stmtList(n) != nil {
+			block, scope = n, blockScope(cf.Info, path[i:])
+			for _, stmt := range stmtList(n) {
+				stmtStart, stmtEnd := tf.Offset(stmt.Pos()), tf.Offset(stmt.End())
+				switch {
+				case start <= stmtStart && stmtEnd <= end:
+					stmts = append(stmts, stmt)
+				case stmtStart < end && start < stmtEnd:
+					return nil, errors.Errorf("the selection must consist of whole statements, line %d is partially selected", tf.Line(stmt.Pos()))
+				}
+			}
+		}
+		if d, ok := n.(*ast.FuncDecl); ok {
+			decl = d
+		}
+	}
+	if decl == nil || block == nil {
+		return nil, errors.New("the selection is not in a function body")
+	}
+	if len(stmts) == 0 {
+		return nil, errors.New("no statements are selected")
+	}
+	if !onlyComments(src[start:tf.Offset(stmts[0].Pos())]) || !onlyComments(src[tf.Offset(stmts[len(stmts)-1].End()):end]) {
+		return nil, errors.New("the selection must consist of whole statements")
+	}
+	if err := checkExtractable(cf.Fset, stmts); err != nil {
+		return nil, errors.Wrap(err, "cannot extract the function")
+	}
+
+	pkgScope := cf.Pkg.Scope()
+	if _, obj := pkgScope.Innermost(stmts[0].Pos()).LookupParent(name, stmts[0].Pos()); obj != nil {
+		return nil, errors.Errorf("%q is already declared", name)
+	}
+
+	selStart, selEnd := stmts[0].Pos(), stmts[len(stmts)-1].End()
+	inSelection := func(pos token.Pos) bool { return selStart <= pos && pos < selEnd }
+	isLocal := func(obj types.Object) bool {
+		return obj.Pkg() == cf.Pkg && obj.Parent() != nil && obj.Parent() != pkgScope && obj.Parent() != types.Universe &&
+			decl.Pos() <= obj.Pos() && obj.Pos() < decl.End()
+	}
+
+	// collect the free variables, and the variables which are modified in the selection
+	var (
+		params   []*types.Var
+		seen     = make(map[types.Object]bool)
+		modified = make(map[types.Object]bool)
+		localErr error
+	)
+	for _, stmt := range stmts {
+		ast.Inspect(stmt, func(n ast.Node) bool {
+			id, ok := n.(*ast.Ident)
+			if !ok {
+				return true
+			}
+			obj := cf.Info.Uses[id]
+			if obj == nil || !isLocal(obj) || inSelection(obj.Pos()) {
+				return true
+			}
+			switch obj := obj.(type) {
+			case *types.Var:
+				if !seen[obj] {
+					seen[obj] = true
+					params = append(params, obj)
+				}
+			case *types.TypeName, *types.Const:
+				if localErr == nil {
+					localErr = errors.Errorf("the selection refers to %s declared in the function at line %d", obj.Name(), tf.Line(obj.Pos()))
+				}
+			}
+			return true
+		})
+		forEachModified(cf.Info, stmt, func(id *ast.Ident) {
+			if obj := cf.Info.ObjectOf(id); obj != nil {
+				modified[obj] = true
+			}
+		})
+	}
+	if localErr != nil {
+		return nil, errors.Wrap(localErr, "cannot extract the function")
+	}
+
+	usedOutside := make(map[types.Object]bool)
+	for id, obj := range cf.Info.Uses {
+		if !inSelection(id.Pos()) && decl.Pos() <= id.Pos() && id.Pos() < decl.End() {
+			usedOutside[obj] = true
+		}
+	}
+
+	// the results are the modified free variables and the variables declared at the top level of the
+	// selection, which are used after the selection
+	var results, declared []*types.Var
+	for _, v := range params {
+		if modified[v] && usedOutside[v] {
+			results = append(results, v)
+		}
+	}
+	for id, obj := range cf.Info.Defs {
+		if v, ok := obj.(*types.Var); ok && inSelection(id.Pos()) && v.Parent() == scope && usedOutside[v] {
+			declared = append(declared, v)
+		}
+	}
+	sort.Slice(declared, func(i, j int) bool { return declared[i].Pos() < declared[j].Pos() })
+	results = append(results, declared...)
+
+	qf, missing := fileQualifier(cf)
+	var sig, call, ret bytes.Buffer
+	names := make([]string, 0, len(params))
+	for i, v := range params {
+		if i > 0 {
+			sig.WriteString(", ")
+		}
+		fmt.Fprintf(&sig, "%s %s", v.Name(), types.TypeString(v.Type(), qf))
+		names = append(names, v.Name())
+	}
+	sig.WriteString(")")
+
+	resultNames := make([]string, 0, len(results))
+	for i, v := range results {
+		if i == 0 {
+			sig.WriteString(" ")
+			if len(results) > 1 {
+				sig.WriteString("(")
+			}
+		} else {
+			sig.WriteString(", ")
+		}
+		sig.WriteString(types.TypeString(v.Type(), qf))
+		resultNames = append(resultNames, v.Name())
+	}
+	if len(results) > 1 {
+		sig.WriteString(")")
+	}
+	if len(*missing) > 0 {
+		return nil, errors.Errorf("cannot extract the function: the type of parameters requires the import of %q", (*missing)[0])
+	}
+	for _, v := range append(params, results...) {
+		if tn := localTypeName(v.Type()); tn != nil && isLocal(tn) {
+			return nil, errors.Errorf("cannot extract the function: the type of %s is declared in the function", v.Name())
+		}
+	}
+
+	switch {
+	case len(results) == 0:
+		// nothing to assign
+	case len(declared) == len(results):
+		fmt.Fprintf(&call, "%s := ", strings.Join(resultNames, ", "))
+	default:
+		for _, v := range declared {
+			fmt.Fprintf(&call, "var %s %s\n", v.Name(), types.TypeString(v.Type(), qf))
+		}
+		fmt.Fprintf(&call, "%s = ", strings.Join(resultNames, ", "))
+	}
+	fmt.Fprintf(&call, "%s(%s)", name, strings.Join(names, ", "))
+	if len(results) > 0 {
+		fmt.Fprintf(&ret, "\nreturn %s", strings.Join(resultNames, ", "))
+	}
+
+	body := src[start:end]
+	if bytes.HasSuffix(body, []byte{'\n'}) {
+		call.WriteByte('\n')
+	}
+	fn := fmt.Sprintf("\n\nfunc %s(%s {\n%s%s\n}", name, sig.String(), bytes.TrimRight(body, "\n"), ret.String())
+
+	declEnd := tf.Offset(decl.End())
+	var buf bytes.Buffer
+	buf.Grow(len(src) + len(fn) + call.Len())
+	buf.Write(src[:start])
+	buf.Write(call.Bytes())
+	buf.Write(src[end:declEnd])
+	buf.WriteString(fn)
+	buf.Write(src[declEnd:])
+
+	return formatChecked(file, src, buf.Bytes())
+}
+
+// blockScope returns the scope of the block path[0].
+// The function body does not have own scope, it is the scope of the function type.
+func blockScope(info *types.Info, path []ast.Node) *types.Scope {
+	if len(path) > 1 {
+		switch n := path[1].(type) {
+		case *ast.FuncDecl:
+			return info.Scopes[n.Type]
+		case *ast.FuncLit:
+			return info.Scopes[n.Type]
+		}
+	}
+
+	return info.Scopes[path[0]]
+}
+
+// onlyComments reports whether the src consists of only the white spaces, comments and semicolons.
+func onlyComments(src []byte) bool {
+	var s scanner.Scanner
+	fset := token.NewFileSet()
+	s.Init(fset.AddFile("", -1, len(src)), src, nil, scanner.ScanComments)
+	for {
+		switch _, tok, _ := s.Scan(); tok {
+		case token.EOF:
+			return true
+		case token.COMMENT, token.SEMICOLON:
+			// continue
+		default:
+			return false
+		}
+	}
+}
+
+// checkExtractable returns an error if stmts contain the statement which changes the control flow of
+// the enclosing function, because it does not work in the extracted function.
+func checkExtractable(fset *token.FileSet, stmts []ast.Stmt) error {
+	// labels declared in the selection
+	labels := make(map[string]bool)
+	for _, stmt := range stmts {
+		ast.Inspect(stmt, func(n ast.Node) bool {
+			switch n := n.(type) {
+			case *ast.FuncLit:
+				return false
+			case *ast.LabeledStmt:
+				labels[n.Label.Name] = true
+			}
+			return true
+		})
+	}
+
+	var (
+		err           error
+		stack         []ast.Node
+		loops, breaks int
+	)
+	for _, stmt := range stmts {
+		ast.Inspect(stmt, func(n ast.Node) bool {
+			if err != nil {
+				return false
+			}
+			if n == nil {
+				switch stack[len(stack)-1].(type) {
+				case *ast.ForStmt, *ast.RangeStmt:
+					loops--
+					breaks--
+				case *ast.SwitchStmt, *ast.TypeSwitchStmt, *ast.SelectStmt:
+					breaks--
+				}
+				stack = stack[:len(stack)-1]
+				return true
+			}
+
+			switch n := n.(type) {
+			case *ast.FuncLit:
+				return false
+			case *ast.ReturnStmt:
+				err = errors.Errorf("the selection contains the return statement at line %d", fset.Position(n.Pos()).Line)
+			case *ast.DeferStmt:
+				err = errors.Errorf("the selection contains the defer statement at line %d", fset.Position(n.Pos()).Line)
+			case *ast.BranchStmt:
+				var jump bool
+				switch {
+				case n.Label != nil:
+					jump = !labels[n.Label.Name]
+				case n.Tok == token.BREAK:
+					jump = breaks == 0
+				case n.Tok == token.CONTINUE:
+					jump = loops == 0
+				case n.Tok == token.FALLTHROUGH:
+					jump = breaks == 0
+				}
+				if jump {
+					err = errors.Errorf("the selection contains the %s statement to outside at line %d", n.Tok, fset.Position(n.Pos()).Line)
+				}
+			case *ast.ForStmt, *ast.RangeStmt:
+				loops++
+				breaks++
+			case *ast.SwitchStmt, *ast.TypeSwitchStmt, *ast.SelectStmt:
+				breaks++
+			}
+			if err != nil {
+				return false
+			}
+
+			stack = append(stack, n)
+			return true
+		})
+	}
+
+	return err
+}
+
+// forEachModified calls fn with the identifiers of the variables which are modified in n, by the
+// assignment, the increment or decrement, or taking the address.
+func forEachModified(info *types.Info, n ast.Node, fn func(id *ast.Ident)) {
+	modify := func(x ast.Expr) {
+		if id := rootIdent(x); id != nil {
+			fn(id)
+		}
+	}
+
+	ast.Inspect(n, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.AssignStmt:
+			for _, lhs := range n.Lhs {
+				modify(lhs)
+			}
+		case *ast.IncDecStmt:
+			modify(n.X)
+		case *ast.RangeStmt:
+			if n.Tok == token.ASSIGN {
+				modify(n.Key)
+				modify(n.Value)
+			}
+		case *ast.UnaryExpr:
+			if n.Op == token.AND {
+				modify(n.X)
+			}
+		case *ast.SelectorExpr:
+			// method call of the pointer receiver takes the address implicitly
+			if sel, ok := info.Selections[n]; ok && sel.Kind() == types.MethodVal {
+				if _, ok := sel.Obj().Type().(*types.Signature).Recv().Type().(*types.Pointer); ok {
+					if _, ok := sel.Recv().Underlying().(*types.Pointer); !ok {
+						modify(n.X)
+					}
+				}
+			}
+		}
+		return true
+	})
+}
+
+// rootIdent returns the variable identifier which is modified by the assignment to x.
+func rootIdent(x ast.Expr) *ast.Ident {
+	for x != nil {
+		switch e := x.(type) {
+		case *ast.Ident:
+			return e
+		case *ast.ParenExpr:
+			x = e.X
+		case *ast.SelectorExpr:
+			x = e.X
+		case *ast.IndexExpr:
+			x = e.X
+		default:
+			// such as the pointer indirection does not modify the variable
+			return nil
+		}
+	}
+
+	return nil
+}
+
+// localTypeName returns the type name which composes typ.
+func localTypeName(typ types.Type) *types.TypeName {
+	for {
+		switch t := typ.(type) {
+		case *types.Named:
+			return t.Obj()
+		case *types.Pointer:
+			typ = t.Elem()
+		case *types.Slice:
+			typ = t.Elem()
+		case *types.Array:
+			typ = t.Elem()
+		case *types.Chan:
+			typ = t.Elem()
+		case *types.Map:
+			if tn := localTypeName(t.Key()); tn != nil {
+				return tn
+			}
+			typ = t.Elem()
+		default:
+			return nil
+		}
+	}
+}
+
+// fileQualifier returns the qualifier which uses the import names of the file.
+// The missing records the import paths of the packages which are not imported by the file.
+func fileQualifier(cf *checkedFile) (qf types.Qualifier, missing *[]string) {
+	names := make(map[*types.Package]string)
+	for _, spec := range cf.File.Imports {
+		obj := cf.Info.Implicits[spec]
+		if spec.Name != nil {
+			obj = cf.Info.Defs[spec.Name]
+		}
+		if pkgName, ok := obj.(*types.PkgName); ok {
+			names[pkgName.Imported()] = pkgName.Name()
+		}
+	}
+
+	missing = new([]string)
+	qf = func(pkg *types.Package) string {
+		if pkg == cf.Pkg {
+			return ""
+		}
+		if name, ok := names[pkg]; ok {
+			return name
+		}
+		*missing = append(*missing, pkg.Path())
+		return pkg.Name()
+	}
+
+	return qf, missing
+}
+
+// extractVar extracts the expression between the start and end offset of src to the new variable named
+// name, and returns the new contents of file.
+func extractVar(file string, src []byte, start, end int, name string) ([]byte, error) {
+	if !token.IsIdentifier(name) {
+		return nil, errors.Errorf("invalid variable name %q", name)
+	}
+
+	for start < end && isSpace(src[start]) {
+		start++
+	}
+	for start < end && isSpace(src[end-1]) {
+		end--
+	}
+	if start >= end {
+		return nil, errors.New("no expression is selected")
+	}
+
+	cf, err := typeCheck(file, src)
+	if err != nil {
+		return nil, err
+	}
+	tf := cf.Fset.File(cf.File.Pos())
+	if end > tf.Size() {
+		return nil, errors.New("no expression is selected")
+	}
+
+	path, _ := astutil.PathEnclosingInterval(cf.File, tf.Pos(start), tf.Pos(end))
+	expr, ok := path[0].(ast.Expr)
+	if !ok || tf.Offset(expr.Pos()) != start || tf.Offset(expr.End()) != end {
+		return nil, errors.New("the selection is not an expression")
+	}
+	tv, ok := cf.Info.Types[expr]
+	switch {
+	case !ok, !tv.IsValue():
+		return nil, errors.Errorf("%s is not a value", src[start:end])
+	case tv.IsNil():
+		return nil, errors.New("cannot extract the untyped nil")
+	}
+	if _, ok := tv.Type.(*types.Tuple); ok {
+		return nil, errors.Errorf("%s is the multiple values", src[start:end])
+	}
+
+	switch p := path[1].(type) {
+	case *ast.AssignStmt:
+		for _, lhs := range p.Lhs {
+			if lhs == expr {
+				return nil, errors.New("cannot extract the left-hand side of the assignment")
+			}
+		}
+	case *ast.IncDecStmt:
+		return nil, errors.New("cannot extract the operand of the increment or decrement statement")
+	case *ast.UnaryExpr:
+		if p.Op == token.AND {
+			return nil, errors.New("cannot extract the operand of the address operator")
+		}
+	case *ast.RangeStmt:
+		if p.Key == expr || p.Value == expr {
+			return nil, errors.New("cannot extract the iteration variable")
+		}
+	case *ast.SelectorExpr:
+		if p.Sel == expr {
+			return nil, errors.New("cannot extract the selector")
+		}
+	case *ast.KeyValueExpr:
+		if id, ok := expr.(*ast.Ident); ok && p.Key == expr {
+			if v, ok := cf.Info.Uses[id].(*types.Var); ok && v.IsField() {
+				return nil, errors.New("cannot extract the field name")
+			}
+		}
+	}
+
+	// find the statement which the variable is declared before
+	stmt, block, err := evaluatedStmt(cf.Info, path)
+	switch err {
+	case nil:
+		// nothing to do
+	case errInFuncLit:
+		return nil, errors.New("cannot extract the expression in the function literal signature")
+	case errEachIteration:
+		return nil, errors.New("cannot extract the expression evaluated on each iteration")
+	case errNotInFuncBody:
+		return nil, errors.New("the selection is not in a function body")
+	default:
+		return nil, errors.New("cannot extract the conditionally evaluated expression")
+	}
+
+	// the variable is declared before the statement, so it must not refer to the identifiers declared in
+	// the statement
+	var declErr error
+	ast.Inspect(expr, func(n ast.Node) bool {
+		if id, ok := n.(*ast.Ident); ok && declErr == nil {
+			if obj := cf.Info.Uses[id]; obj != nil && stmt.Pos() <= obj.Pos() && obj.Pos() < stmt.End() {
+				declErr = errors.Errorf("cannot extract the expression: %s is declared in the statement", id.Name)
+			}
+		}
+		return declErr == nil
+	})
+	if declErr != nil {
+		return nil, declErr
+	}
+
+	// the function calls and receive operations are evaluated in lexical left-to-right order, so the
+	// extracted expression must not be moved before the preceding ones
+	if hasSideEffects(cf.Info, expr) {
+		if prev := sideEffectBefore(cf.Info, stmt, expr); prev != nil {
+			return nil, errors.Errorf("cannot extract the expression: it must be evaluated after %s", src[tf.Offset(prev.Pos()):tf.Offset(prev.End())])
+		}
+	}
+
+	scope := cf.Pkg.Scope().Innermost(stmt.Pos())
+	if _, obj := scope.LookupParent(name, stmt.Pos()); obj != nil {
+		return nil, errors.Errorf("%q is already declared", name)
+	}
+	if block != nil && block.Lookup(name) != nil {
+		return nil, errors.Errorf("%q is already declared in the block", name)
+	}
+
+	decl := fmt.Sprintf("%s := %s\n", name, src[start:end])
+	if tv.Value != nil {
+		// keep the untyped constant
+		decl = fmt.Sprintf("const %s = %s\n", name, src[start:end])
+	}
+
+	pos := tf.Offset(stmt.Pos())
+	var buf bytes.Buffer
+	buf.Grow(len(src) + len(decl) + len(name))
+	buf.Write(src[:pos])
+	buf.WriteString(decl)
+	buf.Write(src[pos:start])
+	buf.WriteString(name)
+	buf.Write(src[end:])
+
+	return formatChecked(file, src, buf.Bytes())
+}
+
+// The errors of evaluatedStmt.
+var (
+	errInFuncLit     = errors.New("the expression is in the function literal")
+	errConditional   = errors.New("the expression is conditionally evaluated")
+	errEachIteration = errors.New("the expression is evaluated on each iteration")
+	errNotInFuncBody = errors.New("the expression is not in a function body")
+)
+
+// evaluatedStmt returns the statement in the statement list which evaluates the expression path[0]
+// exactly once before executing the other part of the statement, and the scope of the statement list.
+func evaluatedStmt(info *types.Info, path []ast.Node) (ast.Stmt, *types.Scope, error) {
+	for i := 1; i < len(path); i++ {
+		child := path[i-1]
+		switch n := path[i].(type) {
+		case *ast.FuncLit:
+			return nil, nil, errInFuncLit
+		case *ast.BinaryExpr:
+			if (n.Op == token.LAND || n.Op == token.LOR) && child == n.Y {
+				return nil, nil, errConditional
+			}
+		case *ast.ForStmt:
+			if child == n.Cond || child == n.Post {
+				return nil, nil, errEachIteration
+			}
+		}
+
+		if list := stmtList(path[i]); list != nil {
+			for _, s := range list {
+				if s == child {
+					return s, blockScope(info, path[i:]), nil
+				}
+			}
+			return nil, nil, errConditional
+		}
+
+		// the intermediate statements must be evaluated before the enclosing statement
+		if s, ok := child.(ast.Stmt); ok {
+			if _, labeled := path[i].(*ast.LabeledStmt); !labeled && s != initStmt(path[i]) {
+				return nil, nil, errConditional
+			}
+		}
+	}
+
+	return nil, nil, errNotInFuncBody
+}
+
+// sideEffectBefore returns the first function call or receive operation in stmt which is evaluated before
+// expr and may have the side effects. Returns nil if there is no such expression.
+func sideEffectBefore(info *types.Info, stmt ast.Stmt, expr ast.Expr) ast.Expr {
+	var found ast.Expr
+	ast.Inspect(stmt, func(n ast.Node) bool {
+		if found != nil || n == nil || n.Pos() >= expr.Pos() {
+			return false
+		}
+		switch n := n.(type) {
+		case *ast.FuncLit:
+			return false
+		case *ast.CallExpr, *ast.UnaryExpr:
+			if n.End() <= expr.Pos() && hasSideEffects(info, n) {
+				found = n.(ast.Expr)
+			}
+		}
+		return found == nil
+	})
+
+	return found
+}
+
+// initStmt returns the init statement of n if any.
+func initStmt(n ast.Node) ast.Stmt {
+	switch n := n.(type) {
+	case *ast.IfStmt:
+		return n.Init
+	case *ast.ForStmt:
+		return n.Init
+	case *ast.SwitchStmt:
+		return n.Init
+	case *ast.TypeSwitchStmt:
+		return n.Init
+	}
+
+	return nil
+}
+
+// isSpace reports whether the c is the white space.
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r'
+}
+
+// formatChecked formats the refactored src, and type checks it. It returns an error if the refactored
+// source has the type errors which the original source does not have.
+func formatChecked(file string, orig, src []byte) ([]byte, error) {
+	out, err := format.Source(src)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not format the refactored source")
+	}
+
+	typeErrors := func(src []byte) ([]types.Error, error) {
+		var errs []types.Error
+		_, err := typeCheckFunc(file, src, func(err error) {
+			if terr, ok := err.(types.Error); ok {
+				errs = append(errs, terr)
+			}
+		})
+		return errs, err
+	}
+
+	origErrs, err := typeErrors(orig)
+	if err != nil {
+		return nil, err
+	}
+	known := make(map[string]int)
+	for _, e := range origErrs {
+		known[e.Msg]++
+	}
+
+	errs, err := typeErrors(out)
+	if err != nil {
+		return nil, err
+	}
+	for _, e := range errs {
+		if known[e.Msg] > 0 {
+			known[e.Msg]--
+			continue
+		}
+		return nil, errors.Errorf("the refactored source does not type check: %s", e)
+	}
+
+	return out, nil
+}
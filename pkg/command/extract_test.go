@@ -0,0 +1,247 @@
+// Copyright 2018 The nvim-go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package command
+
+import (
+	"strings"
+	"testing"
+)
+
+const extractSrc = `package p
+
+import "strings"
+
+type T struct{ n int }
+
+func (t *T) Inc() { t.n++ }
+
+func f(s string, n int) (int, error) {
+	sum := 0
+	for i := 0; i < n; i++ {
+		sum += i
+	}
+	x := strings.Repeat(s, n)
+	y := len(x) + 1
+	var t T
+	t.Inc()
+	if n > 0 && len(s) > 0 {
+		return sum + y, nil
+	}
+	return t.n + sum, nil
+}
+
+func g(n int) int { return n }
+
+func h() int {
+	z := g(1) + strings.Index("ab", "b")
+	return z
+}
+`
+
+// selection returns the start and end offset of the first sel in src.
+func selection(t *testing.T, src, sel string) (int, int) {
+	t.Helper()
+
+	start := strings.Index(src, sel)
+	if start < 0 {
+		t.Fatalf("%q is not found", sel)
+	}
+	return start, start + len(sel)
+}
+
+func TestExtractFunc(t *testing.T) {
+	file, _ := testCheckedFile(t, extractSrc)
+
+	tests := []struct {
+		name    string
+		sel     string
+		fn      string
+		want    []string
+		wantErr string
+	}{
+		{
+			name: "modified free variable",
+			sel:  "\tfor i := 0; i < n; i++ {\n\t\tsum += i\n\t}\n",
+			fn:   "sumUp",
+			want: []string{
+				"\tsum = sumUp(n, sum)\n",
+				"func sumUp(n int, sum int) int {\n\tfor i := 0; i < n; i++ {\n\t\tsum += i\n\t}\n\treturn sum\n}\n",
+			},
+		},
+		{
+			name: "declared variables",
+			sel:  "\tx := strings.Repeat(s, n)\n\ty := len(x) + 1\n",
+			fn:   "repeat",
+			want: []string{
+				"\ty := repeat(s, n)\n",
+				"func repeat(s string, n int) int {\n\tx := strings.Repeat(s, n)\n\ty := len(x) + 1\n\treturn y\n}\n",
+			},
+		},
+		{
+			name: "pointer receiver method call",
+			sel:  "\tt.Inc()\n",
+			fn:   "inc",
+			want: []string{
+				"\tt = inc(t)\n",
+				"func inc(t T) T {\n\tt.Inc()\n\treturn t\n}\n",
+			},
+		},
+		{
+			name:    "return statement",
+			sel:     "\tif n > 0 && len(s) > 0 {\n\t\treturn sum + y, nil\n\t}\n",
+			fn:      "g",
+			wantErr: "return statement",
+		},
+		{
+			name:    "partial statement",
+			sel:     "\t\tsum += i\n\t}\n",
+			fn:      "g",
+			wantErr: "whole statements",
+		},
+		{
+			name:    "declared name",
+			sel:     "\tt.Inc()\n",
+			fn:      "f",
+			wantErr: "already declared",
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			start, end := selection(t, extractSrc, tt.sel)
+			got, err := extractFunc(file, []byte(extractSrc), start, end, tt.fn)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("extractFunc() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(string(got), want) {
+					t.Errorf("extractFunc() =\n%s\nwant contains\n%s", got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestExtractVar(t *testing.T) {
+	file, _ := testCheckedFile(t, extractSrc)
+
+	tests := []struct {
+		name    string
+		sel     string
+		v       string
+		want    []string
+		wantErr string
+	}{
+		{
+			name: "call",
+			sel:  "len(x)",
+			v:    "l",
+			want: []string{"\tl := len(x)\n\ty := l + 1\n"},
+		},
+		{
+			name: "selector",
+			sel:  "t.n + sum",
+			v:    "total",
+			want: []string{"\ttotal := t.n + sum\n\treturn total, nil\n"},
+		},
+		{
+			name: "constant",
+			sel:  "0",
+			v:    "zero",
+			want: []string{"\tconst zero = 0\n\tsum := zero\n"},
+		},
+		{
+			name:    "loop condition",
+			sel:     "i < n",
+			v:       "cond",
+			wantErr: "each iteration",
+		},
+		{
+			name:    "assignment target",
+			sel:     "sum +=",
+			v:       "s2",
+			wantErr: "not an expression",
+		},
+		{
+			name:    "type",
+			sel:     "T",
+			v:       "typ",
+			wantErr: "not a value",
+		},
+		{
+			name:    "conditionally evaluated",
+			sel:     "len(s) > 0",
+			v:       "z",
+			wantErr: "conditionally evaluated",
+		},
+		{
+			name:    "after side effects",
+			sel:     `strings.Index("ab", "b")`,
+			v:       "i",
+			wantErr: "must be evaluated after g(1)",
+		},
+		{
+			name: "before side effects",
+			sel:  "g(1)",
+			v:    "v",
+			want: []string{"\tv := g(1)\n\tz := v + strings.Index(\"ab\", \"b\")\n"},
+		},
+		{
+			name:    "declared name",
+			sel:     "len(x)",
+			v:       "sum",
+			wantErr: "already declared",
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			start, end := selection(t, extractSrc, tt.sel)
+			got, err := extractVar(file, []byte(extractSrc), start, end, tt.v)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("extractVar() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(string(got), want) {
+					t.Errorf("extractVar() =\n%s\nwant contains\n%s", got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestPosOffset(t *testing.T) {
+	src := []byte("ab\ncde\n")
+	tests := []struct {
+		line, col int
+		want      int
+	}{
+		{line: 1, col: 1, want: 0},
+		{line: 2, col: 2, want: 4},
+		{line: 2, col: 2147483647, want: 6},
+		{line: 3, col: 1, want: 7},
+	}
+	for _, tt := range tests {
+		if got := posOffset(src, tt.line, tt.col); got != tt.want {
+			t.Errorf("posOffset(%d, %d) = %d, want %d", tt.line, tt.col, got, tt.want)
+		}
+	}
+}
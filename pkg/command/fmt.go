@@ -83,7 +83,20 @@ func (c *Command) Fmt(pctx context.Context, dir string) interface{} {
 		zap.String("imports.LocalPrefix", imports.LocalPrefix),
 	)
 
-	buf, formatErr := imports.Process("", nvimutil.ToByteSlice(data), &importsOptions)
+	var buf []byte
+	if config.GoplsEnable {
+		if name, err := c.Nvim.BufferName(b); err == nil {
+			// falls back to the builtin formatter if failed, which reports the syntax errors to the error list
+			if buf, err = c.goplsFormat(ctx, name, append(nvimutil.ToByteSlice(data), '\n')); err != nil {
+				logger.FromContext(ctx).Debug("Fmt", zap.Error(err))
+			}
+		}
+	}
+
+	var formatErr error
+	if buf == nil {
+		buf, formatErr = imports.Process("", nvimutil.ToByteSlice(data), &importsOptions)
+	}
 	if formatErr != nil {
 		bufName, err := c.Nvim.BufferName(b)
 		if err != nil {
@@ -0,0 +1,569 @@
+// Copyright 2018 The nvim-go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package command
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"go/ast"
+	"go/build"
+	"go/doc"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/neovim/go-client/nvim"
+	"github.com/pkg/errors"
+	"go.opencensus.io/trace"
+	"golang.org/x/tools/go/ast/astutil"
+
+	"github.com/zchee/nvim-go/pkg/monitoring"
+	"github.com/zchee/nvim-go/pkg/nvimutil"
+)
+
+const (
+	// filetypeGoDoc filetype of the GoDoc floating window.
+	filetypeGoDoc = "godoc"
+	// docWidth text width of the doc comment.
+	docWidth = 80
+)
+
+type cmdDocEval struct {
+	File   string `eval:"expand('%:p')"`
+	BufNr  int    `eval:"bufnr('%')"`
+	Offset int    `eval:"line2byte(line('.')) + (col('.')-2)"`
+}
+
+func (c *Command) cmdDoc(ctx context.Context, args []string, eval *cmdDocEval) {
+	errch := make(chan interface{}, 1)
+	go func() {
+		errch <- c.Doc(ctx, args, eval)
+	}()
+
+	select {
+	case <-ctx.Done():
+		return
+	case err := <-errch:
+		switch e := err.(type) {
+		case error:
+			nvimutil.ErrorWrap(c.Nvim, e)
+		case nil:
+			// nothing to do
+		}
+	}
+}
+
+// Doc shows the documentation of the identifier under the cursor in the floating window.
+// If args is not empty, shows the documentation of "pkg.Symbol" format args[0] instead.
+func (c *Command) Doc(ctx context.Context, args []string, eval *cmdDocEval) interface{} {
+	var span *trace.Span
+	ctx, span = monitoring.StartSpan(ctx, "Doc")
+	defer span.End()
+
+	buf, err := c.Nvim.BufferLines(nvim.Buffer(eval.BufNr), 0, -1, true)
+	if err != nil {
+		span.SetStatus(trace.Status{Code: trace.StatusCodeInternal, Message: err.Error()})
+		return errors.WithStack(err)
+	}
+	src := nvimutil.ToByteSlice(buf)
+
+	var sd *symbolDoc
+	if len(args) > 0 {
+		sd, err = lookupDoc(args[0], filepath.Dir(eval.File), fileImports(eval.File, src))
+	} else {
+		var cf *checkedFile
+		cf, err = typeCheck(eval.File, src)
+		if err == nil {
+			sd, err = cursorDoc(cf, eval.Offset)
+		}
+	}
+	if err != nil {
+		span.SetStatus(trace.Status{Code: trace.StatusCodeNotFound, Message: err.Error()})
+		return errors.WithStack(err)
+	}
+
+	if _, err := nvimutil.OpenFloat(c.Nvim, sd.Lines(), filetypeGoDoc, docWidth+4, 30); err != nil {
+		span.SetStatus(trace.Status{Code: trace.StatusCodeInternal, Message: err.Error()})
+		return errors.WithStack(err)
+	}
+
+	return nil
+}
+
+func (c *Command) cmdDocComplete(ctx context.Context, a *nvim.CommandCompletionArgs, file string) ([]string, error) {
+	src, err := c.bufferSource(file)
+	if err != nil {
+		return nil, err
+	}
+
+	return docCompletion(a.ArgLead, filepath.Dir(file), fileImports(file, src)), nil
+}
+
+// bufferSource returns the source of the current buffer if the buffer name is file, otherwise reads file.
+func (c *Command) bufferSource(file string) ([]byte, error) {
+	b := nvim.Buffer(c.buildContext.BufNr)
+	if name, err := c.Nvim.BufferName(b); err == nil && name == file {
+		buf, err := c.Nvim.BufferLines(b, 0, -1, true)
+		if err != nil {
+			return nil, err
+		}
+		return nvimutil.ToByteSlice(buf), nil
+	}
+
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var src bytes.Buffer
+	if _, err := src.ReadFrom(f); err != nil {
+		return nil, err
+	}
+
+	return src.Bytes(), nil
+}
+
+// symbolDoc represents a documentation of the package or package level symbol.
+type symbolDoc struct {
+	// Name package name.
+	Name string
+	// ImportPath import path of the package.
+	ImportPath string
+	// Synopsis synopsis of the package doc.
+	Synopsis string
+	// Decl declaration or signature of the symbol. Empty if package documentation.
+	Decl string
+	// Doc doc comment text of the symbol or package.
+	Doc string
+}
+
+// Lines returns the buffer lines of sd.
+func (sd *symbolDoc) Lines() [][]byte {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "package %s // import %q\n", sd.Name, sd.ImportPath)
+	if sd.Decl != "" {
+		fmt.Fprintf(&buf, "\n%s\n", sd.Decl)
+	}
+	if sd.Doc != "" {
+		buf.WriteByte('\n')
+		doc.ToText(&buf, sd.Doc, "", "    ", docWidth)
+	}
+	if sd.Decl != "" && sd.Synopsis != "" {
+		fmt.Fprintf(&buf, "\n%s\n", sd.Synopsis)
+	}
+
+	return nvimutil.ToBufferLines(bytes.TrimRight(buf.Bytes(), "\n"))
+}
+
+// cursorDoc returns the documentation of the identifier at the offset.
+func cursorDoc(cf *checkedFile, offset int) (*symbolDoc, error) {
+	pos := cf.Fset.File(cf.File.Pos()).Pos(offset)
+	path, _ := astutil.PathEnclosingInterval(cf.File, pos, pos)
+	if len(path) == 0 {
+		return nil, errors.New("no identifier here")
+	}
+	id, ok := path[0].(*ast.Ident)
+	if !ok {
+		return nil, errors.New("no identifier here")
+	}
+
+	obj := cf.Info.ObjectOf(id)
+	if obj == nil {
+		return nil, errors.New("no object for identifier")
+	}
+
+	if pkgName, ok := obj.(*types.PkgName); ok {
+		return lookupDoc(pkgName.Imported().Path(), filepath.Dir(cf.Fset.Position(cf.File.Pos()).Filename), nil)
+	}
+
+	// builtin identifiers are documented in the "builtin" pseudo package
+	if obj.Pkg() == nil {
+		return lookupDoc("builtin."+obj.Name(), "", nil)
+	}
+
+	name := obj.Name()
+	switch obj := obj.(type) {
+	case *types.Func:
+		if recv := obj.Type().(*types.Signature).Recv(); recv != nil {
+			name = recvName(recv.Type()) + "." + name
+		}
+	case *types.Var:
+		switch {
+		case obj.IsField():
+			owner := fieldOwner(obj)
+			if owner == "" {
+				return objectDoc(obj), nil
+			}
+			name = owner + "." + name
+		case obj.Parent() != obj.Pkg().Scope():
+			// local variable has no documentation
+			return objectDoc(obj), nil
+		}
+	default:
+		if obj.Parent() != nil && obj.Parent() != obj.Pkg().Scope() {
+			return objectDoc(obj), nil
+		}
+	}
+
+	dir := filepath.Dir(cf.position(obj.Pos()).Filename)
+
+	return loadDoc(dir, dirImportPath(dir, obj.Pkg().Path()), name)
+}
+
+// objectDoc returns the documentation of obj which has no doc comment such as local variables.
+func objectDoc(obj types.Object) *symbolDoc {
+	return &symbolDoc{
+		Name:       obj.Pkg().Name(),
+		ImportPath: obj.Pkg().Path(),
+		Decl:       types.ObjectString(obj, types.RelativeTo(obj.Pkg())),
+	}
+}
+
+// recvName returns the receiver type name of typ.
+func recvName(typ types.Type) string {
+	if ptr, ok := typ.(*types.Pointer); ok {
+		typ = ptr.Elem()
+	}
+	if named, ok := typ.(*types.Named); ok {
+		return named.Obj().Name()
+	}
+
+	return types.TypeString(typ, nil)
+}
+
+// fieldOwner returns the name of package level named struct type which has field.
+func fieldOwner(field *types.Var) string {
+	scope := field.Pkg().Scope()
+	for _, name := range scope.Names() {
+		tn, ok := scope.Lookup(name).(*types.TypeName)
+		if !ok {
+			continue
+		}
+		st, ok := tn.Type().Underlying().(*types.Struct)
+		if !ok {
+			continue
+		}
+		for i := 0; i < st.NumFields(); i++ {
+			if st.Field(i) == field {
+				return name
+			}
+		}
+	}
+
+	return ""
+}
+
+// splitDocArg splits the "pkg.Symbol" or "import/path.Type.Method" format arg to the package and symbol.
+func splitDocArg(arg string) (pkg, sym string) {
+	slash := strings.LastIndex(arg, "/")
+	dot := strings.Index(arg[slash+1:], ".")
+	if dot < 0 {
+		return arg, ""
+	}
+
+	return arg[:slash+1+dot], arg[slash+1+dot+1:]
+}
+
+// lookupDoc returns the documentation of the "pkg.Symbol" format arg.
+// The package name of arg is resolved by imports first, which is the map of imported package name and import path.
+func lookupDoc(arg, srcDir string, imports map[string]string) (*symbolDoc, error) {
+	pkg, sym := splitDocArg(arg)
+	if importPath, ok := imports[pkg]; ok {
+		pkg = importPath
+	}
+
+	bp, err := build.Default.Import(pkg, srcDir, build.FindOnly)
+	if err != nil {
+		if strings.Contains(pkg, "/") || srcDir == "" {
+			return nil, errors.Errorf("could not find package %q", pkg)
+		}
+		// the symbol of the current package such as "Type.Method"
+		return loadDoc(srcDir, dirImportPath(srcDir, filepath.Base(srcDir)), arg)
+	}
+
+	return loadDoc(bp.Dir, bp.ImportPath, sym)
+}
+
+// dirImportPath returns the import path of dir. Returns fallback if could not find the import path.
+func dirImportPath(dir, fallback string) string {
+	if bp, err := build.Default.ImportDir(dir, build.FindOnly); err == nil && bp.ImportPath != "." {
+		return bp.ImportPath
+	}
+
+	return fallback
+}
+
+// loadDocPackage parses the Go files in dir and returns the doc.Package.
+func loadDocPackage(dir, importPath string) (*doc.Package, *token.FileSet, error) {
+	fset := token.NewFileSet()
+	filter := func(fi os.FileInfo) bool {
+		if strings.HasSuffix(fi.Name(), "_test.go") {
+			return false
+		}
+		ok, err := build.Default.MatchFile(dir, fi.Name())
+		return ok && err == nil
+	}
+	pkgs, err := parser.ParseDir(fset, dir, filter, parser.ParseComments)
+	if err != nil && len(pkgs) == 0 {
+		return nil, nil, errors.WithStack(err)
+	}
+	if len(pkgs) == 0 {
+		return nil, nil, errors.Errorf("no Go files in %s", dir)
+	}
+
+	names := make([]string, 0, len(pkgs))
+	for name := range pkgs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	astPkg := pkgs[names[0]]
+	if p, ok := pkgs[path.Base(importPath)]; ok {
+		astPkg = p
+	}
+
+	return doc.New(astPkg, importPath, doc.AllDecls), fset, nil
+}
+
+// loadDoc returns the documentation of sym in the package of dir. If sym is empty, returns the package documentation.
+func loadDoc(dir, importPath, sym string) (*symbolDoc, error) {
+	dpkg, fset, err := loadDocPackage(dir, importPath)
+	if err != nil {
+		return nil, err
+	}
+
+	sd := &symbolDoc{
+		Name:       dpkg.Name,
+		ImportPath: importPath,
+		Synopsis:   doc.Synopsis(dpkg.Doc),
+	}
+	if sym == "" {
+		sd.Doc = dpkg.Doc
+		return sd, nil
+	}
+
+	if !findSymbolDoc(fset, dpkg, sym, sd) {
+		return nil, errors.Errorf("no symbol %s in package %s", sym, importPath)
+	}
+
+	return sd, nil
+}
+
+// findSymbolDoc finds sym in dpkg and fills the Decl and Doc of sd, and reports whether the sym is found.
+func findSymbolDoc(fset *token.FileSet, dpkg *doc.Package, sym string, sd *symbolDoc) bool {
+	typeName, member := sym, ""
+	if i := strings.Index(sym, "."); i >= 0 {
+		typeName, member = sym[:i], sym[i+1:]
+	}
+
+	values := append(append([]*doc.Value{}, dpkg.Consts...), dpkg.Vars...)
+	funcs := append([]*doc.Func{}, dpkg.Funcs...)
+	for _, t := range dpkg.Types {
+		values = append(append(values, t.Consts...), t.Vars...)
+		funcs = append(funcs, t.Funcs...)
+
+		if t.Name != typeName {
+			continue
+		}
+		if member == "" {
+			sd.Decl, sd.Doc = nodeString(fset, t.Decl), t.Doc
+			return true
+		}
+		for _, m := range t.Methods {
+			if m.Name == member {
+				sd.Decl, sd.Doc = nodeString(fset, m.Decl), m.Doc
+				return true
+			}
+		}
+		if field := findField(t.Decl, member); field != nil {
+			sd.Decl = fmt.Sprintf("%s %s", sym, nodeString(fset, field.Type))
+			switch {
+			case field.Doc != nil:
+				sd.Doc = field.Doc.Text()
+			case field.Comment != nil:
+				sd.Doc = field.Comment.Text()
+			}
+			return true
+		}
+		return false
+	}
+	if member != "" {
+		return false
+	}
+
+	for _, v := range values {
+		for _, name := range v.Names {
+			if name == sym {
+				sd.Decl, sd.Doc = nodeString(fset, v.Decl), v.Doc
+				return true
+			}
+		}
+	}
+	for _, f := range funcs {
+		if f.Name == sym {
+			sd.Decl, sd.Doc = nodeString(fset, f.Decl), f.Doc
+			return true
+		}
+	}
+
+	return false
+}
+
+// findField returns the struct field or interface method of name in the type decl.
+func findField(decl *ast.GenDecl, name string) *ast.Field {
+	for _, spec := range decl.Specs {
+		ts, ok := spec.(*ast.TypeSpec)
+		if !ok {
+			continue
+		}
+
+		var list *ast.FieldList
+		switch t := ts.Type.(type) {
+		case *ast.StructType:
+			list = t.Fields
+		case *ast.InterfaceType:
+			list = t.Methods
+		}
+		if list == nil {
+			continue
+		}
+		for _, field := range list.List {
+			for _, id := range field.Names {
+				if id.Name == name {
+					return field
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+// nodeString returns the formatted source code of node.
+func nodeString(fset *token.FileSet, node interface{}) string {
+	var buf bytes.Buffer
+	if err := format.Node(&buf, fset, node); err != nil {
+		return ""
+	}
+
+	return buf.String()
+}
+
+// fileImports returns the map of imported package name and import path of the Go source file.
+func fileImports(file string, src []byte) map[string]string {
+	imports := make(map[string]string)
+
+	// the partial imports are enough even if the source has syntax errors
+	f, _ := parser.ParseFile(token.NewFileSet(), file, src, parser.ImportsOnly)
+	if f == nil {
+		return imports
+	}
+
+	for _, spec := range f.Imports {
+		importPath, err := strconv.Unquote(spec.Path.Value)
+		if err != nil {
+			continue
+		}
+		name := path.Base(importPath)
+		if spec.Name != nil {
+			name = spec.Name.Name
+		}
+		if name == "_" || name == "." {
+			continue
+		}
+		imports[name] = importPath
+	}
+
+	return imports
+}
+
+// docCompletion returns the completion candidates of the GoDoc command argument.
+func docCompletion(argLead, srcDir string, imports map[string]string) []string {
+	var candidates []string
+
+	pkg, sym := splitDocArg(argLead)
+	if !strings.Contains(argLead[strings.LastIndex(argLead, "/")+1:], ".") {
+		for name, importPath := range imports {
+			for _, s := range []string{name, importPath} {
+				if strings.HasPrefix(s, argLead) {
+					candidates = append(candidates, s)
+				}
+			}
+		}
+		pkg, sym = "", argLead
+	}
+
+	dir, importPath := srcDir, dirImportPath(srcDir, filepath.Base(srcDir))
+	if pkg != "" {
+		if p, ok := imports[pkg]; ok {
+			importPath = p
+		} else {
+			importPath = pkg
+		}
+		bp, err := build.Default.Import(importPath, srcDir, build.FindOnly)
+		if err != nil {
+			return candidates
+		}
+		dir = bp.Dir
+	}
+
+	if dpkg, _, err := loadDocPackage(dir, importPath); err == nil {
+		for _, s := range docSymbols(dpkg, pkg != "") {
+			if strings.HasPrefix(s, sym) {
+				if pkg != "" {
+					s = pkg + "." + s
+				}
+				candidates = append(candidates, s)
+			}
+		}
+	}
+	sort.Strings(candidates)
+
+	return candidates
+}
+
+// docSymbols returns the symbol names of dpkg such as "Name" or "Type.Method".
+// If exported is true, returns only exported symbols.
+func docSymbols(dpkg *doc.Package, exported bool) []string {
+	var syms []string
+	add := func(names ...string) {
+		for _, name := range names {
+			if !exported || ast.IsExported(name[strings.LastIndex(name, ".")+1:]) {
+				syms = append(syms, name)
+			}
+		}
+	}
+
+	values := append(append([]*doc.Value{}, dpkg.Consts...), dpkg.Vars...)
+	for _, f := range dpkg.Funcs {
+		add(f.Name)
+	}
+	for _, t := range dpkg.Types {
+		if exported && !ast.IsExported(t.Name) {
+			continue
+		}
+		add(t.Name)
+		values = append(append(values, t.Consts...), t.Vars...)
+		for _, f := range t.Funcs {
+			add(f.Name)
+		}
+		for _, m := range t.Methods {
+			add(t.Name + "." + m.Name)
+		}
+	}
+	for _, v := range values {
+		add(v.Names...)
+	}
+
+	return syms
+}
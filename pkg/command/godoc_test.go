@@ -0,0 +1,106 @@
+// Copyright 2018 The nvim-go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package command
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSplitDocArg(t *testing.T) {
+	tests := []struct {
+		arg     string
+		wantPkg string
+		wantSym string
+	}{
+		{arg: "fmt", wantPkg: "fmt"},
+		{arg: "strings.ToUpper", wantPkg: "strings", wantSym: "ToUpper"},
+		{arg: "net/http.Client.Do", wantPkg: "net/http", wantSym: "Client.Do"},
+		{arg: "github.com/pkg/errors", wantPkg: "github.com/pkg/errors"},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.arg, func(t *testing.T) {
+			t.Parallel()
+
+			pkg, sym := splitDocArg(tt.arg)
+			if pkg != tt.wantPkg || sym != tt.wantSym {
+				t.Errorf("splitDocArg(%q) = (%q, %q), want (%q, %q)", tt.arg, pkg, sym, tt.wantPkg, tt.wantSym)
+			}
+		})
+	}
+}
+
+const godocSrc = `package foo
+
+import str "strings"
+
+// Foo is a foo.
+type Foo struct {
+	// Name is the name of foo.
+	Name string
+}
+
+func upper(f Foo) string {
+	s := str.ToUpper(f.Name)
+	return s + string(rune(len(s)))
+}
+`
+
+func TestDoc(t *testing.T) {
+	file, cf := testCheckedFile(t, godocSrc)
+	dir := filepath.Dir(file)
+
+	tests := []struct {
+		name     string
+		arg      string
+		offset   int
+		wantDecl string
+		wantDoc  string
+		wantErr  bool
+	}{
+		{name: "lookup func", arg: "str.ToUpper", wantDecl: "func ToUpper(s string) string", wantDoc: "ToUpper returns s"},
+		{name: "lookup method", arg: "bytes.Buffer.Len", wantDecl: "func (b *Buffer) Len() int", wantDoc: "Len returns the number of bytes"},
+		{name: "lookup current package field", arg: "Foo.Name", wantDecl: "Foo.Name string", wantDoc: "Name is the name of foo."},
+		{name: "lookup unknown", arg: "strings.NoSuchSymbol", wantErr: true},
+		{name: "cursor func", offset: strings.Index(godocSrc, "ToUpper("), wantDecl: "func ToUpper(s string) string", wantDoc: "ToUpper returns s"},
+		{name: "cursor field", offset: strings.Index(godocSrc, "Name)"), wantDecl: "Foo.Name string", wantDoc: "Name is the name of foo."},
+		{name: "cursor local", offset: strings.Index(godocSrc, "s +"), wantDecl: "var s string"},
+		{name: "cursor builtin", offset: strings.Index(godocSrc, "len("), wantDecl: "func len(v Type) int", wantDoc: "The len built-in function"},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			var sd *symbolDoc
+			var err error
+			if tt.arg != "" {
+				sd, err = lookupDoc(tt.arg, dir, fileImports(file, []byte(godocSrc)))
+			} else {
+				sd, err = cursorDoc(cf, tt.offset)
+			}
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if sd.Decl != tt.wantDecl {
+				t.Errorf("Decl = %q, want %q", sd.Decl, tt.wantDecl)
+			}
+			if !strings.HasPrefix(sd.Doc, tt.wantDoc) {
+				t.Errorf("Doc = %q, want prefix %q", sd.Doc, tt.wantDoc)
+			}
+		})
+	}
+}
+
+func TestDocCompletion(t *testing.T) {
+	got := docCompletion("strings.ToU", "", nil)
+	want := []string{"strings.ToUpper", "strings.ToUpperSpecial"}
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("docCompletion() = %v, want %v", got, want)
+	}
+}
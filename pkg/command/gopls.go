@@ -0,0 +1,393 @@
+// Copyright 2018 The nvim-go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package command
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"go/token"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/neovim/go-client/nvim"
+	"github.com/pkg/errors"
+	"go.opencensus.io/trace"
+	"go.uber.org/zap"
+
+	"github.com/zchee/nvim-go/pkg/config"
+	"github.com/zchee/nvim-go/pkg/fs"
+	"github.com/zchee/nvim-go/pkg/logger"
+	"github.com/zchee/nvim-go/pkg/lsp"
+	"github.com/zchee/nvim-go/pkg/monitoring"
+	"github.com/zchee/nvim-go/pkg/nvimutil"
+)
+
+// goplsShutdownTimeout timeout of the gopls shutdown request.
+const goplsShutdownTimeout = 2 * time.Second
+
+// goplsGuruModes GoGuru modes which are served by gopls if the go#gopls#enable is enabled.
+var goplsGuruModes = map[string]bool{
+	"definition": true,
+	"implements": true,
+	"referrers":  true,
+}
+
+// goplsBridge represents the gopls language server process which is the backend of the commands.
+type goplsBridge struct {
+	mu     sync.Mutex
+	client *lsp.Client
+	root   string
+	// docs opened documents, keyed by filename.
+	docs map[string]*goplsDocument
+}
+
+// goplsDocument represents the synchronized text document.
+type goplsDocument struct {
+	version int
+	text    []byte
+}
+
+// goplsClient returns the running gopls client, and starts gopls in the workspace root of dir if not running.
+func (c *Command) goplsClient(ctx context.Context, dir string) (*lsp.Client, error) {
+	gb := c.gopls
+	gb.mu.Lock()
+	defer gb.mu.Unlock()
+
+	if gb.client != nil {
+		select {
+		case <-gb.client.Done():
+			// gopls was exited, restart it
+			gb.client = nil
+		default:
+			return gb.client, nil
+		}
+	}
+
+	log := logger.FromContext(ctx).Named("gopls")
+	root := workspaceRoot(dir)
+	client, err := lsp.Start(ctx, root, config.GoplsPath, config.GoplsArgs, func(ctx context.Context, method string, params json.RawMessage) (interface{}, error) {
+		switch method {
+		case "window/showMessage", "window/logMessage":
+			log.Debug(method, zap.ByteString("params", params))
+			return nil, nil
+		}
+		return lsp.DefaultHandler(ctx, method, params)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if _, err := client.Initialize(ctx, root); err != nil {
+		client.Close()
+		return nil, errors.Wrap(err, "could not initialize gopls")
+	}
+	log.Info("started", zap.String("root", root))
+
+	gb.client, gb.root = client, root
+	gb.docs = make(map[string]*goplsDocument)
+
+	return client, nil
+}
+
+// workspaceRoot returns the workspace root directory of dir, which is the module root, the VCS root or dir itself.
+func workspaceRoot(dir string) string {
+	if root := moduleRoot(dir); root != "" {
+		return root
+	}
+	if root := fs.FindVCSRoot(dir); root != "" {
+		if root, err := filepath.Abs(root); err == nil {
+			return root
+		}
+	}
+
+	return dir
+}
+
+// moduleRoot returns the nearest parent directory of dir which has the go.mod file.
+// Returns empty if dir is not in a module.
+func moduleRoot(dir string) string {
+	for d := dir; ; d = filepath.Dir(d) {
+		if fs.IsExist(filepath.Join(d, "go.mod")) {
+			return d
+		}
+		if d == filepath.Dir(d) {
+			return ""
+		}
+	}
+}
+
+// update synchronizes the text of the file document to gopls. It sends nothing if text is not changed.
+func (gb *goplsBridge) update(ctx context.Context, client *lsp.Client, file string, text []byte) error {
+	gb.mu.Lock()
+	defer gb.mu.Unlock()
+
+	doc, ok := gb.docs[file]
+	if !ok {
+		gb.docs[file] = &goplsDocument{version: 1, text: text}
+		return client.DidOpen(ctx, file, 1, text)
+	}
+	if bytes.Equal(doc.text, text) {
+		return nil
+	}
+	doc.version++
+	doc.text = text
+
+	return client.DidChange(ctx, file, doc.version, text)
+}
+
+// text returns the synchronized text of the file, or reads the file if it is not opened.
+func (gb *goplsBridge) text(file string) ([]byte, error) {
+	gb.mu.Lock()
+	doc, ok := gb.docs[file]
+	gb.mu.Unlock()
+	if ok {
+		return doc.text, nil
+	}
+
+	return ioutil.ReadFile(file)
+}
+
+// goplsSync synchronizes the bufnr buffer text to gopls, and returns the client and the buffer text.
+func (c *Command) goplsSync(ctx context.Context, bufnr int, file string) (*lsp.Client, []byte, error) {
+	lines, err := c.Nvim.BufferLines(nvim.Buffer(bufnr), 0, -1, true)
+	if err != nil {
+		return nil, nil, errors.WithStack(err)
+	}
+	text := append(nvimutil.ToByteSlice(lines), '\n')
+
+	client, err := c.goplsClient(ctx, filepath.Dir(file))
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := c.gopls.update(ctx, client, file, text); err != nil {
+		return nil, nil, errors.WithStack(err)
+	}
+
+	return client, text, nil
+}
+
+// GoplsSync synchronizes the bufnr buffer to gopls on BufEnter, TextChanged and TextChangedI autocmd.
+// Starts gopls if it is not running.
+func (c *Command) GoplsSync(ctx context.Context, bufnr int, file string) error {
+	if !config.GoplsEnable || file == "" {
+		return nil
+	}
+
+	_, _, err := c.goplsSync(ctx, bufnr, file)
+	return err
+}
+
+// GoplsDidSave notifies gopls that the file is saved on BufWritePost autocmd.
+func (c *Command) GoplsDidSave(ctx context.Context, file string) error {
+	gb := c.gopls
+	gb.mu.Lock()
+	client, opened := gb.client, gb.docs[file] != nil
+	gb.mu.Unlock()
+	if client == nil || !opened {
+		return nil
+	}
+
+	return client.DidSave(ctx, file)
+}
+
+// GoplsDidClose notifies gopls that the file is closed on BufDelete autocmd.
+func (c *Command) GoplsDidClose(ctx context.Context, file string) error {
+	gb := c.gopls
+	gb.mu.Lock()
+	client, opened := gb.client, gb.docs[file] != nil
+	delete(gb.docs, file)
+	gb.mu.Unlock()
+	if client == nil || !opened {
+		return nil
+	}
+
+	return client.DidClose(ctx, file)
+}
+
+// GoplsShutdown shuts down gopls if it is running.
+func (c *Command) GoplsShutdown(ctx context.Context) error {
+	gb := c.gopls
+	gb.mu.Lock()
+	client := gb.client
+	gb.client, gb.docs = nil, nil
+	gb.mu.Unlock()
+	if client == nil {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, goplsShutdownTimeout)
+	defer cancel()
+
+	return client.Shutdown(ctx)
+}
+
+// goplsGuru serves the GoGuru definition, referrers and implements queries by gopls.
+func (c *Command) goplsGuru(ctx context.Context, args []string, eval *funcGuruEval) interface{} {
+	var span *trace.Span
+	ctx, span = monitoring.StartSpan(ctx, "goplsGuru")
+	defer span.End()
+
+	mode := args[0]
+	client, src, err := c.goplsSync(ctx, c.buildContext.BufNr, eval.File)
+	if err != nil {
+		span.SetStatus(trace.Status{Code: trace.StatusCodeInternal, Message: err.Error()})
+		return errors.WithStack(err)
+	}
+	pos := lsp.OffsetPosition(src, eval.Offset)
+
+	nvimutil.EchoProgress(c.Nvim, "Guru", fmt.Sprintf("analysing %s", mode))
+
+	var locs []lsp.Location
+	switch mode {
+	case "definition":
+		locs, err = client.Definition(ctx, eval.File, pos)
+	case "referrers":
+		locs, err = client.References(ctx, eval.File, pos, true)
+	case "implements":
+		locs, err = client.Implementation(ctx, eval.File, pos)
+	}
+	if err != nil {
+		span.SetStatus(trace.Status{Code: trace.StatusCodeInternal, Message: err.Error()})
+		return errors.WithStack(err)
+	}
+	if len(locs) == 0 {
+		err := errors.Errorf("%s not found", mode)
+		span.SetStatus(trace.Status{Code: trace.StatusCodeInternal, Message: err.Error()})
+		return err
+	}
+
+	if mode == "definition" {
+		p, _ := c.goplsPosition(locs[0], nil)
+		var bufCmd string
+		if len(args) > 1 {
+			bufCmd = args[1]
+		}
+		return c.jumpDefinition(ctx, p.String(), bufCmd, eval)
+	}
+
+	loclist := c.goplsLoclist(locs, eval.Cwd)
+	if err := c.openGuruLoclist(nvim.Window(c.buildContext.WinID), mode, loclist); err != nil {
+		span.SetStatus(trace.Status{Code: trace.StatusCodeInternal, Message: err.Error()})
+		return errors.WithStack(err)
+	}
+
+	return nil
+}
+
+// goplsPosition returns the token.Position of the start of loc, and the source line of the position.
+// texts caches the file texts if not nil.
+func (c *Command) goplsPosition(loc lsp.Location, texts map[string][]byte) (token.Position, []byte) {
+	fname := loc.URI.Filename()
+	pos := token.Position{Filename: fname, Line: loc.Range.Start.Line + 1, Column: loc.Range.Start.Character + 1}
+
+	text, ok := texts[fname]
+	if !ok {
+		text, _ = c.gopls.text(fname)
+		if texts != nil {
+			texts[fname] = text
+		}
+	}
+	if text == nil {
+		return pos, nil
+	}
+
+	offset, err := loc.Range.Start.Offset(text)
+	if err != nil {
+		return pos, nil
+	}
+	start := bytes.LastIndexByte(text[:offset], '\n') + 1
+	end := bytes.IndexByte(text[offset:], '\n')
+	if end < 0 {
+		end = len(text) - offset
+	}
+	pos.Column = offset - start + 1
+
+	return pos, text[start : offset+end]
+}
+
+// goplsLoclist converts locs to the locationlist items which text is the trimmed source line.
+func (c *Command) goplsLoclist(locs []lsp.Location, cwd string) []*nvim.QuickfixError {
+	texts := make(map[string][]byte)
+	loclist := make([]*nvim.QuickfixError, 0, len(locs))
+	for _, loc := range locs {
+		pos, line := c.goplsPosition(loc, texts)
+		loclist = append(loclist, &nvim.QuickfixError{
+			FileName: fs.Rel(cwd, pos.Filename),
+			LNum:     pos.Line,
+			Col:      pos.Column,
+			Text:     strings.TrimSpace(string(line)),
+		})
+	}
+
+	return loclist
+}
+
+// goplsRename renames the identifier at the offset of the bufnr buffer to renameTo by gopls, and returns the renamed
+// contents of the changed files. The edits are applied to the overlay contents if exists, otherwise the files on disk.
+func (c *Command) goplsRename(ctx context.Context, bufnr int, file string, offset int, renameTo string, overlay map[string][]byte) (map[string][]byte, error) {
+	client, src, err := c.goplsSync(ctx, bufnr, file)
+	if err != nil {
+		return nil, err
+	}
+
+	edit, err := client.Rename(ctx, file, lsp.OffsetPosition(src, offset), renameTo)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	files := make(map[string][]byte)
+	for fname, edits := range edit.Edits() {
+		text, ok := overlay[fname]
+		if !ok {
+			if text, err = ioutil.ReadFile(fname); err != nil {
+				return nil, errors.WithStack(err)
+			}
+		}
+		if files[fname], err = lsp.ApplyEdits(text, edits); err != nil {
+			return nil, errors.Wrapf(err, "could not apply the edits to %s", fname)
+		}
+	}
+
+	return files, nil
+}
+
+// goplsFormat returns the formatted src of the file by gopls.
+// The imports are organized also if the go#fmt#mode is "goimports".
+func (c *Command) goplsFormat(ctx context.Context, file string, src []byte) ([]byte, error) {
+	client, err := c.goplsClient(ctx, filepath.Dir(file))
+	if err != nil {
+		return nil, err
+	}
+	if err := c.gopls.update(ctx, client, file, src); err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	if config.FmtMode == "goimports" {
+		end := lsp.OffsetPosition(src, len(src))
+		actions, err := client.CodeActions(ctx, file, lsp.Range{End: end}, lsp.CodeActionOrganizeImports)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		for _, action := range actions {
+			if src, err = lsp.ApplyEdits(src, action.Edit.Edits()[file]); err != nil {
+				return nil, err
+			}
+		}
+		if err := c.gopls.update(ctx, client, file, src); err != nil {
+			return nil, errors.WithStack(err)
+		}
+	}
+
+	edits, err := client.Formatting(ctx, file)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	return lsp.ApplyEdits(src, edits)
+}
@@ -0,0 +1,65 @@
+// Copyright 2018 The nvim-go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package command
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/neovim/go-client/nvim"
+
+	"github.com/zchee/nvim-go/pkg/lsp"
+)
+
+func TestWorkspaceRoot(t *testing.T) {
+	dir, err := ioutil.TempDir("", "nvim-go-gopls")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	sub := filepath.Join(dir, "a", "b")
+	if err := os.MkdirAll(sub, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "go.mod"), []byte("module example.com/foo\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := workspaceRoot(sub); got != dir {
+		t.Errorf("workspaceRoot(%q) = %q, want %q", sub, got, dir)
+	}
+}
+
+func TestGoplsLoclist(t *testing.T) {
+	const (
+		cwd  = "/go/src/foo"
+		file = "/go/src/foo/foo.go"
+	)
+	src := []byte("package foo\n\nvar s = \"日本語\" + x\n\tfoo(x)\n")
+
+	c := &Command{gopls: &goplsBridge{
+		docs: map[string]*goplsDocument{file: {version: 1, text: src}},
+	}}
+	locs := []lsp.Location{
+		// the "x" after the multibyte string
+		{URI: lsp.FileURI(file), Range: lsp.Range{Start: lsp.Position{Line: 2, Character: 16}}},
+		{URI: lsp.FileURI(file), Range: lsp.Range{Start: lsp.Position{Line: 3, Character: 5}}},
+	}
+
+	want := []*nvim.QuickfixError{
+		{FileName: "foo.go", LNum: 3, Col: 23, Text: `var s = "日本語" + x`},
+		{FileName: "foo.go", LNum: 4, Col: 6, Text: "foo(x)"},
+	}
+	if got := c.goplsLoclist(locs, cwd); !reflect.DeepEqual(got, want) {
+		for i := range got {
+			t.Errorf("goplsLoclist()[%d] = %+v", i, got[i])
+		}
+		t.Errorf("want %+v, %+v", want[0], want[1])
+	}
+}
@@ -9,7 +9,6 @@
 package command
 
 import (
-	"bytes"
 	"context"
 	"fmt"
 	"go/build"
@@ -36,10 +35,9 @@ import (
 )
 
 type funcGuruEval struct {
-	Cwd      string `msgpack:",array"`
-	File     string
-	Modified int
-	Offset   int
+	Cwd    string `msgpack:",array"`
+	File   string
+	Offset int
 }
 
 func (c *Command) funcGuru(ctx context.Context, args []string, eval *funcGuruEval) {
@@ -48,6 +46,13 @@ func (c *Command) funcGuru(ctx context.Context, args []string, eval *funcGuruEva
 		errch <- c.Guru(ctx, args, eval)
 	}()
 
+	// wait the result in the background so that the notification handler returns immediately,
+	// the following GoGuru or GoCancel is not blocked by the long running query.
+	go c.guruResult(ctx, errch)
+}
+
+// guruResult waits the result of Guru from errch and shows it.
+func (c *Command) guruResult(ctx context.Context, errch chan interface{}) {
 	select {
 	case <-ctx.Done():
 		return
@@ -91,24 +96,22 @@ func (c *Command) Guru(pctx context.Context, args []string, eval *funcGuruEval)
 		return nil
 	}()
 
-	b := nvim.Buffer(c.buildContext.BufNr)
+	if config.GoplsEnable && goplsGuruModes[args[0]] {
+		return c.goplsGuru(ctx, args, eval)
+	}
+
 	w := nvim.Window(c.buildContext.WinID)
-	batch := c.Nvim.NewBatch()
 
 	guruContext := &build.Default
 
 	// https://github.com/golang/tools/blob/master/cmd/guru/main.go
-	if eval.Modified != 0 {
-		overlay := make(map[string][]byte)
-		var buf [][]byte
-
-		batch.BufferLines(b, 0, -1, true, &buf)
-		if err := batch.Execute(); err != nil {
-			span.SetStatus(trace.Status{Code: trace.StatusCodeInternal, Message: err.Error()})
-			return errors.WithStack(err)
-		}
-
-		overlay[eval.File] = bytes.Join(buf, []byte{'\n'})
+	// overlay all modified Go buffers, not only the current buffer, so the query sees the unsaved changes of the other files
+	overlay, err := c.modifiedOverlay()
+	if err != nil {
+		span.SetStatus(trace.Status{Code: trace.StatusCodeInternal, Message: err.Error()})
+		return errors.WithStack(err)
+	}
+	if len(overlay) > 0 {
 		guruContext = buildutil.OverlayContext(guruContext, overlay)
 	}
 
@@ -128,65 +131,22 @@ func (c *Command) Guru(pctx context.Context, args []string, eval *funcGuruEval)
 			span.SetStatus(trace.Status{Code: trace.StatusCodeInternal, Message: err.Error()})
 			return errors.WithStack(err)
 		}
-		fname, line, col := nvimutil.SplitPos(obj.ObjPos, eval.Cwd)
-
-		batch.Command("normal! m'")
-		// TODO(zchee): should change nvimutil.SplitPos behavior
-		filename := strings.Split(obj.ObjPos, ":")
 
-		bufCmd := args[1]
-		if bufCmd == "" {
-			bufCmd = "edit" // use same buffer
+		var bufCmd string
+		if len(args) > 1 {
+			bufCmd = args[1]
 		}
-		switch bufCmd {
-		case "edit":
-			if filename[0] == eval.File {
-				// noting to do
-			}
-		case "split", "vsplit", "tabnew":
-			cmd := fmt.Sprintf("keepjumps %s %s", bufCmd, fs.Rel(eval.Cwd, fname))
-			log.Debug("Guru", zap.String("cmd", cmd))
-			batch.Command(cmd)
-		default:
-			nvimutil.Echoerr(c.Nvim, "unknown buffer command: %s\n", bufCmd)
-			return errors.WithStack(err)
-		}
-
-		w = nvim.Window(0)
-		batch.CurrentWindow(&w)
-		batch.SetWindowCursor(w, [2]int{line, col - 1})
-		if err := batch.Execute(); err != nil {
-			span.SetStatus(trace.Status{Code: trace.StatusCodeInternal, Message: err.Error()})
-			return errors.WithStack(err)
-		}
-
-		return c.Nvim.Command(`lclose | normal! zz`)
+		return c.jumpDefinition(ctx, obj.ObjPos, bufCmd, eval)
 	}
 
-	var scopes []string
-	switch c.buildContext.Build.Tool {
-	case "go":
-		root := fs.FindVCSRoot(eval.File)
-		root, _ = filepath.Abs(root)
-		scopes = []string{fs.ToWildcard(fs.TrimGoPath(root))}
-		if vendorDir := filepath.Join(root, "vendor"); fs.IsDirExist(vendorDir) {
-			scopes = append(scopes, "-"+fs.TrimGoPath(vendorDir))
-		}
-		os.Unsetenv("GO111MODULE")
-	case "gb":
-		root := c.buildContext.Build.ProjectRoot
-		var err error
-		scopes, err = fs.GbPackages(root)
-		if err != nil {
-			span.SetStatus(trace.Status{Code: trace.StatusCodeInternal, Message: err.Error()})
-			return errors.Wrap(err, "could not get gb packages")
-		}
-		for i, pkg := range scopes {
-			scopes[i] = fs.ToWildcard(pkg)
-		}
-		if vendorDir := filepath.Join(root, "vendor"); fs.IsDirExist(vendorDir) {
-			scopes = append(scopes, "-"+fs.ToWildcard(vendorDir))
-		}
+	// supersede the running query
+	ctx, done := c.guruQueries.start(ctx)
+	defer done()
+
+	scopes, err := c.guruScopes(eval.File)
+	if err != nil {
+		span.SetStatus(trace.Status{Code: trace.StatusCodeInternal, Message: err.Error()})
+		return errors.WithStack(err)
 	}
 	query.Scope = append(query.Scope, scopes...)
 	log.Info("",
@@ -196,7 +156,6 @@ func (c *Command) Guru(pctx context.Context, args []string, eval *funcGuruEval)
 		zap.Strings("query.Scope", query.Scope))
 
 	var outputMu sync.Mutex
-	var err error
 	output := func(fset *token.FileSet, qr guru.QueryResult) {
 		var err error
 		outputMu.Lock()
@@ -213,9 +172,17 @@ func (c *Command) Guru(pctx context.Context, args []string, eval *funcGuruEval)
 		return errors.WithStack(err)
 	}
 	query.Output = output
+	query.Progress = func(phase string) {
+		nvimutil.EchoProgress(c.Nvim, "Guru", fmt.Sprintf("%s: %s", mode, phase))
+	}
 
 	nvimutil.EchoProgress(c.Nvim, "Guru", fmt.Sprintf("analysing %s", mode))
-	if err := guru.Run(mode, &query); err != nil {
+	if err := guru.Run(ctx, mode, &query); err != nil {
+		if err == context.Canceled {
+			// superseded by the next query or cancelled by GoCancel
+			span.SetStatus(trace.Status{Code: trace.StatusCodeCancelled, Message: err.Error()})
+			return nil
+		}
 		span.SetStatus(trace.Status{Code: trace.StatusCodeInternal, Message: err.Error()})
 		return errors.WithStack(err)
 	}
@@ -225,14 +192,200 @@ func (c *Command) Guru(pctx context.Context, args []string, eval *funcGuruEval)
 		return err
 	}
 
-	defer nvimutil.ClearMsg(c.Nvim)
-	if err := nvimutil.SetLoclist(c.Nvim, loclist); err != nil {
+	if err := c.openGuruLoclist(w, mode, loclist); err != nil {
 		span.SetStatus(trace.Status{Code: trace.StatusCodeInternal, Message: err.Error()})
 		return errors.WithStack(err)
 	}
 
+	return nil
+}
+
+// guruQueries tracks the running guru query, which is cancelled by the next query or GoCancel.
+type guruQueries struct {
+	mu     sync.Mutex
+	cancel context.CancelFunc
+	seq    int
+}
+
+// start cancels the running query, and returns the context of the new query.
+// done must be called when the new query is finished.
+func (g *guruQueries) start(ctx context.Context) (qctx context.Context, done func()) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.cancel != nil {
+		g.cancel()
+	}
+	qctx, cancel := context.WithCancel(ctx)
+	g.seq++
+	seq := g.seq
+	g.cancel = cancel
+
+	return qctx, func() {
+		cancel()
+		g.mu.Lock()
+		if g.seq == seq {
+			g.cancel = nil
+		}
+		g.mu.Unlock()
+	}
+}
+
+// stop cancels the running query, and reports whether there was the running query.
+func (g *guruQueries) stop() bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.cancel == nil {
+		return false
+	}
+	g.cancel()
+	g.cancel = nil
+
+	return true
+}
+
+func (c *Command) cmdCancel(ctx context.Context) {
+	if !c.guruQueries.stop() {
+		nvimutil.Echomsg(c.Nvim, "GoCancel: no running guru query")
+		return
+	}
+	nvimutil.Echomsg(c.Nvim, "GoCancel: guru query cancelled")
+}
+
+// guruScopes returns the guru analysis scope of the project which contains file.
+func (c *Command) guruScopes(file string) ([]string, error) {
+	var scopes []string
+	switch c.buildContext.Build.Tool {
+	case "go":
+		root := fs.FindVCSRoot(file)
+		root, _ = filepath.Abs(root)
+		scopes = []string{fs.ToWildcard(fs.TrimGoPath(root))}
+		if vendorDir := filepath.Join(root, "vendor"); fs.IsDirExist(vendorDir) {
+			scopes = append(scopes, "-"+fs.TrimGoPath(vendorDir))
+		}
+		os.Unsetenv("GO111MODULE")
+	case "gb":
+		root := c.buildContext.Build.ProjectRoot
+		var err error
+		scopes, err = fs.GbPackages(root)
+		if err != nil {
+			return nil, errors.Wrap(err, "could not get gb packages")
+		}
+		for i, pkg := range scopes {
+			scopes[i] = fs.ToWildcard(pkg)
+		}
+		if vendorDir := filepath.Join(root, "vendor"); fs.IsDirExist(vendorDir) {
+			scopes = append(scopes, "-"+fs.ToWildcard(vendorDir))
+		}
+	}
+
+	return scopes, nil
+}
+
+// guruQuery runs the mode guru query at pos with the overlay build context, and returns the query result.
+// The analysis scope is the project which contains file. The query supersedes the running query.
+func (c *Command) guruQuery(ctx context.Context, mode, file, pos string, overlay map[string][]byte) (interface{}, error) {
+	scopes, err := c.guruScopes(file)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, done := c.guruQueries.start(ctx)
+	defer done()
+
+	var res interface{}
+	query := guru.Query{
+		Pos:        pos,
+		Build:      buildutil.OverlayContext(&build.Default, overlay),
+		Scope:      scopes,
+		Reflection: config.GuruReflection,
+		Output: func(fset *token.FileSet, qr guru.QueryResult) {
+			res = qr.Result(fset)
+		},
+		Progress: func(phase string) {
+			nvimutil.EchoProgress(c.Nvim, "Guru", fmt.Sprintf("%s: %s", mode, phase))
+		},
+	}
+	if err := guru.Run(ctx, mode, &query); err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	return res, nil
+}
+
+// jumpDefinition jumps to the objPos definition position which format is "filename:line:col".
+// bufCmd is the command to open the definition file, "edit" is used if it is empty.
+//
+// The position before the jump is pushed to the definition stack of the window.
+// The definition in the GOROOT or the module cache is opened as the read-only buffer.
+func (c *Command) jumpDefinition(ctx context.Context, objPos, bufCmd string, eval *funcGuruEval) error {
+	log := logger.FromContext(ctx).Named("Guru")
+
+	fname, line, col := nvimutil.SplitPos(objPos, eval.Cwd)
+	// TODO(zchee): should change nvimutil.SplitPos behavior
+	filename := strings.Split(objPos, ":")
+
+	var (
+		from   nvim.Window
+		cursor [2]int
+		word   string
+	)
+	batch := c.Nvim.NewBatch()
+	batch.CurrentWindow(&from)
+	batch.WindowCursor(0, &cursor)
+	batch.Call("expand", &word, "<cword>")
+	if err := batch.Execute(); err != nil {
+		return errors.WithStack(err)
+	}
+
+	batch.Command("normal! m'")
+
+	if bufCmd == "" {
+		bufCmd = "edit" // use same buffer
+	}
+	switch bufCmd {
+	case "edit":
+		if filename[0] != eval.File {
+			batch.Command(fmt.Sprintf("keepjumps edit %s", fs.Rel(eval.Cwd, fname)))
+		}
+	case "split", "vsplit", "tabnew":
+		cmd := fmt.Sprintf("keepjumps %s %s", bufCmd, fs.Rel(eval.Cwd, fname))
+		log.Debug("Guru", zap.String("cmd", cmd))
+		batch.Command(cmd)
+	default:
+		return nvimutil.Echoerr(c.Nvim, "unknown buffer command: %s\n", bufCmd)
+	}
+
+	w := nvim.Window(0)
+	batch.CurrentWindow(&w)
+	if err := batch.Execute(); err != nil {
+		return errors.WithStack(err)
+	}
+	c.pushDefStack(from, w, word, token.Position{Filename: eval.File, Line: cursor[0], Column: cursor[1] + 1})
+
+	if isReadOnlySource(&build.Default, filename[0]) {
+		batch.Command("setlocal readonly nomodifiable")
+	}
+	batch.SetWindowCursor(w, [2]int{line, col - 1})
+	if err := batch.Execute(); err != nil {
+		return errors.WithStack(err)
+	}
+
+	return c.Nvim.Command(`lclose | normal! zz`)
+}
+
+// openGuruLoclist sets the loclist of the mode query results to the locationlist of w and opens it.
+// Jumps to the first result instead if the go#guru#jump_first is enabled.
+func (c *Command) openGuruLoclist(w nvim.Window, mode string, loclist []*nvim.QuickfixError) error {
+	defer nvimutil.ClearMsg(c.Nvim)
+	if err := nvimutil.SetLoclist(c.Nvim, loclist); err != nil {
+		return err
+	}
+
 	// jumpfirst or definition mode
 	if config.GuruJumpFirst {
+		batch := c.Nvim.NewBatch()
 		batch.Command(`silent ll 1`)
 		batch.Command(`normal! zz`)
 		return batch.Execute()
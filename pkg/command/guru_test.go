@@ -0,0 +1,60 @@
+// Copyright 2018 The nvim-go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package command
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestGuruQueries(t *testing.T) {
+	g := new(guruQueries)
+
+	first, done1 := g.start(context.Background())
+	second, done2 := g.start(context.Background())
+	if first.Err() != context.Canceled {
+		t.Errorf("first query error = %v, want superseded by the second query", first.Err())
+	}
+	if second.Err() != nil {
+		t.Errorf("second query error = %v, want running", second.Err())
+	}
+
+	// finishing the superseded query must not forget the running query
+	done1()
+	if !g.stop() {
+		t.Fatal("stop() = false, want true for the running second query")
+	}
+	if second.Err() != context.Canceled {
+		t.Errorf("second query error = %v, want cancelled", second.Err())
+	}
+	done2()
+	if g.stop() {
+		t.Error("stop() = true, want false for no running query")
+	}
+}
+
+func TestDescribeCancel(t *testing.T) {
+	dir, err := ioutil.TempDir("", "nvim-go-guru")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "foo.go")
+	if err := ioutil.WriteFile(file, []byte(hoverSrc), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	offset := strings.Index(hoverSrc, "Counter\n\tc.Inc") + 1
+	if d, err := describe(ctx, file, offset, nil); err != context.Canceled {
+		t.Errorf("describe() = (%+v, %v), want %v", d, err, context.Canceled)
+	}
+}
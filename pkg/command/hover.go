@@ -0,0 +1,383 @@
+// Copyright 2018 The nvim-go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package command
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"go/ast"
+	"go/build"
+	"go/parser"
+	"go/token"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/neovim/go-client/nvim"
+	"github.com/pkg/errors"
+	"go.opencensus.io/trace"
+	"golang.org/x/tools/cmd/guru/serial"
+	"golang.org/x/tools/go/ast/astutil"
+	"golang.org/x/tools/go/buildutil"
+
+	"github.com/zchee/nvim-go/pkg/config"
+	"github.com/zchee/nvim-go/pkg/fs"
+	"github.com/zchee/nvim-go/pkg/internal/guru"
+	"github.com/zchee/nvim-go/pkg/monitoring"
+	"github.com/zchee/nvim-go/pkg/nvimutil"
+)
+
+const (
+	// hoverMaxMethods maximum number of the methods displayed in the hover window.
+	hoverMaxMethods = 10
+	// hoverMaxDocLines maximum number of the doc snippet lines displayed in the hover window.
+	hoverMaxDocLines = 5
+)
+
+// hoverWindow represents the hover floating window and the definition position of the described identifier.
+type hoverWindow struct {
+	mu        sync.Mutex
+	window    nvim.Window
+	srcWindow nvim.Window
+	def       token.Position
+}
+
+// CmdHoverEval represents the cursor position of the current buffer for the GoHover command.
+type CmdHoverEval struct {
+	Cwd      string `eval:"getcwd()"`
+	File     string `eval:"expand('%:p')"`
+	BufNr    int    `eval:"bufnr('%')"`
+	WinID    int    `eval:"win_getid()"`
+	Modified int    `eval:"&modified"`
+	Offset   int    `eval:"line2byte(line('.')) + (col('.')-2)"`
+}
+
+func (c *Command) cmdHover(ctx context.Context, eval *CmdHoverEval) {
+	errch := make(chan interface{}, 1)
+	go func() {
+		errch <- c.Hover(ctx, eval)
+	}()
+
+	select {
+	case <-ctx.Done():
+		return
+	case err := <-errch:
+		switch e := err.(type) {
+		case error:
+			nvimutil.ErrorWrap(c.Nvim, e)
+		case nil:
+			// nothing to do
+		}
+	}
+}
+
+// Hover shows the guru describe result of the identifier under the cursor to the floating window.
+//
+// The floating window displays the type, method set, package, doc snippet and definition position.
+// Press <CR> or gd in the floating window jumps to the definition.
+func (c *Command) Hover(ctx context.Context, eval *CmdHoverEval) interface{} {
+	var span *trace.Span
+	ctx, span = monitoring.StartSpan(ctx, "Hover")
+	defer span.End()
+
+	overlay := make(map[string][]byte)
+	if eval.Modified != 0 {
+		buf, err := c.Nvim.BufferLines(nvim.Buffer(eval.BufNr), 0, -1, true)
+		if err != nil {
+			span.SetStatus(trace.Status{Code: trace.StatusCodeInternal, Message: err.Error()})
+			return errors.WithStack(err)
+		}
+		overlay[eval.File] = nvimutil.ToByteSlice(buf)
+	}
+
+	d, err := describe(ctx, eval.File, eval.Offset, overlay)
+	if err != nil {
+		span.SetStatus(trace.Status{Code: trace.StatusCodeNotFound, Message: err.Error()})
+		return errors.WithStack(err)
+	}
+
+	def := describeDefPos(d)
+	var decl *declInfo
+	if def.IsValid() {
+		decl = findDeclInfo(def, overlay[def.Filename])
+	}
+	lines := hoverLines(d, def, decl, eval.Cwd)
+
+	h := c.hover
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if valid, _ := c.Nvim.IsWindowValid(h.window); valid {
+		c.Nvim.CloseWindow(h.window, true)
+	}
+	w, err := nvimutil.OpenFloat(c.Nvim, lines, filetypeGoDoc, docWidth, len(lines))
+	if err != nil {
+		span.SetStatus(trace.Status{Code: trace.StatusCodeInternal, Message: err.Error()})
+		return errors.WithStack(err)
+	}
+	h.window, h.srcWindow, h.def = w, nvim.Window(eval.WinID), def
+
+	b, err := c.Nvim.WindowBuffer(w)
+	if err != nil {
+		span.SetStatus(trace.Status{Code: trace.StatusCodeInternal, Message: err.Error()})
+		return errors.WithStack(err)
+	}
+	batch := c.Nvim.NewBatch()
+	for _, lhs := range []string{"<CR>", "gd"} {
+		batch.SetBufferKeyMap(b, "n", lhs, ":<C-u>call GoHoverJump()<CR>", map[string]bool{"silent": true, "nowait": true})
+	}
+	batch.SetBufferKeyMap(b, "n", "q", ":<C-u>close<CR>", map[string]bool{"silent": true, "nowait": true})
+	if err := batch.Execute(); err != nil {
+		span.SetStatus(trace.Status{Code: trace.StatusCodeInternal, Message: err.Error()})
+		return errors.WithStack(err)
+	}
+
+	return nil
+}
+
+// HoverAuto shows the hover floating window on CursorHold if config.HoverAuto is enabled.
+// Waits config.HoverDelay milliseconds, and does nothing if the cursor was moved while waiting.
+func (c *Command) HoverAuto(ctx context.Context, eval *CmdHoverEval) error {
+	if !config.HoverAuto {
+		return nil
+	}
+
+	time.Sleep(time.Duration(config.HoverDelay) * time.Millisecond)
+
+	var offset, bufnr int
+	batch := c.Nvim.NewBatch()
+	batch.Eval("line2byte(line('.')) + (col('.')-2)", &offset)
+	batch.Eval("bufnr('%')", &bufnr)
+	if err := batch.Execute(); err != nil {
+		return errors.WithStack(err)
+	}
+	if offset != eval.Offset || bufnr != eval.BufNr {
+		return nil
+	}
+
+	// the identifier might not be under the cursor, so ignore the describe error
+	if err, ok := c.Hover(ctx, eval).(error); ok && errors.Cause(err) != errNoIdentifier {
+		return err
+	}
+
+	return nil
+}
+
+func (c *Command) funcHoverJump(ctx context.Context) {
+	if err := c.HoverJump(ctx); err != nil {
+		nvimutil.ErrorWrap(c.Nvim, err)
+	}
+}
+
+// HoverJump closes the hover floating window, and jumps to the definition of the described identifier.
+func (c *Command) HoverJump(ctx context.Context) error {
+	h := c.hover
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if !h.def.IsValid() {
+		return errors.New("no definition position")
+	}
+	if valid, _ := c.Nvim.IsWindowValid(h.window); valid {
+		c.Nvim.CloseWindow(h.window, true)
+	}
+
+	return jumpToPos(c.Nvim, h.srcWindow, h.def)
+}
+
+// errNoIdentifier error of the describe query which the cursor is not on the describable syntax node.
+var errNoIdentifier = errors.New("no identifier here")
+
+// describe runs the guru describe query at the offset of file. overlay is the unsaved buffer contents.
+func describe(ctx context.Context, file string, offset int, overlay map[string][]byte) (d *serial.Describe, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = errors.Errorf("guru internal panic: %v", r)
+		}
+	}()
+
+	bctxt := &build.Default
+	if len(overlay) > 0 {
+		bctxt = buildutil.OverlayContext(bctxt, overlay)
+	}
+	query := guru.Query{
+		Pos:   fmt.Sprintf("%s:#%d", file, offset),
+		Build: bctxt,
+		Output: func(fset *token.FileSet, qr guru.QueryResult) {
+			d, _ = qr.Result(fset).(*serial.Describe)
+		},
+	}
+	if err := guru.Run(ctx, "describe", &query); err != nil {
+		if strings.Contains(err.Error(), "no identifier here") || strings.Contains(err.Error(), "not an expression") {
+			return nil, errNoIdentifier
+		}
+		return nil, err
+	}
+	if d == nil || d.Detail == "" {
+		return nil, errNoIdentifier
+	}
+
+	return d, nil
+}
+
+// describeDefPos returns the definition position of the described identifier.
+func describeDefPos(d *serial.Describe) token.Position {
+	var pos string
+	switch {
+	case d.Value != nil:
+		pos = d.Value.ObjPos
+	case d.Type != nil:
+		pos = d.Type.NamePos
+	}
+
+	return parseLineColPos(pos)
+}
+
+// parseLineColPos parses the "file:line:col" position string. Returns the invalid position if failed.
+func parseLineColPos(pos string) token.Position {
+	i := strings.LastIndexByte(pos, ':')
+	if i < 0 {
+		return token.Position{}
+	}
+	j := strings.LastIndexByte(pos[:i], ':')
+	if j < 0 {
+		return token.Position{}
+	}
+	line, err1 := strconv.Atoi(pos[j+1 : i])
+	col, err2 := strconv.Atoi(pos[i+1:])
+	if err1 != nil || err2 != nil {
+		return token.Position{}
+	}
+
+	return token.Position{Filename: pos[:j], Line: line, Column: col}
+}
+
+// declInfo represents the declaration of the described identifier.
+type declInfo struct {
+	// ImportPath import path of the package which declares the identifier.
+	ImportPath string
+	// Doc doc comment text of the declaration.
+	Doc string
+}
+
+// findDeclInfo parses the definition file and returns the declaration information at pos.
+// src is the contents of the file, reads the file if src is nil.
+func findDeclInfo(pos token.Position, src []byte) *declInfo {
+	fset := token.NewFileSet()
+	var fsrc interface{}
+	if src != nil {
+		fsrc = src
+	}
+	// the declaration may be found even if the file has errors
+	f, _ := parser.ParseFile(fset, pos.Filename, fsrc, parser.ParseComments)
+	if f == nil {
+		return nil
+	}
+
+	dir := filepath.Dir(pos.Filename)
+	info := &declInfo{ImportPath: dirImportPath(dir, f.Name.Name)}
+
+	tf := fset.File(f.Pos())
+	if pos.Line < 1 || tf.LineCount() < pos.Line {
+		return info
+	}
+	p := tf.LineStart(pos.Line) + token.Pos(pos.Column-1)
+	path, _ := astutil.PathEnclosingInterval(f, p, p)
+
+	for _, node := range path {
+		var doc *ast.CommentGroup
+		switch n := node.(type) {
+		case *ast.FuncDecl:
+			doc = n.Doc
+		case *ast.Field:
+			doc = n.Doc
+			if doc == nil {
+				doc = n.Comment
+			}
+		case *ast.TypeSpec:
+			doc = n.Doc
+		case *ast.ValueSpec:
+			doc = n.Doc
+			if doc == nil {
+				doc = n.Comment
+			}
+		case *ast.GenDecl:
+			doc = n.Doc
+		case *ast.BlockStmt:
+			return info // local declaration has no doc comment
+		default:
+			continue
+		}
+		if doc != nil {
+			info.Doc = doc.Text()
+			return info
+		}
+	}
+
+	return info
+}
+
+// hoverLines returns the lines of the hover floating window.
+func hoverLines(d *serial.Describe, def token.Position, decl *declInfo, cwd string) [][]byte {
+	var buf bytes.Buffer
+	fmt.Fprintln(&buf, d.Desc)
+
+	switch {
+	case d.Value != nil:
+		fmt.Fprintf(&buf, "type: %s\n", d.Value.Type)
+		if d.Value.Value != "" {
+			fmt.Fprintf(&buf, "value: %s\n", d.Value.Value)
+		}
+	case d.Type != nil:
+		fmt.Fprintf(&buf, "type: %s\n", d.Type.Type)
+		if d.Type.NameDef != "" {
+			fmt.Fprintf(&buf, "underlying: %s\n", d.Type.NameDef)
+		}
+		if len(d.Type.Methods) > 0 {
+			fmt.Fprintln(&buf, "methods:")
+			for i, m := range d.Type.Methods {
+				if i == hoverMaxMethods {
+					fmt.Fprintf(&buf, "    ... and %d more\n", len(d.Type.Methods)-hoverMaxMethods)
+					break
+				}
+				fmt.Fprintf(&buf, "    %s\n", m.Name)
+			}
+		}
+	case d.Package != nil:
+		fmt.Fprintf(&buf, "package: %s\n", d.Package.Path)
+	}
+
+	if decl != nil {
+		if d.Package == nil {
+			fmt.Fprintf(&buf, "package: %s\n", decl.ImportPath)
+		}
+		if doc := docSnippet(decl.Doc, hoverMaxDocLines); doc != "" {
+			fmt.Fprintf(&buf, "\n%s\n", doc)
+		}
+	}
+
+	if def.IsValid() {
+		fmt.Fprintf(&buf, "\ndefined at %s:%d:%d\n", fs.Rel(cwd, def.Filename), def.Line, def.Column)
+	}
+
+	return nvimutil.ToBufferLines(bytes.TrimSuffix(buf.Bytes(), []byte{'\n'}))
+}
+
+// docSnippet returns the first paragraph of doc which is limited to the max lines.
+func docSnippet(doc string, max int) string {
+	doc = strings.TrimSpace(doc)
+	if i := strings.Index(doc, "\n\n"); i >= 0 {
+		doc = doc[:i]
+	}
+	lines := strings.Split(doc, "\n")
+	if len(lines) > max {
+		lines = append(lines[:max], "...")
+	}
+
+	return strings.Join(lines, "\n")
+}
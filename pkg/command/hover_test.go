@@ -0,0 +1,153 @@
+// Copyright 2018 The nvim-go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package command
+
+import (
+	"context"
+	"go/token"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"golang.org/x/tools/cmd/guru/serial"
+)
+
+func TestParseLineColPos(t *testing.T) {
+	tests := []struct {
+		pos  string
+		want token.Position
+	}{
+		{pos: "/go/src/foo/foo.go:10:5", want: token.Position{Filename: "/go/src/foo/foo.go", Line: 10, Column: 5}},
+		{pos: `C:\go\src\foo.go:1:2`, want: token.Position{Filename: `C:\go\src\foo.go`, Line: 1, Column: 2}},
+		{pos: "foo.go:#123"},
+		{pos: ""},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.pos, func(t *testing.T) {
+			t.Parallel()
+
+			if got := parseLineColPos(tt.pos); got != tt.want {
+				t.Errorf("parseLineColPos(%q) = %+v, want %+v", tt.pos, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDocSnippet(t *testing.T) {
+	tests := []struct {
+		name string
+		doc  string
+		max  int
+		want string
+	}{
+		{name: "first paragraph", doc: "Foo is foo.\nIt is bar.\n\nSecond paragraph.\n", max: 5, want: "Foo is foo.\nIt is bar."},
+		{name: "truncate", doc: "a\nb\nc\n", max: 2, want: "a\nb\n..."},
+		{name: "empty", doc: "", max: 5, want: ""},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := docSnippet(tt.doc, tt.max); got != tt.want {
+				t.Errorf("docSnippet(%q, %d) = %q, want %q", tt.doc, tt.max, got, tt.want)
+			}
+		})
+	}
+}
+
+const hoverSrc = `package foo
+
+// Counter counts the things.
+//
+// The zero value is ready to use.
+type Counter struct {
+	// N is the count.
+	N int
+}
+
+// Inc increments the counter.
+func (c *Counter) Inc() { c.N++ }
+
+func f() {
+	var c Counter
+	c.Inc()
+}
+`
+
+func TestHover(t *testing.T) {
+	file, _ := testCheckedFile(t, hoverSrc)
+	dir := filepath.Dir(file)
+
+	// describe the "c" of "var c Counter"
+	offset := strings.Index(hoverSrc, "Counter\n\tc.Inc") + 1
+	d, err := describe(context.Background(), file, offset, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d.Type == nil {
+		t.Fatalf("describe() = %+v, want the type description", d)
+	}
+
+	def := describeDefPos(d)
+	if def.Filename != file || def.Line != 6 || def.Column != 6 {
+		t.Errorf("describeDefPos() = %+v, want %s:6:6", def, file)
+	}
+
+	decl := findDeclInfo(def, nil)
+	if decl == nil {
+		t.Fatal("findDeclInfo() = nil")
+	}
+	if want := "Counter counts the things.\n\nThe zero value is ready to use.\n"; decl.Doc != want {
+		t.Errorf("decl.Doc = %q, want %q", decl.Doc, want)
+	}
+
+	lines := hoverLines(d, def, decl, dir)
+	got := string(bytesJoinLines(lines))
+	for _, want := range []string{"type: ", "methods:", "Inc()", "Counter counts the things.", "defined at p.go:6:6"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("hoverLines() = %q, want to contain %q", got, want)
+		}
+	}
+	if strings.Contains(got, "ready to use") {
+		t.Errorf("hoverLines() = %q, want only the first paragraph of the doc", got)
+	}
+}
+
+func TestHoverLinesValue(t *testing.T) {
+	d := &serial.Describe{
+		Desc:   "reference to const Max untyped int",
+		Detail: "value",
+		Value:  &serial.DescribeValue{Type: "untyped int", Value: "10", ObjPos: "/foo/foo.go:3:7"},
+	}
+	def := describeDefPos(d)
+	lines := hoverLines(d, def, &declInfo{ImportPath: "example.com/foo", Doc: "Max is max."}, "/foo")
+
+	want := []string{
+		"reference to const Max untyped int",
+		"type: untyped int",
+		"value: 10",
+		"package: example.com/foo",
+		"",
+		"Max is max.",
+		"",
+		"defined at foo.go:3:7",
+	}
+	if got := string(bytesJoinLines(lines)); got != strings.Join(want, "\n") {
+		t.Errorf("hoverLines() =\n%s\nwant:\n%s", got, strings.Join(want, "\n"))
+	}
+}
+
+func bytesJoinLines(lines [][]byte) []byte {
+	var b []byte
+	for i, line := range lines {
+		if i > 0 {
+			b = append(b, '\n')
+		}
+		b = append(b, line...)
+	}
+	return b
+}
@@ -22,6 +22,7 @@ import (
 	"github.com/pkg/errors"
 	"go.opencensus.io/trace"
 	"golang.org/x/tools/go/ast/astutil"
+	"golang.org/x/tools/go/buildutil"
 	"golang.org/x/tools/go/loader"
 
 	"github.com/zchee/nvim-go/pkg/monitoring"
@@ -69,10 +70,17 @@ func (c *Command) Iferr(ctx context.Context, file string) error {
 		return nvimutil.ErrorWrap(c.Nvim, errors.WithStack(err))
 	}
 
+	// the imported packages see the unsaved changes of the other modified buffers
+	overlay, err := c.modifiedOverlay()
+	if err != nil {
+		span.SetStatus(trace.Status{Code: trace.StatusCodeInternal, Message: err.Error()})
+		return nvimutil.ErrorWrap(c.Nvim, errors.WithStack(err))
+	}
+
 	conf := loader.Config{
 		ParserMode:  parser.ParseComments,
 		TypeChecker: types.Config{FakeImportC: true, DisableUnusedImportCheck: true},
-		Build:       &build.Default,
+		Build:       buildutil.OverlayContext(&build.Default, overlay),
 		Cwd:         filepath.Dir(file),
 		AllowErrors: true,
 	}
@@ -0,0 +1,580 @@
+// Copyright 2018 The nvim-go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package command
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"go/parser"
+	"go/token"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/neovim/go-client/nvim"
+	"github.com/pkg/errors"
+	"go.opencensus.io/trace"
+
+	"github.com/zchee/nvim-go/pkg/fs"
+	"github.com/zchee/nvim-go/pkg/monitoring"
+	"github.com/zchee/nvim-go/pkg/nvimutil"
+)
+
+// importGraphBufferName buffer name of the GoImports graph buffer.
+const importGraphBufferName = "__GoImports__"
+
+// listedPackage represents a package which is listed by "go list -json".
+type listedPackage struct {
+	ImportPath string
+	Name       string
+	Dir        string
+	Standard   bool
+	GoFiles    []string
+	CgoFiles   []string
+	Imports    []string
+	// Export export data file of the package. Listed only with the -export flag.
+	Export string
+}
+
+// importGraph represents the GoImports graph tree buffer.
+type importGraph struct {
+	treeView
+
+	cwd string
+	// pkgs packages of the module keyed by the import path.
+	pkgs map[string]*listedPackage
+}
+
+// importNode is the data of the GoImports graph tree node.
+type importNode struct {
+	Path string
+
+	parent *importNode
+}
+
+// inCycle reports whether the ancestors of n have the same package with n.
+func (n *importNode) inCycle() bool {
+	for p := n.parent; p != nil; p = p.parent {
+		if p.Path == n.Path {
+			return true
+		}
+	}
+
+	return false
+}
+
+// goList runs "go list -e -json" with args in dir, and returns the listed packages.
+func goList(ctx context.Context, dir string, args ...string) ([]*listedPackage, error) {
+	cmd := exec.CommandContext(ctx, "go", append([]string{"list", "-e", "-json"}, args...)...)
+	cmd.Dir = dir
+	cmd.Env = os.Environ()
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, errors.Wrap(err, strings.TrimSpace(stderr.String()))
+	}
+
+	var pkgs []*listedPackage
+	dec := json.NewDecoder(&stdout)
+	for {
+		pkg := new(listedPackage)
+		if err := dec.Decode(pkg); err != nil {
+			if err == io.EOF {
+				break
+			}
+			return nil, errors.WithStack(err)
+		}
+		pkgs = append(pkgs, pkg)
+	}
+
+	return pkgs, nil
+}
+
+// packageMap returns the map of pkgs keyed by the import path.
+func packageMap(pkgs []*listedPackage) map[string]*listedPackage {
+	m := make(map[string]*listedPackage, len(pkgs))
+	for _, pkg := range pkgs {
+		m[pkg.ImportPath] = pkg
+	}
+
+	return m
+}
+
+type cmdImportsEval struct {
+	Cwd   string `eval:"getcwd()"`
+	File  string `eval:"expand('%:p')"`
+	BufNr int    `eval:"bufnr('%')"`
+	WinID int    `eval:"win_getid()"`
+}
+
+func (c *Command) cmdImports(ctx context.Context, args []string, eval *cmdImportsEval) {
+	errch := make(chan interface{}, 1)
+	go func() {
+		errch <- c.Imports(ctx, args, eval)
+	}()
+
+	select {
+	case <-ctx.Done():
+		return
+	case err := <-errch:
+		switch e := err.(type) {
+		case error:
+			nvimutil.ErrorWrap(c.Nvim, e)
+		case nil:
+			// nothing to do
+		}
+	}
+}
+
+// Imports runs the import analysis of the module which contains the current buffer.
+//
+// The available subcommand is "graph", which shows the import graph tree of the module packages,
+// and writes the graph to the temporary file in the DOT format.
+func (c *Command) Imports(ctx context.Context, args []string, eval *cmdImportsEval) interface{} {
+	var span *trace.Span
+	ctx, span = monitoring.StartSpan(ctx, "Imports")
+	defer span.End()
+
+	if len(args) == 0 || args[0] != "graph" {
+		err := errors.Errorf("unknown GoImports subcommand: %s", strings.Join(args, " "))
+		span.SetStatus(trace.Status{Code: trace.StatusCodeInvalidArgument, Message: err.Error()})
+		return err
+	}
+
+	root := workspaceRoot(filepath.Dir(eval.File))
+	nvimutil.EchoProgress(c.Nvim, "GoImports", "listing packages of %s", root)
+	pkgs, err := goList(ctx, root, "./...")
+	if err != nil {
+		span.SetStatus(trace.Status{Code: trace.StatusCodeInternal, Message: err.Error()})
+		return err
+	}
+	if len(pkgs) == 0 {
+		err := errors.Errorf("no packages in %s", root)
+		span.SetStatus(trace.Status{Code: trace.StatusCodeNotFound, Message: err.Error()})
+		return err
+	}
+
+	output := filepath.Join(os.TempDir(), "nvim-go-imports.dot")
+	f, err := os.Create(output)
+	if err != nil {
+		span.SetStatus(trace.Status{Code: trace.StatusCodeInternal, Message: err.Error()})
+		return errors.WithStack(err)
+	}
+	if err := writeImportGraph(f, pkgs); err != nil {
+		f.Close()
+		span.SetStatus(trace.Status{Code: trace.StatusCodeInternal, Message: err.Error()})
+		return err
+	}
+	if err := f.Close(); err != nil {
+		span.SetStatus(trace.Status{Code: trace.StatusCodeInternal, Message: err.Error()})
+		return errors.WithStack(err)
+	}
+
+	g := c.imports
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	g.cwd = eval.Cwd
+	g.pkgs = packageMap(pkgs)
+	g.srcBuffer = nvim.Buffer(eval.BufNr)
+	g.srcWindow = nvim.Window(eval.WinID)
+	g.srcFile = eval.File
+
+	var roots []*nvimutil.TreeNode
+	for _, pkg := range pkgs {
+		roots = append(roots, g.newNode(&importNode{Path: pkg.ImportPath}, packagePos(pkg)))
+	}
+	g.tree = nvimutil.NewTree(roots...)
+
+	if !g.isOpened(c.Nvim) {
+		b := nvimutil.NewBuffer(c.Nvim)
+		if err := b.Create(importGraphBufferName, filetypeGoAnalyze, "belowright 60vsplit", sidebarOption(filetypeGoAnalyze)); err != nil {
+			span.SetStatus(trace.Status{Code: trace.StatusCodeInternal, Message: err.Error()})
+			return errors.WithStack(err)
+		}
+		b.SetLocalMapping(nvimutil.NoremapNormal, map[string]string{
+			"<CR>": ":<C-u>call GoImportsAction('jump')<CR>",
+			"o":    ":<C-u>call GoImportsAction('toggle')<CR>",
+			"za":   ":<C-u>call GoImportsAction('toggle')<CR>",
+			"q":    ":<C-u>call GoImportsAction('close')<CR>",
+		})
+		g.buffer = b.Buffer()
+		g.window = b.Window
+	}
+
+	if err := g.render(c.Nvim); err != nil {
+		span.SetStatus(trace.Status{Code: trace.StatusCodeInternal, Message: err.Error()})
+		return errors.WithStack(err)
+	}
+
+	batch := c.Nvim.NewBatch()
+	batch.SetCurrentWindow(g.window)
+	batch.SetWindowCursor(g.window, [2]int{1, 0})
+	if err := batch.Execute(); err != nil {
+		span.SetStatus(trace.Status{Code: trace.StatusCodeInternal, Message: err.Error()})
+		return errors.WithStack(err)
+	}
+
+	return nvimutil.EchoSuccess(c.Nvim, "GoImports", fmt.Sprintf("wrote %d packages to %s", len(pkgs), output))
+}
+
+func (c *Command) cmdImportsComplete(ctx context.Context, a *nvim.CommandCompletionArgs) ([]string, error) {
+	var matches []string
+	for _, cand := range []string{"graph"} {
+		if strings.HasPrefix(cand, a.ArgLead) {
+			matches = append(matches, cand)
+		}
+	}
+
+	return matches, nil
+}
+
+func (c *Command) funcImportsAction(ctx context.Context, args []string, line int) {
+	if err := c.ImportsAction(ctx, args, line); err != nil {
+		nvimutil.ErrorWrap(c.Nvim, err)
+	}
+}
+
+// ImportsAction runs the action of GoImports graph buffer to the node at line.
+//
+// The available actions are "jump", "toggle" and "close".
+func (c *Command) ImportsAction(ctx context.Context, args []string, line int) error {
+	g := c.imports
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.tree == nil || len(args) == 0 {
+		return nil
+	}
+
+	switch args[0] {
+	case "jump":
+		n := g.tree.NodeAt(line)
+		if n == nil || !n.Start.IsValid() {
+			return nil
+		}
+		return jumpToPos(c.Nvim, g.srcWindow, n.Start)
+
+	case "toggle":
+		n := g.tree.NodeAt(line)
+		if n == nil {
+			return nil
+		}
+		g.expand(n)
+		if !g.tree.Toggle(line) {
+			return nil
+		}
+		return g.render(c.Nvim)
+
+	case "close":
+		g.tree = nil
+		return c.Nvim.CloseWindow(g.window, true)
+
+	default:
+		return errors.Errorf("unknown GoImports action: %s", args[0])
+	}
+}
+
+// newNode returns the tree node of the in package. pos is the position of the import statement, or the package clause of the root node.
+// Only the module packages are expandable.
+func (g *importGraph) newNode(in *importNode, pos token.Position) *nvimutil.TreeNode {
+	_, ok := g.pkgs[in.Path]
+	n := &nvimutil.TreeNode{
+		Text:       in.Path,
+		Start:      pos,
+		End:        pos,
+		Expandable: ok,
+		Data:       in,
+	}
+	if pos.IsValid() {
+		n.Text = fmt.Sprintf("%s  %s:%d", in.Path, fs.Rel(g.cwd, pos.Filename), pos.Line)
+	}
+	if ok && in.inCycle() {
+		n.Text += " (cycle)"
+		n.Expandable = false
+	}
+
+	return n
+}
+
+// expand loads the imports of the package of n if n is not loaded yet.
+func (g *importGraph) expand(n *nvimutil.TreeNode) {
+	if !n.Expandable {
+		return
+	}
+	in := n.Data.(*importNode)
+	pkg := g.pkgs[in.Path]
+
+	positions := importPositions(pkg)
+	children := make([]*nvimutil.TreeNode, 0, len(pkg.Imports))
+	for _, path := range pkg.Imports {
+		children = append(children, g.newNode(&importNode{Path: path, parent: in}, positions[path]))
+	}
+	n.Children = children
+	n.Expandable = false
+}
+
+// packagePos returns the position of the package clause of the first Go file of pkg.
+func packagePos(pkg *listedPackage) token.Position {
+	files := packageFilenames(pkg)
+	if len(files) == 0 {
+		return token.Position{}
+	}
+
+	return token.Position{Filename: files[0], Line: 1, Column: 1}
+}
+
+// packageFilenames returns the absolute file paths of the Go files of pkg.
+func packageFilenames(pkg *listedPackage) []string {
+	var files []string
+	for _, name := range append(append([]string(nil), pkg.GoFiles...), pkg.CgoFiles...) {
+		files = append(files, filepath.Join(pkg.Dir, name))
+	}
+	sort.Strings(files)
+
+	return files
+}
+
+// importPositions returns the position of the first import statement of each import path in pkg.
+func importPositions(pkg *listedPackage) map[string]token.Position {
+	positions := make(map[string]token.Position)
+	fset := token.NewFileSet()
+	for _, file := range packageFilenames(pkg) {
+		f, _ := parser.ParseFile(fset, file, nil, parser.ImportsOnly)
+		if f == nil {
+			continue
+		}
+		for _, spec := range f.Imports {
+			path, err := strconv.Unquote(spec.Path.Value)
+			if err != nil {
+				continue
+			}
+			if _, ok := positions[path]; !ok {
+				positions[path] = fset.Position(spec.Pos())
+			}
+		}
+	}
+
+	return positions
+}
+
+// writeImportGraph writes the import graph of pkgs to w in the DOT format.
+// The imports of the standard library packages, except the listed packages, are omitted.
+func writeImportGraph(w io.Writer, pkgs []*listedPackage) error {
+	listed := packageMap(pkgs)
+
+	bw := bufio.NewWriter(w)
+	fmt.Fprintln(bw, "digraph imports {")
+	for _, pkg := range pkgs {
+		fmt.Fprintf(bw, "\t%q;\n", pkg.ImportPath)
+		for _, path := range pkg.Imports {
+			if _, ok := listed[path]; !ok && isStandardImportPath(path) {
+				continue
+			}
+			fmt.Fprintf(bw, "\t%q -> %q;\n", pkg.ImportPath, path)
+		}
+	}
+	fmt.Fprintln(bw, "}")
+
+	return errors.WithStack(bw.Flush())
+}
+
+// isStandardImportPath reports whether path is the standard library package, which first element has no dot.
+func isStandardImportPath(path string) bool {
+	elem := path
+	if i := strings.Index(path, "/"); i >= 0 {
+		elem = path[:i]
+	}
+
+	return !strings.Contains(elem, ".")
+}
+
+type cmdWhyImportEval struct {
+	Cwd  string `eval:"getcwd()"`
+	File string `eval:"expand('%:p')"`
+}
+
+func (c *Command) cmdWhyImport(ctx context.Context, args []string, eval *cmdWhyImportEval) {
+	errch := make(chan interface{}, 1)
+	go func() {
+		errch <- c.WhyImport(ctx, args, eval)
+	}()
+
+	select {
+	case <-ctx.Done():
+		return
+	case err := <-errch:
+		switch e := err.(type) {
+		case error:
+			nvimutil.ErrorWrap(c.Nvim, e)
+		case nil:
+			// nothing to do
+		}
+	}
+}
+
+// WhyImport shows the shortest import chain from the package of the current buffer to the package of args[0]
+// as the locationlist of the import statements.
+func (c *Command) WhyImport(ctx context.Context, args []string, eval *cmdWhyImportEval) interface{} {
+	var span *trace.Span
+	ctx, span = monitoring.StartSpan(ctx, "WhyImport")
+	defer span.End()
+
+	if len(args) == 0 {
+		err := errors.New("GoWhyImport requires the import path")
+		span.SetStatus(trace.Status{Code: trace.StatusCodeInvalidArgument, Message: err.Error()})
+		return err
+	}
+	target := args[0]
+
+	pkgs, err := goList(ctx, filepath.Dir(eval.File), "-deps", ".")
+	if err != nil {
+		span.SetStatus(trace.Status{Code: trace.StatusCodeInternal, Message: err.Error()})
+		return err
+	}
+	if len(pkgs) == 0 {
+		return nil
+	}
+	// "go list -deps" lists the dependencies before the package
+	from := pkgs[len(pkgs)-1].ImportPath
+
+	m := packageMap(pkgs)
+	chain := shortestImportChain(m, from, target)
+	if chain == nil {
+		err := errors.Errorf("%s does not import %s", from, target)
+		span.SetStatus(trace.Status{Code: trace.StatusCodeNotFound, Message: err.Error()})
+		return err
+	}
+
+	loclist := importChainList(m, eval.Cwd, chain)
+	w, err := c.Nvim.CurrentWindow()
+	if err != nil {
+		span.SetStatus(trace.Status{Code: trace.StatusCodeInternal, Message: err.Error()})
+		return errors.WithStack(err)
+	}
+	if err := nvimutil.SetLoclist(c.Nvim, loclist); err != nil {
+		span.SetStatus(trace.Status{Code: trace.StatusCodeInternal, Message: err.Error()})
+		return err
+	}
+
+	return nvimutil.OpenLoclist(c.Nvim, w, loclist, true)
+}
+
+// shortestImportChain returns the shortest import chain from the from package to the to package, including both.
+// Returns nil if from does not import to.
+func shortestImportChain(pkgs map[string]*listedPackage, from, to string) []string {
+	prev := map[string]string{from: ""}
+	queue := []string{from}
+	for len(queue) > 0 {
+		path := queue[0]
+		queue = queue[1:]
+		if path == to {
+			var chain []string
+			for p := to; p != ""; p = prev[p] {
+				chain = append([]string{p}, chain...)
+			}
+			return chain
+		}
+
+		pkg, ok := pkgs[path]
+		if !ok {
+			continue
+		}
+		for _, imp := range pkg.Imports {
+			if _, seen := prev[imp]; !seen {
+				prev[imp] = path
+				queue = append(queue, imp)
+			}
+		}
+	}
+
+	return nil
+}
+
+// importChainList returns the list of the import statements of chain, such as "a imports b".
+// The file name is relative to cwd.
+func importChainList(pkgs map[string]*listedPackage, cwd string, chain []string) []*nvim.QuickfixError {
+	var list []*nvim.QuickfixError
+	for i := 0; i+1 < len(chain); i++ {
+		from, to := chain[i], chain[i+1]
+		item := &nvim.QuickfixError{Text: fmt.Sprintf("%s imports %s", from, to)}
+		if pkg, ok := pkgs[from]; ok {
+			if pos, ok := importPositions(pkg)[to]; ok {
+				item.FileName = fs.Rel(cwd, pos.Filename)
+				item.LNum = pos.Line
+				item.Col = pos.Column
+			}
+		}
+		list = append(list, item)
+	}
+
+	return list
+}
+
+// parseImportCycle parses the import cycle error of the go build output, and returns the import paths of the cycle.
+// The first and last elements of the cycle are the same package. Returns nil if errmsg has no import cycle error.
+//
+// The error message is like:
+//
+//  package example.com/a
+//  	imports example.com/b
+//  	imports example.com/a: import cycle not allowed
+func parseImportCycle(errmsg []byte) []string {
+	if !bytes.Contains(errmsg, []byte("import cycle not allowed")) {
+		return nil
+	}
+
+	var chain []string
+	s := bufio.NewScanner(bytes.NewReader(errmsg))
+	for s.Scan() {
+		line := strings.TrimSuffix(strings.TrimSpace(s.Text()), ": import cycle not allowed")
+		switch i := strings.Index(line, "package "); {
+		case i >= 0:
+			// may be prefixed with "can't load package: "
+			chain = []string{line[i+len("package "):]}
+		case strings.HasPrefix(line, "imports ") && len(chain) > 0:
+			chain = append(chain, strings.TrimPrefix(line, "imports "))
+		}
+	}
+	if len(chain) < 2 {
+		return nil
+	}
+
+	// trim the import chain to the cycle
+	last := chain[len(chain)-1]
+	for i, path := range chain[:len(chain)-1] {
+		if path == last {
+			return chain[i:]
+		}
+	}
+
+	return nil
+}
+
+// importCycleErrors returns the error list of the import statements of the import cycle in the go build output.
+func importCycleErrors(ctx context.Context, cwd string, errmsg []byte) []*nvim.QuickfixError {
+	cycle := parseImportCycle(errmsg)
+	if cycle == nil {
+		return nil
+	}
+
+	pkgs, err := goList(ctx, cwd, cycle[:len(cycle)-1]...)
+	if err != nil {
+		return nil
+	}
+	list := importChainList(packageMap(pkgs), cwd, cycle)
+	for _, item := range list {
+		item.Text = "import cycle: " + item.Text
+	}
+
+	return list
+}
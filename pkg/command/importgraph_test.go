@@ -0,0 +1,145 @@
+// Copyright 2018 The nvim-go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package command
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/neovim/go-client/nvim"
+)
+
+func TestParseImportCycle(t *testing.T) {
+	tests := []struct {
+		name   string
+		errmsg string
+		want   []string
+	}{
+		{
+			name: "cycle",
+			errmsg: `package example.com/m/a
+	imports example.com/m/b
+	imports example.com/m/c
+	imports example.com/m/b: import cycle not allowed
+`,
+			want: []string{"example.com/m/b", "example.com/m/c", "example.com/m/b"},
+		},
+		{
+			name: "cycle from the package",
+			errmsg: `can't load package: package example.com/m/a
+	imports example.com/m/b
+	imports example.com/m/a: import cycle not allowed
+`,
+			want: []string{"example.com/m/a", "example.com/m/b", "example.com/m/a"},
+		},
+		{
+			name:   "not cycle",
+			errmsg: "./a.go:3:2: undefined: foo\n",
+			want:   nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parseImportCycle([]byte(tt.errmsg)); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseImportCycle() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestShortestImportChain(t *testing.T) {
+	pkgs := packageMap([]*listedPackage{
+		{ImportPath: "m/a", Imports: []string{"fmt", "m/b", "m/c"}},
+		{ImportPath: "m/b", Imports: []string{"m/c", "m/d"}},
+		{ImportPath: "m/c", Imports: []string{"m/d"}},
+		{ImportPath: "m/d", Imports: []string{"m/b", "os"}},
+	})
+
+	tests := []struct {
+		name     string
+		from, to string
+		want     []string
+	}{
+		{name: "direct", from: "m/a", to: "fmt", want: []string{"m/a", "fmt"}},
+		{name: "shortest", from: "m/a", to: "m/d", want: []string{"m/a", "m/b", "m/d"}},
+		{name: "through cycle", from: "m/c", to: "m/b", want: []string{"m/c", "m/d", "m/b"}},
+		{name: "deep", from: "m/a", to: "os", want: []string{"m/a", "m/b", "m/d", "os"}},
+		{name: "not imported", from: "m/d", to: "fmt", want: nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := shortestImportChain(pkgs, tt.from, tt.to); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("shortestImportChain(%s, %s) = %v, want %v", tt.from, tt.to, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestImportChainList(t *testing.T) {
+	dir, err := ioutil.TempDir("", "nvim-go-importgraph")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	writeFile(t, filepath.Join(dir, "a", "a.go"), `package a
+
+import (
+	"fmt"
+
+	"m/b"
+)
+
+var _ = fmt.Sprint(b.B)
+`)
+	writeFile(t, filepath.Join(dir, "b", "b.go"), `package b
+
+import "os"
+
+var B = os.Args
+`)
+
+	pkgs := packageMap([]*listedPackage{
+		{ImportPath: "m/a", Dir: filepath.Join(dir, "a"), GoFiles: []string{"a.go"}, Imports: []string{"fmt", "m/b"}},
+		{ImportPath: "m/b", Dir: filepath.Join(dir, "b"), GoFiles: []string{"b.go"}, Imports: []string{"os"}},
+	})
+	got := importChainList(pkgs, dir, []string{"m/a", "m/b", "os"})
+	want := []*nvim.QuickfixError{
+		{FileName: filepath.Join("a", "a.go"), LNum: 6, Col: 2, Text: "m/a imports m/b"},
+		{FileName: filepath.Join("b", "b.go"), LNum: 3, Col: 8, Text: "m/b imports os"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		for _, item := range got {
+			t.Logf("%+v", item)
+		}
+		t.Errorf("importChainList() = %v, want %v", got, want)
+	}
+}
+
+func TestWriteImportGraph(t *testing.T) {
+	pkgs := []*listedPackage{
+		{ImportPath: "example.com/m/a", Imports: []string{"fmt", "example.com/m/b"}},
+		{ImportPath: "example.com/m/b", Imports: []string{"github.com/pkg/errors"}},
+	}
+
+	var buf bytes.Buffer
+	if err := writeImportGraph(&buf, pkgs); err != nil {
+		t.Fatal(err)
+	}
+	want := `digraph imports {
+	"example.com/m/a";
+	"example.com/m/a" -> "example.com/m/b";
+	"example.com/m/b";
+	"example.com/m/b" -> "github.com/pkg/errors";
+}
+`
+	if got := buf.String(); got != want {
+		t.Errorf("writeImportGraph() = %s, want %s", got, want)
+	}
+}
@@ -0,0 +1,856 @@
+// Copyright 2018 The nvim-go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package command
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"io/ioutil"
+	"sort"
+	"strings"
+
+	"github.com/neovim/go-client/nvim"
+	"github.com/pkg/errors"
+	"go.opencensus.io/trace"
+	"golang.org/x/tools/go/ast/astutil"
+
+	"github.com/zchee/nvim-go/pkg/monitoring"
+	"github.com/zchee/nvim-go/pkg/nvimutil"
+)
+
+type cmdInlineEval struct {
+	File   string `eval:"expand('%:p')"`
+	BufNr  int    `eval:"bufnr('%')"`
+	Offset int    `eval:"line2byte(line('.')) + (col('.')-2)"`
+}
+
+func (c *Command) cmdInline(ctx context.Context, eval *cmdInlineEval) {
+	errch := make(chan interface{}, 1)
+	go func() {
+		errch <- c.Inline(ctx, eval)
+	}()
+
+	select {
+	case <-ctx.Done():
+		return
+	case err := <-errch:
+		switch e := err.(type) {
+		case error:
+			nvimutil.ErrorWrap(c.Nvim, e)
+		case nil:
+			// nothing to do
+		}
+	}
+}
+
+// Inline inlines the local variable or the function call under the cursor.
+func (c *Command) Inline(ctx context.Context, eval *cmdInlineEval) interface{} {
+	var span *trace.Span
+	ctx, span = monitoring.StartSpan(ctx, "Inline")
+	defer span.End()
+
+	b := nvim.Buffer(eval.BufNr)
+	in, err := c.Nvim.BufferLines(b, 0, -1, true)
+	if err != nil {
+		span.SetStatus(trace.Status{Code: trace.StatusCodeInternal, Message: err.Error()})
+		return errors.WithStack(err)
+	}
+	src := append(nvimutil.ToByteSlice(in), '\n')
+
+	out, err := inline(eval.File, src, eval.Offset)
+	if err != nil {
+		span.SetStatus(trace.Status{Code: trace.StatusCodeInvalidArgument, Message: err.Error()})
+		return err
+	}
+
+	return minUpdate(ctx, c.Nvim, b, in, nvimutil.ToBufferLines(bytes.TrimSuffix(out, []byte{'\n'})))
+}
+
+// textEdit represents the replacement of src[start:end] with text.
+type textEdit struct {
+	start, end int
+	text       string
+}
+
+// applyTextEdits applies the non-overlapping edits to src.
+func applyTextEdits(src []byte, edits []textEdit) []byte {
+	sort.Slice(edits, func(i, j int) bool { return edits[i].start < edits[j].start })
+
+	var buf bytes.Buffer
+	var last int
+	for _, e := range edits {
+		buf.Write(src[last:e.start])
+		buf.WriteString(e.text)
+		last = e.end
+	}
+	buf.Write(src[last:])
+
+	return buf.Bytes()
+}
+
+// inline inlines the local variable or the function call at the offset of src, and returns the new
+// contents of file.
+func inline(file string, src []byte, offset int) ([]byte, error) {
+	cf, err := typeCheck(file, src)
+	if err != nil {
+		return nil, err
+	}
+	tf := cf.Fset.File(cf.File.Pos())
+	if offset < 0 || offset > tf.Size() {
+		return nil, errors.Errorf("invalid offset %d", offset)
+	}
+	pos := tf.Pos(offset)
+
+	path, _ := astutil.PathEnclosingInterval(cf.File, pos, pos)
+	if id, ok := path[0].(*ast.Ident); ok {
+		if v, ok := cf.Info.ObjectOf(id).(*types.Var); ok && !v.IsField() && v.Parent() != cf.Pkg.Scope() {
+			edits, err := inlineVar(cf, src, v)
+			if err != nil {
+				return nil, errors.Wrapf(err, "cannot inline %s", v.Name())
+			}
+			return formatChecked(file, src, applyTextEdits(src, edits))
+		}
+	}
+
+	for i, n := range path {
+		if call, ok := n.(*ast.CallExpr); ok {
+			edits, err := inlineCall(cf, src, path[i:])
+			if err != nil {
+				return nil, errors.Wrapf(err, "cannot inline %s", src[tf.Offset(call.Fun.Pos()):tf.Offset(call.Fun.End())])
+			}
+			return formatChecked(file, src, applyTextEdits(src, edits))
+		}
+	}
+
+	return nil, errors.New("no local variable or function call at the cursor")
+}
+
+// inlineVar returns the edits which replace the uses of v with the initial value, and delete the
+// declaration of v.
+func inlineVar(cf *checkedFile, src []byte, v *types.Var) ([]textEdit, error) {
+	tf := cf.Fset.File(cf.File.Pos())
+	if tf.Base() > int(v.Pos()) || int(v.Pos()) > tf.Base()+tf.Size() {
+		return nil, errors.New("the variable is not declared in the current file")
+	}
+
+	declPath, _ := astutil.PathEnclosingInterval(cf.File, v.Pos(), v.Pos())
+	var (
+		stmt ast.Stmt
+		rhs  ast.Expr
+		idx  int
+	)
+	switch n := declPath[1].(type) {
+	case *ast.AssignStmt:
+		if n.Tok == token.DEFINE && len(n.Lhs) == 1 && len(n.Rhs) == 1 {
+			stmt, rhs, idx = n, n.Rhs[0], 1
+		}
+	case *ast.ValueSpec:
+		if len(n.Names) == 1 && len(n.Values) == 1 && len(declPath[2].(*ast.GenDecl).Specs) == 1 {
+			if ds, ok := declPath[3].(*ast.DeclStmt); ok {
+				stmt, rhs, idx = ds, n.Values[0], 3
+			}
+		}
+	}
+	if stmt == nil {
+		return nil, errors.New("the variable is not declared by the single variable declaration with the initial value")
+	}
+	list := stmtList(declPath[idx+1])
+	if list == nil {
+		return nil, errors.New("the variable is declared in the statement header")
+	}
+
+	var fn ast.Node
+	for _, n := range declPath {
+		switch n.(type) {
+		case *ast.FuncDecl, *ast.FuncLit:
+			fn = n
+		}
+	}
+	if fn == nil {
+		return nil, errors.New("the variable is not declared in the function")
+	}
+
+	modified := make(map[types.Object]token.Pos)
+	forEachModified(cf.Info, fn, func(id *ast.Ident) {
+		if cf.Info.Defs[id] != nil {
+			return // declaration
+		}
+		if obj := cf.Info.Uses[id]; obj != nil {
+			if _, ok := modified[obj]; !ok {
+				modified[obj] = id.Pos()
+			}
+		}
+	})
+	if pos, ok := modified[v]; ok {
+		return nil, errors.Errorf("it is reassigned at line %d", tf.Line(pos))
+	}
+
+	var uses []*ast.Ident
+	for id, obj := range cf.Info.Uses {
+		if obj == v {
+			uses = append(uses, id)
+		}
+	}
+	sort.Slice(uses, func(i, j int) bool { return uses[i].Pos() < uses[j].Pos() })
+
+	// the operands must not be changed, and must refer to the same objects at the uses
+	var err error
+	ast.Inspect(rhs, func(n ast.Node) bool {
+		id, ok := n.(*ast.Ident)
+		if !ok || err != nil {
+			return err == nil
+		}
+		obj := cf.Info.Uses[id]
+		if !isLexical(cf, obj) {
+			return true
+		}
+		if pos, ok := modified[obj]; ok {
+			err = errors.Errorf("%s in the initial value is modified at line %d", id.Name, tf.Line(pos))
+			return false
+		}
+		for _, use := range uses {
+			if _, o := cf.Pkg.Scope().Innermost(use.Pos()).LookupParent(id.Name, use.Pos()); o != obj {
+				err = errors.Errorf("%s in the initial value refers to the other object at line %d", id.Name, tf.Line(use.Pos()))
+				return false
+			}
+		}
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// the memory which is read by the initial value must not be changed before the uses
+	if readsMemory(cf.Info, rhs) {
+		if err := checkMemoryWrites(cf, fn, stmt, uses); err != nil {
+			return nil, err
+		}
+	}
+
+	if hasSideEffects(cf.Info, rhs) {
+		if len(uses) != 1 {
+			return nil, errors.Errorf("the initial value has side effects, and it is used %d times", len(uses))
+		}
+		if err := checkNextStmt(cf, list, stmt, uses[0]); err != nil {
+			return nil, errors.Wrap(err, "the initial value has side effects")
+		}
+	}
+
+	text := string(src[tf.Offset(rhs.Pos()):tf.Offset(rhs.End())])
+	tv := cf.Info.Types[rhs]
+	basic, numeric := v.Type().Underlying().(*types.Basic)
+	numeric = numeric && basic.Info()&types.IsNumeric != 0
+	// keep the type of the variable, the untyped constant is typed by the context of the uses
+	if (tv.Value != nil && numeric) || !types.Identical(tv.Type, v.Type()) {
+		qf, missing := fileQualifier(cf)
+		text = fmt.Sprintf("%s(%s)", types.TypeString(v.Type(), qf), text)
+		if len(*missing) > 0 {
+			return nil, errors.Errorf("it requires the import of %q", (*missing)[0])
+		}
+	}
+
+	edits := make([]textEdit, 0, len(uses)+1)
+	start, end := stmtRange(src, tf.Offset(stmt.Pos()), tf.Offset(stmt.End()))
+	edits = append(edits, textEdit{start: start, end: end})
+	x, err := parser.ParseExpr(text)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	for _, use := range uses {
+		usePath, _ := astutil.PathEnclosingInterval(cf.File, use.Pos(), use.End())
+		t := text
+		if needsParen(x, usePath[1], use) {
+			t = "(" + t + ")"
+		}
+		edits = append(edits, textEdit{start: tf.Offset(use.Pos()), end: tf.Offset(use.End()), text: t})
+	}
+
+	return edits, nil
+}
+
+// checkNextStmt returns an error if the use is not evaluated in the next statement of stmt, or the
+// next statement evaluates the other side effects before the use.
+func checkNextStmt(cf *checkedFile, list []ast.Stmt, stmt ast.Stmt, use *ast.Ident) error {
+	var next ast.Stmt
+	for i, s := range list {
+		if s == stmt && i+1 < len(list) {
+			next = list[i+1]
+		}
+	}
+	if next == nil || use.Pos() < next.Pos() || next.End() <= use.Pos() {
+		return errors.New("it is not used in the next statement")
+	}
+
+	usePath, _ := astutil.PathEnclosingInterval(cf.File, use.Pos(), use.End())
+	if s, _, err := evaluatedStmt(cf.Info, usePath); err != nil {
+		return err
+	} else if s != next {
+		return errors.New("it is not evaluated by the next statement")
+	}
+
+	var err error
+	ast.Inspect(next, func(n ast.Node) bool {
+		if n == nil || err != nil || n.Pos() >= use.Pos() {
+			return false
+		}
+		if n.End() <= use.Pos() && hasSideEffects(cf.Info, n) {
+			if _, ok := n.(ast.Expr); ok {
+				err = errors.New("the next statement evaluates the other side effects before the use")
+				return false
+			}
+		}
+		return true
+	})
+
+	return err
+}
+
+// checkMemoryWrites returns an error if the statements in fn between stmt and the uses write through a
+// pointer or call a function, which may change the memory read by the initial value of stmt. If the use is
+// in a loop after stmt, the whole loop is checked.
+func checkMemoryWrites(cf *checkedFile, fn ast.Node, stmt ast.Stmt, uses []*ast.Ident) error {
+	tf := cf.Fset.File(cf.File.Pos())
+
+	var limit token.Pos
+	for _, use := range uses {
+		end := use.Pos()
+		usePath, _ := astutil.PathEnclosingInterval(cf.File, use.Pos(), use.End())
+		for _, n := range usePath {
+			switch n.(type) {
+			case *ast.ForStmt, *ast.RangeStmt:
+				if n.Pos() > stmt.Pos() && n.End() > end {
+					end = n.End()
+				}
+			}
+		}
+		if end > limit {
+			limit = end
+		}
+	}
+
+	var err error
+	ast.Inspect(fn, func(n ast.Node) bool {
+		if n == nil || err != nil || n.End() <= stmt.End() || n.Pos() >= limit {
+			return false
+		}
+		if n.Pos() < stmt.End() || n.End() > limit {
+			return true // the enclosing node, such as the statement which contains the use
+		}
+
+		switch n := n.(type) {
+		case *ast.AssignStmt:
+			for _, lhs := range n.Lhs {
+				if writesMemory(cf.Info, lhs) {
+					err = errors.Errorf("the initial value reads the memory which is written at line %d", tf.Line(n.Pos()))
+				}
+			}
+		case *ast.IncDecStmt:
+			if writesMemory(cf.Info, n.X) {
+				err = errors.Errorf("the initial value reads the memory which is written at line %d", tf.Line(n.Pos()))
+			}
+		case ast.Expr:
+			if hasSideEffects(cf.Info, n) {
+				err = errors.Errorf("the initial value reads the memory which may be changed by the function call at line %d", tf.Line(n.Pos()))
+			}
+		}
+		return err == nil
+	})
+
+	return err
+}
+
+// readsMemory reports whether x reads the memory which can be changed without assigning the variables in x,
+// such as the pointer indirection, the field selector through the pointer and the index of the slice or map.
+func readsMemory(info *types.Info, x ast.Expr) bool {
+	var found bool
+	ast.Inspect(x, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.FuncLit:
+			return false
+		case ast.Expr:
+			found = isMemoryAccess(info, n)
+		}
+		return !found
+	})
+
+	return found
+}
+
+// writesMemory reports whether the assignment to lhs writes the memory through the pointer, slice or map.
+func writesMemory(info *types.Info, lhs ast.Expr) bool {
+	for {
+		if isMemoryAccess(info, lhs) {
+			return true
+		}
+		switch e := lhs.(type) {
+		case *ast.ParenExpr:
+			lhs = e.X
+		case *ast.SelectorExpr:
+			lhs = e.X
+		case *ast.IndexExpr:
+			lhs = e.X
+		default:
+			return false
+		}
+	}
+}
+
+// isMemoryAccess reports whether x is the pointer indirection, the field selector through the pointer,
+// or the index of the slice, map or pointer to array.
+func isMemoryAccess(info *types.Info, x ast.Expr) bool {
+	switch x := x.(type) {
+	case *ast.StarExpr:
+		if tv, ok := info.Types[x.X]; ok && !tv.IsType() {
+			return true
+		}
+	case *ast.SelectorExpr:
+		if sel, ok := info.Selections[x]; ok && sel.Kind() == types.FieldVal && sel.Indirect() {
+			return true
+		}
+	case *ast.IndexExpr:
+		if tv, ok := info.Types[x.X]; ok {
+			switch t := tv.Type.Underlying().(type) {
+			case *types.Slice, *types.Map:
+				return true
+			case *types.Pointer:
+				_, ok := t.Elem().Underlying().(*types.Array)
+				return ok
+			}
+		}
+	}
+
+	return false
+}
+
+// isLexical reports whether the obj is the object which is resolved by the lexical scope of the
+// current package, such as the local variable, the package level object and the universe object.
+func isLexical(cf *checkedFile, obj types.Object) bool {
+	switch obj := obj.(type) {
+	case nil:
+		return false
+	case *types.Var:
+		if obj.IsField() {
+			return false
+		}
+	case *types.Func:
+		if obj.Type().(*types.Signature).Recv() != nil {
+			return false // method
+		}
+	}
+
+	return obj.Pkg() == nil || obj.Pkg() == cf.Pkg
+}
+
+// hasSideEffects reports whether the n contains the function call or the channel receive, excluding
+// the conversions and the pure builtin functions.
+func hasSideEffects(info *types.Info, n ast.Node) bool {
+	var found bool
+	ast.Inspect(n, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.FuncLit:
+			return false
+		case *ast.CallExpr:
+			if tv, ok := info.Types[n.Fun]; ok && tv.IsType() {
+				break // conversion
+			}
+			if id, ok := astutil.Unparen(n.Fun).(*ast.Ident); ok {
+				if b, ok := info.Uses[id].(*types.Builtin); ok {
+					switch b.Name() {
+					case "len", "cap", "complex", "real", "imag":
+						return true
+					}
+				}
+			}
+			found = true
+		case *ast.UnaryExpr:
+			if n.Op == token.ARROW {
+				found = true
+			}
+		}
+		return !found
+	})
+
+	return found
+}
+
+// isOperand reports whether the x is the operand or the primary expression, which does not need the
+// parentheses in any expression.
+func isOperand(x ast.Expr) bool {
+	switch x.(type) {
+	case *ast.Ident, *ast.BasicLit, *ast.CompositeLit, *ast.ParenExpr, *ast.SelectorExpr, *ast.IndexExpr,
+		*ast.SliceExpr, *ast.TypeAssertExpr, *ast.CallExpr:
+		return true
+	}
+
+	return false
+}
+
+// needsParen reports whether the x replacing the child of parent needs the parentheses.
+func needsParen(x ast.Expr, parent ast.Node, child ast.Node) bool {
+	if isOperand(x) {
+		return false
+	}
+
+	switch p := parent.(type) {
+	case *ast.CallExpr:
+		return p.Fun == child
+	case *ast.IndexExpr:
+		return p.X == child
+	case *ast.SliceExpr:
+		return p.X == child
+	case *ast.CompositeLit, *ast.KeyValueExpr, *ast.ParenExpr:
+		return false
+	case ast.Expr:
+		return true
+	}
+
+	return false
+}
+
+// stmtRange returns the range of the statement between start and end offset, which includes the
+// whole lines if the statement occupies them.
+func stmtRange(src []byte, start, end int) (int, int) {
+	ls := bytes.LastIndexByte(src[:start], '\n') + 1
+	if len(bytes.TrimSpace(src[ls:start])) != 0 {
+		return start, end
+	}
+	le := bytes.IndexByte(src[end:], '\n')
+	if le < 0 || len(bytes.TrimSpace(src[end:end+le])) != 0 {
+		return start, end
+	}
+
+	return ls, end + le + 1
+}
+
+// inlineCall returns the edits which replace the call path[0] with the body of the called function.
+func inlineCall(cf *checkedFile, src []byte, path []ast.Node) ([]textEdit, error) {
+	tf := cf.Fset.File(cf.File.Pos())
+	call := path[0].(*ast.CallExpr)
+
+	var (
+		fn   *types.Func
+		recv ast.Expr
+	)
+	switch f := astutil.Unparen(call.Fun).(type) {
+	case *ast.Ident:
+		fn, _ = cf.Info.Uses[f].(*types.Func)
+	case *ast.SelectorExpr:
+		if sel, ok := cf.Info.Selections[f]; ok {
+			if sel.Kind() != types.MethodVal {
+				return nil, errors.New("it is not the method call")
+			}
+			if len(sel.Index()) > 1 {
+				return nil, errors.New("it is the promoted method")
+			}
+			fn, recv = sel.Obj().(*types.Func), f.X
+		} else {
+			fn, _ = cf.Info.Uses[f.Sel].(*types.Func)
+		}
+	}
+	if fn == nil {
+		return nil, errors.New("it is not the function")
+	}
+	if fn.Pkg() != cf.Pkg {
+		return nil, errors.New("it is not declared in the current package")
+	}
+
+	var decl *ast.FuncDecl
+	for _, f := range cf.Files {
+		for _, d := range f.Decls {
+			if fd, ok := d.(*ast.FuncDecl); ok && cf.Info.Defs[fd.Name] == fn {
+				decl = fd
+			}
+		}
+	}
+	if decl == nil || decl.Body == nil {
+		return nil, errors.New("the function body is not found")
+	}
+
+	sig := fn.Type().(*types.Signature)
+	if sig.Variadic() || call.Ellipsis.IsValid() {
+		return nil, errors.New("it is the variadic function")
+	}
+	if len(call.Args) != sig.Params().Len() {
+		return nil, errors.New("the arguments are the multiple values")
+	}
+	if err := checkInlinable(cf, decl); err != nil {
+		return nil, err
+	}
+
+	calleeFile := cf.Fset.File(decl.Pos())
+	calleeSrc := src
+	if calleeFile != tf {
+		var err error
+		if calleeSrc, err = ioutil.ReadFile(calleeFile.Name()); err != nil {
+			return nil, errors.WithStack(err)
+		}
+	}
+
+	// bind the parameters to the arguments
+	qf, missing := fileQualifier(cf)
+	var (
+		params []*types.Var
+		args   []ast.Expr
+	)
+	if recv != nil {
+		params, args = append(params, sig.Recv()), append(args, recv)
+	}
+	for i := 0; i < sig.Params().Len(); i++ {
+		params, args = append(params, sig.Params().At(i)), append(args, call.Args[i])
+	}
+	argTexts := make(map[types.Object]string, len(params))
+	for i, param := range params {
+		arg := args[i]
+		text := string(src[tf.Offset(arg.Pos()):tf.Offset(arg.End())])
+		argType := cf.Info.TypeOf(arg)
+		_, argPtr := argType.Underlying().(*types.Pointer)
+		_, paramPtr := param.Type().Underlying().(*types.Pointer)
+		switch {
+		case i == 0 && recv != nil && paramPtr && !argPtr:
+			text = "&" + parenText(arg, text)
+		case i == 0 && recv != nil && !paramPtr && argPtr:
+			text = "*" + parenText(arg, text)
+		case !types.Identical(argType, param.Type()):
+			text = fmt.Sprintf("%s(%s)", types.TypeString(param.Type(), qf), text)
+		}
+		argTexts[param] = text
+	}
+
+	var (
+		edits []textEdit
+		err   error
+	)
+	body := decl.Body.List
+	switch {
+	case len(body) == 1 && sig.Results().Len() == 1:
+		ret, ok := body[0].(*ast.ReturnStmt)
+		if !ok || len(ret.Results) != 1 {
+			return nil, errors.New("the function body must be the single return statement")
+		}
+		edits, err = inlineExpr(cf, calleeSrc, decl, path, params, args, argTexts, ret.Results[0], sig.Results().At(0).Type(), qf)
+	case sig.Results().Len() == 0:
+		if _, ok := path[1].(*ast.ExprStmt); !ok {
+			return nil, errors.New("the call is not the statement")
+		}
+		edits, err = inlineStmts(cf, calleeSrc, path, params, args, argTexts, decl)
+	default:
+		return nil, errors.New("the function body must be the single return statement, or have no results")
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(*missing) > 0 {
+		return nil, errors.Errorf("it requires the import of %q", (*missing)[0])
+	}
+
+	return edits, nil
+}
+
+// checkInlinable returns an error if the function body can not be moved into the caller.
+func checkInlinable(cf *checkedFile, decl *ast.FuncDecl) error {
+	var err error
+	ast.Inspect(decl.Body, func(n ast.Node) bool {
+		if err != nil {
+			return false
+		}
+		switch n := n.(type) {
+		case *ast.FuncLit:
+			return false
+		case *ast.DeferStmt:
+			err = errors.New("the function body contains the defer statement")
+		case *ast.LabeledStmt:
+			err = errors.New("the function body contains the label")
+		case *ast.CallExpr:
+			if id, ok := astutil.Unparen(n.Fun).(*ast.Ident); ok {
+				if cf.Info.Uses[id] == cf.Info.Defs[decl.Name] {
+					err = errors.New("it is the recursive function")
+				} else if b, ok := cf.Info.Uses[id].(*types.Builtin); ok && b.Name() == "recover" {
+					err = errors.New("the function body calls recover")
+				}
+			}
+		}
+		return err == nil
+	})
+
+	return err
+}
+
+// parenText returns the text of x with the parentheses if x is not the operand.
+func parenText(x ast.Expr, text string) string {
+	if !isOperand(x) {
+		return "(" + text + ")"
+	}
+	return text
+}
+
+// substitute returns the text of the callee node n, which replaces the parameters with the arguments.
+// It returns an error if the identifiers in n refer to the other objects at the call site.
+func substitute(cf *checkedFile, calleeSrc []byte, decl *ast.FuncDecl, n ast.Node, callPos token.Pos, argTexts map[types.Object]string, uses map[types.Object][]token.Pos) (string, error) {
+	tf := cf.Fset.File(n.Pos())
+	base := tf.Offset(n.Pos())
+	scope := cf.Pkg.Scope().Innermost(callPos)
+
+	var (
+		edits []textEdit
+		err   error
+		stack []ast.Node
+	)
+	ast.Inspect(n, func(node ast.Node) bool {
+		if node == nil {
+			stack = stack[:len(stack)-1]
+			return true
+		}
+		defer func() { stack = append(stack, node) }()
+
+		id, ok := node.(*ast.Ident)
+		if !ok || err != nil {
+			return err == nil
+		}
+		obj := cf.Info.Uses[id]
+		if text, ok := argTexts[obj]; ok {
+			uses[obj] = append(uses[obj], id.Pos())
+			var parent ast.Node
+			if len(stack) > 0 {
+				parent = stack[len(stack)-1]
+			}
+			if x, err := parser.ParseExpr(text); err != nil || needsParen(x, parent, id) {
+				text = "(" + text + ")"
+			}
+			edits = append(edits, textEdit{start: tf.Offset(id.Pos()) - base, end: tf.Offset(id.End()) - base, text: text})
+			return true
+		}
+		if !isLexical(cf, obj) || (decl.Pos() <= obj.Pos() && obj.Pos() < decl.End()) {
+			return true // declared in the function
+		}
+		_, o := scope.LookupParent(id.Name, callPos)
+		if pkgName, ok := obj.(*types.PkgName); ok {
+			if p, ok := o.(*types.PkgName); !ok || p.Imported() != pkgName.Imported() {
+				err = errors.Errorf("it requires the import of %q as %s", pkgName.Imported().Path(), pkgName.Name())
+			}
+			return true
+		}
+		if o != obj {
+			err = errors.Errorf("%s refers to the other object at the call site", id.Name)
+		}
+		return true
+	})
+	if err != nil {
+		return "", err
+	}
+
+	text := calleeSrc[base:tf.Offset(n.End())]
+	return string(applyTextEdits(text, edits)), nil
+}
+
+// inlineExpr returns the edits which replace the call with the result expression of the function.
+func inlineExpr(cf *checkedFile, calleeSrc []byte, decl *ast.FuncDecl, path []ast.Node, params []*types.Var, args []ast.Expr, argTexts map[types.Object]string, result ast.Expr, resultType types.Type, qf types.Qualifier) ([]textEdit, error) {
+	tf := cf.Fset.File(cf.File.Pos())
+	call := path[0].(*ast.CallExpr)
+
+	uses := make(map[types.Object][]token.Pos)
+	text, err := substitute(cf, calleeSrc, decl, result, call.Pos(), argTexts, uses)
+	if err != nil {
+		return nil, err
+	}
+
+	// the arguments are evaluated before the function body, so the arguments which have side effects
+	// must be evaluated in the same order, exactly once, and before the side effects of the body
+	var last token.Pos
+	for i, param := range params {
+		if !hasSideEffects(cf.Info, args[i]) {
+			continue
+		}
+		if len(uses[param]) != 1 {
+			return nil, errors.Errorf("the argument for %s has side effects, and it is used %d times", param.Name(), len(uses[param]))
+		}
+		if uses[param][0] < last {
+			return nil, errors.New("inlining reorders the side effects of the arguments")
+		}
+		last = uses[param][0]
+		if hasSideEffects(cf.Info, result) {
+			return nil, errors.New("inlining reorders the side effects of the arguments and the function body")
+		}
+	}
+
+	if !types.Identical(cf.Info.TypeOf(result), resultType) {
+		text = fmt.Sprintf("%s(%s)", types.TypeString(resultType, qf), text)
+	} else if x, err := parser.ParseExpr(text); err != nil || needsParen(x, path[1], call) {
+		text = "(" + text + ")"
+	}
+
+	return []textEdit{{start: tf.Offset(call.Pos()), end: tf.Offset(call.End()), text: text}}, nil
+}
+
+// inlineStmts returns the edits which replace the call statement with the block of the function body.
+// The parameters are declared at the beginning of the block.
+func inlineStmts(cf *checkedFile, calleeSrc []byte, path []ast.Node, params []*types.Var, args []ast.Expr, argTexts map[types.Object]string, decl *ast.FuncDecl) ([]textEdit, error) {
+	tf := cf.Fset.File(cf.File.Pos())
+	call := path[0].(*ast.CallExpr)
+
+	var err error
+	ast.Inspect(decl.Body, func(n ast.Node) bool {
+		switch n.(type) {
+		case *ast.FuncLit:
+			return false
+		case *ast.ReturnStmt:
+			err = errors.New("the function body contains the return statement")
+		}
+		return err == nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	used := make(map[types.Object]bool)
+	for _, obj := range cf.Info.Uses {
+		for _, param := range params {
+			if obj == param {
+				used[param] = true
+			}
+		}
+	}
+
+	// the body refers to the parameters declared in the block, so only check the other identifiers
+	body, err := substitute(cf, calleeSrc, decl, decl.Body, call.Pos(), nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	body = strings.TrimSuffix(strings.TrimPrefix(body, "{"), "}")
+
+	var lhs, rhs []string
+	var define bool
+	for i, param := range params {
+		name := param.Name()
+		if !used[param] || name == "_" || name == "" {
+			if !hasSideEffects(cf.Info, args[i]) {
+				continue
+			}
+			name = "_"
+		} else {
+			define = true
+		}
+		lhs, rhs = append(lhs, name), append(rhs, argTexts[param])
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString("{\n")
+	if len(lhs) > 0 {
+		op := "="
+		if define {
+			op = ":="
+		}
+		fmt.Fprintf(&buf, "%s %s %s\n", strings.Join(lhs, ", "), op, strings.Join(rhs, ", "))
+	}
+	buf.WriteString(strings.TrimSpace(body))
+	buf.WriteString("\n}")
+
+	stmt := path[1].(*ast.ExprStmt)
+	return []textEdit{{start: tf.Offset(stmt.Pos()), end: tf.Offset(stmt.End()), text: buf.String()}}, nil
+}
@@ -0,0 +1,195 @@
+// Copyright 2018 The nvim-go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package command
+
+import (
+	"strings"
+	"testing"
+)
+
+const inlineSrc = `package p
+
+import "fmt"
+
+type T struct{ n int }
+
+func (t *T) Get() int { return t.n }
+
+func add(a, b int) int { return a + b }
+
+func sub(a, b int) int { return b - a }
+
+func twice(s string) string { return s + s }
+
+func show(prefix string, n int) {
+	line := fmt.Sprint(prefix, n)
+	fmt.Println(line)
+}
+
+func next() int { return 1 }
+
+func f(n int) int {
+	x := n + 1
+	y := 3
+	z := next()
+	w := next()
+	var t T
+	m := n
+	m++
+	show("n", x*2)
+	r := add(x, y) * 2
+	r += int(twice("a")[0])
+	r += add(next(), next())
+	r -= sub(next(), next())
+	r += t.Get()
+	return r + y/2 + z + w + w + m
+}
+
+func g(p *int, s []int, t *T) {
+	a := *p
+	*p = 4
+	show("a", a)
+	b := s[0]
+	u := s
+	u[0] = 1
+	show("b", b)
+	c := t.n
+	show("", 0)
+	show("c", c)
+	d := *p
+	show("d", d)
+}
+`
+
+func TestInline(t *testing.T) {
+	file, _ := testCheckedFile(t, inlineSrc)
+
+	tests := []struct {
+		name    string
+		at      string // the cursor is placed at the beginning of the first at
+		want    []string
+		wantErr string
+	}{
+		{
+			name: "variable",
+			at:   "x := n + 1",
+			want: []string{"\tshow(\"n\", (n+1)*2)\n", "r := add(n+1, y) * 2\n"},
+		},
+		{
+			name: "untyped constant",
+			at:   "y := 3",
+			want: []string{"r := add(x, int(3)) * 2\n", "return r + int(3)/2 + z"},
+		},
+		{
+			name:    "side effects used twice",
+			at:      "w := next()",
+			wantErr: "used 2 times",
+		},
+		{
+			name:    "side effects not used in the next statement",
+			at:      "z := next()",
+			wantErr: "not used in the next statement",
+		},
+		{
+			name:    "reassigned",
+			at:      "m := n",
+			wantErr: "reassigned at line",
+		},
+		{
+			name: "function",
+			at:   "add(x, y)",
+			want: []string{"r := (x + y) * 2\n"},
+		},
+		{
+			name: "function with the duplicated pure argument",
+			at:   "twice(\"a\")",
+			want: []string{"r += int((\"a\" + \"a\")[0])\n"},
+		},
+		{
+			name: "side effects arguments",
+			at:   "add(next(), next())",
+			want: []string{"r += next() + next()\n"},
+		},
+		{
+			name:    "reordered side effects arguments",
+			at:      "sub(next(), next())",
+			wantErr: "reorders the side effects",
+		},
+		{
+			name:    "pointer indirection written",
+			at:      "a := *p",
+			wantErr: "written at line 41",
+		},
+		{
+			name:    "slice index written",
+			at:      "b := s[0]",
+			wantErr: "written at line 45",
+		},
+		{
+			name:    "pointer field with function call",
+			at:      "c := t.n",
+			wantErr: "function call at line 48",
+		},
+		{
+			name: "pointer indirection not written",
+			at:   "d := *p",
+			want: []string{"show(\"d\", *p)\n"},
+		},
+		{
+			name: "method",
+			at:   "Get()",
+			want: []string{"r += (&t).n\n"},
+		},
+		{
+			name: "statements",
+			at:   "show(\"n\"",
+			want: []string{"\t{\n\t\tprefix, n := \"n\", x*2\n\t\tline := fmt.Sprint(prefix, n)\n\t\tfmt.Println(line)\n\t}\n"},
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			offset := strings.Index(inlineSrc, tt.at)
+			if tt.name == "method" {
+				offset = strings.LastIndex(inlineSrc, tt.at)
+			}
+			got, err := inline(file, []byte(inlineSrc), offset)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("inline() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(string(got), want) {
+					t.Errorf("inline() =\n%s\nwant contains\n%s", got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestStmtRange(t *testing.T) {
+	src := []byte("a := 1\n\tb := 2\nc(); d()\n")
+	tests := []struct {
+		start, end int
+		wantStart  int
+		wantEnd    int
+	}{
+		{start: 0, end: 6, wantStart: 0, wantEnd: 7},
+		{start: 8, end: 14, wantStart: 7, wantEnd: 15},
+		{start: 15, end: 18, wantStart: 15, wantEnd: 18},
+	}
+	for _, tt := range tests {
+		if start, end := stmtRange(src, tt.start, tt.end); start != tt.wantStart || end != tt.wantEnd {
+			t.Errorf("stmtRange(%d, %d) = (%d, %d), want (%d, %d)", tt.start, tt.end, start, end, tt.wantStart, tt.wantEnd)
+		}
+	}
+}
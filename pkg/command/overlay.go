@@ -0,0 +1,48 @@
+// Copyright 2018 The nvim-go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package command
+
+import (
+	"strings"
+
+	"github.com/neovim/go-client/nvim"
+
+	"github.com/zchee/nvim-go/pkg/nvimutil"
+)
+
+// bufferInfo represents the part of getbufinfo() result.
+type bufferInfo struct {
+	BufNr int    `msgpack:"bufnr"`
+	Name  string `msgpack:"name"`
+}
+
+// modifiedOverlay returns the contents of the modified Go buffers, keyed by the file name.
+// The result is used as the overlay of the build context, so the analysis sees the unsaved changes.
+func (c *Command) modifiedOverlay() (map[string][]byte, error) {
+	var infos []bufferInfo
+	if err := c.Nvim.Call("getbufinfo", &infos, map[string]interface{}{"bufmodified": 1}); err != nil {
+		return nil, err
+	}
+
+	batch := c.Nvim.NewBatch()
+	lines := make([][][]byte, len(infos))
+	for i, info := range infos {
+		if strings.HasSuffix(info.Name, ".go") {
+			batch.BufferLines(nvim.Buffer(info.BufNr), 0, -1, true, &lines[i])
+		}
+	}
+	if err := batch.Execute(); err != nil {
+		return nil, err
+	}
+
+	overlay := make(map[string][]byte)
+	for i, info := range infos {
+		if strings.HasSuffix(info.Name, ".go") {
+			overlay[info.Name] = append(nvimutil.ToByteSlice(lines[i]), '\n')
+		}
+	}
+
+	return overlay, nil
+}
@@ -0,0 +1,248 @@
+// Copyright 2018 The nvim-go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package command
+
+import (
+	"go/build"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/mod/semver"
+
+	"github.com/zchee/nvim-go/pkg/internal/gopathwalk"
+)
+
+// packageCacheTTL is the lifetime of the package list cache.
+const packageCacheTTL = 5 * time.Minute
+
+// goPackage represents a Go package found on the GOROOT, GOPATH or module cache.
+type goPackage struct {
+	ImportPath string
+	Dir        string
+	// Version module version of the package. Empty if the package is not in the module cache.
+	Version string
+}
+
+// WarmPackages starts loading the package list of the GoDocBrowse and GoCallGraph completion in the background.
+func (c *Command) WarmPackages() {
+	c.packages.Warm()
+}
+
+// packageCache caches the package list which is found by gopathwalk.
+type packageCache struct {
+	mu       sync.Mutex
+	pkgs     []*goPackage
+	loadedAt time.Time
+	// loading reports whether the package list is being loaded in the background.
+	loading bool
+}
+
+// Packages returns the all packages in the GOROOT, GOPATH and module cache.
+// The package list is cached for the packageCacheTTL.
+//
+// Packages walks the directories if the cache is stale. Use CachedPackages in the completion callback.
+func (pc *packageCache) Packages() []*goPackage {
+	pc.mu.Lock()
+	if !pc.isStale() {
+		defer pc.mu.Unlock()
+		return pc.pkgs
+	}
+	pc.mu.Unlock()
+
+	// do not hold pc.mu while walking so as not to block CachedPackages
+	pkgs := findPackages(packageRoots(&build.Default))
+
+	pc.mu.Lock()
+	defer pc.mu.Unlock()
+	pc.pkgs, pc.loadedAt = pkgs, time.Now()
+
+	return pkgs
+}
+
+// CachedPackages returns the cached package list without walking the directories, and starts reloading it in the
+// background if the cache is stale. Returns nil until the first walk is finished.
+func (pc *packageCache) CachedPackages() []*goPackage {
+	pc.mu.Lock()
+	defer pc.mu.Unlock()
+
+	pc.warm()
+
+	return pc.pkgs
+}
+
+// Warm starts loading the package list in the background if the cache is stale.
+func (pc *packageCache) Warm() {
+	pc.mu.Lock()
+	defer pc.mu.Unlock()
+
+	pc.warm()
+}
+
+// warm starts loading the package list in the background if the cache is stale and is not loading.
+// pc.mu must be held.
+func (pc *packageCache) warm() {
+	if pc.loading || !pc.isStale() {
+		return
+	}
+	pc.loading = true
+
+	go func() {
+		pkgs := findPackages(packageRoots(&build.Default))
+
+		pc.mu.Lock()
+		defer pc.mu.Unlock()
+		pc.pkgs, pc.loadedAt, pc.loading = pkgs, time.Now(), false
+	}()
+}
+
+// isStale reports whether the package list is not loaded or is expired.
+// pc.mu must be held.
+func (pc *packageCache) isStale() bool {
+	return pc.pkgs == nil || time.Since(pc.loadedAt) > packageCacheTTL
+}
+
+// packageRoots returns the gopathwalk roots of GOROOT, GOPATH and module cache.
+func packageRoots(ctxt *build.Context) []gopathwalk.Root {
+	roots := gopathwalk.SrcDirsRoots(ctxt)
+	if modCache := moduleCacheDir(ctxt); modCache != "" {
+		roots = append(roots, gopathwalk.Root{Path: modCache, Type: gopathwalk.RootModuleCache})
+	}
+
+	return roots
+}
+
+// moduleCacheDir returns the module cache directory.
+func moduleCacheDir(ctxt *build.Context) string {
+	if dir := os.Getenv("GOMODCACHE"); dir != "" {
+		return dir
+	}
+	gopath := filepath.SplitList(ctxt.GOPATH)
+	if len(gopath) == 0 {
+		return ""
+	}
+
+	return filepath.Join(gopath[0], "pkg", "mod")
+}
+
+// findPackages walks roots and returns the found packages sorted by import path.
+// If the same import path is found in several module versions, the greatest semantic version is used.
+func findPackages(roots []gopathwalk.Root) []*goPackage {
+	var mu sync.Mutex
+	found := make(map[string]*goPackage)
+
+	add := func(root gopathwalk.Root, dir string) {
+		pkg := packageOfDir(root, dir)
+		if pkg == nil {
+			return
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+		if prev, ok := found[pkg.ImportPath]; !ok || semver.Compare(prev.Version, pkg.Version) < 0 {
+			found[pkg.ImportPath] = pkg
+		}
+	}
+	gopathwalk.Walk(roots, add, gopathwalk.Options{ModulesEnabled: true})
+
+	pkgs := make([]*goPackage, 0, len(found))
+	for _, pkg := range found {
+		pkgs = append(pkgs, pkg)
+	}
+	sort.Slice(pkgs, func(i, j int) bool { return pkgs[i].ImportPath < pkgs[j].ImportPath })
+
+	return pkgs
+}
+
+// goDirs walks root and returns the directories which contain the Go files sorted by path.
+// The "testdata" and "vendor" directories and the directories prefixed by "." or "_" are skipped as same as findPackages.
+func goDirs(root string) []string {
+	var (
+		mu   sync.Mutex
+		dirs []string
+	)
+	add := func(_ gopathwalk.Root, dir string) {
+		if rel, err := filepath.Rel(root, dir); err != nil || isVendoredPath(filepath.ToSlash(rel)) {
+			return
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+		dirs = append(dirs, dir)
+	}
+	gopathwalk.Walk([]gopathwalk.Root{{Path: root, Type: gopathwalk.RootOther}}, add, gopathwalk.Options{ModulesEnabled: true})
+	sort.Strings(dirs)
+
+	return dirs
+}
+
+// isVendoredPath reports whether the slash separated rel path is in the "vendor" directory.
+func isVendoredPath(rel string) bool {
+	for _, elem := range strings.Split(rel, "/") {
+		if elem == "vendor" {
+			return true
+		}
+	}
+
+	return false
+}
+
+// packageOfDir returns the goPackage of dir in the root. Returns nil if dir is not importable.
+func packageOfDir(root gopathwalk.Root, dir string) *goPackage {
+	rel, err := filepath.Rel(root.Path, dir)
+	if err != nil || rel == "." {
+		return nil
+	}
+	rel = filepath.ToSlash(rel)
+
+	for _, elem := range strings.Split(rel, "/") {
+		if elem == "testdata" || elem == "vendor" {
+			return nil
+		}
+	}
+
+	pkg := &goPackage{ImportPath: rel, Dir: dir}
+	if root.Type == gopathwalk.RootModuleCache {
+		// "github.com/!foo/bar@v1.0.0/baz" -> "github.com/Foo/bar/baz"
+		i := strings.Index(rel, "@")
+		if i < 0 {
+			return nil
+		}
+		version := rel[i+1:]
+		suffix := ""
+		if j := strings.Index(version, "/"); j >= 0 {
+			version, suffix = version[:j], version[j:]
+		}
+		pkg.ImportPath = unescapeModulePath(rel[:i]) + suffix
+		pkg.Version = version
+		if strings.HasPrefix(pkg.ImportPath, "cache/") {
+			return nil
+		}
+	}
+
+	return pkg
+}
+
+// unescapeModulePath unescapes the module cache path which is encoded the upper case letter to "!" and lower case letter.
+func unescapeModulePath(path string) string {
+	var b strings.Builder
+	bang := false
+	for _, r := range path {
+		if r == '!' {
+			bang = true
+			continue
+		}
+		if bang && 'a' <= r && r <= 'z' {
+			r -= 'a' - 'A'
+		}
+		bang = false
+		b.WriteRune(r)
+	}
+
+	return b.String()
+}
@@ -0,0 +1,123 @@
+// Copyright 2018 The nvim-go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package command
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/zchee/nvim-go/pkg/internal/gopathwalk"
+)
+
+func TestPackageOfDir(t *testing.T) {
+	gopath := gopathwalk.Root{Path: filepath.FromSlash("/go/src"), Type: gopathwalk.RootGOPATH}
+	modCache := gopathwalk.Root{Path: filepath.FromSlash("/go/pkg/mod"), Type: gopathwalk.RootModuleCache}
+
+	tests := []struct {
+		name string
+		root gopathwalk.Root
+		dir  string
+		want *goPackage
+	}{
+		{
+			name: "gopath",
+			root: gopath,
+			dir:  "/go/src/github.com/foo/bar",
+			want: &goPackage{ImportPath: "github.com/foo/bar", Dir: filepath.FromSlash("/go/src/github.com/foo/bar")},
+		},
+		{
+			name: "testdata",
+			root: gopath,
+			dir:  "/go/src/github.com/foo/bar/testdata/baz",
+		},
+		{
+			name: "vendor",
+			root: gopath,
+			dir:  "/go/src/github.com/foo/bar/vendor/github.com/baz/qux",
+		},
+		{
+			name: "root",
+			root: gopath,
+			dir:  "/go/src",
+		},
+		{
+			name: "module",
+			root: modCache,
+			dir:  "/go/pkg/mod/github.com/!foo/bar@v1.0.0",
+			want: &goPackage{ImportPath: "github.com/Foo/bar", Dir: filepath.FromSlash("/go/pkg/mod/github.com/!foo/bar@v1.0.0"), Version: "v1.0.0"},
+		},
+		{
+			name: "module subpackage",
+			root: modCache,
+			dir:  "/go/pkg/mod/github.com/foo/bar@v1.2.0/baz",
+			want: &goPackage{ImportPath: "github.com/foo/bar/baz", Dir: filepath.FromSlash("/go/pkg/mod/github.com/foo/bar@v1.2.0/baz"), Version: "v1.2.0"},
+		},
+		{
+			name: "module directory without version",
+			root: modCache,
+			dir:  "/go/pkg/mod/github.com/foo",
+		},
+		{
+			name: "download cache",
+			root: modCache,
+			dir:  "/go/pkg/mod/cache/download/github.com/foo/bar/@v",
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := packageOfDir(tt.root, filepath.FromSlash(tt.dir)); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("packageOfDir(%v, %q) = %+v, want %+v", tt.root, tt.dir, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUnescapeModulePath(t *testing.T) {
+	tests := []struct {
+		path string
+		want string
+	}{
+		{path: "github.com/foo/bar", want: "github.com/foo/bar"},
+		{path: "github.com/!burnt!sushi/toml", want: "github.com/BurntSushi/toml"},
+		{path: "github.com/!a!b!c", want: "github.com/ABC"},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.path, func(t *testing.T) {
+			t.Parallel()
+
+			if got := unescapeModulePath(tt.path); got != tt.want {
+				t.Errorf("unescapeModulePath(%q) = %q, want %q", tt.path, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFindPackagesVersion(t *testing.T) {
+	dir := t.TempDir()
+	for _, version := range []string{"v1.9.0", "v1.10.0", "v1.10.0-rc.1"} {
+		pkgDir := filepath.Join(dir, "github.com", "foo", "bar@"+version)
+		if err := os.MkdirAll(pkgDir, 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(filepath.Join(pkgDir, "bar.go"), []byte("package bar\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	pkgs := findPackages([]gopathwalk.Root{{Path: dir, Type: gopathwalk.RootModuleCache}})
+	if len(pkgs) != 1 {
+		t.Fatalf("findPackages found %d packages, want 1", len(pkgs))
+	}
+	if got, want := pkgs[0].Version, "v1.10.0"; got != want {
+		t.Errorf("findPackages version = %q, want %q", got, want)
+	}
+}
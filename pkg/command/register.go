@@ -23,13 +23,93 @@ func Register(ctx context.Context, p *plugin.Plugin, bctxt *buildctxt.Context) *
 
 	// CommandOptions order:
 	//  Name, NArgs, Range, Count, Addr, Bang, Register, Eval, Bar, Complete
+	p.HandleCommand(&plugin.CommandOptions{Name: "GoAddTags", NArgs: "*", Range: ".", Eval: "expand('%:p')"},
+		func(args []string, ranges [2]int, file string) {
+			c.cmdAddTags(ctx, args, ranges, file)
+		})
+	p.HandleCommand(&plugin.CommandOptions{Name: "GoAnalyze", Eval: "*"},
+		func(eval *cmdAnalyzeEval) {
+			c.cmdAnalyze(ctx, eval)
+		})
+	p.HandleFunction(&plugin.FunctionOptions{Name: "GoAnalyzeAction", Eval: "line('.')"},
+		func(args []string, line int) {
+			c.funcAnalyzeAction(ctx, args, line)
+		})
+	p.HandleCommand(&plugin.CommandOptions{Name: "GoAstView", Eval: "*"},
+		func(eval *cmdAstViewEval) {
+			c.cmdAstView(ctx, eval)
+		})
+	p.HandleFunction(&plugin.FunctionOptions{Name: "GoAstViewAction", Eval: "line('.')"},
+		func(args []string, line int) {
+			c.funcAstViewAction(ctx, args, line)
+		})
 	p.HandleCommand(&plugin.CommandOptions{Name: "GoBuild", NArgs: "*", Bang: true, Eval: "[getcwd(), expand('%:p')]"},
 		func(args []string, bang bool, eval *CmdBuildEval) {
 			c.cmdBuild(ctx, args, bang, eval)
 		})
-	p.HandleCommand(&plugin.CommandOptions{Name: "GoCover", Eval: "[getcwd(), expand('%:p')]"},
-		func(eval *cmdCoverEval) {
-			c.cmdCover(ctx, eval)
+	p.HandleCommand(&plugin.CommandOptions{Name: "GoCallGraph", NArgs: "*", Eval: "*", Complete: "customlist,GoCallGraphCompletion"},
+		func(args []string, eval *cmdCallGraphEval) {
+			c.cmdCallGraph(ctx, args, eval)
+		})
+	p.HandleCommand(&plugin.CommandOptions{Name: "GoCallHierarchy", NArgs: "?", Eval: "*"},
+		func(args []string, eval *cmdCallHierarchyEval) {
+			c.cmdCallHierarchy(ctx, args, eval)
+		})
+	p.HandleFunction(&plugin.FunctionOptions{Name: "GoCallHierarchyAction", Eval: "line('.')"},
+		func(args []string, line int) {
+			c.funcCallHierarchyAction(ctx, args, line)
+		})
+	p.HandleCommand(&plugin.CommandOptions{Name: "GoCancel"},
+		func() {
+			c.cmdCancel(ctx)
+		})
+	p.HandleCommand(&plugin.CommandOptions{Name: "GoCover", NArgs: "?", Eval: "[getcwd(), expand('%:p')]"},
+		func(args []string, eval *cmdCoverEval) {
+			c.cmdCover(ctx, args, eval)
+		})
+	p.HandleCommand(&plugin.CommandOptions{Name: "GoDeadCode", Eval: "*"},
+		func(eval *cmdDeadCodeEval) {
+			c.cmdDeadCode(ctx, eval)
+		})
+	p.HandleCommand(&plugin.CommandOptions{Name: "GoDefPop", NArgs: "?"},
+		func(args []string) {
+			c.cmdDefPop(ctx, args)
+		})
+	p.HandleCommand(&plugin.CommandOptions{Name: "GoDefStack", Eval: "getcwd()"},
+		func(cwd string) {
+			c.cmdDefStack(ctx, cwd)
+		})
+	p.HandleCommand(&plugin.CommandOptions{Name: "GoDoc", NArgs: "?", Eval: "*", Complete: "customlist,GoDocCompletion"},
+		func(args []string, eval *cmdDocEval) {
+			c.cmdDoc(ctx, args, eval)
+		})
+	p.HandleCommand(&plugin.CommandOptions{Name: "GoDocBrowse", NArgs: "?", Eval: "*", Complete: "customlist,GoDocBrowseCompletion"},
+		func(args []string, eval *cmdDocBrowseEval) {
+			c.cmdDocBrowse(ctx, args, eval)
+		})
+	p.HandleFunction(&plugin.FunctionOptions{Name: "GoDocBrowseAction", Eval: "[line('.'), col('.')]"},
+		func(args []string, eval *funcDocBrowseActionEval) {
+			c.funcDocBrowseAction(ctx, args, eval)
+		})
+	p.HandleCommand(&plugin.CommandOptions{Name: "GoHover", Eval: "*"},
+		func(eval *CmdHoverEval) {
+			c.cmdHover(ctx, eval)
+		})
+	p.HandleFunction(&plugin.FunctionOptions{Name: "GoHoverJump"},
+		func() {
+			c.funcHoverJump(ctx)
+		})
+	p.HandleCommand(&plugin.CommandOptions{Name: "GoDocComment", Bang: true, Eval: "*"},
+		func(bang bool, eval *cmdDocCommentEval) {
+			c.cmdDocComment(ctx, bang, eval)
+		})
+	p.HandleCommand(&plugin.CommandOptions{Name: "GoExtractFunc", NArgs: "1", Range: ".", Eval: "*"},
+		func(args []string, ranges [2]int, eval *cmdExtractEval) {
+			c.cmdExtractFunc(ctx, args, ranges, eval)
+		})
+	p.HandleCommand(&plugin.CommandOptions{Name: "GoExtractVar", NArgs: "1", Range: ".", Eval: "*"},
+		func(args []string, ranges [2]int, eval *cmdExtractEval) {
+			c.cmdExtractVar(ctx, args, ranges, eval)
 		})
 	p.HandleCommand(&plugin.CommandOptions{Name: "GoFmt", Eval: "expand('%:p:h')"},
 		func(dir string) {
@@ -39,7 +119,7 @@ func Register(ctx context.Context, p *plugin.Plugin, bctxt *buildctxt.Context) *
 		func(args []string, ranges [2]int, bang bool, dir string) {
 			c.cmdGenerateTest(ctx, args, ranges, bang, dir)
 		})
-	p.HandleFunction(&plugin.FunctionOptions{Name: "GoGuru", Eval: "[getcwd(), expand('%:p'), &modified, line2byte(line('.')) + (col('.')-2)]"},
+	p.HandleFunction(&plugin.FunctionOptions{Name: "GoGuru", Eval: "[getcwd(), expand('%:p'), line2byte(line('.')) + (col('.')-2)]"},
 		func(args []string, eval *funcGuruEval) {
 			c.funcGuru(ctx, args, eval)
 		})
@@ -47,6 +127,18 @@ func Register(ctx context.Context, p *plugin.Plugin, bctxt *buildctxt.Context) *
 		func(file string) {
 			c.cmdIferr(ctx, file)
 		})
+	p.HandleCommand(&plugin.CommandOptions{Name: "GoImports", NArgs: "1", Eval: "*", Complete: "customlist,GoImportsCompletion"},
+		func(args []string, eval *cmdImportsEval) {
+			c.cmdImports(ctx, args, eval)
+		})
+	p.HandleFunction(&plugin.FunctionOptions{Name: "GoImportsAction", Eval: "line('.')"},
+		func(args []string, line int) {
+			c.funcImportsAction(ctx, args, line)
+		})
+	p.HandleCommand(&plugin.CommandOptions{Name: "GoInline", Eval: "*"},
+		func(eval *cmdInlineEval) {
+			c.cmdInline(ctx, eval)
+		})
 	p.HandleCommand(&plugin.CommandOptions{Name: "GoLint", NArgs: "?", Eval: "expand('%:p')", Complete: "customlist,GoLintCompletion"},
 		func(args []string, file string) {
 			c.cmdLint(ctx, args, file)
@@ -55,10 +147,18 @@ func Register(ctx context.Context, p *plugin.Plugin, bctxt *buildctxt.Context) *
 		func(cwd string) {
 			c.cmdMetalinter(ctx, cwd)
 		})
+	p.HandleCommand(&plugin.CommandOptions{Name: "GoRemoveTags", NArgs: "*", Range: ".", Eval: "expand('%:p')"},
+		func(args []string, ranges [2]int, file string) {
+			c.cmdRemoveTags(ctx, args, ranges, file)
+		})
 	p.HandleCommand(&plugin.CommandOptions{Name: "GoRename", NArgs: "?", Bang: true, Eval: "[getcwd(), expand('%:p'), expand('<cword>')]"},
 		func(args []string, bang bool, eval *cmdRenameEval) {
 			c.cmdRename(ctx, args, bang, eval)
 		})
+	p.HandleFunction(&plugin.FunctionOptions{Name: "GoRenameApply"},
+		func() {
+			c.funcRenameApply(ctx)
+		})
 	p.HandleCommand(&plugin.CommandOptions{Name: "GoRun", NArgs: "*", Eval: "expand('%:p')"},
 		func(args []string, file string) {
 			c.cmdRun(ctx, args, file)
@@ -67,6 +167,22 @@ func Register(ctx context.Context, p *plugin.Plugin, bctxt *buildctxt.Context) *
 		func(file string) {
 			c.cmdRunLast(ctx, file)
 		})
+	p.HandleCommand(&plugin.CommandOptions{Name: "GoSameIdsToggle", Eval: "*"},
+		func(eval *cmdSameIdsEval) {
+			c.cmdSameIdsToggle(ctx, eval)
+		})
+	p.HandleCommand(&plugin.CommandOptions{Name: "GoSameIdsNext", Eval: "*"},
+		func(eval *cmdSameIdsEval) {
+			c.cmdSameIdsJump(ctx, eval, false)
+		})
+	p.HandleCommand(&plugin.CommandOptions{Name: "GoSameIdsPrev", Eval: "*"},
+		func(eval *cmdSameIdsEval) {
+			c.cmdSameIdsJump(ctx, eval, true)
+		})
+	p.HandleCommand(&plugin.CommandOptions{Name: "GoSymbols", NArgs: "?", Eval: "*"},
+		func(args []string, eval *cmdSymbolsEval) {
+			c.cmdSymbols(ctx, args, eval)
+		})
 	p.HandleCommand(&plugin.CommandOptions{Name: "GoTest", NArgs: "*", Eval: "expand('%:p:h')"},
 		func(args []string, dir string) {
 			c.cmdTest(ctx, args, dir)
@@ -75,12 +191,46 @@ func Register(ctx context.Context, p *plugin.Plugin, bctxt *buildctxt.Context) *
 		func(eval *cmdTestSwitchEval) {
 			c.SwitchTest(ctx, eval)
 		})
+	p.HandleCommand(&plugin.CommandOptions{Name: "GoTypeHierarchy", Eval: "*"},
+		func(eval *cmdTypeHierarchyEval) {
+			c.cmdTypeHierarchy(ctx, eval)
+		})
+	p.HandleFunction(&plugin.FunctionOptions{Name: "GoTypeHierarchyAction", Eval: "line('.')"},
+		func(args []string, line int) {
+			c.funcTypeHierarchyAction(ctx, args, line)
+		})
+	p.HandleCommand(&plugin.CommandOptions{Name: "GoWhyImport", NArgs: "1", Eval: "*", Complete: "customlist,GoDocBrowseCompletion"},
+		func(args []string, eval *cmdWhyImportEval) {
+			c.cmdWhyImport(ctx, args, eval)
+		})
 	p.HandleCommand(&plugin.CommandOptions{Name: "GoVet", NArgs: "*", Eval: "[getcwd(), expand('%:p')]", Complete: "customlist,GoVetCompletion"},
 		func(args []string, eval *CmdVetEval) {
 			c.cmdVet(ctx, args, eval)
 		})
 
+	// Insert mode completion
+	p.HandleFunction(&plugin.FunctionOptions{Name: "GoComplete", Eval: "*"}, // omnifunc
+		func(args *funcCompleteArgs, eval *funcCompleteEval) (interface{}, error) {
+			return c.funcComplete(ctx, args, eval)
+		})
+
 	// Commnad completion
+	p.HandleFunction(&plugin.FunctionOptions{Name: "GoCallGraphCompletion"}, // list the algorithms and root filters
+		func(a *nvim.CommandCompletionArgs) ([]string, error) {
+			return c.cmdCallGraphComplete(ctx, a)
+		})
+	p.HandleFunction(&plugin.FunctionOptions{Name: "GoDocCompletion", Eval: "expand('%:p')"}, // list the package and package symbols
+		func(a *nvim.CommandCompletionArgs, file string) ([]string, error) {
+			return c.cmdDocComplete(ctx, a, file)
+		})
+	p.HandleFunction(&plugin.FunctionOptions{Name: "GoDocBrowseCompletion"}, // list the import paths
+		func(a *nvim.CommandCompletionArgs) ([]string, error) {
+			return c.cmdDocBrowseComplete(ctx, a)
+		})
+	p.HandleFunction(&plugin.FunctionOptions{Name: "GoImportsCompletion"}, // list the subcommands
+		func(a *nvim.CommandCompletionArgs) ([]string, error) {
+			return c.cmdImportsComplete(ctx, a)
+		})
 	p.HandleFunction(&plugin.FunctionOptions{Name: "GoLintCompletion", Eval: "getcwd()"}, // list the file, directory and go packages
 		func(a *nvim.CommandCompletionArgs, cwd string) {
 			c.cmdLintComplete(ctx, a, cwd)
@@ -5,18 +5,24 @@
 package command
 
 import (
+	"bytes"
 	"context"
 	"fmt"
 	"go/build"
 	"io/ioutil"
 	"os"
+	"sort"
+	"sync"
 
 	"github.com/neovim/go-client/nvim"
 	"github.com/pkg/errors"
+	"go.opencensus.io/trace"
 	"go.uber.org/zap"
-	"golang.org/x/tools/refactor/rename"
+	"golang.org/x/tools/go/buildutil"
 
 	"github.com/zchee/nvim-go/pkg/config"
+	"github.com/zchee/nvim-go/pkg/fs"
+	"github.com/zchee/nvim-go/pkg/internal/rename"
 	"github.com/zchee/nvim-go/pkg/logger"
 	"github.com/zchee/nvim-go/pkg/monitoring"
 	"github.com/zchee/nvim-go/pkg/nvimutil"
@@ -24,12 +30,30 @@ import (
 
 const pkgRename = "GoRename"
 
+// renamePreviewBufferName buffer name of the GoRename preview buffer.
+const renamePreviewBufferName = "__GoRenamePreview__"
+
 type cmdRenameEval struct {
 	Cwd        string `msgpack:",array"`
 	File       string
 	RenameFrom string
 }
 
+// renameChange represents the renamed contents of a file.
+type renameChange struct {
+	Filename string
+	Old, New []byte
+}
+
+// renamePreview represents the GoRename preview buffer and the pending changes.
+type renamePreview struct {
+	mu      sync.Mutex
+	buffer  nvim.Buffer
+	window  nvim.Window
+	changes []*renameChange
+	summary string
+}
+
 func (c *Command) cmdRename(ctx context.Context, args []string, bang bool, eval *cmdRenameEval) {
 	errch := make(chan interface{}, 1)
 	go func() {
@@ -53,12 +77,16 @@ func (c *Command) cmdRename(ctx context.Context, args []string, bang bool, eval
 			})
 			nvimutil.ErrorList(c.Nvim, errlist, true)
 		case nil:
-			// nothing to do
+			// forget the conflicts of the previous renaming
+			c.errs.Delete("Rename")
 		}
 	}
 }
 
 // Rename rename the current cursor word use golang.org/x/tools/refactor/rename.
+//
+// The renaming is computed in memory with the overlays of all modified buffers, and shown in the
+// preview diff buffer if go#rename#preview is enabled. Otherwise, applied immediately.
 func (c *Command) Rename(pctx context.Context, args []string, bang bool, eval *cmdRenameEval) interface{} {
 	ctx, span := monitoring.StartSpan(pctx, "Rename")
 	defer span.End()
@@ -97,52 +125,251 @@ func (c *Command) Rename(pctx context.Context, args []string, bang bool, eval *c
 
 	c.Nvim.Command(fmt.Sprintf("echo '%s: Renaming ' | echohl Identifier | echon '%s' | echohl None | echon ' to ' | echohl Identifier | echon '%s' | echohl None | echon ' ...'", pkgRename, eval.RenameFrom, renameTo))
 
-	if bang {
-		rename.Force = true
+	overlay, err := c.modifiedOverlay()
+	if err != nil {
+		span.SetStatus(trace.Status{Code: trace.StatusCodeInternal, Message: err.Error()})
+		return errors.WithStack(err)
+	}
+
+	var (
+		files    map[string][]byte
+		summary  string
+		warnings []*nvim.QuickfixError
+	)
+	if config.GoplsEnable {
+		files, err = c.goplsRename(ctx, c.buildContext.BufNr, eval.File, offset, renameTo, overlay)
+		if err != nil {
+			span.SetStatus(trace.Status{Code: trace.StatusCodeInternal, Message: err.Error()})
+			return errors.WithStack(err)
+		}
+	} else {
+		bctxt := buildutil.OverlayContext(&build.Default, overlay)
+		logger.FromContext(ctx).Debug("Rename", zap.String("bctxt.GOROOT", bctxt.GOROOT), zap.String("bctxt.GOPATH", bctxt.GOPATH), zap.Int("overlays", len(overlay)))
+
+		result, err := rename.Rename(bctxt, pos, renameTo, bang)
+		if err != nil {
+			if len(result.Conflicts) == 0 {
+				span.SetStatus(trace.Status{Code: trace.StatusCodeInternal, Message: err.Error()})
+				return errors.WithStack(err)
+			}
+
+			// cmdRename shows the conflicts with the other errors
+			return conflictList(eval.Cwd, result.Conflicts, "")
+		}
+		// the conflicts of the forced renaming are reported as the warnings
+		files, summary, warnings = result.Files, result.Summary, conflictList(eval.Cwd, result.Conflicts, "W")
 	}
 
-	// TODO(zchee): More elegant way
-	// save original stdout and stderr
-	saveStdout, saveStderr := os.Stdout, os.Stderr
-	rd, wd, err := os.Pipe()
+	changes, err := renameChanges(files, overlay)
 	if err != nil {
+		span.SetStatus(trace.Status{Code: trace.StatusCodeInternal, Message: err.Error()})
+		return errors.WithStack(err)
+	}
+	if len(changes) == 0 {
+		return nvimutil.EchoSuccess(c.Nvim, pkgRename, "nothing to rename")
+	}
+	if summary == "" {
+		summary = fmt.Sprintf("Renamed '%s' to '%s' in %d files.", eval.RenameFrom, renameTo, len(changes))
+	}
+
+	rp := c.renamePreview
+	rp.mu.Lock()
+	defer rp.mu.Unlock()
+
+	rp.changes, rp.summary = changes, summary
+	if !config.RenamePreview {
+		if err := c.applyRename(ctx); err != nil {
+			return err
+		}
+	} else if err := c.showRenamePreview(eval.Cwd, eval.RenameFrom, renameTo); err != nil {
+		span.SetStatus(trace.Status{Code: trace.StatusCodeInternal, Message: err.Error()})
 		return errors.WithStack(err)
 	}
 
-	// migrate and stderr and stdout
-	os.Stderr = os.Stdout
-	os.Stderr = wd
-	defer func() {
-		os.Stderr = saveStdout
-		os.Stderr = saveStderr
-	}()
+	if len(warnings) > 0 {
+		return warnings
+	}
 
-	bctxt := &build.Default
-	logger.FromContext(ctx).Debug("Rename", zap.String("bctxt.GOROOT", bctxt.GOROOT), zap.String("bctxt.GOPATH", bctxt.GOPATH))
+	return nil
+}
 
-	// TODO(zchee): reached race limit, dying when race build
-	if err = rename.Main(bctxt, pos, "", renameTo); err != nil {
-		wd.Close()
-		renameErr, err := ioutil.ReadAll(rd)
-		if err != nil {
-			return errors.WithStack(err)
+// conflictList returns the location list of the renaming conflicts. typ is the type of the items, such as "W".
+func conflictList(cwd string, conflicts []rename.Conflict, typ string) []*nvim.QuickfixError {
+	if len(conflicts) == 0 {
+		return nil
+	}
+
+	loclist := make([]*nvim.QuickfixError, 0, len(conflicts))
+	for _, conflict := range conflicts {
+		loclist = append(loclist, &nvim.QuickfixError{
+			FileName: fs.Rel(cwd, conflict.Pos.Filename),
+			LNum:     conflict.Pos.Line,
+			Col:      conflict.Pos.Column,
+			Text:     conflict.Message,
+			Type:     typ,
+		})
+	}
+
+	return loclist
+}
+
+// renameChanges returns the changes from the current contents to the renamed files contents.
+// The current contents are the overlay if exists, otherwise the file on disk.
+func renameChanges(files map[string][]byte, overlay map[string][]byte) ([]*renameChange, error) {
+	changes := make([]*renameChange, 0, len(files))
+	for fname, content := range files {
+		old, ok := overlay[fname]
+		if !ok {
+			var err error
+			if old, err = ioutil.ReadFile(fname); err != nil {
+				return nil, err
+			}
+		}
+		if bytes.Equal(old, content) {
+			continue
 		}
+		changes = append(changes, &renameChange{Filename: fname, Old: old, New: content})
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Filename < changes[j].Filename })
 
-		loclist, _ := nvimutil.ParseError(ctx, renameErr, eval.Cwd, &c.buildContext.Build, nil)
-		nvimutil.SetLoclist(c.Nvim, loclist)
-		nvimutil.OpenLoclist(c.Nvim, w, loclist, true)
+	return changes, nil
+}
 
-		return loclist
+// showRenamePreview shows the unified diff of the pending changes to the preview buffer.
+// The caller must hold the c.renamePreview.mu.
+func (c *Command) showRenamePreview(cwd, from, to string) error {
+	rp := c.renamePreview
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "# %s: '%s' to '%s'. Press <CR> to apply, q to cancel.\n", pkgRename, from, to)
+	for _, change := range rp.changes {
+		name := fs.Rel(cwd, change.Filename)
+		buf.Write(unifiedDiff("a/"+name, "b/"+name, change.Old, change.New))
 	}
+	lines := nvimutil.ToBufferLines(bytes.TrimSuffix(buf.Bytes(), []byte{'\n'}))
 
-	wd.Close()
-	out, err := ioutil.ReadAll(rd)
-	if err != nil {
+	if rp.buffer == 0 || !nvimutil.IsBufferValid(c.Nvim, rp.buffer) {
+		b := nvimutil.NewBuffer(c.Nvim)
+		option := map[nvimutil.NvimOption]map[string]interface{}{
+			nvimutil.BufferOption: {
+				nvimutil.BufOptionBufhidden:  nvimutil.BufhiddenWipe,
+				nvimutil.BufOptionBuflisted:  false,
+				nvimutil.BufOptionBuftype:    nvimutil.BuftypeNofile,
+				nvimutil.BufOptionFiletype:   "diff",
+				nvimutil.BufOptionModifiable: false,
+				nvimutil.BufOptionSwapfile:   false,
+			},
+			nvimutil.WindowOption: {
+				nvimutil.WinOptionList:           false,
+				nvimutil.WinOptionNumber:         false,
+				nvimutil.WinOptionRelativenumber: false,
+			},
+		}
+		if err := b.Create(renamePreviewBufferName, "diff", "belowright split", option); err != nil {
+			return err
+		}
+		b.SetLocalMapping(nvimutil.NoremapNormal, map[string]string{
+			"<CR>": ":<C-u>call GoRenameApply()<CR>",
+			"q":    ":<C-u>close<CR>",
+		})
+		rp.buffer = b.Buffer()
+		rp.window = b.Window
+	}
+
+	restore := nvimutil.Modifiable(c.Nvim, rp.buffer)
+	err := c.Nvim.SetBufferLines(rp.buffer, 0, -1, true, lines)
+	restore()
+
+	return err
+}
+
+func (c *Command) funcRenameApply(ctx context.Context) {
+	rp := c.renamePreview
+	rp.mu.Lock()
+	defer rp.mu.Unlock()
+
+	if err := c.applyRename(ctx); err != nil {
+		nvimutil.ErrorWrap(c.Nvim, err)
+	}
+}
+
+// applyRename closes the preview buffer and applies the pending changes.
+// All buffers and files are checked that they are not changed since the renaming was computed before applying any change.
+// The changes of the loaded buffers are applied to the buffers by one nvim_buf_set_lines call each,
+// so each buffer can undo the renaming by one undo step. The others are written to the files.
+// The caller must hold the c.renamePreview.mu.
+func (c *Command) applyRename(ctx context.Context) error {
+	rp := c.renamePreview
+	if len(rp.changes) == 0 {
+		return errors.New("GoRename: no pending changes")
+	}
+	changes, summary := rp.changes, rp.summary
+	rp.changes, rp.summary = nil, ""
+
+	if valid, _ := c.Nvim.IsWindowValid(rp.window); valid {
+		if err := c.Nvim.CloseWindow(rp.window, true); err != nil {
+			return errors.WithStack(err)
+		}
+	}
+
+	batch := c.Nvim.NewBatch()
+	bufnrs := make([]int, len(changes))
+	loaded := make([]bool, len(changes))
+	for i, change := range changes {
+		batch.Call("bufnr", &bufnrs[i], change.Filename)
+		batch.Call("bufloaded", &loaded[i], change.Filename)
+	}
+	if err := batch.Execute(); err != nil {
 		return errors.WithStack(err)
 	}
-	defer nvimutil.EchoSuccess(c.Nvim, pkgRename, fmt.Sprintf("%s", out))
 
-	// TODO(zchee): 'edit' command is ugly.
-	// Should create tempfile and use SetBufferLines.
-	return c.Nvim.Command("silent edit")
+	// check all buffers and files before applying any change, so as not to leave the half-applied renaming
+	lines := make([][][]byte, len(changes))
+	for i, change := range changes {
+		var current []byte
+		if loaded[i] {
+			in, err := c.Nvim.BufferLines(nvim.Buffer(bufnrs[i]), 0, -1, true)
+			if err != nil {
+				return errors.WithStack(err)
+			}
+			lines[i] = in
+			current = append(nvimutil.ToByteSlice(in), '\n')
+		} else {
+			var err error
+			if current, err = ioutil.ReadFile(change.Filename); err != nil {
+				return errors.WithStack(err)
+			}
+		}
+		if !bytes.Equal(current, change.Old) {
+			return errors.Errorf("GoRename: %s was changed after the renaming was computed, please retry", change.Filename)
+		}
+	}
+
+	var nbufs int
+	for i, change := range changes {
+		if !loaded[i] {
+			if err := writeRenamedFile(change); err != nil {
+				return errors.Wrapf(err, "could not write %s", change.Filename)
+			}
+			continue
+		}
+
+		out := nvimutil.ToBufferLines(bytes.TrimSuffix(change.New, []byte{'\n'}))
+		if err := minUpdate(ctx, c.Nvim, nvim.Buffer(bufnrs[i]), lines[i], out); err != nil {
+			return errors.WithStack(err)
+		}
+		nbufs++
+	}
+
+	return nvimutil.EchoSuccess(c.Nvim, pkgRename, fmt.Sprintf("%s %d buffers modified.", summary, nbufs))
+}
+
+// writeRenamedFile writes the renamed contents of the unloaded file with the original permission.
+func writeRenamedFile(change *renameChange) error {
+	perm := os.FileMode(0644)
+	if fi, err := os.Stat(change.Filename); err == nil {
+		perm = fi.Mode().Perm()
+	}
+
+	return ioutil.WriteFile(change.Filename, change.New, perm)
 }
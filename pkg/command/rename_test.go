@@ -0,0 +1,87 @@
+// Copyright 2018 The nvim-go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package command
+
+import (
+	"fmt"
+	"go/build"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"golang.org/x/tools/go/buildutil"
+
+	"github.com/zchee/nvim-go/pkg/internal/rename"
+)
+
+func TestRenameOverlay(t *testing.T) {
+	gopath, err := ioutil.TempDir("", "nvim-go-rename")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(gopath)
+
+	dir := filepath.Join(gopath, "src", "foo")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	fooFile, barFile := filepath.Join(dir, "foo.go"), filepath.Join(dir, "bar.go")
+	const (
+		fooSrc = "package foo\n\nfunc Foo() int { return 1 }\n"
+		barSrc = "package foo\n\nfunc bar() int { return 2 }\n"
+		// unsaved buffer contents of bar.go
+		barOverlay = "package foo\n\nfunc bar() int { return Foo() }\n"
+	)
+	writeFile(t, fooFile, fooSrc)
+	writeFile(t, barFile, barSrc)
+
+	saved := os.Getenv("GO111MODULE")
+	os.Setenv("GO111MODULE", "off")
+	defer os.Setenv("GO111MODULE", saved)
+
+	bctxt := build.Default
+	bctxt.GOPATH = gopath
+	overlay := map[string][]byte{barFile: []byte(barOverlay)}
+
+	offset := strings.Index(fooSrc, "Foo")
+	result, err := rename.Rename(buildutil.OverlayContext(&bctxt, overlay), fmt.Sprintf("%s:#%d", fooFile, offset), "Baz", false)
+	if err != nil {
+		t.Fatalf("Rename: %v: %+v", err, result.Conflicts)
+	}
+	if want := "Renamed 2 occurrences in 2 files in 1 package."; result.Summary != want {
+		t.Errorf("result.Summary = %q, want %q", result.Summary, want)
+	}
+
+	changes, err := renameChanges(result.Files, overlay)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(changes) != 2 {
+		t.Fatalf("len(changes) = %d, want 2", len(changes))
+	}
+	// sorted by the file name
+	if changes[0].Filename != barFile || string(changes[0].Old) != barOverlay || string(changes[0].New) != strings.Replace(barOverlay, "Foo", "Baz", 1) {
+		t.Errorf("changes[0] = %s: %q -> %q", changes[0].Filename, changes[0].Old, changes[0].New)
+	}
+	if changes[1].Filename != fooFile || string(changes[1].Old) != fooSrc || string(changes[1].New) != strings.Replace(fooSrc, "Foo", "Baz", 1) {
+		t.Errorf("changes[1] = %s: %q -> %q", changes[1].Filename, changes[1].Old, changes[1].New)
+	}
+
+	// the files on disk are not changed
+	if data, _ := ioutil.ReadFile(fooFile); string(data) != fooSrc {
+		t.Errorf("%s was written: %q", fooFile, data)
+	}
+
+	// conflict
+	result, err = rename.Rename(buildutil.OverlayContext(&bctxt, overlay), fmt.Sprintf("%s:#%d", fooFile, offset), "bar", false)
+	if err != rename.ConflictError {
+		t.Fatalf("Rename to the conflicted name error = %v, want %v", err, rename.ConflictError)
+	}
+	if len(result.Conflicts) == 0 || result.Conflicts[0].Pos.Filename == "" {
+		t.Errorf("result.Conflicts = %+v, want the conflict positions", result.Conflicts)
+	}
+}
@@ -0,0 +1,310 @@
+// Copyright 2018 The nvim-go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package command
+
+import (
+	"context"
+	"go/ast"
+	"go/token"
+	"sync"
+
+	"github.com/neovim/go-client/nvim"
+	"github.com/pkg/errors"
+	"go.opencensus.io/trace"
+	"golang.org/x/tools/go/ast/astutil"
+
+	"github.com/zchee/nvim-go/pkg/config"
+	"github.com/zchee/nvim-go/pkg/monitoring"
+	"github.com/zchee/nvim-go/pkg/nvimutil"
+)
+
+// List of same ids highlight groups.
+const (
+	hlSameIdsRead  = "goSameIdsRead"
+	hlSameIdsWrite = "goSameIdsWrite"
+)
+
+// sameIds represents the highlighted references of the identifier under the cursor.
+type sameIds struct {
+	mu     sync.Mutex
+	nsID   int
+	buffer nvim.Buffer
+}
+
+// sameIdRef represents a reference of the same object.
+type sameIdRef struct {
+	// Line 1-based line number.
+	Line int
+	// StartCol and EndCol 0-based byte column range.
+	StartCol, EndCol int
+	// Write whether the reference assigns to the object.
+	Write bool
+}
+
+// cmdSameIdsEval represents the current buffer and cursor offset.
+type cmdSameIdsEval struct {
+	File   string `eval:"expand('%:p')"`
+	BufNr  int    `eval:"bufnr('%')"`
+	Tick   int    `eval:"b:changedtick"`
+	Offset int    `eval:"line2byte(line('.')) + (col('.')-2)"`
+}
+
+// SameIdsAuto highlights the references of the identifier at the offset in the bufnr buffer on CursorHold if config.HighlightSameIds is enabled.
+func (c *Command) SameIdsAuto(ctx context.Context, file string, bufnr, tick, offset int) error {
+	s := c.sameIds
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !config.HighlightSameIds {
+		return nil
+	}
+
+	return c.SameIds(ctx, &cmdSameIdsEval{
+		File:   file,
+		BufNr:  bufnr,
+		Tick:   tick,
+		Offset: offset,
+	})
+}
+
+// SameIds highlights all references of the object of the identifier at the eval.Offset in the eval.BufNr buffer.
+// The read and write references are highlighted with the different highlight groups.
+// c.sameIds.mu must be held.
+func (c *Command) SameIds(ctx context.Context, eval *cmdSameIdsEval) error {
+	var span *trace.Span
+	ctx, span = monitoring.StartSpan(ctx, "SameIds")
+	defer span.End()
+
+	s := c.sameIds
+	if s.nsID == 0 {
+		nsID, err := c.Nvim.CreateNamespace("nvim-go-sameids")
+		if err != nil {
+			span.SetStatus(trace.Status{Code: trace.StatusCodeInternal, Message: err.Error()})
+			return errors.WithStack(err)
+		}
+		s.nsID = nsID
+	}
+
+	refs, err := c.sameIdRefs(eval)
+	if err != nil {
+		span.SetStatus(trace.Status{Code: trace.StatusCodeInternal, Message: err.Error()})
+		return err
+	}
+
+	b := nvim.Buffer(eval.BufNr)
+	batch := c.Nvim.NewBatch()
+	if s.buffer != 0 && s.buffer != b {
+		batch.ClearBufferNamespace(s.buffer, s.nsID, 0, -1)
+	}
+	batch.ClearBufferNamespace(b, s.nsID, 0, -1)
+	for _, ref := range refs {
+		hl := hlSameIdsRead
+		if ref.Write {
+			hl = hlSameIdsWrite
+		}
+		var id int
+		batch.SetBufferExtmark(b, s.nsID, 0, ref.Line-1, ref.StartCol, map[string]interface{}{
+			"end_col":  ref.EndCol,
+			"hl_group": hl,
+		}, &id)
+	}
+	if err := batch.Execute(); err != nil {
+		span.SetStatus(trace.Status{Code: trace.StatusCodeInternal, Message: err.Error()})
+		return errors.WithStack(err)
+	}
+	s.buffer = b
+
+	return nil
+}
+
+// clear clears the highlights of the same ids.
+// s.mu must be held.
+func (s *sameIds) clear(v *nvim.Nvim) error {
+	if s.nsID == 0 || s.buffer == 0 {
+		return nil
+	}
+	if valid, _ := v.IsBufferValid(s.buffer); valid {
+		if err := v.ClearBufferNamespace(s.buffer, s.nsID, 0, -1); err != nil {
+			return errors.WithStack(err)
+		}
+	}
+	s.buffer = 0
+
+	return nil
+}
+
+// forget forgets the highlighted buffer if it is b, so as not to clear the highlights of the deleted buffer.
+func (s *sameIds) forget(b nvim.Buffer) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.buffer == b {
+		s.buffer = 0
+	}
+}
+
+// sameIdRefs returns the references of the identifier at the eval.Offset, using the type checked file cached by the semantic highlighting.
+func (c *Command) sameIdRefs(eval *cmdSameIdsEval) ([]sameIdRef, error) {
+	sc := c.semantic
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+
+	cf, err := sc.checkedFile(c.Nvim, nvim.Buffer(eval.BufNr), eval.File, eval.Tick)
+	if err != nil {
+		return nil, err
+	}
+
+	return sameIdRefs(cf, eval.Offset), nil
+}
+
+func (c *Command) cmdSameIdsToggle(ctx context.Context, eval *cmdSameIdsEval) {
+	if err := c.SameIdsToggle(ctx, eval); err != nil {
+		nvimutil.ErrorWrap(c.Nvim, err)
+	}
+}
+
+// SameIdsToggle toggles the automatic highlighting of the same ids, and updates the highlights of the current buffer.
+func (c *Command) SameIdsToggle(ctx context.Context, eval *cmdSameIdsEval) error {
+	s := c.sameIds
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	config.HighlightSameIds = !config.HighlightSameIds
+	if !config.HighlightSameIds {
+		return s.clear(c.Nvim)
+	}
+
+	return c.SameIds(ctx, eval)
+}
+
+func (c *Command) cmdSameIdsJump(ctx context.Context, eval *cmdSameIdsEval, backward bool) {
+	if err := c.SameIdsJump(ctx, eval, backward); err != nil {
+		nvimutil.ErrorWrap(c.Nvim, err)
+	}
+}
+
+// SameIdsJump jumps to the next reference of the identifier under the cursor, or the previous reference if backward is true.
+// Wraps around the end of the buffer.
+func (c *Command) SameIdsJump(ctx context.Context, eval *cmdSameIdsEval, backward bool) error {
+	refs, err := c.sameIdRefs(eval)
+	if err != nil {
+		return err
+	}
+	if len(refs) == 0 {
+		return errors.New("no identifier under the cursor")
+	}
+
+	var line, col int
+	batch := c.Nvim.NewBatch()
+	batch.Eval("line('.')", &line)
+	batch.Eval("col('.')", &col)
+	if err := batch.Execute(); err != nil {
+		return errors.WithStack(err)
+	}
+
+	ref := nextSameIdRef(refs, line, col-1, backward)
+	w, err := c.Nvim.CurrentWindow()
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	return jumpToPos(c.Nvim, w, token.Position{Line: ref.Line, Column: ref.StartCol + 1})
+}
+
+// nextSameIdRef returns the first reference after the 1-based line and 0-based col, or the last reference before it if backward is true.
+// refs must be sorted and not empty.
+func nextSameIdRef(refs []sameIdRef, line, col int, backward bool) sameIdRef {
+	if backward {
+		for i := len(refs) - 1; i >= 0; i-- {
+			if r := refs[i]; r.Line < line || (r.Line == line && r.EndCol <= col) {
+				return r
+			}
+		}
+		return refs[len(refs)-1]
+	}
+
+	for _, r := range refs {
+		if r.Line > line || (r.Line == line && r.StartCol > col) {
+			return r
+		}
+	}
+	return refs[0]
+}
+
+// sameIdRefs returns the sorted references of the object of the identifier at offset in the checked file.
+// Returns nil if offset is not on the identifier.
+func sameIdRefs(cf *checkedFile, offset int) []sameIdRef {
+	tf := cf.Fset.File(cf.File.Pos())
+	if offset < 0 || offset > tf.Size() {
+		return nil
+	}
+	pos := tf.Pos(offset)
+	path, _ := astutil.PathEnclosingInterval(cf.File, pos, pos)
+	if len(path) == 0 {
+		return nil
+	}
+	id, ok := path[0].(*ast.Ident)
+	if !ok || id.Name == "_" {
+		return nil
+	}
+	obj := cf.Info.ObjectOf(id)
+	if obj == nil {
+		return nil
+	}
+
+	writes := writeIdents(cf.File)
+	var refs []sameIdRef
+	ast.Inspect(cf.File, func(n ast.Node) bool {
+		id, ok := n.(*ast.Ident)
+		if !ok || cf.Info.ObjectOf(id) != obj {
+			return true
+		}
+		p := cf.Fset.Position(id.Pos())
+		refs = append(refs, sameIdRef{
+			Line:     p.Line,
+			StartCol: p.Column - 1,
+			EndCol:   p.Column - 1 + len(id.Name),
+			Write:    cf.Info.Defs[id] != nil || writes[id],
+		})
+		return true
+	})
+
+	return refs
+}
+
+// writeIdents returns the identifiers which are assigned in f, such as the left hand side of the assignment,
+// the operand of the increment and decrement statement and the key and value of the range statement.
+func writeIdents(f *ast.File) map[*ast.Ident]bool {
+	writes := make(map[*ast.Ident]bool)
+	add := func(expr ast.Expr) {
+		switch x := astutil.Unparen(expr).(type) {
+		case *ast.Ident:
+			writes[x] = true
+		case *ast.SelectorExpr:
+			writes[x.Sel] = true
+		}
+	}
+
+	ast.Inspect(f, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.AssignStmt:
+			for _, lhs := range n.Lhs {
+				add(lhs)
+			}
+		case *ast.IncDecStmt:
+			add(n.X)
+		case *ast.RangeStmt:
+			if n.Key != nil {
+				add(n.Key)
+			}
+			if n.Value != nil {
+				add(n.Value)
+			}
+		}
+		return true
+	})
+
+	return writes
+}
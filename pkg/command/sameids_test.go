@@ -0,0 +1,118 @@
+// Copyright 2018 The nvim-go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package command
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const sameIdsSrc = `package p
+
+type T struct{ n int }
+
+func f(t *T, xs []int) int {
+	sum := 0
+	for _, x := range xs {
+		sum += x
+	}
+	t.n = sum
+	sum++
+	return sum + t.n
+}
+`
+
+func TestSameIdRefs(t *testing.T) {
+	_, cf := testCheckedFile(t, sameIdsSrc)
+
+	tests := []struct {
+		name string
+		at   string
+		want []sameIdRef
+	}{
+		{
+			name: "local variable",
+			at:   "sum := 0",
+			want: []sameIdRef{
+				{Line: 6, StartCol: 1, EndCol: 4, Write: true},
+				{Line: 8, StartCol: 2, EndCol: 5, Write: true},
+				{Line: 10, StartCol: 7, EndCol: 10},
+				{Line: 11, StartCol: 1, EndCol: 4, Write: true},
+				{Line: 12, StartCol: 8, EndCol: 11},
+			},
+		},
+		{
+			name: "field",
+			at:   "n int",
+			want: []sameIdRef{
+				{Line: 3, StartCol: 15, EndCol: 16, Write: true},
+				{Line: 10, StartCol: 3, EndCol: 4, Write: true},
+				{Line: 12, StartCol: 16, EndCol: 17},
+			},
+		},
+		{
+			name: "range value",
+			at:   "x := range",
+			want: []sameIdRef{
+				{Line: 7, StartCol: 8, EndCol: 9, Write: true},
+				{Line: 8, StartCol: 9, EndCol: 10},
+			},
+		},
+		{
+			name: "not identifier",
+			at:   ":= 0",
+			want: nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := sameIdRefs(cf, strings.Index(sameIdsSrc, tt.at)); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("sameIdRefs() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNextSameIdRef(t *testing.T) {
+	refs := []sameIdRef{
+		{Line: 2, StartCol: 4, EndCol: 7},
+		{Line: 5, StartCol: 0, EndCol: 3},
+		{Line: 5, StartCol: 10, EndCol: 13},
+	}
+
+	tests := []struct {
+		name      string
+		line, col int
+		backward  bool
+		want      sameIdRef
+	}{
+		{name: "next on same line", line: 5, col: 1, want: refs[2]},
+		{name: "next on next line", line: 2, col: 5, want: refs[1]},
+		{name: "next wraps around", line: 5, col: 11, want: refs[0]},
+		{name: "prev on same line", line: 5, col: 11, backward: true, want: refs[1]},
+		{name: "prev on previous line", line: 5, col: 1, backward: true, want: refs[0]},
+		{name: "prev wraps around", line: 2, col: 5, backward: true, want: refs[2]},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := nextSameIdRef(refs, tt.line, tt.col, tt.backward); got != tt.want {
+				t.Errorf("nextSameIdRef(%d, %d, %v) = %v, want %v", tt.line, tt.col, tt.backward, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSameIdsForget(t *testing.T) {
+	s := &sameIds{buffer: 2}
+	s.forget(1)
+	if s.buffer != 2 {
+		t.Errorf("forget(1) buffer = %d, want 2", s.buffer)
+	}
+	s.forget(2)
+	if s.buffer != 0 {
+		t.Errorf("forget(2) buffer = %d, want 0", s.buffer)
+	}
+}
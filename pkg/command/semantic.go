@@ -0,0 +1,319 @@
+// Copyright 2018 The nvim-go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package command
+
+import (
+	"context"
+	"go/ast"
+	"go/types"
+	"sync"
+	"time"
+
+	"github.com/neovim/go-client/nvim"
+	"github.com/pkg/errors"
+	"go.opencensus.io/trace"
+	"go.uber.org/zap"
+
+	"github.com/zchee/nvim-go/pkg/config"
+	"github.com/zchee/nvim-go/pkg/logger"
+	"github.com/zchee/nvim-go/pkg/monitoring"
+	"github.com/zchee/nvim-go/pkg/nvimutil"
+)
+
+// List of semantic highlight groups.
+const (
+	hlSemanticPackage   = "goSemanticPackage"
+	hlSemanticType      = "goSemanticType"
+	hlSemanticInterface = "goSemanticInterface"
+	hlSemanticFunction  = "goSemanticFunction"
+	hlSemanticMethod    = "goSemanticMethod"
+	hlSemanticField     = "goSemanticField"
+	hlSemanticParameter = "goSemanticParameter"
+	hlSemanticConstant  = "goSemanticConstant"
+	hlSemanticShadowed  = "goSemanticShadowed"
+	hlSemanticUnused    = "goSemanticUnused"
+)
+
+// semanticDebounce is the delay of the semantic highlighting after the last change in insert mode.
+const semanticDebounce = 300 * time.Millisecond
+
+// semanticCache caches the type checked file of each buffer with the buffer changedtick.
+//
+// The cache entry is evicted by EvictBuffer when the buffer is deleted.
+type semanticCache struct {
+	mu   sync.Mutex
+	nsID int
+	tick map[nvim.Buffer]int
+	file map[nvim.Buffer]*checkedFile
+
+	// timerMu guards timer and gen of the debounced semantic highlighting.
+	timerMu sync.Mutex
+	timer   *time.Timer
+	// gen generation of the latest scheduled semantic highlighting.
+	gen int
+}
+
+// SemanticHighlight highlights the identifiers of the bufnr buffer between the start and end lines based on go/types information.
+//
+// The type check result is cached until the buffer changedtick is changed, so scrolling the window does not re-type check the buffer.
+func (c *Command) SemanticHighlight(ctx context.Context, bufnr int, file string, tick, start, end int) error {
+	if !config.HighlightSemantic {
+		return nil
+	}
+
+	sc := c.semantic
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+
+	return c.semanticHighlight(ctx, bufnr, file, tick, start, end)
+}
+
+// SemanticHighlightDebounced schedules the semantic highlighting after semanticDebounce from the last call.
+// The previously scheduled highlighting is cancelled, and is skipped if it is superseded while waiting for the in-flight type check.
+// Used on TextChangedI so that typing does not type check the buffer on every keystroke.
+func (c *Command) SemanticHighlightDebounced(ctx context.Context, bufnr int, file string, tick, start, end int) {
+	if !config.HighlightSemantic {
+		return
+	}
+
+	sc := c.semantic
+	sc.timerMu.Lock()
+	defer sc.timerMu.Unlock()
+
+	if sc.timer != nil {
+		sc.timer.Stop()
+	}
+	sc.gen++
+	gen := sc.gen
+	sc.timer = time.AfterFunc(semanticDebounce, func() {
+		sc.mu.Lock()
+		defer sc.mu.Unlock()
+
+		if !sc.isLatest(gen) {
+			return
+		}
+		if err := c.semanticHighlight(ctx, bufnr, file, tick, start, end); err != nil {
+			logger.FromContext(ctx).Error("SemanticHighlightDebounced", zap.Error(err))
+		}
+	})
+}
+
+// isLatest reports whether gen is the latest scheduled semantic highlighting.
+func (sc *semanticCache) isLatest(gen int) bool {
+	sc.timerMu.Lock()
+	defer sc.timerMu.Unlock()
+
+	return sc.gen == gen
+}
+
+// EvictBuffer removes the cached type checked file of the deleted bufnr buffer, which is shared by the semantic
+// highlighting and the same ids highlighting, and forgets the same ids highlighted buffer.
+func (c *Command) EvictBuffer(bufnr int) {
+	b := nvim.Buffer(bufnr)
+	c.sameIds.forget(b)
+
+	sc := c.semantic
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+
+	sc.evict(b)
+}
+
+// evict removes the cache entry of b.
+// sc.mu must be held.
+func (sc *semanticCache) evict(b nvim.Buffer) {
+	delete(sc.file, b)
+	delete(sc.tick, b)
+}
+
+// semanticHighlight highlights the identifiers of the bufnr buffer between the start and end lines.
+// c.semantic.mu must be held.
+func (c *Command) semanticHighlight(ctx context.Context, bufnr int, file string, tick, start, end int) error {
+	var span *trace.Span
+	ctx, span = monitoring.StartSpan(ctx, "SemanticHighlight")
+	defer span.End()
+
+	sc := c.semantic
+	if sc.nsID == 0 {
+		nsID, err := c.Nvim.CreateNamespace("nvim-go-semantic")
+		if err != nil {
+			span.SetStatus(trace.Status{Code: trace.StatusCodeInternal, Message: err.Error()})
+			return errors.WithStack(err)
+		}
+		sc.nsID = nsID
+	}
+
+	b := nvim.Buffer(bufnr)
+	cf, err := sc.checkedFile(c.Nvim, b, file, tick)
+	if err != nil {
+		span.SetStatus(trace.Status{Code: trace.StatusCodeInternal, Message: err.Error()})
+		return err
+	}
+
+	batch := c.Nvim.NewBatch()
+	batch.ClearBufferNamespace(b, sc.nsID, start-1, end)
+	for _, tok := range semanticTokens(cf, start, end) {
+		var id int
+		batch.SetBufferExtmark(b, sc.nsID, 0, tok.Line-1, tok.StartCol, map[string]interface{}{
+			"end_col":  tok.EndCol,
+			"hl_group": tok.Group,
+		}, &id)
+	}
+	if err := batch.Execute(); err != nil {
+		span.SetStatus(trace.Status{Code: trace.StatusCodeInternal, Message: err.Error()})
+		return errors.WithStack(err)
+	}
+
+	return nil
+}
+
+// checkedFile returns the type checked file of the b buffer, and caches it with the buffer changedtick.
+// The imported packages and the other files of the package are shared by typeCheck across the changedticks,
+// so a new changedtick only parses the buffer and type checks its package.
+// sc.mu must be held.
+func (sc *semanticCache) checkedFile(v *nvim.Nvim, b nvim.Buffer, file string, tick int) (*checkedFile, error) {
+	if cf, ok := sc.file[b]; ok && sc.tick[b] == tick {
+		return cf, nil
+	}
+
+	buf, err := v.BufferLines(b, 0, -1, true)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	cf, err := typeCheck(file, nvimutil.ToByteSlice(buf))
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	sc.file[b] = cf
+	sc.tick[b] = tick
+
+	return cf, nil
+}
+
+// semanticToken represents a highlight range of identifier.
+type semanticToken struct {
+	// Line 1-based line number.
+	Line int
+	// StartCol and EndCol 0-based byte column range.
+	StartCol, EndCol int
+	// Group highlight group name.
+	Group string
+}
+
+// semanticTokens returns the semantic tokens of identifiers between the start and end lines of the checked file.
+func semanticTokens(cf *checkedFile, start, end int) []semanticToken {
+	params := make(map[types.Object]bool)
+	used := make(map[types.Object]bool)
+	for _, obj := range cf.Info.Uses {
+		used[obj] = true
+	}
+	ast.Inspect(cf.File, func(n ast.Node) bool {
+		var lists []*ast.FieldList
+		switch n := n.(type) {
+		case *ast.FuncDecl:
+			lists = append(lists, n.Recv)
+		case *ast.FuncType:
+			lists = append(lists, n.Params, n.Results)
+		}
+		for _, list := range lists {
+			if list == nil {
+				continue
+			}
+			for _, field := range list.List {
+				for _, name := range field.Names {
+					if obj := cf.Info.Defs[name]; obj != nil {
+						params[obj] = true
+					}
+				}
+			}
+		}
+		return true
+	})
+
+	var toks []semanticToken
+	ast.Inspect(cf.File, func(n ast.Node) bool {
+		if n == nil {
+			return false
+		}
+		startPos, endPos := cf.Fset.Position(n.Pos()), cf.Fset.Position(n.End())
+		if endPos.Line < start || end < startPos.Line {
+			return false
+		}
+
+		id, ok := n.(*ast.Ident)
+		if !ok || id.Name == "_" || startPos.Line < start {
+			return true
+		}
+
+		obj := cf.Info.ObjectOf(id)
+		if obj == nil {
+			return true
+		}
+		if group := semanticGroup(obj, cf.Info.Defs[id] != nil, params[obj], used[obj]); group != "" {
+			toks = append(toks, semanticToken{
+				Line:     startPos.Line,
+				StartCol: startPos.Column - 1,
+				EndCol:   startPos.Column - 1 + len(id.Name),
+				Group:    group,
+			})
+		}
+
+		return true
+	})
+
+	return toks
+}
+
+// semanticGroup returns the highlight group of obj.
+func semanticGroup(obj types.Object, isDef, isParam, isUsed bool) string {
+	switch obj := obj.(type) {
+	case *types.PkgName:
+		return hlSemanticPackage
+	case *types.TypeName:
+		if types.IsInterface(obj.Type()) {
+			return hlSemanticInterface
+		}
+		return hlSemanticType
+	case *types.Func:
+		if sig, ok := obj.Type().(*types.Signature); ok && sig.Recv() != nil {
+			return hlSemanticMethod
+		}
+		return hlSemanticFunction
+	case *types.Const:
+		return hlSemanticConstant
+	case *types.Var:
+		switch {
+		case obj.IsField():
+			return hlSemanticField
+		case isParam:
+			return hlSemanticParameter
+		case isLocal(obj) && isDef && !isUsed:
+			return hlSemanticUnused
+		case isShadowed(obj):
+			return hlSemanticShadowed
+		}
+	}
+
+	return ""
+}
+
+// isLocal reports whether the obj is declared in the function scope.
+func isLocal(obj types.Object) bool {
+	return obj.Pkg() != nil && obj.Parent() != nil && obj.Parent() != obj.Pkg().Scope() && obj.Parent() != types.Universe
+}
+
+// isShadowed reports whether the local variable obj shadows the variable of the outer scope.
+func isShadowed(obj types.Object) bool {
+	if !isLocal(obj) || obj.Parent().Parent() == nil {
+		return false
+	}
+	_, outer := obj.Parent().Parent().LookupParent(obj.Name(), obj.Pos())
+	if outer == nil {
+		return false
+	}
+	_, ok := outer.(*types.Var)
+
+	return ok
+}
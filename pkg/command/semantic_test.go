@@ -0,0 +1,140 @@
+// Copyright 2018 The nvim-go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package command
+
+import (
+	"go/types"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/neovim/go-client/nvim"
+)
+
+const semanticSrc = `package foo
+
+import "strings"
+
+const max = 10
+
+type Reader interface{ Read() }
+
+type T struct{ name string }
+
+func (t T) Name(n int) string {
+	x := 1
+	if n > max {
+		x := strings.ToUpper(t.name)
+		return x
+	}
+	var unused int
+	return helper()
+}
+
+func helper() string { return "" }
+`
+
+func TestSemanticTokens(t *testing.T) {
+	_, cf := testCheckedFile(t, semanticSrc)
+
+	tests := []struct {
+		name       string
+		start, end int
+		want       []semanticToken
+	}{
+		{
+			name:  "declarations",
+			start: 5,
+			end:   9,
+			want: []semanticToken{
+				{Line: 5, StartCol: 6, EndCol: 9, Group: hlSemanticConstant},
+				{Line: 7, StartCol: 5, EndCol: 11, Group: hlSemanticInterface},
+				{Line: 7, StartCol: 23, EndCol: 27, Group: hlSemanticMethod},
+				{Line: 9, StartCol: 5, EndCol: 6, Group: hlSemanticType},
+				{Line: 9, StartCol: 15, EndCol: 19, Group: hlSemanticField},
+				{Line: 9, StartCol: 20, EndCol: 26, Group: hlSemanticType},
+			},
+		},
+		{
+			name:  "function body",
+			start: 13,
+			end:   17,
+			want: []semanticToken{
+				{Line: 13, StartCol: 4, EndCol: 5, Group: hlSemanticParameter},
+				{Line: 13, StartCol: 8, EndCol: 11, Group: hlSemanticConstant},
+				{Line: 14, StartCol: 2, EndCol: 3, Group: hlSemanticShadowed},
+				{Line: 14, StartCol: 7, EndCol: 14, Group: hlSemanticPackage},
+				{Line: 14, StartCol: 15, EndCol: 22, Group: hlSemanticFunction},
+				{Line: 14, StartCol: 23, EndCol: 24, Group: hlSemanticParameter},
+				{Line: 14, StartCol: 25, EndCol: 29, Group: hlSemanticField},
+				{Line: 15, StartCol: 9, EndCol: 10, Group: hlSemanticShadowed},
+				{Line: 17, StartCol: 5, EndCol: 11, Group: hlSemanticUnused},
+				{Line: 17, StartCol: 12, EndCol: 15, Group: hlSemanticType},
+			},
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := semanticTokens(cf, tt.start, tt.end); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("semanticTokens(%d, %d) = %+v, want %+v", tt.start, tt.end, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSemanticRecheck(t *testing.T) {
+	dir, err := ioutil.TempDir("", "nvim-go-semantic")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	file := filepath.Join(dir, "foo.go")
+	writeFile(t, file, semanticSrc)
+	writeFile(t, filepath.Join(dir, "bar.go"), "package foo\n\nfunc bar() {}\n")
+
+	cf, err := typeCheck(file, []byte(semanticSrc))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the next changedtick
+	next, err := typeCheck(file, []byte(semanticSrc+"\nfunc baz() {}\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(next.Files) != 2 || next.Files[1] != cf.Files[1] {
+		t.Error("the other file of the package is parsed again")
+	}
+	imported := func(cf *checkedFile) *types.Package {
+		return cf.Pkg.Imports()[0]
+	}
+	if imported(next) != imported(cf) {
+		t.Error("the imported package is imported again")
+	}
+}
+
+func TestSemanticCacheEvict(t *testing.T) {
+	sc := &semanticCache{
+		tick: map[nvim.Buffer]int{1: 10, 2: 20},
+		file: map[nvim.Buffer]*checkedFile{1: new(checkedFile), 2: new(checkedFile)},
+	}
+	sc.evict(1)
+
+	if _, ok := sc.file[1]; ok {
+		t.Error("evict() kept the checked file of the evicted buffer")
+	}
+	if _, ok := sc.tick[1]; ok {
+		t.Error("evict() kept the changedtick of the evicted buffer")
+	}
+	if _, ok := sc.file[2]; !ok || sc.tick[2] != 20 {
+		t.Error("evict() removed the other buffer")
+	}
+}
@@ -0,0 +1,451 @@
+// Copyright 2018 The nvim-go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package command
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"go/ast"
+	"go/scanner"
+	"go/token"
+	"go/types"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/neovim/go-client/nvim"
+	"github.com/pkg/errors"
+	"go.opencensus.io/trace"
+	"go.uber.org/zap"
+
+	"github.com/zchee/nvim-go/pkg/config"
+	"github.com/zchee/nvim-go/pkg/logger"
+	"github.com/zchee/nvim-go/pkg/monitoring"
+	"github.com/zchee/nvim-go/pkg/nvimutil"
+)
+
+// hlSignatureActiveParameter highlight group of the current parameter of the signature help.
+const hlSignatureActiveParameter = "goSignatureActiveParameter"
+
+// signatureDebounce is the delay of resolving the signature of the new call expression after the last change.
+const signatureDebounce = 150 * time.Millisecond
+
+// signatureHelp represents a signature help floating window and the last resolved signature.
+type signatureHelp struct {
+	mu     sync.Mutex
+	nsID   int
+	window nvim.Window
+
+	// bufnr, lparen and callee are the cache key of label.
+	bufnr  int
+	lparen int
+	callee string
+	label  *signatureLabel
+
+	// timer and gen of the debounced signature resolving.
+	timer *time.Timer
+	gen   int
+}
+
+// SignatureHelp shows the signature of the call expression under the cursor to the floating window.
+//
+// The signature is resolved by go/types only if the call expression is changed, so typing the
+// arguments only re-scans the buffer until the cursor. The resolving is debounced by signatureDebounce,
+// so typing the callee does not type check the buffer on every keystroke.
+func (c *Command) SignatureHelp(ctx context.Context, bufnr int, file string, offset int) error {
+	if !config.SignatureEnable {
+		return nil
+	}
+
+	var span *trace.Span
+	ctx, span = monitoring.StartSpan(ctx, "SignatureHelp")
+	defer span.End()
+
+	sh := c.signature
+	sh.mu.Lock()
+	defer sh.mu.Unlock()
+
+	buf, err := c.Nvim.BufferLines(nvim.Buffer(bufnr), 0, -1, true)
+	if err != nil {
+		span.SetStatus(trace.Status{Code: trace.StatusCodeInternal, Message: err.Error()})
+		return errors.WithStack(err)
+	}
+	src := nvimutil.ToByteSlice(buf)
+
+	site := findCallSite(src, offset)
+	if site == nil {
+		return sh.close(c.Nvim)
+	}
+
+	if sh.timer != nil {
+		sh.timer.Stop()
+	}
+	sh.gen++
+
+	if sh.label == nil || sh.bufnr != bufnr || sh.lparen != site.Lparen || sh.callee != site.Callee {
+		gen := sh.gen
+		sh.timer = time.AfterFunc(signatureDebounce, func() {
+			sh.mu.Lock()
+			defer sh.mu.Unlock()
+
+			// superseded by the later change
+			if sh.gen != gen {
+				return
+			}
+			if err := sh.resolve(c.Nvim, bufnr, file, src, offset, site); err != nil {
+				logger.FromContext(ctx).Error("SignatureHelp", zap.Error(err))
+			}
+		})
+		return nil
+	}
+
+	if err := sh.show(c.Nvim, sh.label, sh.label.Active(site.Arg)); err != nil {
+		span.SetStatus(trace.Status{Code: trace.StatusCodeInternal, Message: err.Error()})
+		return errors.WithStack(err)
+	}
+
+	return nil
+}
+
+// resolve type checks src and resolves the signature of the site, and shows it.
+// sh.mu must be held.
+func (sh *signatureHelp) resolve(v *nvim.Nvim, bufnr int, file string, src []byte, offset int, site *callSite) error {
+	cf, err := typeCheck(file, site.closedSource(src, offset))
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	sh.bufnr, sh.lparen, sh.callee = bufnr, site.Lparen, site.Callee
+	sh.label = callSignature(cf, site)
+	if sh.label == nil {
+		return sh.close(v)
+	}
+
+	return errors.WithStack(sh.show(v, sh.label, sh.label.Active(site.Arg)))
+}
+
+// show displays label to the floating window above the cursor, and highlights the active parameter.
+func (sh *signatureHelp) show(v *nvim.Nvim, label *signatureLabel, active int) error {
+	if sh.nsID == 0 {
+		nsID, err := v.CreateNamespace("nvim-go-signature")
+		if err != nil {
+			return err
+		}
+		sh.nsID = nsID
+	}
+
+	lines := [][]byte{[]byte(label.Text)}
+	width, height := nvimutil.FloatSize(lines, docWidth, 3)
+
+	if valid, _ := v.IsWindowValid(sh.window); !valid {
+		b, err := v.CreateBuffer(false, true)
+		if err != nil {
+			return err
+		}
+		batch := v.NewBatch()
+		batch.SetBufferOption(b, nvimutil.BufOptionBufhidden, nvimutil.BufhiddenWipe)
+		batch.SetBufferOption(b, nvimutil.BufOptionFiletype, "go")
+		if err := batch.Execute(); err != nil {
+			return err
+		}
+
+		w, err := v.OpenWindow(b, false, &nvim.WindowConfig{
+			Relative: "cursor",
+			Anchor:   "SW",
+			Width:    width,
+			Height:   height,
+			Row:      0,
+			Col:      0,
+			Style:    "minimal",
+		})
+		if err != nil {
+			return err
+		}
+		sh.window = w
+
+		batch.SetWindowOption(w, nvimutil.WinOptionWrap, true)
+		batch.Command(fmt.Sprintf("autocmd InsertLeave,BufLeave <buffer> ++once silent! call nvim_win_close(%d, v:true)", w))
+		if err := batch.Execute(); err != nil {
+			return err
+		}
+	}
+
+	b, err := v.WindowBuffer(sh.window)
+	if err != nil {
+		return err
+	}
+
+	batch := v.NewBatch()
+	batch.SetWindowConfig(sh.window, map[string]interface{}{
+		"relative": "cursor",
+		"anchor":   "SW",
+		"width":    width,
+		"height":   height,
+		"row":      0,
+		"col":      0,
+	})
+	batch.SetBufferLines(b, 0, -1, true, lines)
+	batch.ClearBufferNamespace(b, sh.nsID, 0, -1)
+	if 0 <= active && active < len(label.Params) {
+		var id int
+		batch.AddBufferHighlight(b, sh.nsID, hlSignatureActiveParameter, 0, label.Params[active][0], label.Params[active][1], &id)
+	}
+
+	return batch.Execute()
+}
+
+// close closes the signature help floating window if opened.
+func (sh *signatureHelp) close(v *nvim.Nvim) error {
+	if sh.window == 0 {
+		return nil
+	}
+	w := sh.window
+	sh.window = 0
+
+	if valid, _ := v.IsWindowValid(w); !valid {
+		return nil
+	}
+
+	return v.CloseWindow(w, true)
+}
+
+// callSite represents the innermost call expression which encloses the cursor.
+type callSite struct {
+	// Callee source text of the called function such as "fmt.Println".
+	Callee string
+	// Lparen byte offset of the left parenthesis of the call.
+	Lparen int
+	// Arg 0-based index of the argument under the cursor.
+	Arg int
+	// Closers closing tokens of the unclosed parentheses and brackets before the cursor.
+	Closers string
+}
+
+// callFrame represents the opened parenthesis, bracket or brace while scanning the source.
+type callFrame struct {
+	tok    token.Token
+	offset int
+	callee string
+	args   int
+	// params reports whether the parenthesis is the receiver or parameters of the function declaration.
+	params bool
+}
+
+// findCallSite scans src until the offset and returns the innermost call expression which encloses offset.
+// Returns nil if the cursor is not in the arguments of the call expression.
+//
+// findCallSite uses go/scanner instead of go/parser because the source code under typing is usually
+// not parseable.
+func findCallSite(src []byte, offset int) *callSite {
+	if offset < 0 || len(src) < offset {
+		return nil
+	}
+
+	fset := token.NewFileSet()
+	file := fset.AddFile("", -1, offset)
+
+	var s scanner.Scanner
+	s.Init(file, src[:offset], nil, 0)
+
+	type scanned struct {
+		tok    token.Token
+		lit    string
+		offset int
+	}
+	var (
+		stack  []*callFrame
+		toks   []scanned
+		popped *callFrame
+	)
+	for {
+		pos, tok, lit := s.Scan()
+		if tok == token.EOF {
+			break
+		}
+		off := file.Offset(pos)
+
+		switch tok {
+		case token.LPAREN:
+			frame := &callFrame{tok: tok, offset: off}
+			n := len(toks)
+			switch {
+			case n > 0 && toks[n-1].tok == token.FUNC:
+				frame.params = true
+			case n > 0 && toks[n-1].tok == token.IDENT:
+				i := n - 1
+				for i >= 2 && toks[i-1].tok == token.PERIOD && toks[i-2].tok == token.IDENT {
+					i -= 2
+				}
+				switch {
+				case i > 0 && toks[i-1].tok == token.FUNC:
+					frame.params = true // func Foo(
+				case i > 0 && toks[i-1].tok == token.RPAREN && popped != nil && popped.params:
+					frame.params = true // func (r T) Foo(
+				default:
+					var parts []string
+					for j := i; j < n; j += 2 {
+						parts = append(parts, toks[j].lit)
+					}
+					frame.callee = strings.Join(parts, ".")
+				}
+			}
+			stack = append(stack, frame)
+
+		case token.LBRACK, token.LBRACE:
+			stack = append(stack, &callFrame{tok: tok, offset: off})
+
+		case token.RPAREN, token.RBRACK, token.RBRACE:
+			if len(stack) > 0 {
+				popped = stack[len(stack)-1]
+				stack = stack[:len(stack)-1]
+			}
+
+		case token.COMMA:
+			if len(stack) > 0 {
+				stack[len(stack)-1].args++
+			}
+		}
+
+		if tok == token.IDENT || tok == token.PERIOD {
+			toks = append(toks, scanned{tok: tok, lit: lit, offset: off})
+		} else {
+			toks = append(toks, scanned{tok: tok, offset: off})
+		}
+	}
+
+	if len(stack) == 0 {
+		return nil
+	}
+	top := stack[len(stack)-1]
+	if top.tok != token.LPAREN || top.callee == "" {
+		return nil
+	}
+
+	var closers []byte
+	for i := len(stack) - 1; i >= 0 && stack[i].tok != token.LBRACE; i-- {
+		switch stack[i].tok {
+		case token.LPAREN:
+			closers = append(closers, ')')
+		case token.LBRACK:
+			closers = append(closers, ']')
+		}
+	}
+
+	return &callSite{
+		Callee:  top.callee,
+		Lparen:  top.offset,
+		Arg:     top.args,
+		Closers: string(closers),
+	}
+}
+
+// closedSource returns src which is inserted the closing tokens of the call expression at the offset.
+// The closing tokens that already follow the cursor are not inserted.
+func (site *callSite) closedSource(src []byte, offset int) []byte {
+	closers := site.Closers
+	rest := bytes.TrimLeft(src[offset:], " \t")
+	for len(closers) > 0 && len(rest) > 0 && rest[0] == closers[0] {
+		closers, rest = closers[1:], bytes.TrimLeft(rest[1:], " \t")
+	}
+	if closers == "" {
+		return src
+	}
+
+	closed := make([]byte, 0, len(src)+len(closers))
+	closed = append(closed, src[:offset]...)
+	closed = append(closed, closers...)
+
+	return append(closed, src[offset:]...)
+}
+
+// signatureLabel represents a signature help text of the function.
+type signatureLabel struct {
+	Text string
+	// Params 0-based byte column range of each parameter in the Text.
+	Params [][2]int
+	// Variadic reports whether the last parameter is variadic.
+	Variadic bool
+}
+
+// Active returns the index of the parameter for the arg-th argument. Returns -1 if there is no parameter.
+func (l *signatureLabel) Active(arg int) int {
+	if arg >= len(l.Params) {
+		if l.Variadic {
+			return len(l.Params) - 1
+		}
+		return -1
+	}
+
+	return arg
+}
+
+// callSignature returns the signature label of the call expression of site in the checked file.
+// Returns nil if the callee is not a function such as the type conversion.
+func callSignature(cf *checkedFile, site *callSite) *signatureLabel {
+	var call *ast.CallExpr
+	ast.Inspect(cf.File, func(n ast.Node) bool {
+		if call != nil {
+			return false
+		}
+		if ce, ok := n.(*ast.CallExpr); ok && cf.Fset.Position(ce.Lparen).Offset == site.Lparen {
+			call = ce
+			return false
+		}
+		return true
+	})
+	if call == nil {
+		return nil
+	}
+
+	typ := cf.Info.TypeOf(call.Fun)
+	if typ == nil {
+		return nil
+	}
+	sig, ok := typ.Underlying().(*types.Signature)
+	if !ok {
+		return nil
+	}
+
+	return newSignatureLabel(site.Callee, sig, types.RelativeTo(cf.Pkg))
+}
+
+// newSignatureLabel returns the signature label of the name function which has the sig signature.
+func newSignatureLabel(name string, sig *types.Signature, qf types.Qualifier) *signatureLabel {
+	label := &signatureLabel{Variadic: sig.Variadic()}
+
+	var b strings.Builder
+	b.WriteString("func " + name + "(")
+	params := sig.Params()
+	for i := 0; i < params.Len(); i++ {
+		if i > 0 {
+			b.WriteString(", ")
+		}
+		start := b.Len()
+
+		p := params.At(i)
+		if p.Name() != "" {
+			b.WriteString(p.Name() + " ")
+		}
+		// the variadic parameter of append(b, s...) with the string s is the string, not the slice
+		if s, ok := p.Type().(*types.Slice); ok && sig.Variadic() && i == params.Len()-1 {
+			b.WriteString("..." + types.TypeString(s.Elem(), qf))
+		} else {
+			b.WriteString(types.TypeString(p.Type(), qf))
+		}
+
+		label.Params = append(label.Params, [2]int{start, b.Len()})
+	}
+	b.WriteString(")")
+
+	switch results := sig.Results(); {
+	case results.Len() == 1 && results.At(0).Name() == "":
+		b.WriteString(" " + types.TypeString(results.At(0).Type(), qf))
+	case results.Len() > 0:
+		b.WriteString(" " + types.TypeString(results, qf))
+	}
+	label.Text = b.String()
+
+	return label
+}
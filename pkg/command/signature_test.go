@@ -0,0 +1,224 @@
+// Copyright 2018 The nvim-go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package command
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestFindCallSite(t *testing.T) {
+	tests := []struct {
+		name string
+		// src is the source code which the "|" is the cursor position.
+		src  string
+		want *callSite
+	}{
+		{
+			name: "first argument",
+			src:  "package a\nfunc f() {\n\tfmt.Println(|\n}\n",
+			want: &callSite{Callee: "fmt.Println", Lparen: 33, Arg: 0, Closers: ")"},
+		},
+		{
+			name: "second argument",
+			src:  "package a\nfunc f() {\n\tfoo(a, b|)\n}\n",
+			want: &callSite{Callee: "foo", Lparen: 25, Arg: 1, Closers: ")"},
+		},
+		{
+			name: "nested call",
+			src:  "package a\nfunc f() {\n\tfoo(a, bar(x, []int{1, 2}, |\n}\n",
+			want: &callSite{Callee: "bar", Lparen: 32, Arg: 2, Closers: "))"},
+		},
+		{
+			name: "closed nested call",
+			src:  "package a\nfunc f() {\n\tfoo(a, bar(x), |\n}\n",
+			want: &callSite{Callee: "foo", Lparen: 25, Arg: 2, Closers: ")"},
+		},
+		{
+			name: "comma in string and comment",
+			src:  "package a\nfunc f() {\n\tfoo(\",\", /* , */ |\n}\n",
+			want: &callSite{Callee: "foo", Lparen: 25, Arg: 1, Closers: ")"},
+		},
+		{
+			name: "composite literal",
+			src:  "package a\nfunc f() {\n\tfoo(T{a, |\n}\n",
+		},
+		{
+			name: "function declaration",
+			src:  "package a\nfunc f(a, |",
+		},
+		{
+			name: "method declaration",
+			src:  "package a\nfunc (r *T) f(a, |",
+		},
+		{
+			name: "function literal",
+			src:  "package a\nvar f = func(a, |",
+		},
+		{
+			name: "outside of call",
+			src:  "package a\nfunc f() {\n\tfoo(a)|\n}\n",
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			offset := strings.Index(tt.src, "|")
+			src := []byte(strings.Replace(tt.src, "|", "", 1))
+			if got := findCallSite(src, offset); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("findCallSite(%q) = %+v, want %+v", tt.src, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCallSiteClosedSource(t *testing.T) {
+	tests := []struct {
+		name    string
+		src     string
+		closers string
+		want    string
+	}{
+		{
+			name:    "unclosed",
+			src:     "foo(a, |\n}",
+			closers: ")",
+			want:    "foo(a, )\n}",
+		},
+		{
+			name:    "closed",
+			src:     "foo(a, | )\n}",
+			closers: ")",
+			want:    "foo(a,  )\n}",
+		},
+		{
+			name:    "partially closed",
+			src:     "foo(bar(|)\n}",
+			closers: "))",
+			want:    "foo(bar())\n}",
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			offset := strings.Index(tt.src, "|")
+			src := []byte(strings.Replace(tt.src, "|", "", 1))
+			site := &callSite{Closers: tt.closers}
+			if got := string(site.closedSource(src, offset)); got != tt.want {
+				t.Errorf("closedSource(%q) = %q, want %q", tt.src, got, tt.want)
+			}
+		})
+	}
+}
+
+const signatureSrc = `package foo
+
+type T struct{}
+
+func (t *T) Method(n int, opts ...string) (int, error) { return 0, nil }
+
+func Foo(a int, b string) bool { return false }
+
+func f() {
+	var t T
+	var s []int
+	var b []byte
+	var str string
+	Foo(1, |
+	t.Method(1, "a", "b", |)
+	append(s, |)
+	append(b, str...|)
+	string(|)
+}
+`
+
+func TestCallSignature(t *testing.T) {
+	file, _ := testCheckedFile(t, strings.Replace(signatureSrc, "|", "", -1))
+
+	tests := []struct {
+		name       string
+		cursor     int
+		wantText   string
+		wantActive string
+	}{
+		{
+			name:       "function",
+			cursor:     0,
+			wantText:   "func Foo(a int, b string) bool",
+			wantActive: "b string",
+		},
+		{
+			name:       "variadic method",
+			cursor:     1,
+			wantText:   "func t.Method(n int, opts ...string) (int, error)",
+			wantActive: "opts ...string",
+		},
+		{
+			name:       "builtin",
+			cursor:     2,
+			wantText:   "func append([]int, ...int) []int",
+			wantActive: "...int",
+		},
+		{
+			name:       "builtin append string",
+			cursor:     3,
+			wantText:   "func append([]byte, string) []byte",
+			wantActive: "string",
+		},
+		{
+			name:   "conversion",
+			cursor: 4,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			src := signatureSrc
+			for i := 0; i < tt.cursor; i++ {
+				src = strings.Replace(src, "|", "", 1)
+			}
+			offset := strings.Index(src, "|")
+			src = strings.Replace(src, "|", "", -1)
+
+			site := findCallSite([]byte(src), offset)
+			if site == nil {
+				t.Fatal("call site not found")
+			}
+			cf, err := typeCheck(file, site.closedSource([]byte(src), offset))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			label := callSignature(cf, site)
+			if tt.wantText == "" {
+				if label != nil {
+					t.Errorf("callSignature() = %q, want nil", label.Text)
+				}
+				return
+			}
+			if label == nil {
+				t.Fatal("callSignature() = nil")
+			}
+			if !strings.HasPrefix(label.Text, tt.wantText) {
+				t.Errorf("label.Text = %q, want %q", label.Text, tt.wantText)
+			}
+			if tt.wantActive != "" {
+				active := label.Active(site.Arg)
+				if active < 0 {
+					t.Fatalf("label.Active(%d) = %d", site.Arg, active)
+				}
+				r := label.Params[active]
+				if got := label.Text[r[0]:r[1]]; got != tt.wantActive {
+					t.Errorf("active parameter = %q, want %q", got, tt.wantActive)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,413 @@
+// Copyright 2018 The nvim-go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package command
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"go/ast"
+	"go/build"
+	"go/parser"
+	"go/token"
+	"hash/fnv"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+
+	"github.com/zchee/nvim-go/pkg/logger"
+)
+
+const (
+	// symbolIndexVersion version of the persisted symbol index format.
+	symbolIndexVersion = 2
+	// symbolIndexInterval interval of the background symbol index refreshing.
+	symbolIndexInterval = packageCacheTTL
+)
+
+// indexedSymbol represents an exported package-level symbol.
+type indexedSymbol struct {
+	Name string
+	Kind string
+	// Detail type or signature of the symbol such as "func(s string) string".
+	Detail string
+}
+
+// indexedPackage represents the exported symbols of a package.
+type indexedPackage struct {
+	ImportPath string
+	// Name package name. Empty if Dir has no importable Go files such as the main package, which is recorded
+	// to skip re-parsing Dir until it is changed.
+	Name string
+	Dir  string
+	// Std reports whether the package is in the GOROOT.
+	Std bool
+	// Stamp dirStamp of Dir. Used for the incremental refreshing.
+	Stamp   int64
+	Symbols []indexedSymbol
+}
+
+// symbolIndex represents an index of the exported symbols of all packages in the GOROOT, GOPATH and module cache.
+//
+// The index is built in the background, persisted to the user cache directory, and refreshed
+// incrementally which re-parses only the changed package directories.
+type symbolIndex struct {
+	mu     sync.RWMutex
+	pkgs   map[string]*indexedPackage // keyed by import path
+	byName map[string][]*indexedPackage
+
+	warm sync.Once
+	// file persisted index file path.
+	file string
+	// packages returns the list of packages to index.
+	packages func() []*goPackage
+}
+
+// persistedIndex represents the on-disk format of symbolIndex.
+type persistedIndex struct {
+	Version  int
+	Packages []*indexedPackage
+}
+
+// newSymbolIndex returns the new symbolIndex which indexes packages.
+func newSymbolIndex(packages func() []*goPackage) *symbolIndex {
+	idx := &symbolIndex{packages: packages}
+	if dir, err := os.UserCacheDir(); err == nil {
+		idx.file = filepath.Join(dir, "nvim-go", "symbols.json")
+	}
+	idx.set(nil)
+
+	return idx
+}
+
+// Warm starts the background goroutine which builds and refreshes the index at the first call.
+// The goroutine is stopped when ctx is done.
+func (idx *symbolIndex) Warm(ctx context.Context) {
+	idx.warm.Do(func() {
+		go idx.run(ctx)
+	})
+}
+
+// run loads the persisted index, and refreshes it each symbolIndexInterval.
+func (idx *symbolIndex) run(ctx context.Context) {
+	log := logger.FromContext(ctx).Named("symbolIndex")
+
+	if err := idx.load(); err != nil {
+		log.Debug("load", zap.Error(err))
+	}
+
+	ticker := time.NewTicker(symbolIndexInterval)
+	defer ticker.Stop()
+	for {
+		if idx.refresh() {
+			if err := idx.save(); err != nil {
+				log.Error("save", zap.Error(err))
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// set replaces the indexed packages to pkgs.
+func (idx *symbolIndex) set(pkgs []*indexedPackage) {
+	m := make(map[string]*indexedPackage, len(pkgs))
+	byName := make(map[string][]*indexedPackage)
+	for _, pkg := range pkgs {
+		m[pkg.ImportPath] = pkg
+		if pkg.Name != "" {
+			byName[pkg.Name] = append(byName[pkg.Name], pkg)
+		}
+	}
+	for _, pkgs := range byName {
+		sort.Slice(pkgs, func(i, j int) bool {
+			if pkgs[i].Std != pkgs[j].Std {
+				return pkgs[i].Std
+			}
+			return pkgs[i].ImportPath < pkgs[j].ImportPath
+		})
+	}
+
+	idx.mu.Lock()
+	idx.pkgs, idx.byName = m, byName
+	idx.mu.Unlock()
+}
+
+// Lookup returns the packages which package name is name. The GOROOT packages come first.
+func (idx *symbolIndex) Lookup(name string) []*indexedPackage {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	return idx.byName[name]
+}
+
+// refresh re-indexes the changed packages, and reports whether the index is changed.
+func (idx *symbolIndex) refresh() bool {
+	idx.mu.RLock()
+	old := idx.pkgs
+	idx.mu.RUnlock()
+
+	goroot := filepath.Join(build.Default.GOROOT, "src") + string(filepath.Separator)
+
+	var (
+		list []*goPackage
+		dirs []string
+	)
+	for _, p := range idx.packages() {
+		if isInternalPath(p.ImportPath) {
+			continue
+		}
+		list = append(list, p)
+		dirs = append(dirs, p.Dir)
+	}
+
+	var (
+		mu      sync.Mutex
+		pkgs    []*indexedPackage
+		changed bool
+	)
+	forEachDirStamp(dirs, func(i int, stamp int64) {
+		p := list[i]
+		pkg, ok := old[p.ImportPath]
+		if !ok || pkg.Dir != p.Dir || pkg.Stamp != stamp {
+			prev := pkg
+			pkg = indexPackage(p.Dir, p.ImportPath)
+			if pkg == nil {
+				// record the negative entry to not re-parse p.Dir at the next refresh
+				pkg = &indexedPackage{ImportPath: p.ImportPath, Dir: p.Dir}
+			}
+			pkg.Std = strings.HasPrefix(p.Dir, goroot)
+			pkg.Stamp = stamp
+			if !ok || !reflect.DeepEqual(prev, pkg) {
+				mu.Lock()
+				changed = true
+				mu.Unlock()
+			}
+		}
+
+		mu.Lock()
+		pkgs = append(pkgs, pkg)
+		mu.Unlock()
+	})
+
+	if !changed && len(pkgs) == len(old) {
+		return false
+	}
+	idx.set(pkgs)
+
+	return true
+}
+
+// load loads the persisted index file.
+func (idx *symbolIndex) load() error {
+	if idx.file == "" {
+		return nil
+	}
+
+	data, err := ioutil.ReadFile(idx.file)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	var pi persistedIndex
+	if err := json.Unmarshal(data, &pi); err != nil {
+		return errors.WithStack(err)
+	}
+	if pi.Version != symbolIndexVersion {
+		return errors.Errorf("unknown symbol index version: %d", pi.Version)
+	}
+	idx.set(pi.Packages)
+
+	return nil
+}
+
+// save persists the index to the file.
+func (idx *symbolIndex) save() error {
+	if idx.file == "" {
+		return nil
+	}
+
+	idx.mu.RLock()
+	pi := persistedIndex{Version: symbolIndexVersion}
+	for _, pkg := range idx.pkgs {
+		pi.Packages = append(pi.Packages, pkg)
+	}
+	idx.mu.RUnlock()
+	sort.Slice(pi.Packages, func(i, j int) bool { return pi.Packages[i].ImportPath < pi.Packages[j].ImportPath })
+
+	data, err := json.Marshal(&pi)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	if err := os.MkdirAll(filepath.Dir(idx.file), 0755); err != nil {
+		return errors.WithStack(err)
+	}
+
+	// write to the temporary file and rename it for the atomic update
+	tmp := idx.file + ".tmp"
+	if err := ioutil.WriteFile(tmp, data, 0644); err != nil {
+		return errors.WithStack(err)
+	}
+
+	return errors.WithStack(os.Rename(tmp, idx.file))
+}
+
+// isInternalPath reports whether the importPath is the internal package which can not be imported by the other trees.
+func isInternalPath(importPath string) bool {
+	return importPath == "internal" || strings.HasPrefix(importPath, "internal/") ||
+		strings.HasSuffix(importPath, "/internal") || strings.Contains(importPath, "/internal/")
+}
+
+// isIndexedFile reports whether the name file in dir is indexed.
+func isIndexedFile(dir, name string) bool {
+	if !strings.HasSuffix(name, ".go") || strings.HasSuffix(name, "_test.go") {
+		return false
+	}
+	match, err := build.Default.MatchFile(dir, name)
+
+	return err == nil && match
+}
+
+// dirStamp returns the hash of the names, sizes and modification times of the Go files in dir.
+// The stamp is changed if any Go file is added, removed, renamed or modified.
+func dirStamp(dir string) int64 {
+	fis, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return 0
+	}
+
+	h := fnv.New64a()
+	var b [16]byte
+	for _, fi := range fis {
+		if !strings.HasSuffix(fi.Name(), ".go") {
+			continue
+		}
+		io.WriteString(h, fi.Name())
+		binary.LittleEndian.PutUint64(b[:8], uint64(fi.Size()))
+		binary.LittleEndian.PutUint64(b[8:], uint64(fi.ModTime().UnixNano()))
+		h.Write(b[:])
+	}
+
+	return int64(h.Sum64())
+}
+
+// forEachDirStamp calls fn with the index and the dirStamp of each dirs concurrently.
+// At most runtime.NumCPU() fn are called at the same time, and forEachDirStamp waits for all fn to return.
+func forEachDirStamp(dirs []string, fn func(i int, stamp int64)) {
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, runtime.NumCPU())
+	for i, dir := range dirs {
+		i, dir := i, dir
+
+		wg.Add(1)
+		sem <- struct{}{}
+		go func() {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+
+			fn(i, dirStamp(dir))
+		}()
+	}
+	wg.Wait()
+}
+
+// indexPackage parses the Go files in dir and returns the exported symbols.
+// Returns nil if dir has no importable Go files.
+func indexPackage(dir, importPath string) *indexedPackage {
+	fis, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil
+	}
+
+	pkg := &indexedPackage{ImportPath: importPath, Dir: dir}
+	fset := token.NewFileSet()
+	for _, fi := range fis {
+		if !isIndexedFile(dir, fi.Name()) {
+			continue
+		}
+		f, err := parser.ParseFile(fset, filepath.Join(dir, fi.Name()), nil, 0)
+		if err != nil {
+			continue
+		}
+		if pkg.Name == "" {
+			pkg.Name = f.Name.Name
+		}
+		if f.Name.Name != pkg.Name {
+			continue
+		}
+		pkg.Symbols = append(pkg.Symbols, fileSymbols(fset, f)...)
+	}
+	if pkg.Name == "" || pkg.Name == "main" {
+		return nil
+	}
+	sort.Slice(pkg.Symbols, func(i, j int) bool { return pkg.Symbols[i].Name < pkg.Symbols[j].Name })
+
+	return pkg
+}
+
+// fileSymbols returns the exported package-level symbols of f.
+func fileSymbols(fset *token.FileSet, f *ast.File) []indexedSymbol {
+	var syms []indexedSymbol
+	for _, decl := range f.Decls {
+		switch decl := decl.(type) {
+		case *ast.FuncDecl:
+			if decl.Recv == nil && decl.Name.IsExported() {
+				syms = append(syms, indexedSymbol{Name: decl.Name.Name, Kind: completeKindFunc, Detail: nodeString(fset, decl.Type)})
+			}
+
+		case *ast.GenDecl:
+			for _, spec := range decl.Specs {
+				switch spec := spec.(type) {
+				case *ast.TypeSpec:
+					if !spec.Name.IsExported() {
+						continue
+					}
+					sym := indexedSymbol{Name: spec.Name.Name, Kind: completeKindType}
+					switch spec.Type.(type) {
+					case *ast.StructType:
+						sym.Detail = "struct"
+					case *ast.InterfaceType:
+						sym.Detail = "interface"
+					default:
+						sym.Detail = nodeString(fset, spec.Type)
+					}
+					syms = append(syms, sym)
+
+				case *ast.ValueSpec:
+					kind := completeKindVar
+					if decl.Tok == token.CONST {
+						kind = completeKindConst
+					}
+					var detail string
+					if spec.Type != nil {
+						detail = nodeString(fset, spec.Type)
+					}
+					for _, name := range spec.Names {
+						if name.IsExported() {
+							syms = append(syms, indexedSymbol{Name: name.Name, Kind: kind, Detail: detail})
+						}
+					}
+				}
+			}
+		}
+	}
+
+	return syms
+}
@@ -0,0 +1,252 @@
+// Copyright 2018 The nvim-go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package command
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+const symindexSrc = `package foo
+
+type Builder struct{}
+
+type Writer interface{}
+
+type ID int
+
+func NewBuilder(n int) *Builder { return nil }
+
+func (b *Builder) Method() {}
+
+func unexported() {}
+
+const Max, min = 10, 0
+
+var Default *Builder
+`
+
+func writeFile(t *testing.T, name, src string) {
+	t.Helper()
+
+	if err := os.MkdirAll(filepath.Dir(name), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(name, []byte(src), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestIndexPackage(t *testing.T) {
+	dir, err := ioutil.TempDir("", "nvim-go-symindex")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	writeFile(t, filepath.Join(dir, "foo.go"), symindexSrc)
+	writeFile(t, filepath.Join(dir, "foo_test.go"), "package foo\n\nfunc TestFoo() {}\n")
+	writeFile(t, filepath.Join(dir, "ignore.go"), "// +build ignore\n\npackage main\n\nfunc Ignored() {}\n")
+
+	pkg := indexPackage(dir, "example.com/foo")
+	if pkg == nil {
+		t.Fatal("indexPackage() = nil")
+	}
+	if pkg.Name != "foo" {
+		t.Errorf("pkg.Name = %q, want %q", pkg.Name, "foo")
+	}
+
+	want := []indexedSymbol{
+		{Name: "Builder", Kind: completeKindType, Detail: "struct"},
+		{Name: "Default", Kind: completeKindVar, Detail: "*Builder"},
+		{Name: "ID", Kind: completeKindType, Detail: "int"},
+		{Name: "Max", Kind: completeKindConst},
+		{Name: "NewBuilder", Kind: completeKindFunc, Detail: "func(n int) *Builder"},
+		{Name: "Writer", Kind: completeKindType, Detail: "interface"},
+	}
+	if !reflect.DeepEqual(pkg.Symbols, want) {
+		t.Errorf("pkg.Symbols = %+v, want %+v", pkg.Symbols, want)
+	}
+
+	writeFile(t, filepath.Join(dir, "main", "main.go"), "package main\n\nfunc Main() {}\n")
+	if pkg := indexPackage(filepath.Join(dir, "main"), "example.com/foo/main"); pkg != nil {
+		t.Errorf("indexPackage(main) = %+v, want nil", pkg)
+	}
+}
+
+func TestSymbolIndex(t *testing.T) {
+	dir, err := ioutil.TempDir("", "nvim-go-symindex")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	fooDir := filepath.Join(dir, "src", "example.com", "foo")
+	barDir := filepath.Join(dir, "src", "example.com", "bar")
+	cmdDir := filepath.Join(dir, "src", "example.com", "cmd")
+	writeFile(t, filepath.Join(fooDir, "foo.go"), symindexSrc)
+	writeFile(t, filepath.Join(barDir, "bar.go"), "package bar\n\nfunc Bar() {}\n")
+	writeFile(t, filepath.Join(cmdDir, "main.go"), "package main\n\nfunc main() {}\n")
+
+	pkgs := []*goPackage{
+		{ImportPath: "example.com/foo", Dir: fooDir},
+		{ImportPath: "example.com/bar", Dir: barDir},
+		{ImportPath: "example.com/cmd", Dir: cmdDir},
+		{ImportPath: "example.com/foo/internal/baz", Dir: filepath.Join(fooDir, "internal", "baz")},
+	}
+	idx := newSymbolIndex(func() []*goPackage { return pkgs })
+	idx.file = filepath.Join(dir, "cache", "symbols.json")
+
+	if !idx.refresh() {
+		t.Fatal("first refresh() = false, want true")
+	}
+	if idx.refresh() {
+		t.Error("refresh() without changes = true, want false")
+	}
+	if got := idx.Lookup("foo"); len(got) != 1 || got[0].ImportPath != "example.com/foo" {
+		t.Fatalf("Lookup(foo) = %+v", got)
+	}
+	if got := idx.Lookup("main"); len(got) != 0 {
+		t.Errorf("Lookup(main) = %+v, want empty", got)
+	}
+
+	// change the bar package
+	time.Sleep(10 * time.Millisecond)
+	writeFile(t, filepath.Join(barDir, "bar.go"), "package bar\n\nfunc Bar() {}\n\nfunc Baz() {}\n")
+	if !idx.refresh() {
+		t.Error("refresh() after the change = false, want true")
+	}
+	if got := idx.Lookup("bar"); len(got) != 1 || len(got[0].Symbols) != 2 {
+		t.Errorf("Lookup(bar) = %+v, want 2 symbols", got)
+	}
+
+	// remove the file which is not the newest
+	writeFile(t, filepath.Join(barDir, "qux.go"), "package bar\n\nfunc Qux() {}\n")
+	if !idx.refresh() {
+		t.Error("refresh() after the addition = false, want true")
+	}
+	os.Remove(filepath.Join(barDir, "bar.go"))
+	if !idx.refresh() {
+		t.Error("refresh() after the removal = false, want true")
+	}
+	if got := idx.Lookup("bar"); len(got) != 1 || !reflect.DeepEqual(got[0].Symbols, []indexedSymbol{{Name: "Qux", Kind: completeKindFunc, Detail: "func()"}}) {
+		t.Errorf("Lookup(bar) = %+v, want only Qux", got)
+	}
+
+	if err := idx.save(); err != nil {
+		t.Fatal(err)
+	}
+	loaded := newSymbolIndex(func() []*goPackage { return pkgs })
+	loaded.file = idx.file
+	if err := loaded.load(); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(loaded.pkgs, idx.pkgs) {
+		t.Errorf("loaded index = %+v, want %+v", loaded.pkgs, idx.pkgs)
+	}
+	if loaded.refresh() {
+		t.Error("refresh() of the loaded index = true, want false")
+	}
+}
+
+func TestUnimportedCompletions(t *testing.T) {
+	dir, err := ioutil.TempDir("", "nvim-go-autoimport")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	idx := newSymbolIndex(nil)
+	idx.set([]*indexedPackage{
+		{ImportPath: "example.com/strings", Name: "strings", Symbols: []indexedSymbol{{Name: "Builder", Kind: completeKindType}}},
+		{ImportPath: "strings", Name: "strings", Std: true, Symbols: []indexedSymbol{
+			{Name: "Builder", Kind: completeKindType, Detail: "struct"},
+			{Name: "ToUpper", Kind: completeKindFunc, Detail: "func(s string) string"},
+		}},
+	})
+
+	src := "package foo\n\nfunc f() {\n\tstrings.\n}\n"
+	offset := strings.Index(src, "strings.") + len("strings.")
+	items, err := completions(filepath.Join(dir, "foo.go"), []byte(src), offset, "Bu", idx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got []string
+	for _, item := range items {
+		got = append(got, item.Menu+"."+item.Word+" "+item.UserData)
+	}
+	want := []string{
+		"strings.Builder " + autoImportPrefix + "strings",
+		"example.com/strings.Builder " + autoImportPrefix + "example.com/strings",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("completions() = %v, want %v", got, want)
+	}
+}
+
+func TestAddImport(t *testing.T) {
+	tests := []struct {
+		name       string
+		src        string
+		importPath string
+		want       string
+	}{
+		{
+			name:       "no imports",
+			src:        "package foo\n\nfunc f() {\n\tstrings.Builder\n",
+			importPath: "strings",
+			want:       "package foo\n\nimport \"strings\"\n\nfunc f() {\n\tstrings.Builder\n",
+		},
+		{
+			name:       "std group",
+			src:        "// Package foo is foo.\npackage foo\n\nimport (\n\t\"fmt\"\n\n\t\"go.uber.org/zap\"\n)\n\nfunc f() {\n\tstrings.Bu\n",
+			importPath: "strings",
+			want:       "// Package foo is foo.\npackage foo\n\nimport (\n\t\"fmt\"\n\t\"strings\"\n\n\t\"go.uber.org/zap\"\n)\n\nfunc f() {\n\tstrings.Bu\n",
+		},
+		{
+			name:       "third party group",
+			src:        "package foo\n\nimport (\n\t\"fmt\"\n\n\t\"github.com/pkg/errors\"\n)\n\nfunc f() {\n\tzap.Stri\n",
+			importPath: "go.uber.org/zap",
+			want:       "package foo\n\nimport (\n\t\"fmt\"\n\n\t\"github.com/pkg/errors\"\n\t\"go.uber.org/zap\"\n)\n\nfunc f() {\n\tzap.Stri\n",
+		},
+		{
+			name:       "third party to std only",
+			src:        "package foo\n\nimport \"fmt\"\n\nfunc f() {\n\tzap.Stri\n",
+			importPath: "go.uber.org/zap",
+			want:       "package foo\n\nimport (\n\t\"fmt\"\n\n\t\"go.uber.org/zap\"\n)\n\nfunc f() {\n\tzap.Stri\n",
+		},
+		{
+			name:       "trailing comment",
+			src:        "package foo\n\nimport \"fmt\" // for Println\n\nfunc f() {\n\tstrings.Bu\n",
+			importPath: "strings",
+			want:       "package foo\n\nimport (\n\t\"fmt\" // for Println\n\t\"strings\"\n)\n\nfunc f() {\n\tstrings.Bu\n",
+		},
+		{
+			name:       "already imported",
+			src:        "package foo\n\nimport \"fmt\"\n",
+			importPath: "fmt",
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := addImport([]byte(tt.src), tt.importPath)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("addImport(%q)\ngot:\n%s\nwant:\n%s", tt.importPath, got, tt.want)
+			}
+		})
+	}
+}
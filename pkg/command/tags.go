@@ -0,0 +1,462 @@
+// Copyright 2018 The nvim-go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package command
+
+import (
+	"bytes"
+	"context"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/printer"
+	"go/token"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/neovim/go-client/nvim"
+	"github.com/pkg/errors"
+	"go.opencensus.io/trace"
+
+	"github.com/zchee/nvim-go/pkg/config"
+	"github.com/zchee/nvim-go/pkg/internal/strutil"
+	"github.com/zchee/nvim-go/pkg/monitoring"
+	"github.com/zchee/nvim-go/pkg/nvimutil"
+)
+
+func (c *Command) cmdAddTags(ctx context.Context, args []string, ranges [2]int, file string) {
+	errch := make(chan interface{}, 1)
+	go func() {
+		errch <- c.AddTags(ctx, args, ranges, file)
+	}()
+
+	select {
+	case <-ctx.Done():
+		return
+	case err := <-errch:
+		switch e := err.(type) {
+		case error:
+			nvimutil.ErrorWrap(c.Nvim, e)
+		case nil:
+			// nothing to do
+		}
+	}
+}
+
+func (c *Command) cmdRemoveTags(ctx context.Context, args []string, ranges [2]int, file string) {
+	errch := make(chan interface{}, 1)
+	go func() {
+		errch <- c.RemoveTags(ctx, args, ranges, file)
+	}()
+
+	select {
+	case <-ctx.Done():
+		return
+	case err := <-errch:
+		switch e := err.(type) {
+		case error:
+			nvimutil.ErrorWrap(c.Nvim, e)
+		case nil:
+			// nothing to do
+		}
+	}
+}
+
+// AddTags adds the struct field tags to the struct under the cursor or the selected range.
+//
+// The args are the comma separated tag keys and tag options, like "json,yaml,omitempty".
+func (c *Command) AddTags(pctx context.Context, args []string, ranges [2]int, file string) interface{} {
+	ctx, span := monitoring.StartSpan(pctx, "AddTags")
+	defer span.End()
+
+	keys, options := parseTagArgs(args)
+	if len(keys) == 0 {
+		err := errors.New("no tag keys specified")
+		span.SetStatus(trace.Status{Code: trace.StatusCodeInvalidArgument, Message: err.Error()})
+		return err
+	}
+
+	transforms := make(map[string]func(string) string, len(keys))
+	for _, key := range keys {
+		fn, err := tagTransform(key)
+		if err != nil {
+			span.SetStatus(trace.Status{Code: trace.StatusCodeInvalidArgument, Message: err.Error()})
+			return err
+		}
+		transforms[key] = fn
+	}
+
+	return c.modifyTags(ctx, ranges, file, func(field *ast.Field, st structTags) structTags {
+		return st.add(keys, options, fieldName(field), func(key, name string) string {
+			return transforms[key](name)
+		})
+	})
+}
+
+// RemoveTags removes the struct field tags from the struct under the cursor or the selected range.
+//
+// The args are the comma separated tag keys. Removes all tags if args is empty.
+func (c *Command) RemoveTags(pctx context.Context, args []string, ranges [2]int, file string) interface{} {
+	ctx, span := monitoring.StartSpan(pctx, "RemoveTags")
+	defer span.End()
+
+	keys, _ := parseTagArgs(args)
+
+	return c.modifyTags(ctx, ranges, file, func(field *ast.Field, st structTags) structTags {
+		return st.remove(keys)
+	})
+}
+
+// modifyTags rewrites the current buffer struct field tags with fn and updates the buffer.
+func (c *Command) modifyTags(ctx context.Context, ranges [2]int, file string, fn func(*ast.Field, structTags) structTags) error {
+	span := trace.FromContext(ctx)
+
+	b := nvim.Buffer(c.buildContext.BufNr)
+	in, err := c.Nvim.BufferLines(b, 0, -1, true)
+	if err != nil {
+		span.SetStatus(trace.Status{Code: trace.StatusCodeInternal, Message: err.Error()})
+		return errors.WithStack(err)
+	}
+
+	buf, err := rewriteTags(file, nvimutil.ToByteSlice(in), ranges[0], ranges[1], fn)
+	if err != nil {
+		span.SetStatus(trace.Status{Code: trace.StatusCodeInternal, Message: err.Error()})
+		return errors.WithStack(err)
+	}
+
+	out := nvimutil.ToBufferLines(buf)
+
+	return minUpdate(ctx, c.Nvim, b, in, out)
+}
+
+// tagTransform returns the case transform function of the tag key, which is configured by
+// config.TagsTransform or config.TagsDefaultTransform.
+func tagTransform(key string) (func(string) string, error) {
+	transform, ok := config.TagsTransform[key]
+	if !ok {
+		transform = config.TagsDefaultTransform
+	}
+
+	fn, ok := tagTransforms[transform]
+	if !ok {
+		return nil, errors.Errorf("unknown tag transform %q for %q tag", transform, key)
+	}
+
+	return fn, nil
+}
+
+// tagTransforms map of the case transform name and transform function.
+var tagTransforms = map[string]func(string) string{
+	"snakecase":          strutil.ToSnakeCase,
+	"screamingsnakecase": strutil.ToScreamingSnakeCase,
+	"camelcase":          strutil.ToCamelCase,
+	"lowercamelcase":     strutil.ToLowerCamelCase,
+	"kebab":              strutil.ToKebab,
+	"screamingkebab":     strutil.ToScreamingKebab,
+	"keep":               func(s string) string { return s },
+}
+
+// tagOptions list of known struct tag options. Other args are treated as the tag key.
+var tagOptions = map[string]bool{
+	"omitempty": true,
+	"string":    true,
+	"inline":    true,
+	"flow":      true,
+}
+
+// parseTagArgs parses the command args to the tag keys and tag options.
+func parseTagArgs(args []string) (keys, options []string) {
+	for _, arg := range args {
+		for _, s := range strings.Split(arg, ",") {
+			s = strings.TrimSpace(s)
+			switch {
+			case s == "":
+				continue
+			case tagOptions[s]:
+				options = append(options, s)
+			default:
+				keys = append(keys, s)
+			}
+		}
+	}
+
+	return keys, options
+}
+
+// rewriteTags rewrites the struct field tags of src with fn and returns the source.
+// Only the rewritten struct types are gofmt-ed, the rest of src is kept as is.
+//
+// If start equal to end, rewrites all fields of the innermost struct type that contains the start line.
+// Otherwise rewrites the fields on the start and end lines range.
+func rewriteTags(filename string, src []byte, start, end int, fn func(*ast.Field, structTags) structTags) ([]byte, error) {
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, filename, src, parser.ParseComments)
+	if err != nil {
+		return nil, err
+	}
+
+	fields, structs := structFields(fset, f, start, end)
+	if len(fields) == 0 {
+		return nil, errors.New("no struct fields here")
+	}
+
+	for _, field := range fields {
+		var st structTags
+		if field.Tag != nil {
+			tag, err := strconv.Unquote(field.Tag.Value)
+			if err != nil {
+				return nil, errors.Wrapf(err, "could not unquote tag of %s", fieldName(field))
+			}
+			if st, err = parseStructTags(tag); err != nil {
+				return nil, errors.Wrapf(err, "could not parse tag of %s", fieldName(field))
+			}
+		}
+
+		st = fn(field, st)
+		if len(st) == 0 {
+			field.Tag = nil
+			continue
+		}
+
+		value := st.String()
+		if strings.ContainsRune(value, '`') {
+			value = strconv.Quote(value)
+		} else {
+			value = "`" + value + "`"
+		}
+		if field.Tag == nil {
+			field.Tag = &ast.BasicLit{ValuePos: field.Type.End(), Kind: token.STRING}
+		}
+		field.Tag.Value = value
+	}
+
+	// replace the outermost rewritten struct types from the end of src, to keep the offsets of the preceding structs
+	structs = outermostStructs(structs)
+	tf := fset.File(f.Pos())
+	out := src
+	for i := len(structs) - 1; i >= 0; i-- {
+		st := structs[i]
+		var buf bytes.Buffer
+		if err := format.Node(&buf, fset, &printer.CommentedNode{Node: st, Comments: f.Comments}); err != nil {
+			return nil, err
+		}
+
+		startOff, endOff := tf.Offset(st.Pos()), tf.Offset(st.End())
+		// indent the following lines by the indentation of the struct type line
+		lineOff := bytes.LastIndexByte(src[:startOff], '\n') + 1
+		indent := src[lineOff:startOff]
+		indent = indent[:len(indent)-len(bytes.TrimLeft(indent, " \t"))]
+		formatted := bytes.Replace(buf.Bytes(), []byte{'\n'}, append([]byte{'\n'}, indent...), -1)
+
+		out = append(append(append([]byte(nil), out[:startOff]...), formatted...), out[endOff:]...)
+	}
+
+	return out, nil
+}
+
+// outermostStructs returns the structs which are not contained in the other structs, sorted by the position.
+func outermostStructs(structs []*ast.StructType) []*ast.StructType {
+	sort.Slice(structs, func(i, j int) bool { return structs[i].Pos() < structs[j].Pos() })
+
+	var outer []*ast.StructType
+	for _, st := range structs {
+		if n := len(outer); n > 0 && outer[n-1].Pos() <= st.Pos() && st.End() <= outer[n-1].End() {
+			continue
+		}
+		outer = append(outer, st)
+	}
+
+	return outer
+}
+
+// structFields returns the target struct fields of the start and end lines range, and the struct types of the fields.
+func structFields(fset *token.FileSet, f *ast.File, start, end int) ([]*ast.Field, []*ast.StructType) {
+	line := func(pos token.Pos) int { return fset.Position(pos).Line }
+
+	if start == end {
+		var innermost *ast.StructType
+		ast.Inspect(f, func(n ast.Node) bool {
+			st, ok := n.(*ast.StructType)
+			if !ok || n == nil {
+				return true
+			}
+			if line(st.Pos()) <= start && start <= line(st.End()) {
+				innermost = st
+			}
+			return true
+		})
+		if innermost == nil {
+			return nil, nil
+		}
+		return innermost.Fields.List, []*ast.StructType{innermost}
+	}
+
+	var fields []*ast.Field
+	var structs []*ast.StructType
+	ast.Inspect(f, func(n ast.Node) bool {
+		st, ok := n.(*ast.StructType)
+		if !ok {
+			return true
+		}
+		found := false
+		for _, field := range st.Fields.List {
+			if start <= line(field.Pos()) && line(field.Pos()) <= end {
+				fields = append(fields, field)
+				found = true
+			}
+		}
+		if found {
+			structs = append(structs, st)
+		}
+		return true
+	})
+
+	return fields, structs
+}
+
+// fieldName returns the name of field. If the field is embedded, returns the type name.
+func fieldName(field *ast.Field) string {
+	if len(field.Names) > 0 {
+		return field.Names[0].Name
+	}
+
+	typ := field.Type
+	for {
+		switch t := typ.(type) {
+		case *ast.StarExpr:
+			typ = t.X
+		case *ast.SelectorExpr:
+			return t.Sel.Name
+		case *ast.Ident:
+			return t.Name
+		default:
+			return ""
+		}
+	}
+}
+
+// structTag represents a single key:"name,options" pair of the struct tag.
+type structTag struct {
+	Key     string
+	Name    string
+	Options []string
+}
+
+// structTags represents a ordered struct tags.
+type structTags []structTag
+
+// parseStructTags parses the tag to structTags.
+// The parse logic is based on the reflect.StructTag.Lookup.
+func parseStructTags(tag string) (structTags, error) {
+	var st structTags
+
+	for tag != "" {
+		// Skip leading space.
+		i := 0
+		for i < len(tag) && tag[i] == ' ' {
+			i++
+		}
+		tag = tag[i:]
+		if tag == "" {
+			break
+		}
+
+		// Scan to colon. A space, a quote or a control character is a syntax error.
+		i = 0
+		for i < len(tag) && tag[i] > ' ' && tag[i] != ':' && tag[i] != '"' && tag[i] != 0x7f {
+			i++
+		}
+		if i == 0 || i+1 >= len(tag) || tag[i] != ':' || tag[i+1] != '"' {
+			return nil, errors.Errorf("bad syntax for struct tag pair: %q", tag)
+		}
+		key := tag[:i]
+		tag = tag[i+1:]
+
+		// Scan quoted string to find value.
+		i = 1
+		for i < len(tag) && tag[i] != '"' {
+			if tag[i] == '\\' {
+				i++
+			}
+			i++
+		}
+		if i >= len(tag) {
+			return nil, errors.Errorf("bad syntax for struct tag value: %q", tag)
+		}
+		qvalue := tag[:i+1]
+		tag = tag[i+1:]
+
+		value, err := strconv.Unquote(qvalue)
+		if err != nil {
+			return nil, errors.Errorf("bad syntax for struct tag value: %q", qvalue)
+		}
+
+		values := strings.Split(value, ",")
+		st = append(st, structTag{Key: key, Name: values[0], Options: values[1:]})
+	}
+
+	return st, nil
+}
+
+// add adds the keys tag to st. The name of the new tag is the transformed name by transform.
+// If st already has the key, keeps the tag name and appends the missing options.
+func (st structTags) add(keys, options []string, name string, transform func(key, name string) string) structTags {
+	for _, key := range keys {
+		idx := -1
+		for i, t := range st {
+			if t.Key == key {
+				idx = i
+				break
+			}
+		}
+		if idx == -1 {
+			st = append(st, structTag{Key: key, Name: transform(key, name)})
+			idx = len(st) - 1
+		}
+
+	option:
+		for _, opt := range options {
+			for _, o := range st[idx].Options {
+				if o == opt {
+					continue option
+				}
+			}
+			st[idx].Options = append(st[idx].Options, opt)
+		}
+	}
+
+	return st
+}
+
+// remove removes the keys tag from st. Removes all tags if keys is empty.
+func (st structTags) remove(keys []string) structTags {
+	if len(keys) == 0 {
+		return nil
+	}
+
+	var n structTags
+tag:
+	for _, t := range st {
+		for _, key := range keys {
+			if t.Key == key {
+				continue tag
+			}
+		}
+		n = append(n, t)
+	}
+
+	return n
+}
+
+// String implements a fmt.Stringer interface.
+func (st structTags) String() string {
+	tags := make([]string, len(st))
+	for i, t := range st {
+		value := strings.Join(append([]string{t.Name}, t.Options...), ",")
+		tags[i] = t.Key + ":" + strconv.Quote(value)
+	}
+
+	return strings.Join(tags, " ")
+}
@@ -0,0 +1,203 @@
+// Copyright 2018 The nvim-go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package command
+
+import (
+	"go/ast"
+	"reflect"
+	"testing"
+
+	"github.com/zchee/nvim-go/pkg/config"
+	"github.com/zchee/nvim-go/pkg/internal/strutil"
+)
+
+// tagsUnformatted is not gofmt-ed code which must be kept as is by rewriteTags.
+const tagsUnformatted = `
+var  unformatted = map[string]int{ "a":1 }
+`
+
+const tagsSrc = `package foo
+
+type Foo struct {
+	ID        int
+	UserName  string ` + "`" + `xml:"user"` + "`" + `
+	CreatedAt int64
+	Bar       struct {
+		BazQux string
+	}
+}
+` + tagsUnformatted
+
+func TestRewriteTags(t *testing.T) {
+	snake := func(key, name string) string { return strutil.ToSnakeCase(name) }
+
+	tests := []struct {
+		name       string
+		start, end int
+		fn         func(*ast.Field, structTags) structTags
+		want       string
+		wantErr    bool
+	}{
+		{
+			name:  "add json,omitempty",
+			start: 4,
+			end:   4,
+			fn: func(field *ast.Field, st structTags) structTags {
+				return st.add([]string{"json"}, []string{"omitempty"}, fieldName(field), snake)
+			},
+			want: `package foo
+
+type Foo struct {
+	ID        int    ` + "`" + `json:"id,omitempty"` + "`" + `
+	UserName  string ` + "`" + `xml:"user" json:"user_name,omitempty"` + "`" + `
+	CreatedAt int64  ` + "`" + `json:"created_at,omitempty"` + "`" + `
+	Bar       struct {
+		BazQux string
+	} ` + "`" + `json:"bar,omitempty"` + "`" + `
+}
+` + tagsUnformatted,
+		},
+		{
+			name:  "add nested struct",
+			start: 8,
+			end:   8,
+			fn: func(field *ast.Field, st structTags) structTags {
+				return st.add([]string{"yaml"}, nil, fieldName(field), snake)
+			},
+			want: `package foo
+
+type Foo struct {
+	ID        int
+	UserName  string ` + "`" + `xml:"user"` + "`" + `
+	CreatedAt int64
+	Bar       struct {
+		BazQux string ` + "`" + `yaml:"baz_qux"` + "`" + `
+	}
+}
+` + tagsUnformatted,
+		},
+		{
+			name:  "remove all of range",
+			start: 4,
+			end:   5,
+			fn: func(field *ast.Field, st structTags) structTags {
+				return st.remove(nil)
+			},
+			want: `package foo
+
+type Foo struct {
+	ID        int
+	UserName  string
+	CreatedAt int64
+	Bar       struct {
+		BazQux string
+	}
+}
+` + tagsUnformatted,
+		},
+		{
+			name:    "not struct",
+			start:   1,
+			end:     1,
+			fn:      func(field *ast.Field, st structTags) structTags { return st },
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := rewriteTags("foo.go", []byte(tagsSrc), tt.start, tt.end, tt.fn)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("rewriteTags() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if string(got) != tt.want && !tt.wantErr {
+				t.Errorf("rewriteTags() = \n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseStructTags(t *testing.T) {
+	tests := []struct {
+		name    string
+		tag     string
+		want    structTags
+		wantErr bool
+	}{
+		{
+			name: "multiple",
+			tag:  `json:"foo,omitempty" xml:"bar"`,
+			want: structTags{
+				{Key: "json", Name: "foo", Options: []string{"omitempty"}},
+				{Key: "xml", Name: "bar", Options: []string{}},
+			},
+		},
+		{
+			name:    "bad syntax",
+			tag:     `json:foo`,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := parseStructTags(tt.tag)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseStructTags(%q) error = %v, wantErr %v", tt.tag, err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseStructTags(%q) = %#v, want %#v", tt.tag, got, tt.want)
+			}
+			if got.String() != tt.tag && !tt.wantErr {
+				t.Errorf("structTags.String() = %q, want %q", got.String(), tt.tag)
+			}
+		})
+	}
+}
+
+func TestParseTagArgs(t *testing.T) {
+	keys, options := parseTagArgs([]string{"json,yaml,omitempty"})
+	if want := []string{"json", "yaml"}; !reflect.DeepEqual(keys, want) {
+		t.Errorf("keys = %v, want %v", keys, want)
+	}
+	if want := []string{"omitempty"}; !reflect.DeepEqual(options, want) {
+		t.Errorf("options = %v, want %v", options, want)
+	}
+}
+
+func TestTagTransform(t *testing.T) {
+	defer func(transform map[string]string, def string) {
+		config.TagsTransform, config.TagsDefaultTransform = transform, def
+	}(config.TagsTransform, config.TagsDefaultTransform)
+
+	config.TagsTransform = map[string]string{"json": "camelcase", "xml": "unknown"}
+	config.TagsDefaultTransform = "snakecase"
+
+	tests := []struct {
+		key     string
+		want    string
+		wantErr bool
+	}{
+		{key: "json", want: "UserName"},
+		{key: "yaml", want: "user_name"},
+		{key: "xml", wantErr: true},
+	}
+	for _, tt := range tests {
+		fn, err := tagTransform(tt.key)
+		if (err != nil) != tt.wantErr {
+			t.Fatalf("tagTransform(%s) error = %v, wantErr %v", tt.key, err, tt.wantErr)
+		}
+		if tt.wantErr {
+			continue
+		}
+		if got := fn("UserName"); got != tt.want {
+			t.Errorf("tagTransform(%s)(UserName) = %s, want %s", tt.key, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,305 @@
+// Copyright 2018 The nvim-go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package command
+
+import (
+	"context"
+	"go/ast"
+	"go/build"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"golang.org/x/tools/go/buildutil"
+)
+
+// typeCheckCacheMaxBase is the maximum base of the file set of typeCheckCache. The cache is reset if it is
+// exceeded, because the file set can not release the parsed files.
+const typeCheckCacheMaxBase = 1 << 26
+
+// checkedFile represents a type checked Go source file and the package of the file.
+type checkedFile struct {
+	Fset *token.FileSet
+	File *ast.File
+	// Files all files of the package. The File is the first element.
+	Files []*ast.File
+	Pkg   *types.Package
+	Info  *types.Info
+}
+
+// typeCheckCache caches the imported packages and the parsed files of the package for typeCheck.
+//
+// The imported packages are loaded from the export data which is listed by "go list -export", so only
+// the package of the checked file is type checked from source. The cache is reset if the Go files of
+// any imported package outside the GOROOT and module cache are changed, or the module root is changed.
+type typeCheckCache struct {
+	mu   sync.Mutex
+	root string
+	fset *token.FileSet
+	// gc imports the packages from the export data, src imports the packages which have no export data from source.
+	gc, src types.ImporterFrom
+	// exports export data files keyed by import path. Empty if the package has no export data.
+	exports map[string]string
+	// stamps dirStamp of the imported packages keyed by directory.
+	stamps map[string]int64
+	// files parsed files of the checked packages keyed by file path.
+	files map[string]*parsedFile
+}
+
+// parsedFile represents a parsed file and the file info when it is parsed.
+type parsedFile struct {
+	modTime time.Time
+	size    int64
+	file    *ast.File
+}
+
+// checkCache is the typeCheckCache shared by all typeCheck callers.
+var checkCache typeCheckCache
+
+// typeCheck parses and type checks the package of file, using src as the contents of file.
+//
+// The other files of the package are read from the disk. The type errors are ignored because
+// the source code under editing is usually incomplete.
+func typeCheck(file string, src []byte) (*checkedFile, error) {
+	return typeCheckFunc(file, src, func(err error) {})
+}
+
+// typeCheckFunc is like typeCheck, but calls errFn with each type error.
+func typeCheckFunc(file string, src []byte, errFn func(err error)) (*checkedFile, error) {
+	c := &checkCache
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	bctxt := buildutil.OverlayContext(&build.Default, map[string][]byte{file: src})
+	dir := filepath.Dir(file)
+	c.validate(dir)
+
+	var (
+		files   []*ast.File
+		imports []string
+	)
+	for _, name := range packageFiles(bctxt, file) {
+		var (
+			f   *ast.File
+			err error
+		)
+		if name == file {
+			f, err = parser.ParseFile(c.fset, file, src, typeCheckParserMode)
+		} else {
+			f, err = c.parseFile(name)
+		}
+		if f == nil {
+			return nil, errors.WithStack(err)
+		}
+		files = append(files, f)
+		for _, spec := range f.Imports {
+			if path, err := strconv.Unquote(spec.Path.Value); err == nil {
+				imports = append(imports, path)
+			}
+		}
+	}
+	c.listExports(dir, imports)
+
+	info := &types.Info{
+		Types:      make(map[ast.Expr]types.TypeAndValue),
+		Defs:       make(map[*ast.Ident]types.Object),
+		Uses:       make(map[*ast.Ident]types.Object),
+		Implicits:  make(map[ast.Node]types.Object),
+		Selections: make(map[*ast.SelectorExpr]*types.Selection),
+		Scopes:     make(map[ast.Node]*types.Scope),
+	}
+	conf := types.Config{
+		Importer:    c,
+		FakeImportC: true,
+		Error:       errFn,
+	}
+	// the type errors are reported to errFn
+	pkg, _ := conf.Check(files[0].Name.Name, c.fset, files, info)
+
+	return &checkedFile{
+		Fset:  c.fset,
+		File:  files[0],
+		Files: files,
+		Pkg:   pkg,
+		Info:  info,
+	}, nil
+}
+
+// position returns the position of pos. The "$GOROOT" prefix of the file name which is recorded in the
+// export data of the standard packages is expanded to the GOROOT.
+func (cf *checkedFile) position(pos token.Pos) token.Position {
+	p := cf.Fset.Position(pos)
+	if strings.HasPrefix(p.Filename, "$GOROOT"+string(filepath.Separator)) {
+		p.Filename = filepath.Join(build.Default.GOROOT, strings.TrimPrefix(p.Filename, "$GOROOT"))
+	}
+
+	return p
+}
+
+// typeCheckParserMode is the parser mode of typeCheck.
+const typeCheckParserMode = parser.AllErrors | parser.ParseComments
+
+// Import implements types.Importer.
+func (c *typeCheckCache) Import(path string) (*types.Package, error) {
+	return c.ImportFrom(path, "", 0)
+}
+
+// ImportFrom implements types.ImporterFrom. It imports the package from the export data if any, otherwise from source.
+// c.mu must be held.
+func (c *typeCheckCache) ImportFrom(path, dir string, mode types.ImportMode) (*types.Package, error) {
+	if c.exports[path] != "" {
+		if pkg, err := c.gc.ImportFrom(path, dir, mode); err == nil {
+			return pkg, nil
+		}
+	}
+
+	return c.src.ImportFrom(path, dir, mode)
+}
+
+// lookup opens the export data file of the path package for the gc importer.
+// c.mu must be held.
+func (c *typeCheckCache) lookup(path string) (io.ReadCloser, error) {
+	export := c.exports[path]
+	if export == "" {
+		return nil, errors.Errorf("no export data for %s", path)
+	}
+	f, err := os.Open(export)
+
+	return f, errors.WithStack(err)
+}
+
+// reset drops the all cached packages and files, and sets the module root to root.
+// c.mu must be held.
+func (c *typeCheckCache) reset(root string) {
+	c.root = root
+	c.fset = token.NewFileSet()
+	c.gc = importer.ForCompiler(c.fset, "gc", c.lookup).(types.ImporterFrom)
+	c.src = importer.ForCompiler(c.fset, "source", nil).(types.ImporterFrom)
+	c.exports = make(map[string]string)
+	c.stamps = make(map[string]int64)
+	c.files = make(map[string]*parsedFile)
+}
+
+// validate resets the cache if the module root of dir is changed, the file set is too large, or any
+// imported package is changed.
+// c.mu must be held.
+func (c *typeCheckCache) validate(dir string) {
+	root := moduleRoot(dir)
+	if c.fset == nil || c.root != root || c.fset.Base() > typeCheckCacheMaxBase {
+		c.reset(root)
+		return
+	}
+	for d, stamp := range c.stamps {
+		if dirStamp(d) != stamp {
+			c.reset(root)
+			return
+		}
+	}
+}
+
+// listExports lists the export data files of the imports which are not listed yet, and their dependencies.
+// The packages without export data, such as the packages which have errors, are imported from source.
+// c.mu must be held.
+func (c *typeCheckCache) listExports(dir string, imports []string) {
+	var paths []string
+	for _, path := range imports {
+		if _, ok := c.exports[path]; ok || path == "C" || path == "unsafe" {
+			continue
+		}
+		// record the package not listed by go list to not list it again
+		c.exports[path] = ""
+		paths = append(paths, path)
+	}
+	if len(paths) == 0 {
+		return
+	}
+
+	pkgs, err := goList(context.Background(), dir, append([]string{"-export", "-deps"}, paths...)...)
+	if err != nil {
+		return
+	}
+	modCache := moduleCacheDir(&build.Default)
+	for _, pkg := range pkgs {
+		if c.exports[pkg.ImportPath] != "" {
+			continue
+		}
+		c.exports[pkg.ImportPath] = pkg.Export
+		// the GOROOT and module cache are not changed, and the checked package is type checked from source
+		if pkg.Standard || pkg.Dir == "" || pkg.Dir == dir || (modCache != "" && strings.HasPrefix(pkg.Dir, modCache)) {
+			continue
+		}
+		c.stamps[pkg.Dir] = dirStamp(pkg.Dir)
+	}
+}
+
+// parseFile parses the file, or returns the cached file if the file is not changed since it is parsed.
+// c.mu must be held.
+func (c *typeCheckCache) parseFile(name string) (*ast.File, error) {
+	fi, err := os.Stat(name)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	if pf, ok := c.files[name]; ok && pf.modTime.Equal(fi.ModTime()) && pf.size == fi.Size() {
+		return pf.file, nil
+	}
+
+	f, err := parser.ParseFile(c.fset, name, nil, typeCheckParserMode)
+	if f == nil {
+		return nil, errors.WithStack(err)
+	}
+	c.files[name] = &parsedFile{modTime: fi.ModTime(), size: fi.Size(), file: f}
+
+	return f, nil
+}
+
+// packageFiles returns the full path of Go files which are same package with file.
+// The file itself is always the first element.
+func packageFiles(bctxt *build.Context, file string) []string {
+	files := []string{file}
+
+	dir, base := filepath.Split(file)
+	bp, err := bctxt.ImportDir(dir, 0)
+	if err != nil && bp == nil {
+		return files
+	}
+
+	var names []string
+	switch {
+	case contains(bp.XTestGoFiles, base):
+		names = bp.XTestGoFiles
+	case contains(bp.TestGoFiles, base):
+		names = append(append(append(names, bp.GoFiles...), bp.CgoFiles...), bp.TestGoFiles...)
+	case contains(bp.GoFiles, base), contains(bp.CgoFiles, base):
+		names = append(append(names, bp.GoFiles...), bp.CgoFiles...)
+	}
+
+	for _, name := range names {
+		if name != base && strings.HasSuffix(name, ".go") {
+			files = append(files, filepath.Join(dir, name))
+		}
+	}
+
+	return files
+}
+
+// contains reports whether the s contains the str.
+func contains(s []string, str string) bool {
+	for _, v := range s {
+		if v == str {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,67 @@
+// Copyright 2018 The nvim-go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package command
+
+import (
+	"go/types"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestTypeCheckCache(t *testing.T) {
+	dir, err := ioutil.TempDir("", "nvim-go-typecheck")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	writeFile(t, filepath.Join(dir, "go.mod"), "module example.com/m\n")
+	writeFile(t, filepath.Join(dir, "p", "p.go"), "package p\n\nfunc F() int { return 0 }\n")
+	file := filepath.Join(dir, "main.go")
+	src := "package main\n\nimport (\n\t\"fmt\"\n\n\t\"example.com/m/p\"\n)\n\nvar x = p.F()\n\nfunc main() { fmt.Println(x) }\n"
+	writeFile(t, file, src)
+
+	typeOfX := func() string {
+		t.Helper()
+
+		cf, err := typeCheck(file, []byte(src))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return types.TypeString(cf.Pkg.Scope().Lookup("x").Type(), nil)
+	}
+
+	if got := typeOfX(); got != "int" {
+		t.Fatalf("type of x = %s, want int", got)
+	}
+	if export := checkCache.exports["fmt"]; export == "" {
+		t.Error("fmt is not imported from the export data")
+	}
+
+	// the changed package is imported again
+	time.Sleep(10 * time.Millisecond)
+	writeFile(t, filepath.Join(dir, "p", "p.go"), "package p\n\nfunc F() string { return \"\" }\n")
+	if got := typeOfX(); got != "string" {
+		t.Errorf("type of x after the change = %s, want string", got)
+	}
+}
+
+// testCheckedFile writes src to the p.go file in a new temporary directory, and returns the file name and the
+// type checked src.
+func testCheckedFile(t *testing.T, src string) (string, *checkedFile) {
+	t.Helper()
+
+	file := filepath.Join(t.TempDir(), "p.go")
+	writeFile(t, file, src)
+	cf, err := typeCheck(file, []byte(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return file, cf
+}
@@ -0,0 +1,343 @@
+// Copyright 2018 The nvim-go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package command
+
+import (
+	"context"
+	"fmt"
+	"go/ast"
+	"go/token"
+	"go/types"
+
+	"github.com/neovim/go-client/nvim"
+	"github.com/pkg/errors"
+	"go.opencensus.io/trace"
+	"golang.org/x/tools/cmd/guru/serial"
+	"golang.org/x/tools/go/ast/astutil"
+
+	"github.com/zchee/nvim-go/pkg/fs"
+	"github.com/zchee/nvim-go/pkg/monitoring"
+	"github.com/zchee/nvim-go/pkg/nvimutil"
+)
+
+// typeHierarchyBufferName buffer name of the GoTypeHierarchy buffer.
+const typeHierarchyBufferName = "__GoTypeHierarchy__"
+
+type cmdTypeHierarchyEval struct {
+	Cwd    string `eval:"getcwd()"`
+	File   string `eval:"expand('%:p')"`
+	BufNr  int    `eval:"bufnr('%')"`
+	WinID  int    `eval:"win_getid()"`
+	Offset int    `eval:"line2byte(line('.')) + (col('.')-2)"`
+}
+
+func (c *Command) cmdTypeHierarchy(ctx context.Context, eval *cmdTypeHierarchyEval) {
+	errch := make(chan interface{}, 1)
+	go func() {
+		errch <- c.TypeHierarchy(ctx, eval)
+	}()
+
+	// the implements query runs the guru query, do not block the following GoCancel
+	go c.typeHierarchyResult(ctx, errch)
+}
+
+// typeHierarchyResult waits the result of TypeHierarchy from errch and shows it.
+func (c *Command) typeHierarchyResult(ctx context.Context, errch chan interface{}) {
+	select {
+	case <-ctx.Done():
+		return
+	case err := <-errch:
+		switch e := err.(type) {
+		case error:
+			if errors.Cause(e) == context.Canceled {
+				return
+			}
+			nvimutil.ErrorWrap(c.Nvim, e)
+		case nil:
+			// nothing to do
+		}
+	}
+}
+
+// TypeHierarchy shows the type hierarchy tree of the named type under the cursor.
+//
+// The tree has the embedded types of the type, the interfaces which the type satisfies and,
+// for the interface type, the types which implement the interface within the guru scope.
+func (c *Command) TypeHierarchy(ctx context.Context, eval *cmdTypeHierarchyEval) interface{} {
+	var span *trace.Span
+	ctx, span = monitoring.StartSpan(ctx, "TypeHierarchy")
+	defer span.End()
+
+	h := c.typeHierarchy
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	buf, err := c.Nvim.BufferLines(nvim.Buffer(eval.BufNr), 0, -1, true)
+	if err != nil {
+		span.SetStatus(trace.Status{Code: trace.StatusCodeInternal, Message: err.Error()})
+		return errors.WithStack(err)
+	}
+
+	cf, err := typeCheck(eval.File, nvimutil.ToByteSlice(buf))
+	if err != nil {
+		span.SetStatus(trace.Status{Code: trace.StatusCodeInternal, Message: err.Error()})
+		return errors.WithStack(err)
+	}
+
+	named, err := typeHierarchyRoot(cf, eval.Offset)
+	if err != nil {
+		span.SetStatus(trace.Status{Code: trace.StatusCodeInvalidArgument, Message: err.Error()})
+		return err
+	}
+
+	overlay, err := c.modifiedOverlay()
+	if err != nil {
+		span.SetStatus(trace.Status{Code: trace.StatusCodeInternal, Message: err.Error()})
+		return errors.WithStack(err)
+	}
+	decl := cf.position(named.Obj().Pos())
+	pos, err := guruPos(decl, overlay)
+	if err != nil {
+		span.SetStatus(trace.Status{Code: trace.StatusCodeInternal, Message: err.Error()})
+		return errors.WithStack(err)
+	}
+	res, err := c.guruQuery(ctx, "implements", eval.File, pos, overlay)
+	nvimutil.ClearMsg(c.Nvim)
+	if err != nil {
+		span.SetStatus(trace.Status{Code: trace.StatusCodeInternal, Message: err.Error()})
+		return errors.WithStack(err)
+	}
+	impls, ok := res.(*serial.Implements)
+	if !ok {
+		span.SetStatus(trace.Status{Code: trace.StatusCodeInternal, Message: errTypeAssertion.Error()})
+		return errTypeAssertion
+	}
+
+	h.srcBuffer = nvim.Buffer(eval.BufNr)
+	h.srcWindow = nvim.Window(eval.WinID)
+	h.srcFile = eval.File
+	h.tree = nvimutil.NewTree(typeHierarchyTree(cf.Fset, eval.Cwd, named, impls))
+
+	if !h.isOpened(c.Nvim) {
+		b := nvimutil.NewBuffer(c.Nvim)
+		if err := b.Create(typeHierarchyBufferName, filetypeGoAnalyze, "belowright 60vsplit", sidebarOption(filetypeGoAnalyze)); err != nil {
+			span.SetStatus(trace.Status{Code: trace.StatusCodeInternal, Message: err.Error()})
+			return errors.WithStack(err)
+		}
+		b.SetLocalMapping(nvimutil.NoremapNormal, map[string]string{
+			"<CR>": ":<C-u>call GoTypeHierarchyAction('jump')<CR>",
+			"o":    ":<C-u>call GoTypeHierarchyAction('toggle')<CR>",
+			"za":   ":<C-u>call GoTypeHierarchyAction('toggle')<CR>",
+			"q":    ":<C-u>call GoTypeHierarchyAction('close')<CR>",
+		})
+		h.buffer = b.Buffer()
+		h.window = b.Window
+	}
+
+	if err := h.render(c.Nvim); err != nil {
+		span.SetStatus(trace.Status{Code: trace.StatusCodeInternal, Message: err.Error()})
+		return errors.WithStack(err)
+	}
+
+	batch := c.Nvim.NewBatch()
+	batch.SetCurrentWindow(h.window)
+	batch.SetWindowCursor(h.window, [2]int{1, 0})
+
+	return batch.Execute()
+}
+
+func (c *Command) funcTypeHierarchyAction(ctx context.Context, args []string, line int) {
+	if err := c.TypeHierarchyAction(ctx, args, line); err != nil {
+		nvimutil.ErrorWrap(c.Nvim, err)
+	}
+}
+
+// TypeHierarchyAction runs the action of GoTypeHierarchy buffer to the node at line.
+//
+// The available actions are "jump", "toggle" and "close".
+func (c *Command) TypeHierarchyAction(ctx context.Context, args []string, line int) error {
+	h := c.typeHierarchy
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.tree == nil || len(args) == 0 {
+		return nil
+	}
+
+	switch args[0] {
+	case "jump":
+		n := h.tree.NodeAt(line)
+		if n == nil || !n.Start.IsValid() {
+			return nil
+		}
+		return jumpToPos(c.Nvim, h.srcWindow, n.Start)
+
+	case "toggle":
+		if !h.tree.Toggle(line) {
+			return nil
+		}
+		return h.render(c.Nvim)
+
+	case "close":
+		h.tree = nil
+		return c.Nvim.CloseWindow(h.window, true)
+
+	default:
+		return errors.Errorf("unknown GoTypeHierarchy action: %s", args[0])
+	}
+}
+
+// typeHierarchyRoot returns the named type of the identifier at offset.
+// If the identifier is not a named type, returns the type declaration enclosing offset.
+func typeHierarchyRoot(cf *checkedFile, offset int) (*types.Named, error) {
+	pos := cf.Fset.File(cf.File.Pos()).Pos(offset)
+	path, _ := astutil.PathEnclosingInterval(cf.File, pos, pos)
+
+	var T types.Type
+	if id, ok := path[0].(*ast.Ident); ok {
+		switch obj := cf.Info.ObjectOf(id).(type) {
+		case *types.TypeName:
+			T = obj.Type()
+		case *types.Func:
+			// the method name, use the receiver type
+			if recv := obj.Type().(*types.Signature).Recv(); recv != nil {
+				T = recv.Type()
+			}
+		case nil:
+			// nothing to do
+		default:
+			T = obj.Type()
+		}
+	}
+	if p, ok := T.(*types.Pointer); ok {
+		T = p.Elem()
+	}
+	named, ok := T.(*types.Named)
+	if !ok {
+		// not a named type, use the type declaration enclosing offset
+		for _, n := range path {
+			if spec, isSpec := n.(*ast.TypeSpec); isSpec {
+				named, ok = cf.Info.TypeOf(spec.Name).(*types.Named)
+				break
+			}
+		}
+	}
+	if !ok || named.Obj().Pkg() == nil || !named.Obj().Pos().IsValid() {
+		return nil, errors.New("no named type here")
+	}
+
+	return named, nil
+}
+
+// typeHierarchyTree returns the tree of the type hierarchy of named. impls is the result of the implements query of named.
+func typeHierarchyTree(fset *token.FileSet, cwd string, named *types.Named, impls *serial.Implements) *nvimutil.TreeNode {
+	qualifier := func(pkg *types.Package) string { return pkg.Name() }
+
+	root := &nvimutil.TreeNode{
+		Text:     fmt.Sprintf("%s %s", impls.T.Kind, typeNodeText(cwd, types.TypeString(named, qualifier), fset.Position(named.Obj().Pos()))),
+		Start:    fset.Position(named.Obj().Pos()),
+		Expanded: true,
+	}
+
+	if embedded := embeddedNodes(fset, cwd, named, qualifier, nil); len(embedded) > 0 {
+		root.Children = append(root.Children, &nvimutil.TreeNode{Text: "embedded", Children: embedded, Expanded: true})
+	}
+
+	if types.IsInterface(named) {
+		var impl []*nvimutil.TreeNode
+		for _, t := range impls.AssignableTo {
+			impl = append(impl, implementsNode(cwd, t, ""))
+		}
+		if len(impl) > 0 {
+			root.Children = append(root.Children, &nvimutil.TreeNode{Text: "implemented by", Children: impl, Expanded: true})
+		}
+	}
+
+	var super []*nvimutil.TreeNode
+	for _, t := range impls.AssignableFrom {
+		super = append(super, implementsNode(cwd, t, ""))
+	}
+	for _, t := range impls.AssignableFromPtr {
+		super = append(super, implementsNode(cwd, t, " (*"+named.Obj().Name()+")"))
+	}
+	if len(super) > 0 {
+		root.Children = append(root.Children, &nvimutil.TreeNode{Text: "implements", Children: super, Expanded: true})
+	}
+
+	return root
+}
+
+// embeddedNodes returns the tree nodes of the embedded fields of the struct T, or the embedded interfaces of the interface T.
+// The nodes of the embedded types of each embedded type are the children of the node.
+// seen is the named types of the ancestors to stop the recursive embedding through the pointer.
+func embeddedNodes(fset *token.FileSet, cwd string, T types.Type, qualifier types.Qualifier, seen []*types.Named) []*nvimutil.TreeNode {
+	var embedded []types.Type
+	switch u := T.Underlying().(type) {
+	case *types.Struct:
+		for i := 0; i < u.NumFields(); i++ {
+			if f := u.Field(i); f.Anonymous() {
+				embedded = append(embedded, f.Type())
+			}
+		}
+	case *types.Interface:
+		for i := 0; i < u.NumEmbeddeds(); i++ {
+			embedded = append(embedded, u.EmbeddedType(i))
+		}
+	}
+
+	if named, ok := T.(*types.Named); ok {
+		seen = append(seen, named)
+	}
+
+	var nodes []*nvimutil.TreeNode
+	for _, t := range embedded {
+		elem := t
+		if p, ok := elem.(*types.Pointer); ok {
+			elem = p.Elem()
+		}
+
+		var pos token.Position
+		named, ok := elem.(*types.Named)
+		if ok {
+			pos = fset.Position(named.Obj().Pos())
+		}
+		n := &nvimutil.TreeNode{
+			Text:  typeNodeText(cwd, types.TypeString(t, qualifier), pos),
+			Start: pos,
+		}
+
+		cycle := false
+		for _, s := range seen {
+			cycle = cycle || s == named
+		}
+		if cycle {
+			n.Text += " (cycle)"
+		} else if ok {
+			n.Children = embeddedNodes(fset, cwd, named, qualifier, seen)
+		}
+		nodes = append(nodes, n)
+	}
+
+	return nodes
+}
+
+// implementsNode returns the tree node of the implements query result type t. suffix is appended to the node text.
+func implementsNode(cwd string, t serial.ImplementsType, suffix string) *nvimutil.TreeNode {
+	pos := parseLineColPos(t.Pos)
+
+	return &nvimutil.TreeNode{
+		Text:  t.Kind + " " + typeNodeText(cwd, trimPkgPath(t.Name)+suffix, pos),
+		Start: pos,
+	}
+}
+
+// typeNodeText returns the node text of the type name, with the relative declaration position to cwd.
+func typeNodeText(cwd, name string, pos token.Position) string {
+	if !pos.IsValid() {
+		return name
+	}
+
+	return fmt.Sprintf("%s  %s:%d", name, fs.Rel(cwd, pos.Filename), pos.Line)
+}
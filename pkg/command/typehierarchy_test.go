@@ -0,0 +1,124 @@
+// Copyright 2018 The nvim-go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package command
+
+import (
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"golang.org/x/tools/cmd/guru/serial"
+
+	"github.com/zchee/nvim-go/pkg/nvimutil"
+)
+
+const typeHierarchySrc = `package p
+
+type Reader interface{ Read() }
+
+type ReadCloser interface {
+	Reader
+	Close()
+}
+
+type Base struct{}
+
+type Node struct {
+	Base
+	*Node
+	next int
+}
+
+var n Node
+`
+
+func TestTypeHierarchyTree(t *testing.T) {
+	file, cf := testCheckedFile(t, typeHierarchySrc)
+	dir := filepath.Dir(file)
+	other := filepath.Join(dir, "q.go") + ":3:6"
+
+	tests := []struct {
+		name     string
+		at       string
+		impls    *serial.Implements
+		wantRoot string
+		want     []string
+	}{
+		{
+			name: "struct",
+			at:   "next int",
+			impls: &serial.Implements{
+				T:                 serial.ImplementsType{Kind: "struct"},
+				AssignableFromPtr: []serial.ImplementsType{{Name: "github.com/foo/q.Linker", Pos: other, Kind: "interface"}},
+			},
+			wantRoot: "Node",
+			want: []string{
+				"struct p.Node  p.go:12",
+				"  embedded",
+				"    p.Base  p.go:10",
+				"    *p.Node  p.go:12 (cycle)",
+				"  implements",
+				"    interface q.Linker (*Node)  q.go:3",
+			},
+		},
+		{
+			name: "interface",
+			at:   "ReadCloser interface",
+			impls: &serial.Implements{
+				T:            serial.ImplementsType{Kind: "interface"},
+				AssignableTo: []serial.ImplementsType{{Name: "github.com/foo/q.file", Pos: other, Kind: "struct"}},
+			},
+			wantRoot: "ReadCloser",
+			want: []string{
+				"interface p.ReadCloser  p.go:5",
+				"  embedded",
+				"    p.Reader  p.go:3",
+				"  implemented by",
+				"    struct q.file  q.go:3",
+			},
+		},
+		{
+			name:     "variable",
+			at:       "n Node",
+			impls:    &serial.Implements{T: serial.ImplementsType{Kind: "struct"}},
+			wantRoot: "Node",
+			want: []string{
+				"struct p.Node  p.go:12",
+				"  embedded",
+				"    p.Base  p.go:10",
+				"    *p.Node  p.go:12 (cycle)",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			named, err := typeHierarchyRoot(cf, strings.Index(typeHierarchySrc, tt.at))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := named.Obj().Name(); got != tt.wantRoot {
+				t.Errorf("typeHierarchyRoot() = %v, want %v", got, tt.wantRoot)
+			}
+
+			var got []string
+			var walk func(n *nvimutil.TreeNode, indent string)
+			walk = func(n *nvimutil.TreeNode, indent string) {
+				got = append(got, indent+n.Text)
+				for _, c := range n.Children {
+					walk(c, indent+"  ")
+				}
+			}
+			walk(typeHierarchyTree(cf.Fset, dir, named, tt.impls), "")
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("typeHierarchyTree() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	if _, err := typeHierarchyRoot(cf, strings.Index(typeHierarchySrc, "package")); err == nil {
+		t.Error("typeHierarchyRoot() at package clause: want error")
+	}
+}
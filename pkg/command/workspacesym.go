@@ -0,0 +1,435 @@
+// Copyright 2018 The nvim-go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package command
+
+import (
+	"context"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/neovim/go-client/nvim"
+	"github.com/pkg/errors"
+	"go.opencensus.io/trace"
+
+	"github.com/zchee/nvim-go/pkg/fs"
+	"github.com/zchee/nvim-go/pkg/monitoring"
+	"github.com/zchee/nvim-go/pkg/nvimutil"
+)
+
+// workspaceSymbolLimit is the maximum number of the GoSymbols results.
+const workspaceSymbolLimit = 200
+
+// workspaceSymbol represents a package-level declaration or method in the workspace.
+type workspaceSymbol struct {
+	Name string
+	Kind string
+	// Container receiver type name of the method without "*". Empty if the symbol is not a method.
+	Container string
+	// Package package name of the file which declares the symbol.
+	Package string
+	Pos     token.Position
+}
+
+// workspaceIndex represents an index of the package-level declarations and methods of all Go files in the workspace root.
+//
+// The index is refreshed at each search which re-parses only the changed directories as same as symbolIndex,
+// and updated incrementally which re-parses only the written file.
+type workspaceIndex struct {
+	// buildMu serializes the build. ws.mu is not held while building so as not to block UpdateSymbols.
+	buildMu sync.Mutex
+
+	mu   sync.Mutex
+	root string
+	dirs map[string]*workspaceDir // keyed by directory path
+	// building root of the running build. The files written while building are queued to pending,
+	// and re-indexed after the built index is swapped in.
+	building string
+	pending  []string
+}
+
+// workspaceDir represents the symbols of the Go files in a directory.
+type workspaceDir struct {
+	// stamp dirStamp of the directory when it is parsed.
+	stamp int64
+	files map[string][]workspaceSymbol // keyed by file path
+}
+
+// cmdSymbolsEval represents the current working directory and the directory of the current buffer.
+type cmdSymbolsEval struct {
+	Cwd string `eval:"getcwd()"`
+	Dir string `eval:"expand('%:p:h')"`
+}
+
+func (c *Command) cmdSymbols(ctx context.Context, args []string, eval *cmdSymbolsEval) {
+	errch := make(chan interface{}, 1)
+	go func() {
+		errch <- c.Symbols(ctx, args, eval)
+	}()
+
+	// indexing the large workspace takes a while, do not block the following commands
+	go func() {
+		select {
+		case <-ctx.Done():
+			return
+		case err := <-errch:
+			switch e := err.(type) {
+			case error:
+				nvimutil.ErrorWrap(c.Nvim, e)
+			case nil:
+				// nothing to do
+			}
+		}
+	}()
+}
+
+// Symbols fuzzy searches the package-level declarations and methods in the workspace of the current buffer by the query,
+// and sets the results to the quickfix list.
+func (c *Command) Symbols(ctx context.Context, args []string, eval *cmdSymbolsEval) interface{} {
+	var span *trace.Span
+	ctx, span = monitoring.StartSpan(ctx, "Symbols")
+	defer span.End()
+
+	query := strings.Join(args, "")
+	if query == "" {
+		if err := c.Nvim.Call("input", &query, "GoSymbols: "); err != nil {
+			span.SetStatus(trace.Status{Code: trace.StatusCodeInternal, Message: err.Error()})
+			return errors.WithStack(err)
+		}
+		if query = strings.TrimSpace(query); query == "" {
+			return nil
+		}
+	}
+
+	dir := eval.Dir
+	if !fs.IsDir(dir) {
+		dir = eval.Cwd
+	}
+	root := workspaceRoot(dir)
+
+	ws := c.workspace
+	ws.mu.Lock()
+	indexed := ws.root == root
+	ws.mu.Unlock()
+	if !indexed {
+		nvimutil.EchoProgress(c.Nvim, "GoSymbols", "indexing %s", root)
+	}
+	ws.build(root)
+
+	ws.mu.Lock()
+	syms := ws.search(query, workspaceSymbolLimit)
+	ws.mu.Unlock()
+
+	if len(syms) == 0 {
+		nvimutil.Echo(c.Nvim, "GoSymbols: no symbols matching %s", query)
+		return nil
+	}
+
+	qflist := make([]*nvim.QuickfixError, 0, len(syms))
+	for _, sym := range syms {
+		qflist = append(qflist, &nvim.QuickfixError{
+			FileName: fs.Rel(eval.Cwd, sym.Pos.Filename),
+			LNum:     sym.Pos.Line,
+			Col:      sym.Pos.Column,
+			Text:     workspaceSymbolText(sym),
+		})
+	}
+
+	w, err := c.Nvim.CurrentWindow()
+	if err != nil {
+		span.SetStatus(trace.Status{Code: trace.StatusCodeInternal, Message: err.Error()})
+		return errors.WithStack(err)
+	}
+	batch := c.Nvim.NewBatch()
+	nvimutil.SetQuickfix(batch, qflist)
+	if err := nvimutil.OpenOuickfix(batch, w, false); err != nil {
+		span.SetStatus(trace.Status{Code: trace.StatusCodeInternal, Message: err.Error()})
+		return errors.WithStack(err)
+	}
+	nvimutil.ClearMsg(c.Nvim)
+
+	return nil
+}
+
+// UpdateSymbols re-indexes the file of the workspace symbol index on BufWritePost.
+// Does nothing if the file is not in the indexed workspace.
+func (c *Command) UpdateSymbols(ctx context.Context, file string) {
+	ws := c.workspace
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
+
+	if ws.building != "" && isWorkspacePath(ws.building, file) {
+		ws.pending = append(ws.pending, file)
+	}
+	if ws.root != "" && isWorkspacePath(ws.root, file) {
+		ws.update(file)
+	}
+}
+
+// isWorkspacePath reports whether the file is in the workspace root.
+func isWorkspacePath(root, file string) bool {
+	return strings.HasPrefix(file, root+string(filepath.Separator))
+}
+
+// workspaceSymbolText returns the quickfix text of sym, such as "method (T).Name [pkg]".
+func workspaceSymbolText(sym workspaceSymbol) string {
+	name := sym.Name
+	if sym.Container != "" {
+		name = "(" + sym.Container + ")." + name
+	}
+
+	return fmt.Sprintf("%s %s [%s]", sym.Kind, name, sym.Package)
+}
+
+// build indexes all Go files in root, and re-parses only the directories which are changed since the last build.
+// ws.mu must not be held.
+func (ws *workspaceIndex) build(root string) {
+	ws.buildMu.Lock()
+	defer ws.buildMu.Unlock()
+
+	ws.mu.Lock()
+	old := make(map[string]*workspaceDir, len(ws.dirs))
+	for dir, d := range ws.dirs {
+		old[dir] = d
+	}
+	ws.building, ws.pending = root, nil
+	ws.mu.Unlock()
+
+	var mu sync.Mutex
+	dirs := goDirs(root)
+	index := make(map[string]*workspaceDir, len(dirs))
+	forEachDirStamp(dirs, func(i int, stamp int64) {
+		d, ok := old[dirs[i]]
+		if !ok || d.stamp != stamp {
+			d = parseWorkspaceDir(dirs[i], stamp)
+		}
+
+		mu.Lock()
+		index[dirs[i]] = d
+		mu.Unlock()
+	})
+
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
+
+	ws.root, ws.dirs = root, index
+	for _, file := range ws.pending {
+		ws.update(file)
+	}
+	ws.building, ws.pending = "", nil
+}
+
+// update re-parses the file. The file is removed from the index if the file is removed or is not parsable.
+// ws.mu must be held.
+func (ws *workspaceIndex) update(file string) {
+	if !isWorkspaceFile(filepath.Base(file)) {
+		return
+	}
+
+	// copy the directory entry which may be shared with the running build
+	dir := filepath.Dir(file)
+	d := &workspaceDir{files: make(map[string][]workspaceSymbol)}
+	if prev, ok := ws.dirs[dir]; ok {
+		d.stamp = prev.stamp
+		for name, syms := range prev.files {
+			d.files[name] = syms
+		}
+	}
+
+	syms, err := parseWorkspaceSymbols(file)
+	if err != nil {
+		delete(d.files, file)
+	} else {
+		d.files[file] = syms
+	}
+	ws.dirs[dir] = d
+}
+
+// search returns the symbols matching query sorted by the fuzzy match score. At most limit symbols are returned.
+// ws.mu must be held.
+func (ws *workspaceIndex) search(query string, limit int) []workspaceSymbol {
+	type match struct {
+		sym   workspaceSymbol
+		score int
+	}
+
+	var matches []match
+	for _, d := range ws.dirs {
+		for _, syms := range d.files {
+			for _, sym := range syms {
+				if score, ok := fuzzyScore(query, sym.Name); ok {
+					matches = append(matches, match{sym: sym, score: score})
+				}
+			}
+		}
+	}
+	sort.Slice(matches, func(i, j int) bool {
+		mi, mj := matches[i], matches[j]
+		if mi.score != mj.score {
+			return mi.score > mj.score
+		}
+		if mi.sym.Name != mj.sym.Name {
+			return mi.sym.Name < mj.sym.Name
+		}
+		if mi.sym.Pos.Filename != mj.sym.Pos.Filename {
+			return mi.sym.Pos.Filename < mj.sym.Pos.Filename
+		}
+		return mi.sym.Pos.Line < mj.sym.Pos.Line
+	})
+	if len(matches) > limit {
+		matches = matches[:limit]
+	}
+
+	syms := make([]workspaceSymbol, len(matches))
+	for i, m := range matches {
+		syms[i] = m.sym
+	}
+
+	return syms
+}
+
+// isWorkspaceFile reports whether the file name is indexed to the workspace symbol index.
+func isWorkspaceFile(name string) bool {
+	return strings.HasSuffix(name, ".go") && !strings.HasSuffix(name, "_test.go") &&
+		!strings.HasPrefix(name, ".") && !strings.HasPrefix(name, "_")
+}
+
+// parseWorkspaceDir parses the Go files in dir. The files which are not parsable are skipped.
+func parseWorkspaceDir(dir string, stamp int64) *workspaceDir {
+	d := &workspaceDir{stamp: stamp, files: make(map[string][]workspaceSymbol)}
+	fis, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return d
+	}
+	for _, fi := range fis {
+		if !fi.Mode().IsRegular() || !isWorkspaceFile(fi.Name()) {
+			continue
+		}
+		file := filepath.Join(dir, fi.Name())
+		syms, err := parseWorkspaceSymbols(file)
+		if err != nil {
+			continue
+		}
+		d.files[file] = syms
+	}
+
+	return d
+}
+
+// parseWorkspaceSymbols parses the file and returns the package-level declarations and methods.
+func parseWorkspaceSymbols(file string) ([]workspaceSymbol, error) {
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, file, nil, 0)
+	if f == nil {
+		return nil, errors.WithStack(err)
+	}
+
+	return declSymbols(fset, f), nil
+}
+
+// declSymbols returns the package-level declarations and methods of f.
+func declSymbols(fset *token.FileSet, f *ast.File) []workspaceSymbol {
+	var syms []workspaceSymbol
+	add := func(id *ast.Ident, kind, container string) {
+		if id.Name == "_" {
+			return
+		}
+		syms = append(syms, workspaceSymbol{
+			Name:      id.Name,
+			Kind:      kind,
+			Container: container,
+			Package:   f.Name.Name,
+			Pos:       fset.Position(id.Pos()),
+		})
+	}
+
+	for _, decl := range f.Decls {
+		switch decl := decl.(type) {
+		case *ast.FuncDecl:
+			if decl.Recv == nil || len(decl.Recv.List) == 0 {
+				add(decl.Name, completeKindFunc, "")
+				continue
+			}
+			add(decl.Name, completeKindMethod, recvTypeName(decl))
+
+		case *ast.GenDecl:
+			for _, spec := range decl.Specs {
+				switch spec := spec.(type) {
+				case *ast.TypeSpec:
+					add(spec.Name, completeKindType, "")
+				case *ast.ValueSpec:
+					kind := completeKindVar
+					if decl.Tok == token.CONST {
+						kind = completeKindConst
+					}
+					for _, name := range spec.Names {
+						add(name, kind, "")
+					}
+				}
+			}
+		}
+	}
+
+	return syms
+}
+
+// fuzzyScore reports whether the all characters of pattern appear in name in order, ignoring case,
+// and returns the score of the match. The higher score is the better match.
+//
+// The consecutive matches, the matches at the start of the name and the matches at the word boundary
+// such as the upper case letter of the camel case and the letter after "_" get the bonus.
+func fuzzyScore(pattern, name string) (int, bool) {
+	if pattern == "" {
+		return 0, true
+	}
+
+	score := 0
+	prevMatch := false
+	prev := rune(0)
+	p := []rune(pattern)
+	pi := 0
+	for i, r := range name {
+		if pi == len(p) {
+			break
+		}
+
+		if unicode.ToLower(r) != unicode.ToLower(p[pi]) {
+			prevMatch = false
+			prev = r
+			continue
+		}
+
+		score++
+		switch {
+		case i == 0:
+			score += 8
+		case prev == '_' || (unicode.IsUpper(r) && !unicode.IsUpper(prev)):
+			score += 6
+		}
+		if prevMatch {
+			score += 4
+		}
+		if r == p[pi] {
+			score++
+		}
+		prevMatch = true
+		prev = r
+		pi++
+	}
+	if pi < len(p) {
+		return 0, false
+	}
+
+	// the shorter name is the better match
+	return score*8 - utf8.RuneCountInString(name), true
+}
@@ -0,0 +1,137 @@
+// Copyright 2018 The nvim-go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package command
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestWorkspaceIndex(t *testing.T) {
+	dir, err := ioutil.TempDir("", "nvim-go-workspacesym")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	writeFile(t, filepath.Join(dir, "go.mod"), "module example.com/foo\n")
+	writeFile(t, filepath.Join(dir, "foo.go"), `package foo
+
+const maxSize = 10
+
+type Server struct{}
+
+func (s *Server) ServeHTTP() {}
+
+func NewServer() *Server { return nil }
+`)
+	writeFile(t, filepath.Join(dir, "bar", "bar.go"), `package bar
+
+var serverName, _ = "bar", 0
+`)
+	writeFile(t, filepath.Join(dir, "foo_test.go"), "package foo\n\nfunc TestServer() {}\n")
+	writeFile(t, filepath.Join(dir, "testdata", "x.go"), "package x\n\nfunc Server() {}\n")
+	writeFile(t, filepath.Join(dir, "vendor", "v", "v.go"), "package v\n\nfunc Server() {}\n")
+
+	texts := func(syms []workspaceSymbol) []string {
+		var s []string
+		for _, sym := range syms {
+			s = append(s, workspaceSymbolText(sym))
+		}
+		return s
+	}
+
+	ws := new(workspaceIndex)
+	ws.build(dir)
+
+	got := texts(ws.search("srv", workspaceSymbolLimit))
+	want := []string{
+		"var serverName [bar]",
+		"type Server [foo]",
+		"method (Server).ServeHTTP [foo]",
+		"func NewServer [foo]",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("search(%q) = %q, want %q", "srv", got, want)
+	}
+
+	if got := ws.search("srv", 1); len(got) != 1 {
+		t.Errorf("search(%q) with limit 1 returns %d symbols", "srv", len(got))
+	}
+
+	// update the written file incrementally
+	writeFile(t, filepath.Join(dir, "bar", "bar.go"), "package bar\n\nfunc Serve() {}\n")
+	ws.update(filepath.Join(dir, "bar", "bar.go"))
+	got = texts(ws.search("serve", workspaceSymbolLimit))
+	want = []string{
+		"func Serve [bar]",
+		"type Server [foo]",
+		"method (Server).ServeHTTP [foo]",
+		"func NewServer [foo]",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("search(%q) after update = %q, want %q", "serve", got, want)
+	}
+
+	os.Remove(filepath.Join(dir, "bar", "bar.go"))
+	ws.update(filepath.Join(dir, "bar", "bar.go"))
+	if got := texts(ws.search("Serve", workspaceSymbolLimit)); len(got) != 3 {
+		t.Errorf("search(%q) after remove = %q", "Serve", got)
+	}
+
+	// rebuild re-parses only the changed directories
+	foo := ws.dirs[dir]
+	time.Sleep(10 * time.Millisecond)
+	writeFile(t, filepath.Join(dir, "bar", "baz.go"), "package bar\n\nfunc Serve() {}\n")
+	ws.build(dir)
+	if ws.dirs[dir] != foo {
+		t.Error("build() re-parses the unchanged directory")
+	}
+	if got := texts(ws.search("serve", workspaceSymbolLimit)); !reflect.DeepEqual(got, want) {
+		t.Errorf("search(%q) after rebuild = %q, want %q", "serve", got, want)
+	}
+}
+
+func TestFuzzyScore(t *testing.T) {
+	tests := []struct {
+		pattern string
+		name    string
+		ok      bool
+	}{
+		{pattern: "nsrv", name: "NewServer", ok: true},
+		{pattern: "NEWSERVER", name: "NewServer", ok: true},
+		{pattern: "", name: "NewServer", ok: true},
+		{pattern: "svn", name: "NewServer", ok: false},
+		{pattern: "newserverx", name: "NewServer", ok: false},
+	}
+	for _, tt := range tests {
+		if _, ok := fuzzyScore(tt.pattern, tt.name); ok != tt.ok {
+			t.Errorf("fuzzyScore(%q, %q) = %v, want %v", tt.pattern, tt.name, ok, tt.ok)
+		}
+	}
+
+	// better matches have higher scores
+	ranks := []struct {
+		pattern       string
+		better, worse string
+	}{
+		{pattern: "srv", better: "Server", worse: "observer"},
+		{pattern: "ns", better: "NewServer", worse: "Names"},
+		{pattern: "serve", better: "Serve", worse: "Server"},
+		{pattern: "Serve", better: "Serve", worse: "serve"},
+		{pattern: "cfg", better: "load_cfg", worse: "loadcfgx"},
+	}
+	for _, tt := range ranks {
+		better, _ := fuzzyScore(tt.pattern, tt.better)
+		worse, _ := fuzzyScore(tt.pattern, tt.worse)
+		if better <= worse {
+			t.Errorf("fuzzyScore(%q): %q = %d, want higher than %q = %d", tt.pattern, tt.better, better, tt.worse, worse)
+		}
+	}
+}
@@ -14,16 +14,23 @@ import (
 type Config struct {
 	Global *Global
 
-	Build    *build
-	Cover    *cover
-	Fmt      *fmt
-	Generate *generate
-	Guru     *guru
-	Iferr    *iferr
-	Lint     *lint
-	Rename   *rename
-	Terminal *terminal
-	Test     *test
+	Build     *build
+	CallGraph *callGraph
+	Cover     *cover
+	DeadCode  *deadCode
+	Fmt       *fmt
+	Generate  *generate
+	Gopls     *gopls
+	Guru      *guru
+	Highlight *highlight
+	Hover     *hover
+	Iferr     *iferr
+	Lint      *lint
+	Rename    *rename
+	Signature *signature
+	Tags      *tags
+	Terminal  *terminal
+	Test      *test
 
 	Debug *debug
 }
@@ -44,11 +51,23 @@ type build struct {
 	IsNotGb   bool     `eval:"get(g:, 'go#build#is_not_gb', v:false)"`
 }
 
+// callGraph represents a GoCallGraph command config variable.
+type callGraph struct {
+	Format string `eval:"get(g:, 'go#callgraph#format', 'dot')"`
+	Output string `eval:"get(g:, 'go#callgraph#output', '')"`
+}
+
 type cover struct {
 	Flags []string `eval:"get(g:, 'go#cover#flags', [])"`
 	Mode  string   `eval:"get(g:, 'go#cover#mode', 'atomic')"`
 }
 
+// deadCode represents a GoDeadCode command config variable.
+type deadCode struct {
+	ExcludeGenerated bool `eval:"get(g:, 'go#deadcode#exclude_generated', v:false)"`
+	ExcludeTests     bool `eval:"get(g:, 'go#deadcode#exclude_tests', v:false)"`
+}
+
 // fmt represents a GoFmt command config variable.
 type fmt struct {
 	Autosave       bool     `eval:"get(g:, 'go#fmt#autosave', v:false)"`
@@ -67,6 +86,13 @@ type generate struct {
 	TemplateParamsPath string `eval:"get(g:, 'go#generate#test#template_params_path', '')"`
 }
 
+// gopls represents a gopls language server backend config variable.
+type gopls struct {
+	Enable bool     `eval:"get(g:, 'go#gopls#enable', v:false)"`
+	Path   string   `eval:"get(g:, 'go#gopls#path', 'gopls')"`
+	Args   []string `eval:"get(g:, 'go#gopls#args', [])"`
+}
+
 // guru represents a GoGuru command config variable.
 type guru struct {
 	Reflection bool            `eval:"get(g:, 'go#guru#reflection', v:false)"`
@@ -74,6 +100,18 @@ type guru struct {
 	JumpFirst  bool            `eval:"get(g:, 'go#guru#jump_first', v:false)"`
 }
 
+// highlight represents a semantic highlighting config variable.
+type highlight struct {
+	Semantic bool `eval:"get(g:, 'go#highlight#semantic', v:false)"`
+	SameIds  bool `eval:"get(g:, 'go#highlight#same_ids', v:false)"`
+}
+
+// hover represents a GoHover command config variable.
+type hover struct {
+	Auto  bool  `eval:"get(g:, 'go#hover#auto', v:false)"`
+	Delay int64 `eval:"get(g:, 'go#hover#delay', 500)"`
+}
+
 // iferr represents a GoIferr command config variable.
 type iferr struct {
 	Autosave bool `eval:"get(g:, 'go#iferr#autosave', v:false)"`
@@ -98,6 +136,18 @@ type lint struct {
 // rename represents a GoRename command config variable.
 type rename struct {
 	Prefill bool `eval:"get(g:, 'go#rename#prefill', v:false)"`
+	Preview bool `eval:"get(g:, 'go#rename#preview', v:true)"`
+}
+
+// signature represents a signature help config variable.
+type signature struct {
+	Enable bool `eval:"get(g:, 'go#signature#enable', v:true)"`
+}
+
+// tags represents a GoAddTags and GoRemoveTags commands config variable.
+type tags struct {
+	Transform        map[string]string `eval:"get(g:, 'go#tags#transform', {})"`
+	DefaultTransform string            `eval:"get(g:, 'go#tags#default_transform', 'snakecase')"`
 }
 
 // terminal represents a configure of Neovim terminal buffer.
@@ -142,11 +192,21 @@ var (
 	// BuildIsNotGb workaround for not ues gb compiler.
 	BuildIsNotGb bool
 
+	// CallGraphFormat output format of the GoCallGraph command, "dot" or "json".
+	CallGraphFormat string
+	// CallGraphOutput output file path of the GoCallGraph command. Uses the temporary file if empty.
+	CallGraphOutput string
+
 	// CoverFlags flags for cover command.
 	CoverFlags []string
 	// CoverMode mode of cover command.
 	CoverMode string
 
+	// DeadCodeExcludeGenerated excludes the generated files from the GoDeadCode results.
+	DeadCodeExcludeGenerated bool
+	// DeadCodeExcludeTests excludes the _test.go files from the GoDeadCode results.
+	DeadCodeExcludeTests bool
+
 	// FmtAutosave call the GoFmt command automatically at during the BufWritePre.
 	FmtAutosave bool
 	// FmtMode formatting mode of Fmt command.
@@ -169,6 +229,13 @@ var (
 	// GenerateTestTemplateParamsPath path to custom paramters json file(s).
 	GenerateTestTemplateParamsPath string
 
+	// GoplsEnable use gopls as the backend of GoGuruDefinition, GoGuruReferrers, GoGuruImplements, GoRename and GoFmt commands.
+	GoplsEnable bool
+	// GoplsPath path of the gopls binary.
+	GoplsPath string
+	// GoplsArgs additional arguments of the gopls.
+	GoplsArgs []string
+
 	// GuruReflection use the type reflection on GoGuru commmands.
 	GuruReflection bool
 	// GuruKeepCursor keep the cursor focus to source buffer instead of quickfix or locationlist.
@@ -176,6 +243,16 @@ var (
 	// GuruJumpFirst jump the first error position on GoGuru commands.
 	GuruJumpFirst bool
 
+	// HighlightSemantic enable the type-aware semantic highlighting.
+	HighlightSemantic bool
+	// HighlightSameIds highlight the references of the identifier under the cursor at during the CursorHold.
+	HighlightSameIds bool
+
+	// HoverAuto show the hover floating window automatically at during the CursorHold.
+	HoverAuto bool
+	// HoverDelay delay milliseconds of the HoverAuto after the CursorHold.
+	HoverDelay int64
+
 	// IferrAutosave call the GoIferr command automatically at during the BufWritePre.
 	IferrAutosave bool
 
@@ -206,6 +283,16 @@ var (
 
 	// RenamePrefill Enable naming prefill.
 	RenamePrefill bool
+	// RenamePreview shows the diff of the renaming to the preview buffer before applying.
+	RenamePreview bool
+
+	// SignatureEnable enable the signature help of the call expression on insert mode.
+	SignatureEnable bool
+
+	// TagsTransform case transform of the struct tag name for each tag key.
+	TagsTransform map[string]string
+	// TagsDefaultTransform case transform of the struct tag name if TagsTransform has not the tag key.
+	TagsDefaultTransform string
 
 	// TerminalMode open the terminal window mode.
 	TerminalMode string
@@ -245,10 +332,18 @@ func Get(v *nvim.Nvim, cfg *Config) {
 	BuildFlags = cfg.Build.Flags
 	BuildIsNotGb = cfg.Build.IsNotGb
 
+	// CallGraph
+	CallGraphFormat = cfg.CallGraph.Format
+	CallGraphOutput = cfg.CallGraph.Output
+
 	// Cover
 	CoverFlags = cfg.Cover.Flags
 	CoverMode = cfg.Cover.Mode
 
+	// DeadCode
+	DeadCodeExcludeGenerated = cfg.DeadCode.ExcludeGenerated
+	DeadCodeExcludeTests = cfg.DeadCode.ExcludeTests
+
 	// Fmt
 	FmtAutosave = cfg.Fmt.Autosave
 	FmtMode = cfg.Fmt.Mode
@@ -263,11 +358,24 @@ func Get(v *nvim.Nvim, cfg *Config) {
 	GenerateTestTemplateDir = cfg.Generate.TestTemplateDir
 	GenerateTestTemplateParamsPath = cfg.Generate.TemplateParamsPath
 
+	// Gopls
+	GoplsEnable = cfg.Gopls.Enable
+	GoplsPath = cfg.Gopls.Path
+	GoplsArgs = cfg.Gopls.Args
+
 	// Guru
 	GuruReflection = cfg.Guru.Reflection
 	GuruKeepCursor = cfg.Guru.KeepCursor
 	GuruJumpFirst = cfg.Guru.JumpFirst
 
+	// Highlight
+	HighlightSemantic = cfg.Highlight.Semantic
+	HighlightSameIds = cfg.Highlight.SameIds
+
+	// Hover
+	HoverAuto = cfg.Hover.Auto
+	HoverDelay = cfg.Hover.Delay
+
 	// Iferr
 	IferrAutosave = cfg.Iferr.Autosave
 
@@ -287,6 +395,14 @@ func Get(v *nvim.Nvim, cfg *Config) {
 
 	// Rename
 	RenamePrefill = cfg.Rename.Prefill
+	RenamePreview = cfg.Rename.Preview
+
+	// Signature
+	SignatureEnable = cfg.Signature.Enable
+
+	// Tags
+	TagsTransform = cfg.Tags.Transform
+	TagsDefaultTransform = cfg.Tags.DefaultTransform
 
 	// Terminal
 	TerminalMode = cfg.Terminal.Mode
@@ -28,7 +28,7 @@ func callees(q *Query) error {
 	}
 
 	// Load/parse/type-check the program.
-	lprog, err := loadWithSoftErrors(&lconf)
+	lprog, err := loadWithSoftErrors(q, &lconf)
 	if err != nil {
 		return err
 	}
@@ -119,6 +119,9 @@ func callees(q *Query) error {
 	}
 
 	// Defer SSA construction till after errors are reported.
+	if err := q.phase(PhaseSSA); err != nil {
+		return err
+	}
 	prog.Build()
 
 	// Ascertain calling function and call site.
@@ -133,7 +136,7 @@ func callees(q *Query) error {
 		return err
 	}
 
-	funcs, err := findCallees(ptaConfig, site)
+	funcs, err := findCallees(q, ptaConfig, site)
 	if err != nil {
 		return err
 	}
@@ -154,7 +157,7 @@ func findCallSite(fn *ssa.Function, call *ast.CallExpr) (ssa.CallInstruction, er
 	return callInstr, nil
 }
 
-func findCallees(conf *pointer.Config, site ssa.CallInstruction) ([]*ssa.Function, error) {
+func findCallees(q *Query, conf *pointer.Config, site ssa.CallInstruction) ([]*ssa.Function, error) {
 	// Avoid running the pointer analysis for static calls.
 	if callee := site.Common().StaticCallee(); callee != nil {
 		switch callee.String() {
@@ -169,7 +172,11 @@ func findCallees(conf *pointer.Config, site ssa.CallInstruction) ([]*ssa.Functio
 
 	// Dynamic call: use pointer analysis.
 	conf.BuildCallGraph = true
-	cg := ptrAnalysis(conf).CallGraph
+	ptares, err := ptrAnalysis(q, conf)
+	if err != nil {
+		return nil, err
+	}
+	cg := ptares.CallGraph
 	cg.DeleteSyntheticNodes()
 
 	// Find all call edges from the site.
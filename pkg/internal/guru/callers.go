@@ -27,7 +27,7 @@ func callers(q *Query) error {
 	}
 
 	// Load/parse/type-check the program.
-	lprog, err := loadWithSoftErrors(&lconf)
+	lprog, err := loadWithSoftErrors(q, &lconf)
 	if err != nil {
 		return err
 	}
@@ -53,6 +53,9 @@ func callers(q *Query) error {
 	}
 
 	// Defer SSA construction till after errors are reported.
+	if err := q.phase(PhaseSSA); err != nil {
+		return err
+	}
 	prog.Build()
 
 	target := ssa.EnclosingFunction(pkg, qpos.path)
@@ -69,7 +72,11 @@ func callers(q *Query) error {
 		// (Pointer analysis may return fewer results than
 		// directCallsTo because it ignores dead code.)
 		ptaConfig.BuildCallGraph = true
-		cg = ptrAnalysis(ptaConfig).CallGraph
+		ptares, err := ptrAnalysis(q, ptaConfig)
+		if err != nil {
+			return err
+		}
+		cg = ptares.CallGraph
 	}
 	cg.DeleteSyntheticNodes()
 	edges := cg.CreateNode(target).In
@@ -35,7 +35,7 @@ func callstack(q *Query) error {
 	}
 
 	// Load/parse/type-check the program.
-	lprog, err := loadWithSoftErrors(&lconf)
+	lprog, err := loadWithSoftErrors(q, &lconf)
 	if err != nil {
 		return err
 	}
@@ -62,6 +62,9 @@ func callstack(q *Query) error {
 	}
 
 	// Defer SSA construction till after errors are reported.
+	if err := q.phase(PhaseSSA); err != nil {
+		return err
+	}
 	prog.Build()
 
 	target := ssa.EnclosingFunction(pkg, qpos.path)
@@ -88,7 +91,11 @@ func callstack(q *Query) error {
 	// Run the pointer analysis and build a complete call graph.
 	if callpath == nil {
 		ptaConfig.BuildCallGraph = true
-		cg := ptrAnalysis(ptaConfig).CallGraph
+		ptares, err := ptrAnalysis(q, ptaConfig)
+		if err != nil {
+			return err
+		}
+		cg := ptares.CallGraph
 		cg.DeleteSyntheticNodes()
 		callpath = callgraph.PathSearch(cg.Root, isEnd)
 		if callpath != nil {
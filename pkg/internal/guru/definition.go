@@ -70,6 +70,10 @@ func definition(q *Query) error {
 		return err
 	}
 
+	if err := q.phase(PhaseLoad); err != nil {
+		return err
+	}
+
 	// Load/parse/type-check the program.
 	lprog, err := lconf.Load()
 	if err != nil {
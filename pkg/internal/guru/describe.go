@@ -35,6 +35,10 @@ func describe(q *Query) error {
 		return err
 	}
 
+	if err := q.phase(PhaseLoad); err != nil {
+		return err
+	}
+
 	// Load/parse/type-check the program.
 	lprog, err := lconf.Load()
 	if err != nil {
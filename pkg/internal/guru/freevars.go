@@ -37,6 +37,10 @@ func freevars(q *Query) error {
 		return err
 	}
 
+	if err := q.phase(PhaseLoad); err != nil {
+		return err
+	}
+
 	// Load/parse/type-check the program.
 	lprog, err := lconf.Load()
 	if err != nil {
@@ -11,6 +11,7 @@ package guru
 //   (&T{}, var t T, new(T), new(struct{array [3]T}), etc.
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"go/ast"
@@ -19,7 +20,9 @@ import (
 	"go/token"
 	"go/types"
 	"io"
+	"io/ioutil"
 	"log"
+	"os"
 	"path/filepath"
 	"strings"
 
@@ -79,10 +82,82 @@ type Query struct {
 
 	// result-printing function, safe for concurrent use
 	Output func(*token.FileSet, QueryResult)
+
+	// (optional) progress-reporting function, called at the start of each query phase
+	Progress func(phase string)
+
+	ctx context.Context
+}
+
+// Phases of the query, reported to Query.Progress.
+const (
+	PhaseLoad    = "loading packages"
+	PhaseSSA     = "building SSA"
+	PhasePointer = "pointer analysis"
+)
+
+// phase reports the start of the phase to q.Progress.
+// It returns the context error instead if the query has been cancelled.
+func (q *Query) phase(name string) error {
+	if err := q.ctx.Err(); err != nil {
+		return err
+	}
+	if q.Progress != nil {
+		q.Progress(name)
+	}
+	return nil
 }
 
 // Run runs an guru query and populates its Fset and Result.
-func Run(mode string, q *Query) error {
+//
+// The query is cancelled when ctx is done. Package loading is aborted
+// by failing the file system access of q.Build; SSA construction and
+// pointer analysis cannot be interrupted, so the cancellation takes
+// effect at the next phase boundary. No result is output after the cancellation.
+func Run(ctx context.Context, mode string, q *Query) error {
+	qc := *q
+	qc.ctx = ctx
+	qc.Build = cancelContext(ctx, q.Build)
+	if q.Output != nil {
+		qc.Output = func(fset *token.FileSet, qr QueryResult) {
+			if ctx.Err() == nil {
+				q.Output(fset, qr)
+			}
+		}
+	}
+
+	err := run(mode, &qc)
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	return err
+}
+
+// cancelContext returns a copy of ctxt whose file system access fails after ctx is done.
+func cancelContext(ctx context.Context, ctxt *build.Context) *build.Context {
+	bctxt := *ctxt
+	bctxt.OpenFile = func(path string) (io.ReadCloser, error) {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		if ctxt.OpenFile != nil {
+			return ctxt.OpenFile(path)
+		}
+		return os.Open(path)
+	}
+	bctxt.ReadDir = func(dir string) ([]os.FileInfo, error) {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		if ctxt.ReadDir != nil {
+			return ctxt.ReadDir(dir)
+		}
+		return ioutil.ReadDir(dir)
+	}
+	return &bctxt
+}
+
+func run(mode string, q *Query) error {
 	switch mode {
 	case "callees":
 		return callees(q)
@@ -257,7 +332,10 @@ func parseQueryPos(lprog *loader.Program, pos string, needExact bool) (*queryPos
 // loadWithSoftErrors calls lconf.Load, suppressing "soft" errors.  (See Go issue 16530.)
 // TODO(adonovan): Once the loader has an option to allow soft errors,
 // replace calls to loadWithSoftErrors with loader calls with that parameter.
-func loadWithSoftErrors(lconf *loader.Config) (*loader.Program, error) {
+func loadWithSoftErrors(q *Query, lconf *loader.Config) (*loader.Program, error) {
+	if err := q.phase(PhaseLoad); err != nil {
+		return nil, err
+	}
 	lconf.AllowErrors = true
 
 	// Ideally we would just return conf.Load() here, but go/types
@@ -269,6 +347,9 @@ func loadWithSoftErrors(lconf *loader.Config) (*loader.Program, error) {
 	if err != nil {
 		return nil, err
 	}
+	if err := q.ctx.Err(); err != nil {
+		return nil, err
+	}
 	var errpkgs []string
 	// Report hard errors in indirectly imported packages.
 	for _, info := range prog.AllPackages {
@@ -315,12 +396,16 @@ func allowErrors(lconf *loader.Config) {
 }
 
 // ptrAnalysis runs the pointer analysis and returns its result.
-func ptrAnalysis(conf *pointer.Config) *pointer.Result {
+// It returns the context error instead if the query has been cancelled.
+func ptrAnalysis(q *Query, conf *pointer.Config) (*pointer.Result, error) {
+	if err := q.phase(PhasePointer); err != nil {
+		return nil, err
+	}
 	result, err := pointer.Analyze(conf)
 	if err != nil {
 		panic(err) // pointer analysis internal error
 	}
-	return result
+	return result, nil
 }
 
 func unparen(e ast.Expr) ast.Expr { return astutil.Unparen(e) }
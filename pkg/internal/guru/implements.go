@@ -58,6 +58,10 @@ func implements(q *Query) error {
 		// provide the []*ast.File.
 	}
 
+	if err := q.phase(PhaseLoad); err != nil {
+		return err
+	}
+
 	// Load/parse/type-check the program.
 	lprog, err := lconf.Load()
 	if err != nil {
@@ -15,6 +15,7 @@ package guru
 
 import (
 	"bufio"
+	"context"
 	"flag"
 	"fmt"
 	"go/build"
@@ -222,7 +223,7 @@ func main() {
 		Output:     output,
 	}
 
-	if err := Run(mode, &query); err != nil {
+	if err := Run(context.Background(), mode, &query); err != nil {
 		log.Fatal(err)
 	}
 }
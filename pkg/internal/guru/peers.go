@@ -31,7 +31,7 @@ func peers(q *Query) error {
 	}
 
 	// Load/parse/type-check the program.
-	lprog, err := loadWithSoftErrors(&lconf)
+	lprog, err := loadWithSoftErrors(q, &lconf)
 	if err != nil {
 		return err
 	}
@@ -54,6 +54,9 @@ func peers(q *Query) error {
 	}
 
 	// Defer SSA construction till after errors are reported.
+	if err := q.phase(PhaseSSA); err != nil {
+		return err
+	}
 	prog.Build()
 
 	var queryOp chanOp // the originating send or receive operation
@@ -96,7 +99,10 @@ func peers(q *Query) error {
 	ops = ops[:i]
 
 	// Run the pointer analysis.
-	ptares := ptrAnalysis(ptaConfig)
+	ptares, err := ptrAnalysis(q, ptaConfig)
+	if err != nil {
+		return err
+	}
 
 	// Find the points-to set.
 	queryChanPtr := ptares.Queries[queryOp.ch]
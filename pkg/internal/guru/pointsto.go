@@ -34,7 +34,7 @@ func pointsto(q *Query) error {
 	}
 
 	// Load/parse/type-check the program.
-	lprog, err := loadWithSoftErrors(&lconf)
+	lprog, err := loadWithSoftErrors(q, &lconf)
 	if err != nil {
 		return err
 	}
@@ -94,10 +94,13 @@ func pointsto(q *Query) error {
 	}
 
 	// Defer SSA construction till after errors are reported.
+	if err := q.phase(PhaseSSA); err != nil {
+		return err
+	}
 	prog.Build()
 
 	// Run the pointer analysis.
-	ptrs, err := runPTA(ptaConfig, value, isAddr)
+	ptrs, err := runPTA(q, ptaConfig, value, isAddr)
 	if err != nil {
 		return err // e.g. analytically unreachable
 	}
@@ -157,7 +160,7 @@ func ssaValueForExpr(prog *ssa.Program, qinfo *loader.PackageInfo, path []ast.No
 }
 
 // runPTA runs the pointer analysis of the selected SSA value or address.
-func runPTA(conf *pointer.Config, v ssa.Value, isAddr bool) (ptrs []pointerResult, err error) {
+func runPTA(q *Query, conf *pointer.Config, v ssa.Value, isAddr bool) (ptrs []pointerResult, err error) {
 	T := v.Type()
 	if isAddr {
 		conf.AddIndirectQuery(v)
@@ -165,7 +168,10 @@ func runPTA(conf *pointer.Config, v ssa.Value, isAddr bool) (ptrs []pointerResul
 	} else {
 		conf.AddQuery(v)
 	}
-	ptares := ptrAnalysis(conf)
+	ptares, err := ptrAnalysis(q, conf)
+	if err != nil {
+		return nil, err
+	}
 
 	var ptr pointer.Pointer
 	if isAddr {
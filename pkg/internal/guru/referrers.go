@@ -44,6 +44,10 @@ func referrers(q *Query) error {
 		lconf.ImportPkgs[path] = true
 	}
 
+	if err := q.phase(PhaseLoad); err != nil {
+		return err
+	}
+
 	// Load/parse/type-check the query package.
 	lprog, err := lconf.Load()
 	if err != nil {
@@ -191,9 +195,15 @@ func packageReferrers(q *Query, path string) error {
 		clearInfoFields(info) // save memory
 	}
 
+	if err := q.phase(PhaseLoad); err != nil {
+		return err
+	}
 	lconf.Load() // ignore error
 
 	if qpkg == nil {
+		if err := q.ctx.Err(); err != nil {
+			return err
+		}
 		log.Fatalf("query package %q not found during reloading", path)
 	}
 
@@ -284,7 +294,7 @@ func globalReferrers(q *Query, qpkg, defpkg string, objposn token.Position) erro
 			if qobj == nil && info.Pkg.Path() == defpkg {
 				// Find the object by its position (slightly ugly).
 				qobj = findObject(fset, &info.Info, objposn)
-				if qobj == nil {
+				if qobj == nil && q.ctx.Err() == nil {
 					// It really ought to be there;
 					// we found it once already.
 					log.Fatalf("object at %s not found in package %s",
@@ -303,9 +313,15 @@ func globalReferrers(q *Query, qpkg, defpkg string, objposn token.Position) erro
 		clearInfoFields(info) // save memory
 	}
 
+	if err := q.phase(PhaseLoad); err != nil {
+		return err
+	}
 	lconf.Load() // ignore error
 
 	if qobj == nil {
+		if err := q.ctx.Err(); err != nil {
+			return err
+		}
 		log.Fatal("query object not found during reloading")
 	}
 
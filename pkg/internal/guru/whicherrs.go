@@ -37,7 +37,7 @@ func whicherrs(q *Query) error {
 	}
 
 	// Load/parse/type-check the program.
-	lprog, err := loadWithSoftErrors(&lconf)
+	lprog, err := loadWithSoftErrors(q, &lconf)
 	if err != nil {
 		return err
 	}
@@ -91,6 +91,9 @@ func whicherrs(q *Query) error {
 	}
 
 	// Defer SSA construction till after errors are reported.
+	if err := q.phase(PhaseSSA); err != nil {
+		return err
+	}
 	prog.Build()
 
 	globals := findVisibleErrs(prog, qpos)
@@ -139,7 +142,10 @@ func whicherrs(q *Query) error {
 		ptaConfig.AddQuery(v)
 	}
 
-	ptares := ptrAnalysis(ptaConfig)
+	ptares, err := ptrAnalysis(q, ptaConfig)
+	if err != nil {
+		return err
+	}
 	valueptr := ptares.Queries[value]
 	if valueptr == (pointer.Pointer{}) {
 		return fmt.Errorf("pointer analysis did not find expression (dead code?)")
@@ -0,0 +1,71 @@
+// Copyright 2018 The nvim-go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package rename
+
+import (
+	"fmt"
+	"go/build"
+	"go/token"
+	"sync"
+)
+
+// mu serializes Rename because the renaming uses the package level seams.
+var mu sync.Mutex
+
+// Conflict represents a conflict or warning reported while the renaming.
+type Conflict struct {
+	Pos     token.Position
+	Message string
+}
+
+// Result represents the result of Rename.
+type Result struct {
+	// Files renamed contents of the changed files, keyed by filename.
+	Files map[string][]byte
+	// Conflicts conflicts and warnings reported while the renaming.
+	Conflicts []Conflict
+	// Summary summary message of the renaming such as "Renamed 2 occurrences in 1 file in 1 package."
+	Summary string
+}
+
+// Rename renames the identifier at the offset to the to name, same as Main, but returns the renamed
+// contents instead of writing the files.
+//
+// The offset format is the same as the -offset flag of gorename, such as "file.go:#123".
+// The files are read through ctxt, so the unsaved contents can be passed by buildutil.OverlayContext.
+// If force is false and the renaming has conflicts, Rename returns the ConflictError error and the Result
+// which has the conflicts. If force is true, the conflicts are reported as the warnings in the Result.
+func Rename(ctxt *build.Context, offset, to string, force bool) (*Result, error) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	result := &Result{Files: make(map[string][]byte)}
+
+	savedWriteFile, savedReportError, savedReportRenamed, savedForce, savedDiff := writeFile, reportError, reportRenamed, Force, Diff
+	defer func() {
+		writeFile, reportError, reportRenamed, Force, Diff = savedWriteFile, savedReportError, savedReportRenamed, savedForce, savedDiff
+	}()
+
+	writeFile = func(filename string, content []byte) error {
+		result.Files[filename] = content
+		return nil
+	}
+	reportError = func(posn token.Position, message string) {
+		result.Conflicts = append(result.Conflicts, Conflict{Pos: posn, Message: message})
+	}
+	reportRenamed = func(nidents, nfiles, npkgs int) {
+		result.Summary = fmt.Sprintf("Renamed %d occurrence%s in %d file%s in %d package%s.",
+			nidents, plural(nidents),
+			nfiles, plural(nfiles),
+			npkgs, plural(npkgs))
+	}
+	Force, Diff = force, false
+
+	if err := Main(ctxt, offset, "", to); err != nil {
+		return result, err
+	}
+
+	return result, nil
+}
@@ -0,0 +1,487 @@
+// Copyright 2018 The nvim-go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package lsp
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Handler handles the request or notification from the server.
+// The returned result or error is replied to the server if the message is a request.
+type Handler func(ctx context.Context, method string, params json.RawMessage) (interface{}, error)
+
+// DefaultHandler replies the minimal results of the requests which the language servers usually send to the client,
+// and ignores all notifications.
+func DefaultHandler(ctx context.Context, method string, params json.RawMessage) (interface{}, error) {
+	switch method {
+	case "workspace/configuration":
+		var p ConfigurationParams
+		if err := json.Unmarshal(params, &p); err != nil {
+			return nil, &Error{Code: CodeInvalidParams, Message: err.Error()}
+		}
+		result := make([]map[string]interface{}, len(p.Items))
+		for i := range result {
+			result[i] = map[string]interface{}{}
+		}
+		return result, nil
+
+	case "client/registerCapability", "client/unregisterCapability", "window/workDoneProgress/create":
+		return nil, nil
+
+	default:
+		return nil, &Error{Code: CodeMethodNotFound, Message: "method not found: " + method}
+	}
+}
+
+// Client represents a language server client.
+type Client struct {
+	stream  *Stream
+	closer  io.Closer
+	handler Handler
+
+	mu      sync.Mutex
+	seq     int64
+	pending map[int64]chan *Message
+
+	done chan struct{}
+	err  error // the read error which stopped the client, valid after done is closed
+}
+
+// NewClient returns the new Client which communicates to the server over rwc.
+// handler handles the requests and notifications from the server; DefaultHandler is used if it is nil.
+// The client stops when the ctx is done or rwc is closed.
+func NewClient(ctx context.Context, rwc io.ReadWriteCloser, handler Handler) *Client {
+	if handler == nil {
+		handler = DefaultHandler
+	}
+	c := &Client{
+		stream:  NewStream(rwc, rwc),
+		closer:  rwc,
+		handler: handler,
+		pending: make(map[int64]chan *Message),
+		done:    make(chan struct{}),
+	}
+	go c.run(ctx)
+
+	return c
+}
+
+// run reads the messages from the server until the stream is closed.
+func (c *Client) run(ctx context.Context) {
+	go func() {
+		select {
+		case <-ctx.Done():
+			c.closer.Close()
+		case <-c.done:
+		}
+	}()
+
+	var err error
+	for {
+		var msg *Message
+		msg, err = c.stream.Read()
+		if err != nil {
+			break
+		}
+
+		if msg.IsResponse() {
+			// the requests are sent with the number IDs, so the other responses are not ours
+			if id, ok := msg.idNumber(); ok {
+				c.mu.Lock()
+				ch, ok := c.pending[id]
+				delete(c.pending, id)
+				c.mu.Unlock()
+				if ok {
+					ch <- msg
+				}
+			}
+			continue
+		}
+
+		go c.handle(ctx, msg)
+	}
+
+	if err == io.EOF {
+		err = errors.New("language server connection closed")
+	}
+	c.mu.Lock()
+	c.err = err
+	c.mu.Unlock()
+	close(c.done)
+}
+
+// handle handles the request or notification from the server, and replies the result if msg is a request.
+func (c *Client) handle(ctx context.Context, msg *Message) {
+	result, err := c.handler(ctx, msg.Method, msg.Params)
+	if msg.ID == nil {
+		return
+	}
+
+	reply := &Message{ID: msg.ID}
+	if err != nil {
+		rpcErr, ok := err.(*Error)
+		if !ok {
+			rpcErr = &Error{Code: CodeInternalError, Message: err.Error()}
+		}
+		reply.Error = rpcErr
+	} else {
+		data, err := json.Marshal(result)
+		if err != nil {
+			reply.Error = &Error{Code: CodeInternalError, Message: err.Error()}
+		}
+		reply.Result = data
+	}
+	c.stream.Write(reply)
+}
+
+// Done returns a channel which is closed when the client is stopped.
+func (c *Client) Done() <-chan struct{} {
+	return c.done
+}
+
+// Call sends the method request with params to the server, and waits for the response.
+// The result is unmarshaled into result if it is not nil.
+//
+// If ctx is done before the response, Call sends the $/cancelRequest notification to the server and
+// returns the ctx.Err() without waiting.
+func (c *Client) Call(ctx context.Context, method string, params, result interface{}) error {
+	data, err := json.Marshal(params)
+	if err != nil {
+		return errors.Wrapf(err, "could not marshal %s params", method)
+	}
+
+	ch := make(chan *Message, 1)
+	c.mu.Lock()
+	c.seq++
+	id := c.seq
+	c.pending[id] = ch
+	c.mu.Unlock()
+
+	if err := c.stream.Write(&Message{ID: numberID(id), Method: method, Params: data}); err != nil {
+		c.mu.Lock()
+		delete(c.pending, id)
+		c.mu.Unlock()
+		return errors.Wrapf(err, "could not send %s request", method)
+	}
+
+	select {
+	case <-ctx.Done():
+		c.mu.Lock()
+		delete(c.pending, id)
+		c.mu.Unlock()
+		c.Notify(context.Background(), "$/cancelRequest", &CancelParams{ID: id})
+		return ctx.Err()
+
+	case <-c.done:
+		return c.err
+
+	case msg := <-ch:
+		if msg.Error != nil {
+			return msg.Error
+		}
+		if result == nil || len(msg.Result) == 0 {
+			return nil
+		}
+		return errors.Wrapf(json.Unmarshal(msg.Result, result), "could not unmarshal %s result", method)
+	}
+}
+
+// Notify sends the method notification with params to the server.
+func (c *Client) Notify(ctx context.Context, method string, params interface{}) error {
+	data, err := json.Marshal(params)
+	if err != nil {
+		return errors.Wrapf(err, "could not marshal %s params", method)
+	}
+
+	select {
+	case <-c.done:
+		return c.err
+	default:
+	}
+
+	return c.stream.Write(&Message{Method: method, Params: data})
+}
+
+// Close closes the connection to the server.
+func (c *Client) Close() error {
+	return c.closer.Close()
+}
+
+// process represents the stdio of the language server process.
+type process struct {
+	io.ReadCloser  // stdout
+	io.WriteCloser // stdin
+	cmd            *exec.Cmd
+}
+
+// shutdownTimeout timeout of waiting for the language server process to exit.
+const shutdownTimeout = 3 * time.Second
+
+// Close closes the stdin and waits for the process to exit. Kills the process if it is not exited in shutdownTimeout.
+func (p *process) Close() error {
+	p.WriteCloser.Close()
+
+	errc := make(chan error, 1)
+	go func() {
+		errc <- p.cmd.Wait()
+	}()
+
+	select {
+	case err := <-errc:
+		return err
+	case <-time.After(shutdownTimeout):
+		p.cmd.Process.Kill()
+		return errors.New("language server process killed")
+	}
+}
+
+// Start starts the name language server process with args in the dir directory,
+// and returns the Client which communicates to the process over stdio.
+func Start(ctx context.Context, dir, name string, args []string, handler Handler) (*Client, error) {
+	cmd := exec.Command(name, args...)
+	cmd.Dir = dir
+
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, errors.Wrapf(err, "could not start %s", name)
+	}
+
+	return NewClient(ctx, &process{ReadCloser: stdout, WriteCloser: stdin, cmd: cmd}, handler), nil
+}
+
+// Initialize sends the initialize request with the root directory and the initialized notification.
+func (c *Client) Initialize(ctx context.Context, root string) (*InitializeResult, error) {
+	params := &InitializeParams{
+		ProcessID:  os.Getpid(),
+		ClientInfo: &ClientInfo{Name: "nvim-go"},
+		RootURI:    FileURI(root),
+		Capabilities: map[string]interface{}{
+			"workspace": map[string]interface{}{
+				"configuration": true,
+				"workspaceEdit": map[string]interface{}{"documentChanges": true},
+			},
+			"textDocument": map[string]interface{}{
+				"synchronization": map[string]interface{}{"didSave": true},
+				"definition":      map[string]interface{}{},
+				"references":      map[string]interface{}{},
+				"implementation":  map[string]interface{}{},
+				"rename":          map[string]interface{}{},
+				"formatting":      map[string]interface{}{},
+				"codeAction": map[string]interface{}{
+					"codeActionLiteralSupport": map[string]interface{}{
+						"codeActionKind": map[string]interface{}{
+							"valueSet": []string{CodeActionOrganizeImports},
+						},
+					},
+				},
+			},
+		},
+		WorkspaceFolders: []WorkspaceFolder{{URI: FileURI(root), Name: filepath.Base(root)}},
+	}
+
+	var result InitializeResult
+	if err := c.Call(ctx, "initialize", params, &result); err != nil {
+		return nil, err
+	}
+	if err := c.Notify(ctx, "initialized", struct{}{}); err != nil {
+		return nil, err
+	}
+
+	return &result, nil
+}
+
+// Shutdown sends the shutdown request and the exit notification, and closes the connection.
+func (c *Client) Shutdown(ctx context.Context) error {
+	defer c.Close()
+
+	if err := c.Call(ctx, "shutdown", nil, nil); err != nil {
+		return err
+	}
+
+	return c.Notify(ctx, "exit", nil)
+}
+
+// DidOpen notifies that the filename document is opened with text.
+func (c *Client) DidOpen(ctx context.Context, filename string, version int, text []byte) error {
+	return c.Notify(ctx, "textDocument/didOpen", &DidOpenTextDocumentParams{
+		TextDocument: TextDocumentItem{
+			URI:        FileURI(filename),
+			LanguageID: "go",
+			Version:    version,
+			Text:       string(text),
+		},
+	})
+}
+
+// DidChange notifies that the whole text of the filename document is changed to text.
+func (c *Client) DidChange(ctx context.Context, filename string, version int, text []byte) error {
+	return c.Notify(ctx, "textDocument/didChange", &DidChangeTextDocumentParams{
+		TextDocument:   VersionedTextDocumentIdentifier{URI: FileURI(filename), Version: version},
+		ContentChanges: []TextDocumentContentChangeEvent{{Text: string(text)}},
+	})
+}
+
+// DidSave notifies that the filename document is saved.
+func (c *Client) DidSave(ctx context.Context, filename string) error {
+	return c.Notify(ctx, "textDocument/didSave", &DidSaveTextDocumentParams{
+		TextDocument: TextDocumentIdentifier{URI: FileURI(filename)},
+	})
+}
+
+// DidClose notifies that the filename document is closed.
+func (c *Client) DidClose(ctx context.Context, filename string) error {
+	return c.Notify(ctx, "textDocument/didClose", &DidCloseTextDocumentParams{
+		TextDocument: TextDocumentIdentifier{URI: FileURI(filename)},
+	})
+}
+
+// positionParams returns the TextDocumentPositionParams of the pos in filename.
+func positionParams(filename string, pos Position) TextDocumentPositionParams {
+	return TextDocumentPositionParams{
+		TextDocument: TextDocumentIdentifier{URI: FileURI(filename)},
+		Position:     pos,
+	}
+}
+
+// Definition returns the definition locations of the identifier at the pos in filename.
+func (c *Client) Definition(ctx context.Context, filename string, pos Position) ([]Location, error) {
+	var raw json.RawMessage
+	if err := c.Call(ctx, "textDocument/definition", positionParams(filename, pos), &raw); err != nil {
+		return nil, err
+	}
+
+	return decodeLocations(raw)
+}
+
+// References returns the reference locations of the identifier at the pos in filename.
+func (c *Client) References(ctx context.Context, filename string, pos Position, includeDeclaration bool) ([]Location, error) {
+	params := &ReferenceParams{
+		TextDocumentPositionParams: positionParams(filename, pos),
+		Context:                    ReferenceContext{IncludeDeclaration: includeDeclaration},
+	}
+	var raw json.RawMessage
+	if err := c.Call(ctx, "textDocument/references", params, &raw); err != nil {
+		return nil, err
+	}
+
+	return decodeLocations(raw)
+}
+
+// Implementation returns the implementation locations of the type or method at the pos in filename.
+func (c *Client) Implementation(ctx context.Context, filename string, pos Position) ([]Location, error) {
+	var raw json.RawMessage
+	if err := c.Call(ctx, "textDocument/implementation", positionParams(filename, pos), &raw); err != nil {
+		return nil, err
+	}
+
+	return decodeLocations(raw)
+}
+
+// Rename returns the workspace edit which renames the identifier at the pos in filename to newName.
+func (c *Client) Rename(ctx context.Context, filename string, pos Position, newName string) (*WorkspaceEdit, error) {
+	params := &RenameParams{
+		TextDocumentPositionParams: positionParams(filename, pos),
+		NewName:                    newName,
+	}
+	edit := new(WorkspaceEdit)
+	if err := c.Call(ctx, "textDocument/rename", params, edit); err != nil {
+		return nil, err
+	}
+
+	return edit, nil
+}
+
+// Formatting returns the text edits which format the filename document.
+func (c *Client) Formatting(ctx context.Context, filename string) ([]TextEdit, error) {
+	params := &DocumentFormattingParams{
+		TextDocument: TextDocumentIdentifier{URI: FileURI(filename)},
+		Options:      FormattingOptions{TabSize: 8},
+	}
+	var edits []TextEdit
+	if err := c.Call(ctx, "textDocument/formatting", params, &edits); err != nil {
+		return nil, err
+	}
+
+	return edits, nil
+}
+
+// CodeActions returns the code actions of the only kinds in the rng of filename.
+// The command based code actions are omitted.
+func (c *Client) CodeActions(ctx context.Context, filename string, rng Range, only ...string) ([]CodeAction, error) {
+	params := &CodeActionParams{
+		TextDocument: TextDocumentIdentifier{URI: FileURI(filename)},
+		Range:        rng,
+		Context:      CodeActionContext{Diagnostics: []interface{}{}, Only: only},
+	}
+	var actions []CodeAction
+	if err := c.Call(ctx, "textDocument/codeAction", params, &actions); err != nil {
+		return nil, err
+	}
+
+	result := actions[:0]
+	for _, action := range actions {
+		if action.Edit != nil {
+			result = append(result, action)
+		}
+	}
+
+	return result, nil
+}
+
+// locationLink represents a LocationLink which the server may return instead of Location.
+type locationLink struct {
+	TargetURI            DocumentURI `json:"targetUri"`
+	TargetSelectionRange Range       `json:"targetSelectionRange"`
+}
+
+// decodeLocations decodes the Location, []Location or []LocationLink result.
+func decodeLocations(raw json.RawMessage) ([]Location, error) {
+	if len(raw) == 0 || string(raw) == "null" {
+		return nil, nil
+	}
+
+	if raw[0] != '[' {
+		var loc Location
+		if err := json.Unmarshal(raw, &loc); err != nil {
+			return nil, errors.WithStack(err)
+		}
+		return []Location{loc}, nil
+	}
+
+	var links []locationLink
+	if err := json.Unmarshal(raw, &links); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	if len(links) > 0 && links[0].TargetURI != "" {
+		locs := make([]Location, len(links))
+		for i, link := range links {
+			locs[i] = Location{URI: link.TargetURI, Range: link.TargetSelectionRange}
+		}
+		return locs, nil
+	}
+
+	var locs []Location
+	if err := json.Unmarshal(raw, &locs); err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	return locs, nil
+}
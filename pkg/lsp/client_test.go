@@ -0,0 +1,439 @@
+// Copyright 2018 The nvim-go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package lsp
+
+import (
+	"context"
+	"encoding/json"
+	"go/format"
+	"net"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeServer represents a fake language server which serves the minimal requests over the stream.
+type fakeServer struct {
+	stream *Stream
+
+	mu   sync.Mutex
+	docs map[DocumentURI]string
+	// config reply of the workspace/configuration request sent after the initialize.
+	config chan *Message
+	// cancelled ids of the $/cancelRequest notifications.
+	cancelled chan int64
+	// slow pending "slow" requests, keyed by id.
+	slow map[int64]chan struct{}
+}
+
+func newFakeServer(t *testing.T) (*fakeServer, net.Conn) {
+	t.Helper()
+
+	client, server := net.Pipe()
+	s := &fakeServer{
+		stream:    NewStream(server, server),
+		docs:      make(map[DocumentURI]string),
+		config:    make(chan *Message, 1),
+		cancelled: make(chan int64, 1),
+		slow:      make(map[int64]chan struct{}),
+	}
+	go s.serve(t)
+
+	return s, client
+}
+
+func (s *fakeServer) serve(t *testing.T) {
+	for {
+		msg, err := s.stream.Read()
+		if err != nil {
+			return
+		}
+		if msg.IsResponse() {
+			s.config <- msg
+			continue
+		}
+		if msg.ID == nil {
+			// handles the notifications in order, such as the document changes
+			s.handle(t, msg)
+			continue
+		}
+		go s.handle(t, msg)
+	}
+}
+
+func (s *fakeServer) reply(id json.RawMessage, result interface{}, rpcErr *Error) {
+	data, _ := json.Marshal(result)
+	s.stream.Write(&Message{ID: id, Result: data, Error: rpcErr})
+}
+
+func (s *fakeServer) doc(uri DocumentURI) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.docs[uri]
+}
+
+func (s *fakeServer) handle(t *testing.T, msg *Message) {
+	switch msg.Method {
+	case "initialize":
+		s.reply(msg.ID, map[string]interface{}{"capabilities": map[string]interface{}{"renameProvider": true}}, nil)
+		params, _ := json.Marshal(map[string]interface{}{"items": []map[string]string{{"section": "gopls"}, {"section": "go"}}})
+		s.stream.Write(&Message{ID: json.RawMessage(`"config"`), Method: "workspace/configuration", Params: params})
+
+	case "textDocument/didOpen":
+		var p DidOpenTextDocumentParams
+		json.Unmarshal(msg.Params, &p)
+		s.mu.Lock()
+		s.docs[p.TextDocument.URI] = p.TextDocument.Text
+		s.mu.Unlock()
+
+	case "textDocument/didChange":
+		var p DidChangeTextDocumentParams
+		json.Unmarshal(msg.Params, &p)
+		s.mu.Lock()
+		s.docs[p.TextDocument.URI] = p.ContentChanges[len(p.ContentChanges)-1].Text
+		s.mu.Unlock()
+
+	case "textDocument/didClose":
+		var p DidCloseTextDocumentParams
+		json.Unmarshal(msg.Params, &p)
+		s.mu.Lock()
+		delete(s.docs, p.TextDocument.URI)
+		s.mu.Unlock()
+
+	case "textDocument/definition":
+		// returns the LocationLink of the first line
+		var p TextDocumentPositionParams
+		json.Unmarshal(msg.Params, &p)
+		s.reply(msg.ID, []map[string]interface{}{{
+			"targetUri":            p.TextDocument.URI,
+			"targetRange":          Range{End: Position{Line: 1}},
+			"targetSelectionRange": Range{Start: Position{Character: 8}, End: Position{Character: 11}},
+		}}, nil)
+
+	case "textDocument/references":
+		// returns the all occurrences of the identifier under the position
+		var p ReferenceParams
+		json.Unmarshal(msg.Params, &p)
+		s.reply(msg.ID, s.occurrences(p.TextDocument.URI, p.Position), nil)
+
+	case "textDocument/rename":
+		var p RenameParams
+		json.Unmarshal(msg.Params, &p)
+		var edits []TextEdit
+		for _, loc := range s.occurrences(p.TextDocument.URI, p.Position) {
+			edits = append(edits, TextEdit{Range: loc.Range, NewText: p.NewName})
+		}
+		s.reply(msg.ID, &WorkspaceEdit{Changes: map[DocumentURI][]TextEdit{p.TextDocument.URI: edits}}, nil)
+
+	case "textDocument/formatting":
+		var p DocumentFormattingParams
+		json.Unmarshal(msg.Params, &p)
+		src := s.doc(p.TextDocument.URI)
+		out, err := format.Source([]byte(src))
+		if err != nil {
+			s.reply(msg.ID, nil, &Error{Code: CodeInternalError, Message: err.Error()})
+			return
+		}
+		end := OffsetPosition([]byte(src), len(src))
+		s.reply(msg.ID, []TextEdit{{Range: Range{End: end}, NewText: string(out)}}, nil)
+
+	case "slow":
+		ch := make(chan struct{})
+		id, _ := msg.idNumber()
+		s.mu.Lock()
+		s.slow[id] = ch
+		s.mu.Unlock()
+		<-ch
+		s.reply(msg.ID, nil, &Error{Code: CodeRequestCancelled, Message: "cancelled"})
+
+	case "$/cancelRequest":
+		var p CancelParams
+		json.Unmarshal(msg.Params, &p)
+		s.mu.Lock()
+		if ch, ok := s.slow[p.ID]; ok {
+			close(ch)
+			delete(s.slow, p.ID)
+		}
+		s.mu.Unlock()
+		s.cancelled <- p.ID
+
+	case "shutdown":
+		s.reply(msg.ID, nil, nil)
+
+	case "exit", "initialized":
+		// nothing to do
+
+	default:
+		if msg.ID != nil {
+			s.reply(msg.ID, nil, &Error{Code: CodeMethodNotFound, Message: msg.Method})
+		}
+	}
+}
+
+// occurrences returns the locations of the identifier at pos in the uri document.
+func (s *fakeServer) occurrences(uri DocumentURI, pos Position) []Location {
+	src := []byte(s.doc(uri))
+	offset, err := pos.Offset(src)
+	if err != nil {
+		return nil
+	}
+	start, end := offset, offset
+	for start > 0 && isIdent(src[start-1]) {
+		start--
+	}
+	for end < len(src) && isIdent(src[end]) {
+		end++
+	}
+	ident := string(src[start:end])
+
+	var locs []Location
+	for i := 0; i < len(src); {
+		j := strings.Index(string(src[i:]), ident)
+		if j < 0 {
+			break
+		}
+		i += j
+		if (i == 0 || !isIdent(src[i-1])) && (i+len(ident) == len(src) || !isIdent(src[i+len(ident)])) {
+			locs = append(locs, Location{URI: uri, Range: Range{Start: OffsetPosition(src, i), End: OffsetPosition(src, i+len(ident))}})
+		}
+		i += len(ident)
+	}
+
+	return locs
+}
+
+func isIdent(c byte) bool {
+	return c == '_' || 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9' || c >= 0x80
+}
+
+const clientSrc = `package p
+
+func foo() int { return 1 }
+
+func bar() int {
+	return foo()+foo()
+}
+`
+
+func TestClient(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	s, conn := newFakeServer(t)
+	c := NewClient(ctx, conn, nil)
+
+	if _, err := c.Initialize(ctx, "/go/src/foo"); err != nil {
+		t.Fatalf("Initialize: %v", err)
+	}
+	select {
+	case reply := <-s.config:
+		if got, want := string(reply.ID), `"config"`; got != want {
+			t.Errorf("workspace/configuration reply id = %s, want %s", got, want)
+		}
+		if got, want := string(reply.Result), `[{},{}]`; got != want {
+			t.Errorf("workspace/configuration reply = %s, want %s", got, want)
+		}
+	case <-ctx.Done():
+		t.Fatal("workspace/configuration was not replied")
+	}
+
+	const file = "/go/src/foo/foo.go"
+	if err := c.DidOpen(ctx, file, 1, []byte(clientSrc)); err != nil {
+		t.Fatalf("DidOpen: %v", err)
+	}
+
+	// the "foo" in the "return foo()"
+	pos := OffsetPosition([]byte(clientSrc), strings.Index(clientSrc, "foo()+"))
+
+	defs, err := c.Definition(ctx, file, pos)
+	if err != nil {
+		t.Fatalf("Definition: %v", err)
+	}
+	wantDef := []Location{{URI: FileURI(file), Range: Range{Start: Position{Character: 8}, End: Position{Character: 11}}}}
+	if !reflect.DeepEqual(defs, wantDef) {
+		t.Errorf("Definition() = %+v, want %+v", defs, wantDef)
+	}
+
+	refs, err := c.References(ctx, file, pos, true)
+	if err != nil {
+		t.Fatalf("References: %v", err)
+	}
+	var lines []int
+	for _, ref := range refs {
+		if ref.URI.Filename() != file {
+			t.Errorf("reference filename = %s, want %s", ref.URI.Filename(), file)
+		}
+		lines = append(lines, ref.Range.Start.Line)
+	}
+	if want := []int{2, 5, 5}; !reflect.DeepEqual(lines, want) {
+		t.Errorf("References() lines = %v, want %v", lines, want)
+	}
+
+	edit, err := c.Rename(ctx, file, pos, "baz")
+	if err != nil {
+		t.Fatalf("Rename: %v", err)
+	}
+	renamed, err := ApplyEdits([]byte(clientSrc), edit.Edits()[file])
+	if err != nil {
+		t.Fatalf("ApplyEdits: %v", err)
+	}
+	if want := strings.Replace(clientSrc, "foo()", "baz()", -1); string(renamed) != want {
+		t.Errorf("renamed source =\n%s\nwant:\n%s", renamed, want)
+	}
+
+	// document sync: the formatting uses the changed text
+	if err := c.DidChange(ctx, file, 2, renamed); err != nil {
+		t.Fatalf("DidChange: %v", err)
+	}
+	edits, err := c.Formatting(ctx, file)
+	if err != nil {
+		t.Fatalf("Formatting: %v", err)
+	}
+	formatted, err := ApplyEdits(renamed, edits)
+	if err != nil {
+		t.Fatalf("ApplyEdits: %v", err)
+	}
+	if want := strings.Replace(string(renamed), "baz()+baz()", "baz() + baz()", 1); string(formatted) != want {
+		t.Errorf("formatted source =\n%s\nwant:\n%s", formatted, want)
+	}
+
+	// method not found error
+	err = c.Call(ctx, "unknown", nil, nil)
+	if rpcErr, ok := err.(*Error); !ok || rpcErr.Code != CodeMethodNotFound {
+		t.Errorf("Call(unknown) error = %v, want the method not found error", err)
+	}
+
+	if err := c.Shutdown(ctx); err != nil {
+		t.Fatalf("Shutdown: %v", err)
+	}
+	select {
+	case <-c.Done():
+	case <-ctx.Done():
+		t.Fatal("client was not stopped after Shutdown")
+	}
+}
+
+func TestClientCancel(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	s, conn := newFakeServer(t)
+	c := NewClient(ctx, conn, nil)
+	defer c.Close()
+
+	callCtx, callCancel := context.WithCancel(ctx)
+	errc := make(chan error, 1)
+	go func() {
+		errc <- c.Call(callCtx, "slow", nil, nil)
+	}()
+	time.Sleep(50 * time.Millisecond)
+	callCancel()
+
+	if err := <-errc; err != context.Canceled {
+		t.Errorf("Call() error = %v, want %v", err, context.Canceled)
+	}
+	select {
+	case id := <-s.cancelled:
+		if id != 1 {
+			t.Errorf("$/cancelRequest id = %d, want 1", id)
+		}
+	case <-ctx.Done():
+		t.Fatal("$/cancelRequest was not sent")
+	}
+
+	// the late response of the cancelled request must not break the following requests
+	if err := c.Call(ctx, "shutdown", nil, nil); err != nil {
+		t.Errorf("Call(shutdown) after cancel: %v", err)
+	}
+}
+
+func TestPositionOffset(t *testing.T) {
+	src := []byte("package foo\n\nvar s = \"日本語\" + \"\U0001F600\" + x\n")
+
+	tests := []struct {
+		name   string
+		offset int
+		pos    Position
+	}{
+		{name: "first", offset: 0, pos: Position{Line: 0, Character: 0}},
+		{name: "empty line", offset: 12, pos: Position{Line: 1, Character: 0}},
+		{name: "after BMP", offset: strings.Index(string(src), " + \"\U0001F600"), pos: Position{Line: 2, Character: 13}},
+		{name: "after surrogate pair", offset: strings.Index(string(src), " + x"), pos: Position{Line: 2, Character: 20}},
+		{name: "end", offset: len(src), pos: Position{Line: 3, Character: 0}},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := OffsetPosition(src, tt.offset); got != tt.pos {
+				t.Errorf("OffsetPosition(%d) = %+v, want %+v", tt.offset, got, tt.pos)
+			}
+			got, err := tt.pos.Offset(src)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.offset {
+				t.Errorf("%+v.Offset() = %d, want %d", tt.pos, got, tt.offset)
+			}
+		})
+	}
+
+	if _, err := (Position{Line: 10}).Offset(src); err == nil {
+		t.Error("Offset() beyond the end of file: want error")
+	}
+}
+
+func TestApplyEdits(t *testing.T) {
+	src := []byte("a := 1\nb := 2\n")
+
+	tests := []struct {
+		name    string
+		edits   []TextEdit
+		want    string
+		wantErr bool
+	}{
+		{
+			name: "unsorted",
+			edits: []TextEdit{
+				{Range: Range{Start: Position{Line: 1}, End: Position{Line: 1, Character: 1}}, NewText: "y"},
+				{Range: Range{Start: Position{Line: 0}, End: Position{Line: 0, Character: 1}}, NewText: "x"},
+			},
+			want: "x := 1\ny := 2\n",
+		},
+		{
+			name: "insert in order",
+			edits: []TextEdit{
+				{Range: Range{Start: Position{Line: 2}, End: Position{Line: 2}}, NewText: "c"},
+				{Range: Range{Start: Position{Line: 2}, End: Position{Line: 2}}, NewText: " := 3\n"},
+			},
+			want: "a := 1\nb := 2\nc := 3\n",
+		},
+		{
+			name: "overlap",
+			edits: []TextEdit{
+				{Range: Range{Start: Position{Line: 0}, End: Position{Line: 1}}, NewText: ""},
+				{Range: Range{Start: Position{Line: 0, Character: 2}, End: Position{Line: 0, Character: 3}}, NewText: ""},
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := ApplyEdits(src, tt.edits)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ApplyEdits() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && string(got) != tt.want {
+				t.Errorf("ApplyEdits() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
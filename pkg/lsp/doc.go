@@ -0,0 +1,9 @@
+// Copyright 2018 The nvim-go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package lsp implements a small Language Server Protocol client which speaks JSON-RPC 2.0 over stdio.
+//
+// The client implements only the subset of the protocol which nvim-go uses as the backend of its commands,
+// such as the document synchronization, definition, references, implementation, rename and formatting.
+package lsp
@@ -0,0 +1,136 @@
+// Copyright 2018 The nvim-go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package lsp
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// JSON-RPC 2.0 and LSP error codes.
+const (
+	CodeParseError       = -32700
+	CodeInvalidRequest   = -32600
+	CodeMethodNotFound   = -32601
+	CodeInvalidParams    = -32602
+	CodeInternalError    = -32603
+	CodeRequestCancelled = -32800
+)
+
+// Error represents a JSON-RPC error object.
+type Error struct {
+	Code    int64           `json:"code"`
+	Message string          `json:"message"`
+	Data    json.RawMessage `json:"data,omitempty"`
+}
+
+// Error implements error.
+func (e *Error) Error() string {
+	return fmt.Sprintf("jsonrpc2: code %d: %s", e.Code, e.Message)
+}
+
+// Message represents a JSON-RPC 2.0 request, notification or response message.
+//
+// ID is nil if the message is a notification, and Method is empty if the message is a response.
+// ID is the number or the string, and kept as the raw JSON value to echo back the request ID unchanged.
+type Message struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method,omitempty"`
+	Params  json.RawMessage `json:"params,omitempty"`
+	Result  json.RawMessage `json:"result,omitempty"`
+	Error   *Error          `json:"error,omitempty"`
+}
+
+// IsResponse reports whether the m is a response message.
+func (m *Message) IsResponse() bool {
+	return m.Method == "" && m.ID != nil
+}
+
+// numberID returns the JSON value of the number request ID.
+func numberID(id int64) json.RawMessage {
+	return json.RawMessage(strconv.FormatInt(id, 10))
+}
+
+// idNumber returns the number request ID of m. The ok is false if the ID is not a number.
+func (m *Message) idNumber() (id int64, ok bool) {
+	if err := json.Unmarshal(m.ID, &id); err != nil {
+		return 0, false
+	}
+
+	return id, true
+}
+
+// Stream reads and writes the JSON-RPC messages with the "Content-Length" header framing of the LSP base protocol.
+type Stream struct {
+	r *bufio.Reader
+
+	mu sync.Mutex // guards w
+	w  io.Writer
+}
+
+// NewStream returns the new Stream which reads from r and writes to w.
+func NewStream(r io.Reader, w io.Writer) *Stream {
+	return &Stream{
+		r: bufio.NewReader(r),
+		w: w,
+	}
+}
+
+// Read reads the next message.
+func (s *Stream) Read() (*Message, error) {
+	header, err := textproto.NewReader(s.r).ReadMIMEHeader()
+	if err != nil {
+		return nil, err
+	}
+
+	cl := header.Get("Content-Length")
+	if cl == "" {
+		return nil, errors.New("missing Content-Length header")
+	}
+	n, err := strconv.ParseInt(strings.TrimSpace(cl), 10, 64)
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid Content-Length header")
+	}
+
+	data := make([]byte, n)
+	if _, err := io.ReadFull(s.r, data); err != nil {
+		return nil, err
+	}
+
+	msg := new(Message)
+	if err := json.Unmarshal(data, msg); err != nil {
+		return nil, errors.Wrap(err, "could not unmarshal message")
+	}
+
+	return msg, nil
+}
+
+// Write writes msg.
+func (s *Stream) Write(msg *Message) error {
+	msg.JSONRPC = "2.0"
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return errors.Wrap(err, "could not marshal message")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, err := fmt.Fprintf(s.w, "Content-Length: %d\r\n\r\n", len(data)); err != nil {
+		return err
+	}
+	_, err = s.w.Write(data)
+
+	return err
+}
@@ -0,0 +1,332 @@
+// Copyright 2018 The nvim-go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package lsp
+
+import (
+	"bytes"
+	"net/url"
+	"path/filepath"
+	"sort"
+	"unicode/utf8"
+
+	"github.com/pkg/errors"
+)
+
+// DocumentURI represents a URI of the text document such as "file:///path/to/file.go".
+type DocumentURI string
+
+// FileURI returns the DocumentURI of the filename.
+func FileURI(filename string) DocumentURI {
+	u := url.URL{Scheme: "file", Path: filepath.ToSlash(filename)}
+	return DocumentURI(u.String())
+}
+
+// Filename returns the filename of the "file" scheme URI.
+func (u DocumentURI) Filename() string {
+	p, err := url.Parse(string(u))
+	if err != nil || p.Scheme != "file" {
+		return ""
+	}
+	return filepath.FromSlash(p.Path)
+}
+
+// Position represents a position in the text document.
+// Character is the UTF-16 code unit offset in the line.
+type Position struct {
+	Line      int `json:"line"`
+	Character int `json:"character"`
+}
+
+// Range represents a range in the text document. End is exclusive.
+type Range struct {
+	Start Position `json:"start"`
+	End   Position `json:"end"`
+}
+
+// Location represents a range in the text document of the URI.
+type Location struct {
+	URI   DocumentURI `json:"uri"`
+	Range Range       `json:"range"`
+}
+
+// TextEdit represents a text replacement of the range.
+type TextEdit struct {
+	Range   Range  `json:"range"`
+	NewText string `json:"newText"`
+}
+
+// TextDocumentIdentifier identifies the text document.
+type TextDocumentIdentifier struct {
+	URI DocumentURI `json:"uri"`
+}
+
+// VersionedTextDocumentIdentifier identifies the version of the text document.
+type VersionedTextDocumentIdentifier struct {
+	URI     DocumentURI `json:"uri"`
+	Version int         `json:"version"`
+}
+
+// TextDocumentItem represents the opened text document.
+type TextDocumentItem struct {
+	URI        DocumentURI `json:"uri"`
+	LanguageID string      `json:"languageId"`
+	Version    int         `json:"version"`
+	Text       string      `json:"text"`
+}
+
+// TextDocumentEdit represents the edits of the versioned text document.
+type TextDocumentEdit struct {
+	TextDocument VersionedTextDocumentIdentifier `json:"textDocument"`
+	Edits        []TextEdit                      `json:"edits"`
+}
+
+// WorkspaceEdit represents the changes to many text documents.
+type WorkspaceEdit struct {
+	Changes         map[DocumentURI][]TextEdit `json:"changes,omitempty"`
+	DocumentChanges []TextDocumentEdit         `json:"documentChanges,omitempty"`
+}
+
+// Edits returns the text edits of each filename. DocumentChanges takes precedence over Changes.
+func (e *WorkspaceEdit) Edits() map[string][]TextEdit {
+	edits := make(map[string][]TextEdit)
+	if len(e.DocumentChanges) > 0 {
+		for _, dc := range e.DocumentChanges {
+			fname := dc.TextDocument.URI.Filename()
+			edits[fname] = append(edits[fname], dc.Edits...)
+		}
+		return edits
+	}
+	for uri, te := range e.Changes {
+		fname := uri.Filename()
+		edits[fname] = append(edits[fname], te...)
+	}
+
+	return edits
+}
+
+// TextDocumentPositionParams represents the position in the text document.
+type TextDocumentPositionParams struct {
+	TextDocument TextDocumentIdentifier `json:"textDocument"`
+	Position     Position               `json:"position"`
+}
+
+// ReferenceContext represents the context of the textDocument/references request.
+type ReferenceContext struct {
+	IncludeDeclaration bool `json:"includeDeclaration"`
+}
+
+// ReferenceParams represents the params of the textDocument/references request.
+type ReferenceParams struct {
+	TextDocumentPositionParams
+	Context ReferenceContext `json:"context"`
+}
+
+// RenameParams represents the params of the textDocument/rename request.
+type RenameParams struct {
+	TextDocumentPositionParams
+	NewName string `json:"newName"`
+}
+
+// FormattingOptions represents the options of the textDocument/formatting request.
+type FormattingOptions struct {
+	TabSize      int  `json:"tabSize"`
+	InsertSpaces bool `json:"insertSpaces"`
+}
+
+// DocumentFormattingParams represents the params of the textDocument/formatting request.
+type DocumentFormattingParams struct {
+	TextDocument TextDocumentIdentifier `json:"textDocument"`
+	Options      FormattingOptions      `json:"options"`
+}
+
+// CodeActionContext represents the context of the textDocument/codeAction request.
+type CodeActionContext struct {
+	Diagnostics []interface{} `json:"diagnostics"`
+	Only        []string      `json:"only,omitempty"`
+}
+
+// CodeActionParams represents the params of the textDocument/codeAction request.
+type CodeActionParams struct {
+	TextDocument TextDocumentIdentifier `json:"textDocument"`
+	Range        Range                  `json:"range"`
+	Context      CodeActionContext      `json:"context"`
+}
+
+// CodeAction represents a code action. Command based code actions are not supported.
+type CodeAction struct {
+	Title string         `json:"title"`
+	Kind  string         `json:"kind,omitempty"`
+	Edit  *WorkspaceEdit `json:"edit,omitempty"`
+}
+
+// CodeActionOrganizeImports kind of the organize imports code action.
+const CodeActionOrganizeImports = "source.organizeImports"
+
+// DidOpenTextDocumentParams represents the params of the textDocument/didOpen notification.
+type DidOpenTextDocumentParams struct {
+	TextDocument TextDocumentItem `json:"textDocument"`
+}
+
+// TextDocumentContentChangeEvent represents a full content change of the text document.
+type TextDocumentContentChangeEvent struct {
+	Text string `json:"text"`
+}
+
+// DidChangeTextDocumentParams represents the params of the textDocument/didChange notification.
+type DidChangeTextDocumentParams struct {
+	TextDocument   VersionedTextDocumentIdentifier  `json:"textDocument"`
+	ContentChanges []TextDocumentContentChangeEvent `json:"contentChanges"`
+}
+
+// DidSaveTextDocumentParams represents the params of the textDocument/didSave notification.
+type DidSaveTextDocumentParams struct {
+	TextDocument TextDocumentIdentifier `json:"textDocument"`
+}
+
+// DidCloseTextDocumentParams represents the params of the textDocument/didClose notification.
+type DidCloseTextDocumentParams struct {
+	TextDocument TextDocumentIdentifier `json:"textDocument"`
+}
+
+// WorkspaceFolder represents a workspace folder.
+type WorkspaceFolder struct {
+	URI  DocumentURI `json:"uri"`
+	Name string      `json:"name"`
+}
+
+// ClientInfo represents the information of the client.
+type ClientInfo struct {
+	Name    string `json:"name"`
+	Version string `json:"version,omitempty"`
+}
+
+// InitializeParams represents the params of the initialize request.
+type InitializeParams struct {
+	ProcessID        int               `json:"processId"`
+	ClientInfo       *ClientInfo       `json:"clientInfo,omitempty"`
+	RootURI          DocumentURI       `json:"rootUri"`
+	Capabilities     interface{}       `json:"capabilities"`
+	WorkspaceFolders []WorkspaceFolder `json:"workspaceFolders,omitempty"`
+}
+
+// InitializeResult represents the result of the initialize request.
+type InitializeResult struct {
+	Capabilities map[string]interface{} `json:"capabilities"`
+}
+
+// CancelParams represents the params of the $/cancelRequest notification.
+type CancelParams struct {
+	ID int64 `json:"id"`
+}
+
+// ConfigurationParams represents the params of the workspace/configuration request from the server.
+type ConfigurationParams struct {
+	Items []struct {
+		ScopeURI DocumentURI `json:"scopeUri,omitempty"`
+		Section  string      `json:"section,omitempty"`
+	} `json:"items"`
+}
+
+// OffsetPosition returns the Position of the byte offset in src.
+func OffsetPosition(src []byte, offset int) Position {
+	if offset > len(src) {
+		offset = len(src)
+	}
+
+	line := bytes.Count(src[:offset], []byte{'\n'})
+	start := bytes.LastIndexByte(src[:offset], '\n') + 1
+
+	return Position{
+		Line:      line,
+		Character: utf16Len(src[start:offset]),
+	}
+}
+
+// Offset returns the byte offset of the pos in src.
+func (pos Position) Offset(src []byte) (int, error) {
+	offset := 0
+	for i := 0; i < pos.Line; i++ {
+		nl := bytes.IndexByte(src[offset:], '\n')
+		if nl < 0 {
+			return 0, errors.Errorf("line %d is beyond the end of file", pos.Line+1)
+		}
+		offset += nl + 1
+	}
+
+	line := src[offset:]
+	if nl := bytes.IndexByte(line, '\n'); nl >= 0 {
+		line = line[:nl]
+	}
+	for chr := 0; chr < pos.Character; {
+		if len(line) == 0 {
+			return 0, errors.Errorf("column %d is beyond the end of line %d", pos.Character+1, pos.Line+1)
+		}
+		r, size := utf8.DecodeRune(line)
+		chr += utf16RuneLen(r)
+		line = line[size:]
+		offset += size
+	}
+
+	return offset, nil
+}
+
+// utf16Len returns the number of the UTF-16 code units of b.
+func utf16Len(b []byte) int {
+	n := 0
+	for len(b) > 0 {
+		r, size := utf8.DecodeRune(b)
+		n += utf16RuneLen(r)
+		b = b[size:]
+	}
+
+	return n
+}
+
+// utf16RuneLen returns the number of the UTF-16 code units of r.
+func utf16RuneLen(r rune) int {
+	if r >= 0x10000 {
+		return 2 // surrogate pair
+	}
+	return 1
+}
+
+// ApplyEdits returns src which is applied the edits.
+// The edits must not overlap, and the edits which have the same start position are applied in order.
+func ApplyEdits(src []byte, edits []TextEdit) ([]byte, error) {
+	type span struct {
+		start, end int
+		text       string
+	}
+	spans := make([]span, len(edits))
+	for i, e := range edits {
+		start, err := e.Range.Start.Offset(src)
+		if err != nil {
+			return nil, err
+		}
+		end, err := e.Range.End.Offset(src)
+		if err != nil {
+			return nil, err
+		}
+		if end < start {
+			return nil, errors.Errorf("invalid edit range: %v", e.Range)
+		}
+		spans[i] = span{start: start, end: end, text: e.NewText}
+	}
+	sort.SliceStable(spans, func(i, j int) bool { return spans[i].start < spans[j].start })
+
+	var buf bytes.Buffer
+	last := 0
+	for _, s := range spans {
+		if s.start < last {
+			return nil, errors.Errorf("overlapping edits at offset %d", s.start)
+		}
+		buf.Write(src[last:s.start])
+		buf.WriteString(s.text)
+		last = s.end
+	}
+	buf.Write(src[last:])
+
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,86 @@
+// Copyright 2018 The nvim-go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package nvimutil
+
+import (
+	"fmt"
+	"unicode/utf8"
+
+	"github.com/neovim/go-client/nvim"
+)
+
+// FloatSize returns the width and height of the floating window which displays lines.
+// The width is limited by maxWidth, and the height is limited by maxHeight with the wrapped lines.
+func FloatSize(lines [][]byte, maxWidth, maxHeight int) (width, height int) {
+	for _, line := range lines {
+		if w := utf8.RuneCount(line); w > width {
+			width = w
+		}
+	}
+	if width > maxWidth {
+		width = maxWidth
+	}
+	if width == 0 {
+		width = 1
+	}
+
+	for _, line := range lines {
+		if n := utf8.RuneCount(line); n > 0 {
+			height += (n + width - 1) / width
+			continue
+		}
+		height++
+	}
+	if height > maxHeight {
+		height = maxHeight
+	}
+
+	return width, height
+}
+
+// OpenFloat opens the floating window which displays lines under the cursor of the current window.
+//
+// The floating window is closed automatically when the cursor is moved, or leaves the current buffer
+// to the other than the floating window. Also closed when the cursor leaves the floating window.
+func OpenFloat(v *nvim.Nvim, lines [][]byte, filetype string, maxWidth, maxHeight int) (nvim.Window, error) {
+	b, err := v.CreateBuffer(false, true)
+	if err != nil {
+		return 0, err
+	}
+
+	batch := v.NewBatch()
+	batch.SetBufferLines(b, 0, -1, true, lines)
+	batch.SetBufferOption(b, BufOptionBufhidden, BufhiddenWipe)
+	batch.SetBufferOption(b, BufOptionFiletype, filetype)
+	batch.SetBufferOption(b, BufOptionModifiable, false)
+	if err := batch.Execute(); err != nil {
+		return 0, err
+	}
+
+	width, height := FloatSize(lines, maxWidth, maxHeight)
+	w, err := v.OpenWindow(b, false, &nvim.WindowConfig{
+		Relative: "cursor",
+		Anchor:   "NW",
+		Width:    width,
+		Height:   height,
+		Row:      1,
+		Col:      0,
+		Style:    "minimal",
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	batch.SetWindowOption(w, WinOptionWrap, true)
+	batch.Command(fmt.Sprintf("autocmd CursorMoved,CursorMovedI,InsertEnter <buffer> ++once silent! call nvim_win_close(%d, v:true)", w))
+	// keep the floating window while the cursor is in the floating window, and close it if leaves
+	batch.Command(fmt.Sprintf("autocmd BufEnter * ++once if win_getid() != %d | silent! call nvim_win_close(%d, v:true) | endif", w, w))
+	batch.Command(fmt.Sprintf("autocmd WinLeave <buffer=%d> ++once silent! call nvim_win_close(%d, v:true)", b, w))
+	if err := batch.Execute(); err != nil {
+		return 0, err
+	}
+
+	return w, nil
+}
@@ -0,0 +1,34 @@
+// Copyright 2018 The nvim-go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package nvimutil
+
+import "testing"
+
+func TestFloatSize(t *testing.T) {
+	tests := []struct {
+		name       string
+		lines      [][]byte
+		maxWidth   int
+		maxHeight  int
+		wantWidth  int
+		wantHeight int
+	}{
+		{name: "fit", lines: [][]byte{[]byte("foo"), {}, []byte("foobar")}, maxWidth: 80, maxHeight: 20, wantWidth: 6, wantHeight: 3},
+		{name: "wrap", lines: [][]byte{[]byte("foobarbaz")}, maxWidth: 4, maxHeight: 20, wantWidth: 4, wantHeight: 3},
+		{name: "max height", lines: [][]byte{[]byte("a"), []byte("b"), []byte("c")}, maxWidth: 80, maxHeight: 2, wantWidth: 1, wantHeight: 2},
+		{name: "multibyte", lines: [][]byte{[]byte("▼ foo")}, maxWidth: 80, maxHeight: 20, wantWidth: 5, wantHeight: 1},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			width, height := FloatSize(tt.lines, tt.maxWidth, tt.maxHeight)
+			if width != tt.wantWidth || height != tt.wantHeight {
+				t.Errorf("FloatSize() = (%d, %d), want (%d, %d)", width, height, tt.wantWidth, tt.wantHeight)
+			}
+		})
+	}
+}
@@ -47,6 +47,10 @@ const (
 	WinOptionRelativenumber = "relativenumber" // bool
 	// WinOptionWinfixheight represents a winfixheight.
 	WinOptionWinfixheight = "winfixheight" // bool
+	// WinOptionWinfixwidth represents a winfixwidth.
+	WinOptionWinfixwidth = "winfixwidth" // bool
+	// WinOptionWrap represents a wrap.
+	WinOptionWrap = "wrap" // bool
 )
 
 const (
@@ -0,0 +1,142 @@
+// Copyright 2018 The nvim-go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package nvimutil
+
+import (
+	"go/token"
+	"strings"
+)
+
+const (
+	// FoldOpenSymbol symbol of expanded tree node.
+	//
+	// ▼  BLACK DOWN-POINTING TRIANGLE         (U+25BC)
+	FoldOpenSymbol = "▼"
+	// FoldCloseSymbol symbol of collapsed tree node.
+	//
+	// ▶  BLACK RIGHT-POINTING TRIANGLE        (U+25B6)
+	FoldCloseSymbol = "▶"
+	// LeafSymbol symbol of tree leaf node.
+	LeafSymbol = "-"
+)
+
+// TreeNode represents a node of the tree buffer.
+type TreeNode struct {
+	// Text is the display text of node.
+	Text string
+	// Start and End are the source range of node. Start is also the jump destination.
+	Start, End token.Position
+	// Children child nodes of node.
+	Children []*TreeNode
+	// Expanded whether the node is expanded.
+	Expanded bool
+	// Expandable whether the node has the children which are not loaded yet.
+	// The tree user loads the Children lazily when the node is expanded.
+	Expandable bool
+	// Data is the arbitrary data of node for the tree user.
+	Data interface{}
+}
+
+// IsLeaf reports whether the n has no child nodes and is not expandable.
+func (n *TreeNode) IsLeaf() bool { return len(n.Children) == 0 && !n.Expandable }
+
+// Tree represents a tree of the tree buffer.
+type Tree struct {
+	// Roots root nodes of the tree.
+	Roots []*TreeNode
+
+	// lines is the rendered node of each buffer lines.
+	lines []*TreeNode
+}
+
+// NewTree returns the new Tree with the roots nodes.
+func NewTree(roots ...*TreeNode) *Tree {
+	return &Tree{Roots: roots}
+}
+
+// Render renders the expanded nodes of tree to the buffer lines.
+func (t *Tree) Render() [][]byte {
+	t.lines = t.lines[:0]
+
+	var lines [][]byte
+	var walk func(nodes []*TreeNode, depth int)
+	walk = func(nodes []*TreeNode, depth int) {
+		for _, n := range nodes {
+			icon := LeafSymbol
+			if !n.IsLeaf() {
+				icon = FoldCloseSymbol
+				if n.Expanded {
+					icon = FoldOpenSymbol
+				}
+			}
+
+			lines = append(lines, []byte(strings.Repeat("  ", depth)+icon+" "+n.Text))
+			t.lines = append(t.lines, n)
+
+			if n.Expanded {
+				walk(n.Children, depth+1)
+			}
+		}
+	}
+	walk(t.Roots, 0)
+
+	return lines
+}
+
+// NodeAt returns the rendered node of the line. line is 1-based.
+func (t *Tree) NodeAt(line int) *TreeNode {
+	if line < 1 || line > len(t.lines) {
+		return nil
+	}
+
+	return t.lines[line-1]
+}
+
+// LineOf returns the 1-based rendered line number of n. Returns 0 if n is not rendered.
+func (t *Tree) LineOf(n *TreeNode) int {
+	for i, node := range t.lines {
+		if node == n {
+			return i + 1
+		}
+	}
+
+	return 0
+}
+
+// Toggle toggles the expand state of the node at line, and reports whether the node toggled.
+func (t *Tree) Toggle(line int) bool {
+	n := t.NodeAt(line)
+	if n == nil || n.IsLeaf() {
+		return false
+	}
+	n.Expanded = !n.Expanded
+
+	return true
+}
+
+// Enclosing returns the innermost rendered node whose source range encloses the offset.
+// If the innermost node is hidden by collapsed parent, returns the visible parent node.
+func (t *Tree) Enclosing(filename string, offset int) *TreeNode {
+	var found *TreeNode
+	size := -1
+
+	var walk func(nodes []*TreeNode, visible *TreeNode, shown bool)
+	walk = func(nodes []*TreeNode, visible *TreeNode, shown bool) {
+		for _, n := range nodes {
+			if shown {
+				visible = n
+			}
+			if n.Start.IsValid() && n.Start.Filename == filename && n.Start.Offset <= offset && offset <= n.End.Offset {
+				if s := n.End.Offset - n.Start.Offset; size == -1 || s < size {
+					found, size = visible, s
+				}
+			}
+			walk(n.Children, visible, shown && n.Expanded)
+		}
+	}
+	walk(t.Roots, nil, true)
+
+	return found
+}
@@ -0,0 +1,111 @@
+// Copyright 2018 The nvim-go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package nvimutil
+
+import (
+	"go/token"
+	"reflect"
+	"testing"
+)
+
+func testTree() *Tree {
+	pos := func(offset int) token.Position {
+		return token.Position{Filename: "foo.go", Offset: offset, Line: 1, Column: offset + 1}
+	}
+
+	method := &TreeNode{Text: "Bar()", Start: pos(30), End: pos(40)}
+	typ := &TreeNode{Text: "Foo", Start: pos(10), End: pos(50), Children: []*TreeNode{method}}
+	types := &TreeNode{Text: "types", Children: []*TreeNode{typ}, Expanded: true}
+
+	return NewTree(types, &TreeNode{Text: "funcs"})
+}
+
+func TestTree_Render(t *testing.T) {
+	tree := testTree()
+
+	want := [][]byte{
+		[]byte("▼ types"),
+		[]byte("  ▶ Foo"),
+		[]byte("- funcs"),
+	}
+	if got := tree.Render(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Tree.Render() = %q, want %q", got, want)
+	}
+
+	if !tree.Toggle(2) {
+		t.Fatal("Tree.Toggle(2) = false, want true")
+	}
+	want = [][]byte{
+		[]byte("▼ types"),
+		[]byte("  ▼ Foo"),
+		[]byte("    - Bar()"),
+		[]byte("- funcs"),
+	}
+	if got := tree.Render(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Tree.Render() = %q, want %q", got, want)
+	}
+	if n := tree.NodeAt(3); n == nil || n.Text != "Bar()" {
+		t.Errorf("Tree.NodeAt(3) = %v, want Bar()", n)
+	}
+	if line := tree.LineOf(tree.NodeAt(4)); line != 4 {
+		t.Errorf("Tree.LineOf() = %d, want 4", line)
+	}
+	if tree.Toggle(4) {
+		t.Error("Tree.Toggle(4) = true, want false for leaf node")
+	}
+}
+
+func TestTree_Expandable(t *testing.T) {
+	lazy := &TreeNode{Text: "lazy", Expandable: true}
+	tree := NewTree(lazy)
+
+	want := [][]byte{[]byte("▶ lazy")}
+	if got := tree.Render(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Tree.Render() = %q, want %q", got, want)
+	}
+
+	lazy.Children = []*TreeNode{{Text: "child"}}
+	lazy.Expandable = false
+	if !tree.Toggle(1) {
+		t.Fatal("Tree.Toggle(1) = false, want true")
+	}
+	want = [][]byte{[]byte("▼ lazy"), []byte("  - child")}
+	if got := tree.Render(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Tree.Render() = %q, want %q", got, want)
+	}
+}
+
+func TestTree_Enclosing(t *testing.T) {
+	tests := []struct {
+		name   string
+		toggle int
+		offset int
+		want   string
+	}{
+		{name: "collapsed", offset: 15, want: "Foo"},
+		{name: "expanded child", toggle: 2, offset: 35, want: "Bar()"},
+		{name: "hidden child", offset: 35, want: "Foo"},
+		{name: "outside", offset: 60, want: ""},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			tree := testTree()
+			tree.Render()
+			if tt.toggle > 0 {
+				tree.Toggle(tt.toggle)
+				tree.Render()
+			}
+
+			var got string
+			if n := tree.Enclosing("foo.go", tt.offset); n != nil {
+				got = n.Text
+			}
+			if got != tt.want {
+				t.Errorf("Tree.Enclosing(%d) = %q, want %q", tt.offset, got, tt.want)
+			}
+		})
+	}
+}
@@ -38,10 +38,15 @@ endfunction
 
 call remote#host#Register(s:plugin_name, '', function('s:JobStart'))
 call remote#host#RegisterPlugin('nvim-go', '0', [
-\ {'type': 'autocmd', 'name': 'BufEnter', 'sync': 0, 'opts': {'eval': '{''BufNr'': bufnr(''%''), ''WinID'': win_getid(), ''Dir'': expand(''%:p:h''), ''Cfg'': {''Global'': {''ServerName'': v:servername, ''ErrorListType'': get(g:, ''go#global#errorlisttype'', ''locationlist'')}, ''Build'': {''Appengine'': get(g:, ''go#build#appengine'', v:false), ''Autosave'': get(g:, ''go#build#autosave'', v:false), ''Force'': get(g:, ''go#build#force'', v:false), ''Flags'': get(g:, ''go#build#flags'', []), ''IsNotGb'': get(g:, ''go#build#is_not_gb'', v:false)}, ''Cover'': {''Flags'': get(g:, ''go#cover#flags'', []), ''Mode'': get(g:, ''go#cover#mode'', ''atomic'')}, ''Fmt'': {''Autosave'': get(g:, ''go#fmt#autosave'', v:false), ''Mode'': get(g:, ''go#fmt#mode'', ''goimports''), ''GoImportsLocal'': get(g:, ''go#fmt#goimports_local'', [])}, ''Generate'': {''TestAllFuncs'': get(g:, ''go#generate#test#allfuncs'', v:true), ''TestExclFuncs'': get(g:, ''go#generate#test#exclude'', ''''), ''TestExportedFuncs'': get(g:, ''go#generate#test#exportedfuncs'', v:false), ''TestSubTest'': get(g:, ''go#generate#test#subtest'', v:true), ''TestParallel'': get(g:, ''go#generate#test#parallel'', v:true), ''TestTemplateDir'': get(g:, ''go#generate#test#template_dir'', ''''), ''TemplateParamsPath'': get(g:, ''go#generate#test#template_params_path'', '''')}, ''Guru'': {''Reflection'': get(g:, ''go#guru#reflection'', v:false), ''KeepCursor'': get(g:, ''go#guru#keep_cursor'', {''callees'':v:false,''callers'':v:false,''callstack'':v:false,''definition'':v:false,''describe'':v:false,''freevars'':v:false,''implements'':v:false,''peers'':v:false,''pointsto'':v:false,''referrers'':v:false,''whicherrs'':v:false}), ''JumpFirst'': get(g:, ''go#guru#jump_first'', v:false)}, ''Iferr'': {''Autosave'': get(g:, ''go#iferr#autosave'', v:false)}, ''Lint'': {''GolintAutosave'': get(g:, ''go#lint#golint#autosave'', v:false), ''GolintIgnore'': get(g:, ''go#lint#golint#ignore'', []), ''GolintMinConfidence'': get(g:, ''go#lint#golint#min_confidence'', 0.8), ''GolintMode'': get(g:, ''go#lint#golint#mode'', ''current''), ''GoVetAutosave'': get(g:, ''go#lint#govet#autosave'', v:false), ''GoVetFlags'': get(g:, ''go#lint#govet#flags'', []), ''GoVetIgnore'': get(g:, ''go#lint#govet#ignore'', []), ''MetalinterAutosave'': get(g:, ''go#lint#metalinter#autosave'', v:false), ''MetalinterAutosaveTools'': get(g:, ''go#lint#metalinter#autosave#tools'', [''vet'', ''golint'']), ''MetalinterTools'': get(g:, ''go#lint#metalinter#tools'', [''vet'', ''golint'']), ''MetalinterDeadline'': get(g:, ''go#lint#metalinter#deadline'', ''5s''), ''MetalinterSkipDir'': get(g:, ''go#lint#metalinter#skip_dir'', [])}, ''Rename'': {''Prefill'': get(g:, ''go#rename#prefill'', v:false)}, ''Terminal'': {''Mode'': get(g:, ''go#terminal#mode'', ''vsplit''), ''Position'': get(g:, ''go#terminal#position'', ''belowright''), ''Height'': get(g:, ''go#terminal#height'', 0), ''Width'': get(g:, ''go#terminal#width'', 0), ''StopInsert'': get(g:, ''go#terminal#stop_insert'', v:true)}, ''Test'': {''AllPackage'': get(g:, ''go#test#all_package'', v:false), ''Autosave'': get(g:, ''go#test#autosave'', v:false), ''Flags'': get(g:, ''go#test#flags'', [])}, ''Debug'': {''Enable'': get(g:, ''go#debug'', v:false), ''Pprof'': get(g:, ''go#debug#pprof'', v:false)}}}', 'group': 'nvim-go', 'pattern': '*.go'}},
+\ {'type': 'autocmd', 'name': 'BufDelete,BufWipeout', 'sync': 0, 'opts': {'eval': '{''BufNr'': str2nr(expand(''<abuf>'')), ''File'': expand(''<afile>:p'')}', 'group': 'nvim-go', 'pattern': '*.go'}},
+\ {'type': 'autocmd', 'name': 'BufEnter', 'sync': 0, 'opts': {'eval': '{''BufNr'': bufnr(''%''), ''WinID'': win_getid(), ''Dir'': expand(''%:p:h''), ''File'': expand(''%:p''), ''Cfg'': {''Global'': {''ServerName'': v:servername, ''ErrorListType'': get(g:, ''go#global#errorlisttype'', ''locationlist'')}, ''Build'': {''Appengine'': get(g:, ''go#build#appengine'', v:false), ''Autosave'': get(g:, ''go#build#autosave'', v:false), ''Force'': get(g:, ''go#build#force'', v:false), ''Flags'': get(g:, ''go#build#flags'', []), ''IsNotGb'': get(g:, ''go#build#is_not_gb'', v:false)}, ''CallGraph'': {''Format'': get(g:, ''go#callgraph#format'', ''dot''), ''Output'': get(g:, ''go#callgraph#output'', '''')}, ''Cover'': {''Flags'': get(g:, ''go#cover#flags'', []), ''Mode'': get(g:, ''go#cover#mode'', ''atomic'')}, ''DeadCode'': {''ExcludeGenerated'': get(g:, ''go#deadcode#exclude_generated'', v:false), ''ExcludeTests'': get(g:, ''go#deadcode#exclude_tests'', v:false)}, ''Fmt'': {''Autosave'': get(g:, ''go#fmt#autosave'', v:false), ''Mode'': get(g:, ''go#fmt#mode'', ''goimports''), ''GoImportsLocal'': get(g:, ''go#fmt#goimports_local'', [])}, ''Generate'': {''TestAllFuncs'': get(g:, ''go#generate#test#allfuncs'', v:true), ''TestExclFuncs'': get(g:, ''go#generate#test#exclude'', ''''), ''TestExportedFuncs'': get(g:, ''go#generate#test#exportedfuncs'', v:false), ''TestSubTest'': get(g:, ''go#generate#test#subtest'', v:true), ''TestParallel'': get(g:, ''go#generate#test#parallel'', v:true), ''TestTemplateDir'': get(g:, ''go#generate#test#template_dir'', ''''), ''TemplateParamsPath'': get(g:, ''go#generate#test#template_params_path'', '''')}, ''Gopls'': {''Enable'': get(g:, ''go#gopls#enable'', v:false), ''Path'': get(g:, ''go#gopls#path'', ''gopls''), ''Args'': get(g:, ''go#gopls#args'', [])}, ''Guru'': {''Reflection'': get(g:, ''go#guru#reflection'', v:false), ''KeepCursor'': get(g:, ''go#guru#keep_cursor'', {''callees'':v:false,''callers'':v:false,''callstack'':v:false,''definition'':v:false,''describe'':v:false,''freevars'':v:false,''implements'':v:false,''peers'':v:false,''pointsto'':v:false,''referrers'':v:false,''whicherrs'':v:false}), ''JumpFirst'': get(g:, ''go#guru#jump_first'', v:false)}, ''Highlight'': {''Semantic'': get(g:, ''go#highlight#semantic'', v:false), ''SameIds'': get(g:, ''go#highlight#same_ids'', v:false)}, ''Hover'': {''Auto'': get(g:, ''go#hover#auto'', v:false), ''Delay'': get(g:, ''go#hover#delay'', 500)}, ''Iferr'': {''Autosave'': get(g:, ''go#iferr#autosave'', v:false)}, ''Lint'': {''GolintAutosave'': get(g:, ''go#lint#golint#autosave'', v:false), ''GolintIgnore'': get(g:, ''go#lint#golint#ignore'', []), ''GolintMinConfidence'': get(g:, ''go#lint#golint#min_confidence'', 0.8), ''GolintMode'': get(g:, ''go#lint#golint#mode'', ''current''), ''GoVetAutosave'': get(g:, ''go#lint#govet#autosave'', v:false), ''GoVetFlags'': get(g:, ''go#lint#govet#flags'', []), ''GoVetIgnore'': get(g:, ''go#lint#govet#ignore'', []), ''MetalinterAutosave'': get(g:, ''go#lint#metalinter#autosave'', v:false), ''MetalinterAutosaveTools'': get(g:, ''go#lint#metalinter#autosave#tools'', [''vet'', ''golint'']), ''MetalinterTools'': get(g:, ''go#lint#metalinter#tools'', [''vet'', ''golint'']), ''MetalinterDeadline'': get(g:, ''go#lint#metalinter#deadline'', ''5s''), ''MetalinterSkipDir'': get(g:, ''go#lint#metalinter#skip_dir'', [])}, ''Rename'': {''Prefill'': get(g:, ''go#rename#prefill'', v:false), ''Preview'': get(g:, ''go#rename#preview'', v:true)}, ''Signature'': {''Enable'': get(g:, ''go#signature#enable'', v:true)}, ''Tags'': {''Transform'': get(g:, ''go#tags#transform'', {}), ''DefaultTransform'': get(g:, ''go#tags#default_transform'', ''snakecase'')}, ''Terminal'': {''Mode'': get(g:, ''go#terminal#mode'', ''vsplit''), ''Position'': get(g:, ''go#terminal#position'', ''belowright''), ''Height'': get(g:, ''go#terminal#height'', 0), ''Width'': get(g:, ''go#terminal#width'', 0), ''StopInsert'': get(g:, ''go#terminal#stop_insert'', v:true)}, ''Test'': {''AllPackage'': get(g:, ''go#test#all_package'', v:false), ''Autosave'': get(g:, ''go#test#autosave'', v:false), ''Flags'': get(g:, ''go#test#flags'', [])}, ''Debug'': {''Enable'': get(g:, ''go#debug'', v:false), ''Pprof'': get(g:, ''go#debug#pprof'', v:false)}}}', 'group': 'nvim-go', 'pattern': '*.go'}},
 \ {'type': 'autocmd', 'name': 'BufNewFile,BufReadPre', 'sync': 0, 'opts': {'eval': '{}', 'group': 'nvim-go', 'pattern': '*'}},
+\ {'type': 'autocmd', 'name': 'BufWinEnter,TextChanged,TextChangedI,WinScrolled', 'sync': 0, 'opts': {'eval': '{''BufNr'': bufnr(''%''), ''File'': expand(''%:p''), ''Tick'': b:changedtick, ''Start'': line(''w0''), ''End'': line(''w$''), ''Mode'': mode(), ''Offset'': line2byte(line(''.'')) + (col(''.'')-2)}', 'group': 'nvim-go', 'pattern': '*.go'}},
 \ {'type': 'autocmd', 'name': 'BufWritePost', 'sync': 0, 'opts': {'eval': '{''Cwd'': getcwd(), ''File'': expand(''%:p'')}', 'group': 'nvim-go', 'pattern': '*.go'}},
 \ {'type': 'autocmd', 'name': 'BufWritePre', 'sync': 0, 'opts': {'eval': '{''Cwd'': getcwd(), ''File'': expand(''%:p'')}', 'group': 'nvim-go', 'pattern': '*.go'}},
+\ {'type': 'autocmd', 'name': 'CompleteDone', 'sync': 0, 'opts': {'eval': '{''BufNr'': bufnr(''%''), ''Item'': v:completed_item}', 'group': 'nvim-go', 'pattern': '*.go'}},
+\ {'type': 'autocmd', 'name': 'CursorHold', 'sync': 0, 'opts': {'eval': '{''Cwd'': getcwd(), ''File'': expand(''%:p''), ''BufNr'': bufnr(''%''), ''WinID'': win_getid(), ''Modified'': &modified, ''Tick'': b:changedtick, ''Offset'': line2byte(line(''.'')) + (col(''.'')-2)}', 'group': 'nvim-go', 'pattern': '*.go'}},
+\ {'type': 'autocmd', 'name': 'CursorMoved', 'sync': 0, 'opts': {'eval': '{''BufNr'': bufnr(''%''), ''Offset'': line2byte(line(''.'')) + (col(''.'')-2)}', 'group': 'nvim-go', 'pattern': '*.go'}},
 \ {'type': 'autocmd', 'name': 'VimLeavePre', 'sync': 0, 'opts': {'group': 'nvim-go', 'pattern': '*.go'}},
 \ {'type': 'autocmd', 'name': 'VimLeavePre', 'sync': 0, 'opts': {'group': 'nvim-go', 'pattern': '*.go,terminal,context,thread'}},
 \ {'type': 'command', 'name': 'DlvBreakpoint', 'sync': 0, 'opts': {'complete': 'customlist,FunctionsCompletion', 'eval': '[expand(''%:p'')]', 'nargs': '*'}},
@@ -53,29 +58,66 @@ call remote#host#RegisterPlugin('nvim-go', '0', [
 \ {'type': 'command', 'name': 'DlvRestart', 'sync': 0, 'opts': {}},
 \ {'type': 'command', 'name': 'DlvState', 'sync': 0, 'opts': {}},
 \ {'type': 'command', 'name': 'DlvStdin', 'sync': 0, 'opts': {}},
+\ {'type': 'command', 'name': 'GoAddTags', 'sync': 0, 'opts': {'eval': 'expand(''%:p'')', 'nargs': '*', 'range': ''}},
+\ {'type': 'command', 'name': 'GoAnalyze', 'sync': 0, 'opts': {'eval': '{''File'': expand(''%:p''), ''BufNr'': bufnr(''%''), ''WinID'': win_getid()}'}},
+\ {'type': 'command', 'name': 'GoAstView', 'sync': 0, 'opts': {'eval': '{''File'': expand(''%:p''), ''BufNr'': bufnr(''%''), ''WinID'': win_getid(), ''Offset'': line2byte(line(''.'')) + (col(''.'')-2)}'}},
 \ {'type': 'command', 'name': 'GoBuffers', 'sync': 0, 'opts': {}},
 \ {'type': 'command', 'name': 'GoBuild', 'sync': 0, 'opts': {'bang': '', 'eval': '[getcwd(), expand(''%:p'')]', 'nargs': '*'}},
 \ {'type': 'command', 'name': 'GoByteOffset', 'sync': 0, 'opts': {'eval': 'expand(''%:p'')'}},
+\ {'type': 'command', 'name': 'GoCallGraph', 'sync': 0, 'opts': {'complete': 'customlist,GoCallGraphCompletion', 'eval': '{''Cwd'': getcwd(), ''File'': expand(''%:p''), ''Offset'': line2byte(line(''.'')) + (col(''.'')-2)}', 'nargs': '*'}},
+\ {'type': 'command', 'name': 'GoCallHierarchy', 'sync': 0, 'opts': {'eval': '{''Cwd'': getcwd(), ''File'': expand(''%:p''), ''BufNr'': bufnr(''%''), ''WinID'': win_getid(), ''Offset'': line2byte(line(''.'')) + (col(''.'')-2)}', 'nargs': '?'}},
+\ {'type': 'command', 'name': 'GoCancel', 'sync': 0, 'opts': {}},
 \ {'type': 'command', 'name': 'GoCover', 'sync': 0, 'opts': {'eval': '[getcwd(), expand(''%:p'')]', 'nargs': '?'}},
 \ {'type': 'command', 'name': 'GoCoverClear', 'sync': 0, 'opts': {}},
+\ {'type': 'command', 'name': 'GoDeadCode', 'sync': 0, 'opts': {'eval': '{''Cwd'': getcwd(), ''File'': expand(''%:p'')}'}},
+\ {'type': 'command', 'name': 'GoDefPop', 'sync': 0, 'opts': {'nargs': '?'}},
+\ {'type': 'command', 'name': 'GoDefStack', 'sync': 0, 'opts': {'eval': 'getcwd()'}},
+\ {'type': 'command', 'name': 'GoDoc', 'sync': 0, 'opts': {'complete': 'customlist,GoDocCompletion', 'eval': '{''File'': expand(''%:p''), ''BufNr'': bufnr(''%''), ''Offset'': line2byte(line(''.'')) + (col(''.'')-2)}', 'nargs': '?'}},
+\ {'type': 'command', 'name': 'GoDocBrowse', 'sync': 0, 'opts': {'complete': 'customlist,GoDocBrowseCompletion', 'eval': '{''File'': expand(''%:p''), ''WinID'': win_getid()}', 'nargs': '?'}},
+\ {'type': 'command', 'name': 'GoDocComment', 'sync': 0, 'opts': {'bang': '', 'eval': '{''File'': expand(''%:p''), ''Offset'': line2byte(line(''.'')) + (col(''.'')-2)}'}},
+\ {'type': 'command', 'name': 'GoExtractFunc', 'sync': 0, 'opts': {'eval': '{''File'': expand(''%:p''), ''BufNr'': bufnr(''%''), ''Start'': getpos("''<")[1:2], ''End'': getpos("''>")[1:2]}', 'nargs': '1', 'range': ''}},
+\ {'type': 'command', 'name': 'GoExtractVar', 'sync': 0, 'opts': {'eval': '{''File'': expand(''%:p''), ''BufNr'': bufnr(''%''), ''Start'': getpos("''<")[1:2], ''End'': getpos("''>")[1:2]}', 'nargs': '1', 'range': ''}},
 \ {'type': 'command', 'name': 'GoFmt', 'sync': 0, 'opts': {'eval': 'expand(''%:p:h'')'}},
 \ {'type': 'command', 'name': 'GoGenerateTest', 'sync': 0, 'opts': {'addr': 'line', 'bang': '', 'complete': 'file', 'eval': 'expand(''%:p:h'')', 'nargs': '*', 'range': '%'}},
+\ {'type': 'command', 'name': 'GoHover', 'sync': 0, 'opts': {'eval': '{''Cwd'': getcwd(), ''File'': expand(''%:p''), ''BufNr'': bufnr(''%''), ''WinID'': win_getid(), ''Modified'': &modified, ''Offset'': line2byte(line(''.'')) + (col(''.'')-2)}'}},
 \ {'type': 'command', 'name': 'GoIferr', 'sync': 0, 'opts': {'eval': 'expand(''%:p'')'}},
+\ {'type': 'command', 'name': 'GoImports', 'sync': 0, 'opts': {'complete': 'customlist,GoImportsCompletion', 'eval': '{''Cwd'': getcwd(), ''File'': expand(''%:p''), ''BufNr'': bufnr(''%''), ''WinID'': win_getid()}', 'nargs': '1'}},
+\ {'type': 'command', 'name': 'GoInline', 'sync': 0, 'opts': {'eval': '{''File'': expand(''%:p''), ''BufNr'': bufnr(''%''), ''Offset'': line2byte(line(''.'')) + (col(''.'')-2)}'}},
 \ {'type': 'command', 'name': 'GoLint', 'sync': 0, 'opts': {'complete': 'customlist,GoLintCompletion', 'eval': 'expand(''%:p'')', 'nargs': '?'}},
 \ {'type': 'command', 'name': 'GoMetalinter', 'sync': 0, 'opts': {'eval': 'getcwd()'}},
 \ {'type': 'command', 'name': 'GoNotify', 'sync': 0, 'opts': {'nargs': '*'}},
+\ {'type': 'command', 'name': 'GoRemoveTags', 'sync': 0, 'opts': {'eval': 'expand(''%:p'')', 'nargs': '*', 'range': ''}},
 \ {'type': 'command', 'name': 'GoRename', 'sync': 0, 'opts': {'bang': '', 'eval': '[getcwd(), expand(''%:p''), expand(''<cword>'')]', 'nargs': '?'}},
 \ {'type': 'command', 'name': 'GoRun', 'sync': 0, 'opts': {'eval': 'expand(''%:p'')', 'nargs': '*'}},
 \ {'type': 'command', 'name': 'GoRunLast', 'sync': 0, 'opts': {'eval': 'expand(''%:p'')'}},
+\ {'type': 'command', 'name': 'GoSameIdsNext', 'sync': 0, 'opts': {'eval': '{''File'': expand(''%:p''), ''BufNr'': bufnr(''%''), ''Tick'': b:changedtick, ''Offset'': line2byte(line(''.'')) + (col(''.'')-2)}'}},
+\ {'type': 'command', 'name': 'GoSameIdsPrev', 'sync': 0, 'opts': {'eval': '{''File'': expand(''%:p''), ''BufNr'': bufnr(''%''), ''Tick'': b:changedtick, ''Offset'': line2byte(line(''.'')) + (col(''.'')-2)}'}},
+\ {'type': 'command', 'name': 'GoSameIdsToggle', 'sync': 0, 'opts': {'eval': '{''File'': expand(''%:p''), ''BufNr'': bufnr(''%''), ''Tick'': b:changedtick, ''Offset'': line2byte(line(''.'')) + (col(''.'')-2)}'}},
 \ {'type': 'command', 'name': 'GoSwitchTest', 'sync': 0, 'opts': {'eval': '{''Cwd'': getcwd(), ''File'': expand(''%:p''), ''Offset'': line2byte(line(''.'')) + (col(''.'')-2)}'}},
+\ {'type': 'command', 'name': 'GoSymbols', 'sync': 0, 'opts': {'eval': '{''Cwd'': getcwd(), ''Dir'': expand(''%:p:h'')}', 'nargs': '?'}},
 \ {'type': 'command', 'name': 'GoTabpages', 'sync': 0, 'opts': {}},
 \ {'type': 'command', 'name': 'GoTest', 'sync': 0, 'opts': {'eval': 'expand(''%:p:h'')', 'nargs': '*'}},
 \ {'type': 'command', 'name': 'GoTestFunc', 'sync': 0, 'opts': {'eval': '{''File'': expand(''%:p''), ''Offset'': line2byte(line(''.'')) + (col(''.'')-2)}', 'nargs': '*'}},
+\ {'type': 'command', 'name': 'GoTypeHierarchy', 'sync': 0, 'opts': {'eval': '{''Cwd'': getcwd(), ''File'': expand(''%:p''), ''BufNr'': bufnr(''%''), ''WinID'': win_getid(), ''Offset'': line2byte(line(''.'')) + (col(''.'')-2)}'}},
 \ {'type': 'command', 'name': 'GoVet', 'sync': 0, 'opts': {'complete': 'customlist,GoVetCompletion', 'eval': '[getcwd(), expand(''%:p'')]', 'nargs': '*'}},
+\ {'type': 'command', 'name': 'GoWhyImport', 'sync': 0, 'opts': {'complete': 'customlist,GoDocBrowseCompletion', 'eval': '{''Cwd'': getcwd(), ''File'': expand(''%:p'')}', 'nargs': '1'}},
 \ {'type': 'command', 'name': 'GoWindows', 'sync': 0, 'opts': {}},
 \ {'type': 'function', 'name': 'FunctionsCompletion', 'sync': 1, 'opts': {}},
-\ {'type': 'function', 'name': 'GoGuru', 'sync': 0, 'opts': {'eval': '[getcwd(), expand(''%:p''), &modified, line2byte(line(''.'')) + (col(''.'')-2)]'}},
+\ {'type': 'function', 'name': 'GoAnalyzeAction', 'sync': 0, 'opts': {'eval': 'line(''.'')'}},
+\ {'type': 'function', 'name': 'GoAstViewAction', 'sync': 0, 'opts': {'eval': 'line(''.'')'}},
+\ {'type': 'function', 'name': 'GoCallGraphCompletion', 'sync': 1, 'opts': {}},
+\ {'type': 'function', 'name': 'GoCallHierarchyAction', 'sync': 0, 'opts': {'eval': 'line(''.'')'}},
+\ {'type': 'function', 'name': 'GoComplete', 'sync': 1, 'opts': {'eval': '{''File'': expand(''%:p''), ''BufNr'': bufnr(''%''), ''Offset'': line2byte(line(''.'')) + (col(''.'')-2)}'}},
+\ {'type': 'function', 'name': 'GoDocBrowseAction', 'sync': 0, 'opts': {'eval': '[line(''.''), col(''.'')]'}},
+\ {'type': 'function', 'name': 'GoDocBrowseCompletion', 'sync': 1, 'opts': {}},
+\ {'type': 'function', 'name': 'GoDocCompletion', 'sync': 1, 'opts': {'eval': 'expand(''%:p'')'}},
+\ {'type': 'function', 'name': 'GoGuru', 'sync': 0, 'opts': {'eval': '[getcwd(), expand(''%:p''), line2byte(line(''.'')) + (col(''.'')-2)]'}},
+\ {'type': 'function', 'name': 'GoHoverJump', 'sync': 0, 'opts': {}},
+\ {'type': 'function', 'name': 'GoImportsAction', 'sync': 0, 'opts': {'eval': 'line(''.'')'}},
+\ {'type': 'function', 'name': 'GoImportsCompletion', 'sync': 1, 'opts': {}},
 \ {'type': 'function', 'name': 'GoLintCompletion', 'sync': 0, 'opts': {'eval': 'getcwd()'}},
+\ {'type': 'function', 'name': 'GoRenameApply', 'sync': 0, 'opts': {}},
+\ {'type': 'function', 'name': 'GoTypeHierarchyAction', 'sync': 0, 'opts': {'eval': 'line(''.'')'}},
 \ {'type': 'function', 'name': 'GoVetCompletion', 'sync': 0, 'opts': {'eval': 'getcwd()'}},
 \ ])
 
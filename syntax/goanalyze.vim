@@ -1,5 +1,5 @@
 syn match       goOperator        /[+%<>!&|^*=]=\?/
-syn match       goFoldIcon        /[▼-]=\?/
+syn match       goFoldIcon        /^\s*[▼▶-]/
 syn keyword     goAstViewKind     ast
 syn keyword     goAstViewKind     File    Ident   Decl    GenDecl    BasicLit   Spec
 syn keyword     goAstViewKind     ImportSpec    FuncDecl    BlockStmt   Stmt    ExprStmt
@@ -8,7 +8,26 @@ syn keyword     goAstViewKind     KeyValueExpr    Object    FucnType    FieldLis
 syn keyword     goAstViewKind     StarExpr    StructType    AssignStmt    Expr    IfStmt
 syn keyword     goAstViewKind     BinaryExpr    DeclStmt    ValueSpec    ArrayType    RangeStmt
 syn keyword     goAstViewKind     CaseClause    ReturnStmt    Scope
+syn keyword     goAstViewKind     FuncType    FuncLit   InterfaceType   MapType   ChanType    Ellipsis
+syn keyword     goAstViewKind     ParenExpr   IndexExpr   SliceExpr   TypeAssertExpr    KeyValueExpr
+syn keyword     goAstViewKind     IncDecStmt    GoStmt    DeferStmt   BranchStmt    ForStmt   LabeledStmt
+syn keyword     goAstViewKind     SwitchStmt    TypeSwitchStmt    SelectStmt    CommClause    SendStmt
+syn keyword     goAstViewKind     TypeSpec    Comment   CommentGroup    BadExpr   BadStmt   BadDecl
+syn keyword     goAstViewType     types   constant
+syn match       goAstViewPos      /\[\d\+:\d\+-\d\+:\d\+\]/
+syn match       goCallHierarchyPos    /\s\s\S\+:\d\+\( (cycle)\)\=$/ contains=goCallHierarchyCycle
+syn match       goCallHierarchyCycle  /(cycle)$/ contained
+syn match       goCallHierarchyDynamic  /(dynamic)/
+syn match       goTypeHierarchyGroup    /^\s*[▼▶-] \(embedded\|implements\|implemented by\)$/ contains=goFoldIcon
 
 hi def link     goOperator        Operator
 hi def link     goFoldIcon        Statement
 hi def link     goAstViewKind     Identifier
+hi def link     goAstViewType     Type
+hi def link     goAstViewPos      Comment
+hi def link     goCallHierarchyPos      Comment
+hi def link     goCallHierarchyCycle    WarningMsg
+hi def link     goCallHierarchyDynamic  Special
+hi def link     goTypeHierarchyGroup    Title
+hi def link     goAnalyzeCurrent  Search
+hi def link     goAstViewRange    Visual
@@ -0,0 +1,31 @@
+" Copyright 2018 The nvim-go Authors. All rights reserved.
+" Use of this source code is governed by a BSD-style
+" license that can be found in the LICENSE file.
+
+" ----------------------------------------------------------------------------
+" initialize
+
+if exists("b:current_syntax")
+  finish
+endif
+
+" ----------------------------------------------------------------------------
+" set syntax highlight
+
+syn match       goDocPackage      /^package \w\+/
+syn match       goDocImport       /\/\/ import ".*"$/
+syn match       goDocDecl         /^\(func\|type\|var\|const\)\>.*$/ contains=goDocKeyword
+syn keyword     goDocKeyword      func type var const struct interface contained
+syn match       goDocCode         /^    .*$/
+syn match       goDocSection      /^\(INDEX\|EXAMPLES\|CONSTANTS\|VARIABLES\|FUNCTIONS\|TYPES\)$/
+syn match       goDocExample      /^Example .*$/
+
+hi def link     goDocPackage      Include
+hi def link     goDocImport       Comment
+hi def link     goDocDecl         Identifier
+hi def link     goDocKeyword      Keyword
+hi def link     goDocCode         String
+hi def link     goDocSection      Title
+hi def link     goDocExample      Statement
+
+let b:current_syntax = "godoc"